@@ -16,6 +16,8 @@ type Config struct {
 	ExcludeNics          string  `json:"exclude_nics" env:"AGENT_EXCLUDE_NICS"`                       // 统计时排除的网卡，逗号分隔的网卡名称列表，支持通配符
 	IncludeMountpoints   string  `json:"include_mountpoints" env:"AGENT_INCLUDE_MOUNTPOINTS"`         // 磁盘统计的包含挂载点列表，使用分号分隔
 	MonthRotate          int     `json:"month_rotate" env:"AGENT_MONTH_ROTATE"`                       // 流量统计的月份重置日期（0表示禁用）
+	OfflineBufferSize    int     `json:"offline_buffer_size" env:"AGENT_OFFLINE_BUFFER_SIZE"`         // 断线期间最多缓存的上报条数（0表示禁用）
+	OfflineBufferFile    string  `json:"offline_buffer_file" env:"AGENT_OFFLINE_BUFFER_FILE"`         // 断线缓存的持久化文件，为空时仅保存在内存中
 	CFAccessClientID     string  `json:"cf_access_client_id" env:"AGENT_CF_ACCESS_CLIENT_ID"`         // Cloudflare Access Client ID
 	CFAccessClientSecret string  `json:"cf_access_client_secret" env:"AGENT_CF_ACCESS_CLIENT_SECRET"` // Cloudflare Access Client Secret
 	MemoryIncludeCache   bool    `json:"memory_include_cache" env:"AGENT_MEMORY_INCLUDE_CACHE"`       // 包括缓存/缓冲区的内存使用情况
@@ -26,6 +28,7 @@ type Config struct {
 	CustomIpv6           string  `json:"custom_ipv6" env:"AGENT_CUSTOM_IPV6"`                         // 自定义 IPv6 地址
 	GetIpAddrFromNic     bool    `json:"get_ip_addr_from_nic" env:"AGENT_GET_IP_ADDR_FROM_NIC"`       // 从网卡获取IP地址
 	ConfigFile           string  `json:"config_file" env:"AGENT_CONFIG_FILE"`                         // JSON配置文件路径
+	FileTransferRoot     string  `json:"file_transfer_root" env:"AGENT_FILE_TRANSFER_ROOT"`           // 文件传输允许访问的根目录，为空时不限制
 }
 
 var GlobalConfig = &Config{}
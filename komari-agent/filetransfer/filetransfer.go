@@ -0,0 +1,427 @@
+package filetransfer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	pkg_flags "github.com/komari-monitor/komari-agent/cmd/flags"
+)
+
+var flags = pkg_flags.GlobalConfig
+
+const (
+	// 下载时每个 binary 帧的大小
+	chunkSize = 64 * 1024
+	// 未完成上传的临时文件后缀，续传时在此文件上追加
+	partSuffix = ".komari-part"
+)
+
+// Request 面板发来的请求，文本帧
+type Request struct {
+	ID        int64  `json:"id"`
+	Op        string `json:"op"`
+	Path      string `json:"path"`
+	Target    string `json:"target,omitempty"`    // rename 的目标路径
+	Offset    int64  `json:"offset,omitempty"`    // 上传/下载的起始位置，用于断点续传
+	Size      int64  `json:"size,omitempty"`      // 上传的文件总大小
+	Mode      string `json:"mode,omitempty"`      // 上传文件的权限，八进制，例如 0644
+	Recursive bool   `json:"recursive,omitempty"` // remove 时删除非空目录
+}
+
+// Response 对请求的应答，文本帧
+type Response struct {
+	ID      int64   `json:"id"`
+	Op      string  `json:"op"`
+	OK      bool    `json:"ok"`
+	Error   string  `json:"error,omitempty"`
+	Path    string  `json:"path,omitempty"`
+	Entries []Entry `json:"entries,omitempty"`
+	Entry   *Entry  `json:"entry,omitempty"`
+	Offset  int64   `json:"offset,omitempty"`
+	Size    int64   `json:"size,omitempty"`
+	Partial int64   `json:"partial,omitempty"` // 未完成上传已写入的字节数
+	Done    bool    `json:"done,omitempty"`
+}
+
+// Entry 文件或目录信息
+type Entry struct {
+	Name    string    `json:"name"`
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	Mode    string    `json:"mode"`
+	ModTime time.Time `json:"mod_time"`
+	IsDir   bool      `json:"is_dir"`
+	IsLink  bool      `json:"is_link"`
+}
+
+type session struct {
+	conn    *websocket.Conn
+	root    string // 允许访问的根目录（已解析符号链接），为空时不限制
+	maxSize int64
+	// 上传过程中读到的下一条请求，交回主循环处理
+	pending *Request
+}
+
+// Serve 处理一个文件传输会话，maxSize 为单个文件大小上限（字节），0 表示不限制
+func Serve(conn *websocket.Conn, maxSize int64) {
+	defer conn.Close()
+	if flags.DisableWebSsh {
+		conn.WriteJSON(Response{Op: "error", Error: "File transfer is disabled. Enable it by running without the --disable-web-ssh flag."})
+		return
+	}
+	s := &session{conn: conn, maxSize: maxSize}
+	if flags.FileTransferRoot != "" {
+		root, err := filepath.EvalSymlinks(flags.FileTransferRoot)
+		if err == nil {
+			root, err = filepath.Abs(root)
+		}
+		if err != nil {
+			conn.WriteJSON(Response{Op: "error", Error: fmt.Sprintf("invalid file transfer root: %v", err)})
+			return
+		}
+		s.root = root
+	}
+	for {
+		var req Request
+		if s.pending != nil {
+			req, s.pending = *s.pending, nil
+		} else {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			// 没有进行中的上传时忽略数据帧
+			if messageType != websocket.TextMessage {
+				continue
+			}
+			if err := json.Unmarshal(data, &req); err != nil {
+				continue
+			}
+		}
+		if err := s.handle(req); err != nil {
+			return
+		}
+	}
+}
+
+// handle 处理单个请求，仅在连接出错时返回 error
+func (s *session) handle(req Request) error {
+	resp := Response{ID: req.ID, Op: req.Op}
+	path, err := s.resolve(req.Path)
+	if err != nil {
+		return s.fail(resp, err)
+	}
+	resp.Path = path
+	switch req.Op {
+	case "list":
+		entries, err := list(path)
+		if err != nil {
+			return s.fail(resp, err)
+		}
+		resp.OK, resp.Entries = true, entries
+	case "stat":
+		entry, err := stat(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return s.fail(resp, err)
+		}
+		if info, perr := os.Lstat(path + partSuffix); perr == nil && info.Mode().IsRegular() {
+			resp.Partial = info.Size()
+		}
+		if entry == nil && resp.Partial == 0 {
+			return s.fail(resp, err)
+		}
+		resp.OK, resp.Entry = true, entry
+	case "download":
+		return s.download(req, resp, path)
+	case "upload":
+		return s.upload(req, resp, path)
+	case "mkdir":
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return s.fail(resp, err)
+		}
+		resp.OK = true
+	case "remove":
+		if path == s.root || path == filepath.Dir(path) {
+			return s.fail(resp, fmt.Errorf("refusing to remove %s", path))
+		}
+		if req.Recursive {
+			err = os.RemoveAll(path)
+		} else {
+			err = os.Remove(path)
+		}
+		if err != nil {
+			return s.fail(resp, err)
+		}
+		resp.OK = true
+	case "rename":
+		target, err := s.resolve(req.Target)
+		if err != nil {
+			return s.fail(resp, err)
+		}
+		if err := os.Rename(path, target); err != nil {
+			return s.fail(resp, err)
+		}
+		resp.OK, resp.Path = true, target
+	default:
+		return s.fail(resp, fmt.Errorf("unsupported operation: %s", req.Op))
+	}
+	return s.conn.WriteJSON(resp)
+}
+
+func (s *session) fail(resp Response, err error) error {
+	resp.OK = false
+	if err == nil {
+		err = os.ErrNotExist
+	}
+	resp.Error = err.Error()
+	return s.conn.WriteJSON(resp)
+}
+
+func (s *session) download(req Request, resp Response, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return s.fail(resp, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return s.fail(resp, err)
+	}
+	if info.IsDir() {
+		return s.fail(resp, fmt.Errorf("%s is a directory", path))
+	}
+	if s.maxSize > 0 && info.Size() > s.maxSize {
+		return s.fail(resp, fmt.Errorf("file exceeds size limit of %d bytes", s.maxSize))
+	}
+	if req.Offset < 0 || req.Offset > info.Size() {
+		return s.fail(resp, fmt.Errorf("invalid offset %d", req.Offset))
+	}
+	if _, err := f.Seek(req.Offset, io.SeekStart); err != nil {
+		return s.fail(resp, err)
+	}
+	resp.OK, resp.Size, resp.Offset = true, info.Size(), req.Offset
+	if err := s.conn.WriteJSON(resp); err != nil {
+		return err
+	}
+	buf := make([]byte, chunkSize)
+	sent := req.Offset
+	for sent < info.Size() {
+		n, err := f.Read(buf)
+		if n > 0 {
+			if werr := s.conn.WriteMessage(websocket.BinaryMessage, buf[:n]); werr != nil {
+				return werr
+			}
+			sent += int64(n)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			resp.Done = true
+			return s.fail(resp, err)
+		}
+	}
+	resp.Done, resp.Offset = true, sent
+	return s.conn.WriteJSON(resp)
+}
+
+func (s *session) upload(req Request, resp Response, path string) error {
+	if req.Size < 0 {
+		return s.fail(resp, fmt.Errorf("invalid size %d", req.Size))
+	}
+	if s.maxSize > 0 && req.Size > s.maxSize {
+		return s.fail(resp, fmt.Errorf("file exceeds size limit of %d bytes", s.maxSize))
+	}
+	mode := os.FileMode(0644)
+	if req.Mode != "" {
+		m, err := strconv.ParseUint(req.Mode, 8, 32)
+		if err != nil || m > 0777 {
+			return s.fail(resp, fmt.Errorf("invalid mode %q", req.Mode))
+		}
+		mode = os.FileMode(m)
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return s.fail(resp, fmt.Errorf("%s is a directory", path))
+	}
+	// 临时文件同样需要检查，并且不跟随符号链接，避免预先放置的链接把写入引到根目录之外
+	part, err := s.resolve(path + partSuffix)
+	if err != nil {
+		return s.fail(resp, err)
+	}
+	flag := os.O_WRONLY | os.O_CREATE | oNoFollow
+	if req.Offset == 0 {
+		flag |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(part, flag, 0600)
+	if err != nil {
+		return s.fail(resp, err)
+	}
+	defer f.Close()
+	// 续传时偏移必须与已写入的大小一致
+	info, err := f.Stat()
+	if err != nil {
+		return s.fail(resp, err)
+	}
+	if info.Size() != req.Offset || req.Offset > req.Size {
+		resp.Partial = info.Size()
+		return s.fail(resp, fmt.Errorf("offset mismatch, %d bytes already uploaded", info.Size()))
+	}
+	if _, err := f.Seek(req.Offset, io.SeekStart); err != nil {
+		return s.fail(resp, err)
+	}
+	resp.OK, resp.Size, resp.Offset = true, req.Size, req.Offset
+	if err := s.conn.WriteJSON(resp); err != nil {
+		return err
+	}
+
+	received := req.Offset
+	for received < req.Size {
+		messageType, data, err := s.conn.ReadMessage()
+		if err != nil {
+			// 连接断开时保留临时文件以便续传
+			return err
+		}
+		if messageType == websocket.TextMessage {
+			// 上传被新的请求打断，已写入部分保留以便续传
+			var next Request
+			if json.Unmarshal(data, &next) == nil {
+				s.pending = &next
+			}
+			resp.OK, resp.Partial = false, received
+			resp.Error = "upload interrupted"
+			return s.conn.WriteJSON(resp)
+		}
+		if int64(len(data)) > req.Size-received {
+			f.Close()
+			os.Remove(part)
+			return s.fail(resp, fmt.Errorf("received more data than declared size"))
+		}
+		if _, err := f.Write(data); err != nil {
+			resp.Partial = received
+			return s.fail(resp, err)
+		}
+		received += int64(len(data))
+	}
+	if err := f.Chmod(mode); err != nil {
+		return s.fail(resp, err)
+	}
+	if err := f.Close(); err != nil {
+		return s.fail(resp, err)
+	}
+	if err := os.Rename(part, path); err != nil {
+		return s.fail(resp, err)
+	}
+	resp.OK, resp.Done, resp.Offset = true, true, received
+	return s.conn.WriteJSON(resp)
+}
+
+// resolve 将请求路径转换为绝对路径，设置了根目录时拒绝越界访问（包括经由符号链接）
+func (s *session) resolve(p string) (string, error) {
+	base := s.root
+	if base == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			base = home
+		} else {
+			base = string(filepath.Separator)
+		}
+	}
+	if p == "" {
+		p = base
+	} else if !filepath.IsAbs(p) {
+		p = filepath.Join(base, p)
+	}
+	p = filepath.Clean(p)
+	if s.root == "" {
+		return p, nil
+	}
+	real, err := evalExisting(p)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(s.root, real)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %s is outside of %s", p, s.root)
+	}
+	return p, nil
+}
+
+// evalExisting 解析路径中已存在部分的符号链接，不存在的部分原样拼接
+func evalExisting(p string) (string, error) {
+	rest := ""
+	for {
+		real, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			return filepath.Join(real, rest), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return filepath.Join(p, rest), nil
+		}
+		rest = filepath.Join(filepath.Base(p), rest)
+		p = parent
+	}
+}
+
+func list(dir string) ([]Entry, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(dirEntries))
+	for _, de := range dirEntries {
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, toEntry(filepath.Join(dir, de.Name()), info))
+	}
+	// 目录在前，按名称排序
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].IsDir != entries[j].IsDir {
+			return entries[i].IsDir
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+func stat(path string) (*Entry, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	entry := toEntry(path, info)
+	return &entry, nil
+}
+
+func toEntry(path string, info os.FileInfo) Entry {
+	entry := Entry{
+		Name:    info.Name(),
+		Path:    path,
+		Size:    info.Size(),
+		Mode:    info.Mode().String(),
+		ModTime: info.ModTime(),
+		IsDir:   info.IsDir(),
+		IsLink:  info.Mode()&os.ModeSymlink != 0,
+	}
+	// 指向目录的符号链接可以继续浏览
+	if entry.IsLink {
+		if target, err := os.Stat(path); err == nil {
+			entry.IsDir = target.IsDir()
+		}
+	}
+	return entry
+}
@@ -0,0 +1,155 @@
+package filetransfer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func startServer(t *testing.T, root string, maxSize int64) *websocket.Conn {
+	t.Helper()
+	flags.FileTransferRoot = root
+	t.Cleanup(func() { flags.FileTransferRoot = "" })
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		Serve(conn, maxSize)
+	}))
+	t.Cleanup(srv.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func call(t *testing.T, conn *websocket.Conn, req Request) Response {
+	t.Helper()
+	if err := conn.WriteJSON(req); err != nil {
+		t.Fatal(err)
+	}
+	var resp Response
+	if err := conn.ReadJSON(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.ID != req.ID {
+		t.Fatalf("expected response %d, got %d", req.ID, resp.ID)
+	}
+	return resp
+}
+
+func TestUploadResumeAndDownload(t *testing.T) {
+	root := t.TempDir()
+	conn := startServer(t, root, 1024)
+
+	// 第一次上传中途被新请求打断
+	if resp := call(t, conn, Request{ID: 1, Op: "upload", Path: "a.txt", Size: 10}); !resp.OK {
+		t.Fatalf("upload rejected: %s", resp.Error)
+	}
+	conn.WriteMessage(websocket.BinaryMessage, []byte("hello"))
+	conn.WriteJSON(Request{ID: 2, Op: "stat", Path: "a.txt"})
+	var resp Response
+	if err := conn.ReadJSON(&resp); err != nil || resp.ID != 1 || resp.OK || resp.Partial != 5 {
+		t.Fatalf("expected interrupted upload with 5 bytes, got %+v, %v", resp, err)
+	}
+	// 打断上传的请求随后被处理
+	if err := conn.ReadJSON(&resp); err != nil || resp.ID != 2 || resp.Partial != 5 {
+		t.Fatalf("unexpected stat response %+v, %v", resp, err)
+	}
+
+	if resp := call(t, conn, Request{ID: 3, Op: "upload", Path: "a.txt", Size: 10, Offset: 3}); resp.OK || resp.Partial != 5 {
+		t.Fatalf("expected offset mismatch, got %+v", resp)
+	}
+	if resp := call(t, conn, Request{ID: 4, Op: "upload", Path: "a.txt", Size: 10, Offset: 5, Mode: "0600"}); !resp.OK {
+		t.Fatalf("resume rejected: %s", resp.Error)
+	}
+	conn.WriteMessage(websocket.BinaryMessage, []byte("world"))
+	if err := conn.ReadJSON(&resp); err != nil || !resp.Done || resp.Offset != 10 {
+		t.Fatalf("unexpected upload result %+v, %v", resp, err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "a.txt"))
+	if err != nil || string(data) != "helloworld" {
+		t.Fatalf("unexpected file content %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "a.txt"+partSuffix)); !os.IsNotExist(err) {
+		t.Fatal("part file should be renamed")
+	}
+
+	if resp := call(t, conn, Request{ID: 5, Op: "download", Path: "a.txt", Offset: 5}); !resp.OK || resp.Size != 10 {
+		t.Fatalf("download rejected: %+v", resp)
+	}
+	_, chunk, err := conn.ReadMessage()
+	if err != nil || string(chunk) != "world" {
+		t.Fatalf("unexpected chunk %q, %v", chunk, err)
+	}
+	if err := conn.ReadJSON(&resp); err != nil || !resp.Done {
+		t.Fatalf("unexpected download result %+v, %v", resp, err)
+	}
+
+	if resp := call(t, conn, Request{ID: 6, Op: "upload", Path: "big", Size: 2048}); resp.OK {
+		t.Fatal("upload over size limit should be rejected")
+	}
+
+	call(t, conn, Request{ID: 7, Op: "mkdir", Path: "dir"})
+	resp = call(t, conn, Request{ID: 8, Op: "list"})
+	if !resp.OK || len(resp.Entries) != 2 || resp.Entries[0].Name != "dir" || !resp.Entries[0].IsDir {
+		t.Fatalf("unexpected listing %+v", resp)
+	}
+}
+
+func TestRootRestriction(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Skip("symlink not supported:", err)
+	}
+	conn := startServer(t, root, 0)
+	for i, p := range []string{"../", "/etc/passwd", "escape/file", filepath.Join(root, "..")} {
+		if resp := call(t, conn, Request{ID: int64(i), Op: "stat", Path: p}); resp.OK {
+			t.Errorf("%s should be rejected", p)
+		}
+	}
+	if resp := call(t, conn, Request{ID: 9, Op: "remove", Path: ""}); resp.OK {
+		t.Error("removing the root should be rejected")
+	}
+}
+
+func TestUploadPartSymlink(t *testing.T) {
+	root := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "target")
+	if err := os.Symlink(outside, filepath.Join(root, "a.txt"+partSuffix)); err != nil {
+		t.Skip("symlink not supported:", err)
+	}
+	conn := startServer(t, root, 0)
+	if resp := call(t, conn, Request{ID: 1, Op: "upload", Path: "a.txt", Size: 5}); resp.OK {
+		t.Fatal("upload through a symlinked part file should be rejected")
+	}
+	if _, err := os.Lstat(outside); !os.IsNotExist(err) {
+		t.Fatal("file outside of root should not be created")
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	// 指向根目录内的链接也不跟随
+	inside := filepath.Join(root, "inside")
+	if err := os.Symlink(inside, filepath.Join(root, "b.txt"+partSuffix)); err != nil {
+		t.Fatal(err)
+	}
+	if resp := call(t, conn, Request{ID: 2, Op: "upload", Path: "b.txt", Size: 5}); resp.OK {
+		t.Fatal("symlinked part file should not be followed")
+	}
+	if _, err := os.Lstat(inside); !os.IsNotExist(err) {
+		t.Fatal("symlink target should not be created")
+	}
+}
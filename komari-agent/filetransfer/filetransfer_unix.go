@@ -0,0 +1,8 @@
+//go:build !windows
+
+package filetransfer
+
+import "syscall"
+
+// oNoFollow 打开上传临时文件时不跟随符号链接
+const oNoFollow = syscall.O_NOFOLLOW
@@ -0,0 +1,6 @@
+//go:build windows
+
+package filetransfer
+
+// oNoFollow Windows 没有对应的打开标志，依靠 resolve 检查临时文件路径
+const oNoFollow = 0
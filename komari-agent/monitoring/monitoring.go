@@ -47,6 +47,12 @@ func GenerateReport() []byte {
 		"total": disk.Total,
 		"used":  disk.Used,
 	}
+	if mounts := monitoring.Mounts(); len(mounts) > 0 {
+		data["mounts"] = mounts
+	}
+	if diskIO := monitoring.DiskIO(); len(diskIO) > 0 {
+		data["disk_io"] = diskIO
+	}
 
 	totalUp, totalDown, networkUp, networkDown, err := monitoring.NetworkSpeed()
 	if err != nil {
@@ -58,6 +64,9 @@ func GenerateReport() []byte {
 		"totalUp":   totalUp,
 		"totalDown": totalDown,
 	}
+	if interfaces := monitoring.NetworkInterfaces(); len(interfaces) > 0 {
+		data["interfaces"] = interfaces
+	}
 
 	tcpCount, udpCount, err := monitoring.ConnectionsCount()
 	if err != nil {
@@ -117,6 +126,11 @@ func GenerateReport() []byte {
 	}
 	// 基础模式下，GPU信息已在basicInfo中处理
 
+	temperature := monitoring.Temperatures()
+	if len(temperature.Sensors) > 0 {
+		data["temperature"] = temperature
+	}
+
 	data["message"] = message
 
 	s, err := json.Marshal(data)
@@ -0,0 +1,161 @@
+package monitoring
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// MountInfo 单个挂载点的容量与 inode 使用情况
+type MountInfo struct {
+	Mountpoint  string `json:"mountpoint"`
+	Device      string `json:"device"`
+	Fstype      string `json:"fstype"`
+	Total       uint64 `json:"total"`
+	Used        uint64 `json:"used"`
+	InodesTotal uint64 `json:"inodes_total"`
+	InodesUsed  uint64 `json:"inodes_used"`
+}
+
+// DiskIOInfo 单个块设备的吞吐与 IOPS，为两次采样之间的平均值
+type DiskIOInfo struct {
+	Device     string  `json:"device"`
+	ReadBytes  uint64  `json:"read_bytes"`  // 读取速率(字节/秒)
+	WriteBytes uint64  `json:"write_bytes"` // 写入速率(字节/秒)
+	ReadIOPS   float64 `json:"read_iops"`
+	WriteIOPS  float64 `json:"write_iops"`
+}
+
+// Mounts 返回各挂载点的使用情况，挂载点的选取规则与 Disk() 一致
+func Mounts() []MountInfo {
+	parts, err := disk.Partitions(true)
+	if err != nil {
+		return nil
+	}
+	var selected []disk.PartitionStat
+	if flags.IncludeMountpoints != "" {
+		known := make(map[string]disk.PartitionStat, len(parts))
+		for _, part := range parts {
+			known[part.Mountpoint] = part
+		}
+		for _, mountpoint := range strings.Split(flags.IncludeMountpoints, ";") {
+			mountpoint = strings.TrimSpace(mountpoint)
+			if mountpoint == "" {
+				continue
+			}
+			part, ok := known[mountpoint]
+			if !ok {
+				part = disk.PartitionStat{Mountpoint: mountpoint}
+			}
+			selected = append(selected, part)
+		}
+	} else {
+		for _, part := range parts {
+			if isPhysicalDisk(part) {
+				selected = append(selected, part)
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	mounts := make([]MountInfo, 0, len(selected))
+	for _, part := range selected {
+		if seen[part.Mountpoint] {
+			continue
+		}
+		seen[part.Mountpoint] = true
+		u, err := disk.Usage(part.Mountpoint)
+		if err != nil || u.Total == 0 {
+			continue
+		}
+		fstype := part.Fstype
+		if fstype == "" {
+			fstype = u.Fstype
+		}
+		mounts = append(mounts, MountInfo{
+			Mountpoint:  part.Mountpoint,
+			Device:      part.Device,
+			Fstype:      fstype,
+			Total:       u.Total,
+			Used:        u.Used,
+			InodesTotal: u.InodesTotal,
+			InodesUsed:  u.InodesUsed,
+		})
+	}
+	return mounts
+}
+
+var (
+	diskIOMu       sync.Mutex
+	lastDiskIO     map[string]disk.IOCountersStat
+	lastDiskIOTime time.Time
+)
+
+// DiskIO 与上一次调用的计数器比较得出各块设备的速率，首次调用只记录基准并返回空
+func DiskIO() []DiskIOInfo {
+	counters, err := disk.IOCounters()
+	if err != nil {
+		return nil
+	}
+	now := time.Now()
+
+	diskIOMu.Lock()
+	defer diskIOMu.Unlock()
+	prev, prevTime := lastDiskIO, lastDiskIOTime
+	lastDiskIO, lastDiskIOTime = counters, now
+	if prev == nil {
+		return nil
+	}
+	elapsed := now.Sub(prevTime).Seconds()
+	if elapsed <= 0 {
+		return nil
+	}
+
+	result := make([]DiskIOInfo, 0, len(counters))
+	for name, cur := range counters {
+		if !isWholeBlockDevice(name) {
+			continue
+		}
+		old, ok := prev[name]
+		if !ok || cur.ReadCount+cur.WriteCount == 0 {
+			continue
+		}
+		result = append(result, DiskIOInfo{
+			Device:     name,
+			ReadBytes:  uint64(float64(counterDelta(cur.ReadBytes, old.ReadBytes)) / elapsed),
+			WriteBytes: uint64(float64(counterDelta(cur.WriteBytes, old.WriteBytes)) / elapsed),
+			ReadIOPS:   float64(counterDelta(cur.ReadCount, old.ReadCount)) / elapsed,
+			WriteIOPS:  float64(counterDelta(cur.WriteCount, old.WriteCount)) / elapsed,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Device < result[j].Device })
+	return result
+}
+
+// counterDelta 计数器回绕或设备重新挂载时视为 0
+func counterDelta(cur, old uint64) uint64 {
+	if cur < old {
+		return 0
+	}
+	return cur - old
+}
+
+// isWholeBlockDevice Linux 下仅统计整块设备，避免分区与所在磁盘重复计算，并排除 loop/ram 等虚拟设备
+func isWholeBlockDevice(name string) bool {
+	if runtime.GOOS != "linux" {
+		return true
+	}
+	for _, prefix := range []string{"loop", "ram", "zram", "sr", "fd"} {
+		if strings.HasPrefix(name, prefix) {
+			return false
+		}
+	}
+	_, err := os.Stat(filepath.Join("/sys/block", name))
+	return err == nil
+}
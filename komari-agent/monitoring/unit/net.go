@@ -2,7 +2,9 @@ package monitoring
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/komari-monitor/komari-agent/monitoring/netstatic"
@@ -38,6 +40,27 @@ var (
 	}
 )
 
+// InterfaceInfo 单个网卡的实时速率与自启动以来的累计流量
+type InterfaceInfo struct {
+	Name      string `json:"name"`
+	Up        uint64 `json:"up"`        // 发送速率(字节/秒)
+	Down      uint64 `json:"down"`      // 接收速率(字节/秒)
+	TotalUp   uint64 `json:"totalUp"`   // 累计发送(字节)，系统计数器
+	TotalDown uint64 `json:"totalDown"` // 累计接收(字节)，系统计数器
+}
+
+var (
+	interfacesMu   sync.Mutex
+	lastInterfaces []InterfaceInfo
+)
+
+// NetworkInterfaces 返回最近一次 NetworkSpeed 采样得到的各网卡数据，网卡过滤规则与汇总值一致
+func NetworkInterfaces() []InterfaceInfo {
+	interfacesMu.Lock()
+	defer interfacesMu.Unlock()
+	return append([]InterfaceInfo(nil), lastInterfaces...)
+}
+
 // VnstatInterface represents a network interface in vnstat output
 type VnstatInterface struct {
 	Name    string        `json:"name"`
@@ -198,13 +221,32 @@ func getNetworkSpeedFallback(includeNics, excludeNics map[string]struct{}) (tota
 	}
 
 	// 统计第二次所有非回环接口的流量
+	first := make(map[string]net.IOCountersStat, len(ioCounters1))
+	for _, interfaceStats := range ioCounters1 {
+		first[interfaceStats.Name] = interfaceStats
+	}
 	var totalUp2, totalDown2 uint64
+	interfaces := make([]InterfaceInfo, 0, len(ioCounters2))
 	for _, interfaceStats := range ioCounters2 {
 		if shouldInclude(interfaceStats.Name, includeNics, excludeNics) {
 			totalUp2 += interfaceStats.BytesSent
 			totalDown2 += interfaceStats.BytesRecv
+			info := InterfaceInfo{
+				Name:      interfaceStats.Name,
+				TotalUp:   interfaceStats.BytesSent,
+				TotalDown: interfaceStats.BytesRecv,
+			}
+			if prev, ok := first[interfaceStats.Name]; ok {
+				info.Up = counterDelta(interfaceStats.BytesSent, prev.BytesSent)
+				info.Down = counterDelta(interfaceStats.BytesRecv, prev.BytesRecv)
+			}
+			interfaces = append(interfaces, info)
 		}
 	}
+	sort.Slice(interfaces, func(i, j int) bool { return interfaces[i].Name < interfaces[j].Name })
+	interfacesMu.Lock()
+	lastInterfaces = interfaces
+	interfacesMu.Unlock()
 
 	// 计算速度 (每秒的速率)
 	upSpeed = totalUp2 - totalUp1
@@ -0,0 +1,77 @@
+package monitoring
+
+import (
+	"strconv"
+	"strings"
+)
+
+// TemperatureSensor 单个温度传感器读数
+type TemperatureSensor struct {
+	Name        string  `json:"name"`
+	Temperature float64 `json:"temperature"` // 摄氏度
+}
+
+// TemperatureInfo 温度汇总，Package 为 CPU 封装温度，无法识别时为 0
+type TemperatureInfo struct {
+	Package float64             `json:"package"`
+	Max     float64             `json:"max"`
+	Sensors []TemperatureSensor `json:"sensors"`
+}
+
+// 超出该范围的读数视为传感器未接入或数据异常
+const (
+	minValidTemperature = -40
+	maxValidTemperature = 150
+)
+
+// CPU 封装温度的常见传感器名称，按优先级排列
+var packageSensorHints = []string{
+	"package id", // Intel coretemp
+	"tctl",       // AMD k10temp
+	"tdie",
+	"x86_pkg_temp",
+	"cpu_thermal", // 树莓派等 ARM 设备
+	"cpu-thermal",
+	"soc_thermal",
+	"soc-thermal",
+	"cpu",
+}
+
+// Temperatures 采集所有温度传感器并计算封装温度与最高温度
+func Temperatures() TemperatureInfo {
+	info := TemperatureInfo{Sensors: []TemperatureSensor{}}
+	seen := make(map[string]int)
+	for _, s := range readTemperatureSensors() {
+		if s.Temperature <= minValidTemperature || s.Temperature >= maxValidTemperature {
+			continue
+		}
+		// 同名传感器（如多块 NVMe）追加序号区分
+		if n := seen[s.Name]; n > 0 {
+			seen[s.Name] = n + 1
+			s.Name = s.Name + "#" + strconv.Itoa(n+1)
+		} else {
+			seen[s.Name] = 1
+		}
+		info.Sensors = append(info.Sensors, s)
+		if s.Temperature > info.Max {
+			info.Max = s.Temperature
+		}
+	}
+	info.Package = packageTemperature(info.Sensors)
+	return info
+}
+
+func packageTemperature(sensors []TemperatureSensor) float64 {
+	for _, hint := range packageSensorHints {
+		best := 0.0
+		for _, s := range sensors {
+			if strings.Contains(strings.ToLower(s.Name), hint) && s.Temperature > best {
+				best = s.Temperature
+			}
+		}
+		if best > 0 {
+			return best
+		}
+	}
+	return 0
+}
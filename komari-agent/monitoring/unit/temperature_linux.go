@@ -0,0 +1,82 @@
+//go:build linux
+// +build linux
+
+package monitoring
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// readTemperatureSensors 优先读取 hwmon（带芯片名与标签），没有时退回 thermal zone
+func readTemperatureSensors() []TemperatureSensor {
+	sensors := readHwmon()
+	if len(sensors) == 0 {
+		sensors = readThermalZones()
+	}
+	return sensors
+}
+
+func readHwmon() []TemperatureSensor {
+	var sensors []TemperatureSensor
+	chips, _ := filepath.Glob("/sys/class/hwmon/hwmon*")
+	sort.Strings(chips)
+	for _, chip := range chips {
+		name := readSysString(filepath.Join(chip, "name"))
+		if name == "" {
+			name = filepath.Base(chip)
+		}
+		inputs, _ := filepath.Glob(filepath.Join(chip, "temp*_input"))
+		sort.Strings(inputs)
+		for _, input := range inputs {
+			milli, ok := readSysInt(input)
+			if !ok {
+				continue
+			}
+			prefix := strings.TrimSuffix(filepath.Base(input), "_input")
+			label := readSysString(filepath.Join(chip, prefix+"_label"))
+			if label == "" {
+				label = prefix
+			}
+			sensors = append(sensors, TemperatureSensor{
+				Name:        name + "/" + label,
+				Temperature: float64(milli) / 1000,
+			})
+		}
+	}
+	return sensors
+}
+
+func readThermalZones() []TemperatureSensor {
+	var sensors []TemperatureSensor
+	zones, _ := filepath.Glob("/sys/class/thermal/thermal_zone*")
+	sort.Strings(zones)
+	for _, zone := range zones {
+		milli, ok := readSysInt(filepath.Join(zone, "temp"))
+		if !ok {
+			continue
+		}
+		name := readSysString(filepath.Join(zone, "type"))
+		if name == "" {
+			name = filepath.Base(zone)
+		}
+		sensors = append(sensors, TemperatureSensor{Name: name, Temperature: float64(milli) / 1000})
+	}
+	return sensors
+}
+
+func readSysString(path string) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func readSysInt(path string) (int64, bool) {
+	v, err := strconv.ParseInt(readSysString(path), 10, 64)
+	return v, err == nil
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+// +build !linux
+
+package monitoring
+
+import (
+	"github.com/shirou/gopsutil/v4/sensors"
+)
+
+func readTemperatureSensors() []TemperatureSensor {
+	stats, err := sensors.SensorsTemperatures()
+	if err != nil && len(stats) == 0 {
+		return nil
+	}
+	result := make([]TemperatureSensor, 0, len(stats))
+	for _, s := range stats {
+		result = append(result, TemperatureSensor{Name: s.SensorKey, Temperature: s.Temperature})
+	}
+	return result
+}
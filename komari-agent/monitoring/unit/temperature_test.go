@@ -0,0 +1,24 @@
+package monitoring
+
+import "testing"
+
+func TestPackageTemperature(t *testing.T) {
+	sensors := []TemperatureSensor{
+		{Name: "nvme/Composite", Temperature: 61},
+		{Name: "coretemp/Core 0", Temperature: 55},
+		{Name: "coretemp/Package id 0", Temperature: 58},
+		{Name: "coretemp/Package id 1", Temperature: 60},
+	}
+	if got := packageTemperature(sensors); got != 60 {
+		t.Fatalf("expected hottest package 60, got %v", got)
+	}
+
+	amd := []TemperatureSensor{{Name: "k10temp/Tctl", Temperature: 70}, {Name: "acpitz/temp1", Temperature: 40}}
+	if got := packageTemperature(amd); got != 70 {
+		t.Fatalf("expected Tctl 70, got %v", got)
+	}
+
+	if got := packageTemperature([]TemperatureSensor{{Name: "acpitz/temp1", Temperature: 40}}); got != 0 {
+		t.Fatalf("expected 0 without package sensor, got %v", got)
+	}
+}
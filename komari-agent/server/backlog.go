@@ -0,0 +1,178 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/komari-monitor/komari-agent/ws"
+)
+
+const (
+	// 断线期间每隔该时间缓存一次上报，服务端按分钟聚合，无需保留每一次采集
+	backlogSampleInterval = 15 * time.Second
+	// 每条补发消息携带的上报数量
+	backlogBatchSize = 60
+)
+
+// reportBacklog 断线期间缓存的上报，重连后按原始时间补发。
+// 超过容量时丢弃最旧的数据；配置了文件路径时同步写入磁盘，Agent 重启后仍可补发
+type reportBacklog struct {
+	mu       sync.Mutex
+	once     sync.Once
+	items    []json.RawMessage
+	lastAdd  time.Time
+	capacity int
+	path     string
+}
+
+var backlog = &reportBacklog{}
+
+func (b *reportBacklog) init() {
+	b.once.Do(func() {
+		b.capacity = flags.OfflineBufferSize
+		b.path = flags.OfflineBufferFile
+		if b.path == "" || b.capacity <= 0 {
+			return
+		}
+		f, err := os.Open(b.path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Println("Failed to open offline buffer file:", err)
+			}
+			return
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 || !json.Valid(line) {
+				continue
+			}
+			b.items = append(b.items, append(json.RawMessage(nil), line...))
+		}
+		b.trimLocked()
+		if len(b.items) > 0 {
+			log.Printf("Loaded %d buffered reports from %s", len(b.items), b.path)
+		}
+	})
+}
+
+// Add 缓存一条上报，并以 updated_at 记录采集时间
+func (b *reportBacklog) Add(report []byte, at time.Time) {
+	b.init()
+	if b.capacity <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if at.Sub(b.lastAdd) < backlogSampleInterval {
+		return
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(report, &data); err != nil {
+		return
+	}
+	data["updated_at"] = at.Format(time.RFC3339Nano)
+	item, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	b.lastAdd = at
+	b.items = append(b.items, item)
+	if b.trimLocked() {
+		b.rewriteLocked()
+	} else {
+		b.appendLocked(item)
+	}
+}
+
+// trimLocked 超出容量时一次丢弃最旧的 10%，避免每次写入都重写文件
+func (b *reportBacklog) trimLocked() bool {
+	if len(b.items) <= b.capacity {
+		return false
+	}
+	drop := len(b.items) - b.capacity + b.capacity/10
+	if drop > len(b.items) {
+		drop = len(b.items)
+	}
+	b.items = append([]json.RawMessage(nil), b.items[drop:]...)
+	return true
+}
+
+func (b *reportBacklog) appendLocked(item json.RawMessage) {
+	if b.path == "" {
+		return
+	}
+	f, err := os.OpenFile(b.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		log.Println("Failed to write offline buffer file:", err)
+		return
+	}
+	defer f.Close()
+	f.Write(append(item, '\n'))
+}
+
+func (b *reportBacklog) rewriteLocked() {
+	if b.path == "" {
+		return
+	}
+	if len(b.items) == 0 {
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			log.Println("Failed to remove offline buffer file:", err)
+		}
+		return
+	}
+	var buf bytes.Buffer
+	for _, item := range b.items {
+		buf.Write(item)
+		buf.WriteByte('\n')
+	}
+	tmp := b.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		log.Println("Failed to write offline buffer file:", err)
+		return
+	}
+	if err := os.Rename(tmp, b.path); err != nil {
+		log.Println("Failed to write offline buffer file:", err)
+	}
+}
+
+// Flush 分批补发缓存的上报，发送失败时保留剩余部分等待下次重连
+func (b *reportBacklog) Flush(conn *ws.SafeConn) error {
+	b.init()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.items) == 0 {
+		return nil
+	}
+	total := len(b.items)
+	var err error
+	for len(b.items) > 0 {
+		n := len(b.items)
+		if n > backlogBatchSize {
+			n = backlogBatchSize
+		}
+		err = conn.WriteJSON(map[string]interface{}{
+			"type":    "report_history",
+			"reports": b.items[:n],
+		})
+		if err != nil {
+			break
+		}
+		b.items = b.items[n:]
+	}
+	b.rewriteLocked()
+	if err != nil {
+		log.Printf("Failed to replay buffered reports, %d left: %v", len(b.items), err)
+		return err
+	}
+	log.Printf("Replayed %d buffered reports", total)
+	b.items = nil
+	return nil
+}
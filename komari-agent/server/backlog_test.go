@@ -0,0 +1,57 @@
+package server
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestBacklog(capacity int, path string) *reportBacklog {
+	b := &reportBacklog{capacity: capacity, path: path}
+	b.once.Do(func() {})
+	return b
+}
+
+func TestBacklogSampleAndTrim(t *testing.T) {
+	b := newTestBacklog(5, "")
+	start := time.Unix(1700000000, 0)
+	for i := 0; i < 30; i++ {
+		// 每 5 秒一次，仅每 15 秒保留一条
+		b.Add([]byte(`{"cpu":{"usage":1}}`), start.Add(time.Duration(i)*5*time.Second))
+	}
+	if len(b.items) != 5 {
+		t.Fatalf("expected 5 buffered reports, got %d", len(b.items))
+	}
+	var first struct {
+		UpdatedAt time.Time `json:"updated_at"`
+	}
+	if err := json.Unmarshal(b.items[0], &first); err != nil {
+		t.Fatal(err)
+	}
+	// 共采样 10 条，容量为 5 时保留最新的 5 条
+	if !first.UpdatedAt.Equal(start.Add(75 * time.Second)) {
+		t.Fatalf("unexpected updated_at: %v", first.UpdatedAt)
+	}
+}
+
+func TestBacklogPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backlog.jsonl")
+	b := newTestBacklog(100, path)
+	start := time.Unix(1700000000, 0)
+	for i := 0; i < 3; i++ {
+		b.Add([]byte(`{"uptime":1}`), start.Add(time.Duration(i)*time.Minute))
+	}
+
+	flags.OfflineBufferSize = 100
+	flags.OfflineBufferFile = path
+	defer func() {
+		flags.OfflineBufferSize = 0
+		flags.OfflineBufferFile = ""
+	}()
+	loaded := &reportBacklog{}
+	loaded.init()
+	if len(loaded.items) != 3 {
+		t.Fatalf("expected 3 reports loaded from file, got %d", len(loaded.items))
+	}
+}
@@ -39,6 +39,8 @@ type wsMessage struct {
 	TriggerKind  string `json:"trigger_kind,omitempty"`
 	TriggerName  string `json:"trigger_name,omitempty"`
 	TimeoutSec   int    `json:"timeout_sec,omitempty"`
+	MaxSize      int64  `json:"max_size,omitempty"`
+	TunnelPort   int    `json:"port,omitempty"`
 	Dependencies []struct {
 		ID         uint   `json:"id"`
 		Name       string `json:"name"`
@@ -5,6 +5,8 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log"
 	"math"
 	"net"
@@ -13,41 +15,75 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/komari-monitor/komari-agent/ws"
 	ping "github.com/prometheus-community/pro-bing"
 )
 
-func NewTask(task_id, command string) {
+const (
+	// 增量输出的推送间隔与单块上限
+	taskOutputFlushInterval = 500 * time.Millisecond
+	taskOutputChunkSize     = 4096
+	// 终止命令后等待输出管道关闭的时间，避免子进程占用管道导致无法返回
+	taskKillWaitDelay = 5 * time.Second
+)
+
+var (
+	execCancelMap = make(map[string]context.CancelFunc)
+	execCancelMu  sync.Mutex
+)
+
+// CancelTask 终止正在执行的命令，返回 false 表示任务不存在或已结束
+func CancelTask(taskID string) bool {
+	execCancelMu.Lock()
+	cancel, ok := execCancelMap[taskID]
+	execCancelMu.Unlock()
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// NewTask 执行远程命令，执行过程中通过 conn 推送增量输出，结束后上报完整结果。
+// timeoutSec 大于 0 时超时终止；被取消或超时时上报终止前的输出
+func NewTask(conn *ws.SafeConn, task_id, command string, timeoutSec int) {
 	if task_id == "" {
 		return
 	}
 	if command == "" {
-		uploadTaskResult(task_id, "No command provided", 0, time.Now())
+		uploadTaskResult(task_id, "No command provided", 0, time.Now(), "")
 		return
 	}
 	if flags.DisableWebSsh {
-		uploadTaskResult(task_id, "Remote control is disabled.", -1, time.Now())
+		uploadTaskResult(task_id, "Remote control is disabled.", -1, time.Now(), "")
 		return
 	}
 	log.Printf("Executing task %s with command: %s", task_id, command)
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		cmd = exec.Command("powershell", "-NoProfile", "-ExecutionPolicy", "Bypass", "-Command", "[Console]::OutputEncoding = [System.Text.Encoding]::UTF8; "+command)
-	} else {
-		cmd = exec.Command("sh", "-c", command)
-	}
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
+	ctx, cancel := context.WithCancel(context.Background())
+	if timeoutSec > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
+	}
+	defer cancel()
+	execCancelMu.Lock()
+	execCancelMap[task_id] = cancel
+	execCancelMu.Unlock()
+	defer func() {
+		execCancelMu.Lock()
+		delete(execCancelMap, task_id)
+		execCancelMu.Unlock()
+	}()
+
+	output := newTaskOutput(conn, task_id)
+	err := runTaskCommand(ctx, command, output.Writer("stdout"), output.Writer("stderr"))
 	finishedAt := time.Now()
+	output.Close()
 
-	result := stdout.String()
-	if stderr.Len() > 0 {
-		result += "\n" + stderr.String()
+	result := output.stdout.String()
+	if output.stderr.Len() > 0 {
+		result += "\n" + output.stderr.String()
 	}
 	result = strings.ReplaceAll(result, "\r\n", "\n")
 	exitCode := 0
@@ -57,16 +93,183 @@ func NewTask(task_id, command string) {
 		}
 	}
 
-	uploadTaskResult(task_id, result, exitCode, finishedAt)
+	status := ""
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		status = "timeout"
+		exitCode = -1
+		result += fmt.Sprintf("\n[Task timed out after %ds]", timeoutSec)
+	case context.Canceled:
+		status = "cancelled"
+		exitCode = -1
+		result += "\n[Task cancelled]"
+	}
+	if status != "" {
+		log.Printf("Task %s %s", task_id, status)
+	}
+
+	uploadTaskResult(task_id, result, exitCode, finishedAt, status)
+}
+
+// runTaskCommand 执行命令直到结束，ctx 取消时终止命令创建的整个进程树，而不只是外层 shell
+func runTaskCommand(ctx context.Context, command string, stdout, stderr io.Writer) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "powershell", "-NoProfile", "-ExecutionPolicy", "Bypass", "-Command", "[Console]::OutputEncoding = [System.Text.Encoding]::UTF8; "+command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.WaitDelay = taskKillWaitDelay
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	release, err := startTaskCmd(cmd)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return cmd.Wait()
+}
+
+// taskOutput 保存命令的完整输出，同时按时间间隔或块大小将新输出推送给服务端
+type taskOutput struct {
+	mu      sync.Mutex
+	conn    *ws.SafeConn
+	taskID  string
+	stdout  bytes.Buffer
+	stderr  bytes.Buffer
+	pending map[string]*bytes.Buffer
+	broken  bool
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newTaskOutput(conn *ws.SafeConn, taskID string) *taskOutput {
+	o := &taskOutput{
+		conn:    conn,
+		taskID:  taskID,
+		pending: map[string]*bytes.Buffer{"stdout": {}, "stderr": {}},
+		done:    make(chan struct{}),
+	}
+	if conn != nil {
+		o.wg.Add(1)
+		go o.loop()
+	}
+	return o
+}
+
+type taskOutputWriter struct {
+	o      *taskOutput
+	stream string
 }
 
-func uploadTaskResult(taskID, result string, exitCode int, finishedAt time.Time) {
+func (w taskOutputWriter) Write(p []byte) (int, error) {
+	o := w.o
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if w.stream == "stderr" {
+		o.stderr.Write(p)
+	} else {
+		o.stdout.Write(p)
+	}
+	if o.conn == nil || o.broken {
+		return len(p), nil
+	}
+	buf := o.pending[w.stream]
+	buf.Write(p)
+	if buf.Len() >= taskOutputChunkSize {
+		o.flushLocked(w.stream, false)
+	}
+	return len(p), nil
+}
+
+// Writer 返回写入指定输出流的 io.Writer
+func (o *taskOutput) Writer(stream string) taskOutputWriter {
+	return taskOutputWriter{o: o, stream: stream}
+}
+
+func (o *taskOutput) loop() {
+	defer o.wg.Done()
+	ticker := time.NewTicker(taskOutputFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			o.mu.Lock()
+			o.flushLocked("stdout", false)
+			o.flushLocked("stderr", false)
+			o.mu.Unlock()
+		case <-o.done:
+			return
+		}
+	}
+}
+
+// Close 停止定时推送并发送剩余输出
+func (o *taskOutput) Close() {
+	if o.conn == nil {
+		return
+	}
+	close(o.done)
+	o.wg.Wait()
+	o.mu.Lock()
+	o.flushLocked("stdout", true)
+	o.flushLocked("stderr", true)
+	o.mu.Unlock()
+}
+
+// flushLocked 推送待发送的输出。非最终推送时保留末尾不完整的 UTF-8 字符，留到下次发送
+func (o *taskOutput) flushLocked(stream string, final bool) {
+	buf := o.pending[stream]
+	if o.broken || buf.Len() == 0 {
+		return
+	}
+	data := buf.Bytes()
+	n := len(data)
+	if !final {
+		n = utf8PrefixLen(data)
+	}
+	if n == 0 {
+		return
+	}
+	err := o.conn.WriteJSON(map[string]interface{}{
+		"type":    "task_output",
+		"task_id": o.taskID,
+		"stream":  stream,
+		"data":    strings.ToValidUTF8(string(data[:n]), "\uFFFD"),
+		"time":    time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		// 连接断开后不再推送，完整输出仍会在结束后上报
+		o.broken = true
+		return
+	}
+	buf.Next(n)
+}
+
+// utf8PrefixLen 返回 b 中不包含末尾不完整字符的长度
+func utf8PrefixLen(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if utf8.FullRune(b[i:]) {
+				return len(b)
+			}
+			return i
+		}
+	}
+	return len(b)
+}
+
+// uploadTaskResult 上报任务结果，status 为 cancelled / timeout 时表示命令被终止
+func uploadTaskResult(taskID, result string, exitCode int, finishedAt time.Time, status string) {
 	payload := map[string]interface{}{
 		"task_id":     taskID,
 		"result":      result,
 		"exit_code":   exitCode,
 		"finished_at": finishedAt,
 	}
+	if status != "" {
+		payload["status"] = status
+	}
 
 	jsonData, _ := json.Marshal(payload)
 	endpoint := flags.Endpoint + "/api/clients/task/result?token=" + flags.Token
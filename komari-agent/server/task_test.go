@@ -60,3 +60,17 @@ func TestHTTPPing(t *testing.T) {
 		})
 	}
 }
+
+func TestUTF8PrefixLen(t *testing.T) {
+	s := []byte("ab中文")
+	if n := utf8PrefixLen(s); n != len(s) {
+		t.Fatalf("complete string: got %d, want %d", n, len(s))
+	}
+	// 截断最后一个字符的部分字节
+	if n := utf8PrefixLen(s[:len(s)-1]); n != 5 {
+		t.Fatalf("truncated string: got %d, want 5", n)
+	}
+	if n := utf8PrefixLen(s[:3]); n != 2 {
+		t.Fatalf("truncated first rune: got %d, want 2", n)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+
+package server
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// startTaskCmd 以独立进程组启动命令，取消时向整个进程组发送 SIGKILL，
+// 避免 sh -c 的子进程（如管道中的命令）在 shell 被终止后继续运行
+func startTaskCmd(cmd *exec.Cmd) (func(), error) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return func() {}, nil
+}
@@ -0,0 +1,87 @@
+//go:build linux
+
+package server
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 取消时管道中的 sleep 也必须被终止，否则 Wait 会等满 WaitDelay 且进程残留
+func TestRunTaskCommandKillsProcessGroup(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	start := time.Now()
+	go func() {
+		done <- runTaskCommand(ctx, "echo $$ > "+pidFile+"; sleep 100 | cat", io.Discard, io.Discard)
+	}()
+
+	var pgid int
+	for pgid == 0 {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("command did not start")
+		}
+		if data, err := os.ReadFile(pidFile); err == nil && strings.HasSuffix(string(data), "\n") {
+			pgid, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// 等待管道中的进程启动
+	time.Sleep(200 * time.Millisecond)
+	if len(groupProcesses(t, pgid)) == 0 {
+		t.Fatal("no process found in the task process group")
+	}
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(taskKillWaitDelay):
+		t.Fatal("command did not return after cancel")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		alive := groupProcesses(t, pgid)
+		if len(alive) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("processes still running after cancel: %v", alive)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// groupProcesses 返回进程组内未退出的进程（忽略等待回收的僵尸进程）
+func groupProcesses(t *testing.T, pgid int) []string {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		t.Skip("/proc not available")
+	}
+	var alive []string
+	for _, e := range entries {
+		if _, err := strconv.Atoi(e.Name()); err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join("/proc", e.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		// pid (comm) state ppid pgrp ...
+		rest := string(data[strings.LastIndexByte(string(data), ')')+2:])
+		fields := strings.Fields(rest)
+		if len(fields) < 3 || fields[0] == "Z" {
+			continue
+		}
+		if fields[2] == strconv.Itoa(pgid) {
+			alive = append(alive, e.Name())
+		}
+	}
+	return alive
+}
@@ -0,0 +1,31 @@
+//go:build windows
+
+package server
+
+import (
+	"os/exec"
+
+	"golang.org/x/sys/windows"
+)
+
+// startTaskCmd 启动命令并将其加入作业对象，取消时终止作业内的全部进程。
+// 在加入作业前就已创建的子进程无法覆盖，此时仍会终止外层进程
+func startTaskCmd(cmd *exec.Cmd) (func(), error) {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Cancel = func() error {
+		_ = windows.TerminateJobObject(job, 1)
+		return cmd.Process.Kill()
+	}
+	if err := cmd.Start(); err != nil {
+		windows.CloseHandle(job)
+		return nil, err
+	}
+	if h, err := windows.OpenProcess(windows.PROCESS_TERMINATE|windows.PROCESS_SET_QUOTA, false, uint32(cmd.Process.Pid)); err == nil {
+		_ = windows.AssignProcessToJobObject(job, h)
+		windows.CloseHandle(h)
+	}
+	return func() { windows.CloseHandle(job) }, nil
+}
@@ -11,8 +11,10 @@ import (
 
 	"github.com/gorilla/websocket"
 	"github.com/komari-monitor/komari-agent/dnsresolver"
+	"github.com/komari-monitor/komari-agent/filetransfer"
 	"github.com/komari-monitor/komari-agent/monitoring"
 	"github.com/komari-monitor/komari-agent/terminal"
+	"github.com/komari-monitor/komari-agent/tunnel"
 	"github.com/komari-monitor/komari-agent/utils"
 	"github.com/komari-monitor/komari-agent/ws"
 )
@@ -67,6 +69,8 @@ func EstablishWebSocketConnection() {
 					} else {
 						log.Println("Failed to connect to WebSocket:", err)
 					}
+					// 断线期间继续采集，重连后补发
+					backlog.Add(monitoring.GenerateReport(), time.Now())
 					retry++
 					time.Sleep(time.Duration(flags.ReconnectInterval) * time.Second)
 				}
@@ -75,12 +79,19 @@ func EstablishWebSocketConnection() {
 					log.Println("Max retries reached.")
 					return
 				}
+				if err := backlog.Flush(conn); err != nil {
+					conn.Close()
+					conn = nil
+					continue
+				}
 			}
 
+			generatedAt := time.Now()
 			data := monitoring.GenerateReport()
 			err = conn.WriteMessage(websocket.TextMessage, data)
 			if err != nil {
 				log.Println("Failed to send WebSocket message:", err)
+				backlog.Add(data, generatedAt)
 				conn.Close()
 				conn = nil // Mark connection as dead
 				continue
@@ -138,12 +149,25 @@ func handleWebSocketMessages(conn *ws.SafeConn, done chan<- struct{}) {
 			go establishLgConnection(flags.Token, id, flags.Endpoint)
 			continue
 		}
+		// 文件传输与端口转发同样携带 request_id，需在 terminal 之前判断
+		if message.Message == "file" {
+			go establishFileConnection(flags.Token, message.TerminalId, flags.Endpoint, message.MaxSize)
+			continue
+		}
+		if message.Message == "tunnel" {
+			go establishTunnelConnection(flags.Token, message.TerminalId, flags.Endpoint, message.TunnelPort)
+			continue
+		}
 		if message.Message == "terminal" || message.TerminalId != "" {
 			go establishTerminalConnection(flags.Token, message.TerminalId, flags.Endpoint)
 			continue
 		}
 		if message.Message == "exec" {
-			go NewTask(message.ExecTaskID, message.ExecCommand)
+			go NewTask(conn, message.ExecTaskID, message.ExecCommand, message.TimeoutSec)
+			continue
+		}
+		if message.Message == "exec_cancel" && message.ExecTaskID != "" {
+			CancelTask(message.ExecTaskID)
 			continue
 		}
 		if message.Message == "script_stop" && message.ScriptExecID != "" {
@@ -202,6 +226,50 @@ func establishTerminalConnection(token, id, endpoint string) {
 	}
 }
 
+// establishFileConnection 建立文件传输连接
+func establishFileConnection(token, id, endpoint string, maxSize int64) {
+	if id == "" {
+		return
+	}
+	url := strings.TrimSuffix(endpoint, "/") + "/api/clients/file?token=" + token + "&id=" + id
+	url = "ws" + strings.TrimPrefix(url, "http")
+
+	if convertedEndpoint, err := utils.ConvertIDNToASCII(url); err == nil {
+		url = convertedEndpoint
+	} else {
+		log.Printf("Warning: Failed to convert file transfer WebSocket IDN to ASCII: %v", err)
+	}
+
+	conn, _, err := newWSDialer().Dial(url, newWSHeaders())
+	if err != nil {
+		log.Println("Failed to establish file transfer connection:", err)
+		return
+	}
+	filetransfer.Serve(conn, maxSize)
+}
+
+// establishTunnelConnection 为端口转发的一条 TCP 连接建立回连
+func establishTunnelConnection(token, id, endpoint string, port int) {
+	if id == "" {
+		return
+	}
+	url := strings.TrimSuffix(endpoint, "/") + "/api/clients/tunnel?token=" + token + "&id=" + id
+	url = "ws" + strings.TrimPrefix(url, "http")
+
+	if convertedEndpoint, err := utils.ConvertIDNToASCII(url); err == nil {
+		url = convertedEndpoint
+	} else {
+		log.Printf("Warning: Failed to convert tunnel WebSocket IDN to ASCII: %v", err)
+	}
+
+	conn, _, err := newWSDialer().Dial(url, newWSHeaders())
+	if err != nil {
+		log.Println("Failed to establish tunnel connection:", err)
+		return
+	}
+	tunnel.Serve(conn, port)
+}
+
 // establishLgConnection 建立 LG 会话
 func establishLgConnection(token, id, endpoint string) {
 	if id == "" {
@@ -0,0 +1,86 @@
+package tunnel
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	pkg_flags "github.com/komari-monitor/komari-agent/cmd/flags"
+)
+
+var flags = pkg_flags.GlobalConfig
+
+const dialTimeout = 10 * time.Second
+
+type status struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// Serve 将面板的隧道连接转发到本机 127.0.0.1:port
+//
+// 连上目标后先回复一条文本消息，之后双向以 binary 帧传输原始数据
+func Serve(conn *websocket.Conn, port int) {
+	defer conn.Close()
+	if flags.DisableWebSsh {
+		conn.WriteJSON(status{Error: "Port forwarding is disabled. Enable it by running without the --disable-web-ssh flag."})
+		return
+	}
+	if port < 1 || port > 65535 {
+		conn.WriteJSON(status{Error: fmt.Sprintf("invalid port: %d", port)})
+		return
+	}
+	target, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), dialTimeout)
+	if err != nil {
+		conn.WriteJSON(status{Error: err.Error()})
+		return
+	}
+	defer target.Close()
+	if err := conn.WriteJSON(status{OK: true}); err != nil {
+		return
+	}
+
+	var once sync.Once
+	closeAll := func() {
+		once.Do(func() {
+			target.Close()
+			conn.Close()
+		})
+	}
+	go func() {
+		defer closeAll()
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := target.Read(buf)
+			if n > 0 {
+				if werr := conn.WriteMessage(websocket.BinaryMessage, buf[:n]); werr != nil {
+					return
+				}
+			}
+			if err != nil {
+				if err == io.EOF {
+					conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+				}
+				return
+			}
+		}
+	}()
+	defer closeAll()
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if messageType != websocket.BinaryMessage {
+			continue
+		}
+		if _, err := target.Write(data); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package tunnel
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func dial(t *testing.T, port int) *websocket.Conn {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		Serve(conn, port)
+	}))
+	t.Cleanup(srv.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestServeForwardsData(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		buf := make([]byte, 64)
+		n, _ := c.Read(buf)
+		c.Write([]byte(strings.ToUpper(string(buf[:n]))))
+		c.Close()
+	}()
+
+	conn := dial(t, ln.Addr().(*net.TCPAddr).Port)
+	var st status
+	if err := conn.ReadJSON(&st); err != nil || !st.OK {
+		t.Fatalf("unexpected status %+v, %v", st, err)
+	}
+	conn.WriteMessage(websocket.BinaryMessage, []byte("ping"))
+	messageType, data, err := conn.ReadMessage()
+	if err != nil || messageType != websocket.BinaryMessage || string(data) != "PING" {
+		t.Fatalf("unexpected reply %q, %v", data, err)
+	}
+	// 目标关闭连接后隧道随之关闭
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Fatalf("expected normal closure, got %v", err)
+	}
+}
+
+func TestServeRejectsUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	for _, p := range []int{0, 70000, port} {
+		var st status
+		if err := dial(t, p).ReadJSON(&st); err != nil || st.OK || st.Error == "" {
+			t.Errorf("port %d: unexpected status %+v, %v", p, st, err)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/komari-monitor/komari/database/accounts"
+	"github.com/komari-monitor/komari/database/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,6 +15,21 @@ func AdminAuthMiddleware() gin.HandlerFunc {
 		apiKey := c.GetHeader("Authorization")
 		if isApiKeyValid(apiKey) {
 			c.Set("api_key", apiKey)
+			c.Set("role", models.RoleAdmin)
+			c.Next()
+			return
+		}
+		// scoped API token authentication
+		if token := VerifyApiToken(apiKey, c.ClientIP()); token != nil {
+			c.Set("api_key", apiKey)
+			c.Set("api_token", token)
+			// 审计日志记录具体令牌
+			c.Set("uuid", token.Actor())
+			if token.HasScope(models.TokenScopeAdmin) {
+				c.Set("role", models.RoleAdmin)
+			} else {
+				c.Set("role", models.RoleReadOnly)
+			}
 			c.Next()
 			return
 		}
@@ -25,17 +41,24 @@ func AdminAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Komari is a single user system
 		uuid, err := accounts.GetSession(session)
 		if err != nil {
 			RespondError(c, http.StatusUnauthorized, "Unauthorized.")
 			c.Abort()
 			return
 		}
+		user, err := accounts.GetUserByUUID(uuid)
+		if err != nil {
+			RespondError(c, http.StatusUnauthorized, "Unauthorized.")
+			c.Abort()
+			return
+		}
 		accounts.UpdateLatest(session, c.Request.UserAgent(), c.ClientIP())
-		// 将 session 和 用户 UUID 传递到后续处理器
+		// 将 session、用户 UUID 与角色传递到后续处理器
 		c.Set("session", session)
-		c.Set("uuid", uuid)
+		c.Set("uuid", user.UUID)
+		c.Set("user", &user)
+		c.Set("role", user.Role)
 
 		c.Next()
 	}
@@ -3,6 +3,7 @@ package api
 import (
 	"log"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,22 +14,41 @@ import (
 
 	"strconv"
 
+	"github.com/komari-monitor/komari/database/apitokens"
+	"github.com/komari-monitor/komari/database/clients"
 	"github.com/komari-monitor/komari/database/config"
 	"github.com/komari-monitor/komari/database/dbcore"
 	"github.com/komari-monitor/komari/database/models"
+	recordsdb "github.com/komari-monitor/komari/database/records"
 	"github.com/komari-monitor/komari/utils"
+	"github.com/komari-monitor/komari/utils/termrec"
 )
 
 var (
 	Records = cache.New(1*time.Minute, 1*time.Minute)
 )
 
+// TerminalConn 被控端终端连接，*websocket.Conn 与 Nezha IOStream 适配器均实现该接口
+type TerminalConn interface {
+	ReadMessage() (messageType int, p []byte, err error)
+	WriteMessage(messageType int, data []byte) error
+	Close() error
+}
+
 type TerminalSession struct {
 	UUID        string
 	UserUUID    string
 	Browser     *websocket.Conn
-	Agent       *websocket.Conn
+	Agent       TerminalConn
 	RequesterIp string
+
+	// 共享终端：其他浏览器以观察者身份附加，见 terminal_share.go
+	mu            sync.Mutex
+	agentMu       sync.Mutex // 发起者与可输入的观察者共用 Agent 连接，写入需串行
+	establishedAt time.Time
+	viewers       map[string]*terminalViewer
+	history       []byte // 最近的输出，供后加入的观察者恢复画面
+	recording     *termrec.Session
 }
 
 var TerminalSessionsMutex = &sync.Mutex{}
@@ -59,8 +79,15 @@ var LgSessions = make(map[string]*LgSession)
 
 func SaveClientReportToDB() error {
 	lastMinute := time.Now().Add(-time.Minute).Unix()
-	var records []models.Record
-	var gpuRecords []models.GPURecord
+	batch := &reportBatch{}
+
+	// 流量账本按各节点的结算日归入周期
+	resetDays := make(map[string]int)
+	if clientList, err := clients.GetAllClientBasicInfo(); err == nil {
+		for _, c := range clientList {
+			resetDays[c.UUID] = c.TrafficResetDay
+		}
+	}
 
 	// 遍历所有客户端记录
 	for uuid, x := range Records.Items() {
@@ -87,21 +114,55 @@ func SaveClientReportToDB() error {
 
 		// 计算平均报告并添加到记录列表
 		if len(filtered) > 0 {
-			r := utils.AverageReport(uuid, time.Now(), filtered, 0.3)
-			records = append(records, r)
+			// 聚合时会原地排序，需先取出最新的上报
+			latest := latestReport(filtered)
+			batch.add(uuid, time.Now(), filtered)
+			if err := recordsdb.ObserveTraffic(uuid, resetDays[uuid], latest.Interfaces, time.Now()); err != nil {
+				log.Printf("Failed to update traffic ledger for %s: %v", uuid, err)
+			}
+		}
+	}
+
+	return batch.save()
+}
 
-			// 使用与其他数据相同的聚合逻辑处理GPU数据
-			gpuAggregated := utils.AverageGPUReports(uuid, time.Now(), filtered, 0.3)
-			gpuRecords = append(gpuRecords, gpuAggregated...)
+// latestReport 返回 UpdatedAt 最大的上报
+func latestReport(reports []common.Report) common.Report {
+	latest := reports[0]
+	for _, r := range reports[1:] {
+		if r.UpdatedAt.After(latest.UpdatedAt) {
+			latest = r
 		}
 	}
+	return latest
+}
+
+// reportBatch 一批按分钟聚合后的记录，实时上报与离线补发共用
+type reportBatch struct {
+	records        []models.Record
+	gpuRecords     []models.GPURecord
+	tempRecords    []models.TemperatureRecord
+	diskRecords    []models.DiskRecord
+	networkRecords []models.NetworkRecord
+}
+
+// add 将同一客户端一分钟内的上报聚合为一组记录，记录时间为 t
+func (b *reportBatch) add(uuid string, t time.Time, reports []common.Report) {
+	b.records = append(b.records, utils.AverageReport(uuid, t, reports, 0.3))
+	// 使用与其他数据相同的聚合逻辑处理GPU数据
+	b.gpuRecords = append(b.gpuRecords, utils.AverageGPUReports(uuid, t, reports, 0.3)...)
+	b.tempRecords = append(b.tempRecords, utils.AverageTemperatureReports(uuid, t, reports, 0.3)...)
+	b.diskRecords = append(b.diskRecords, utils.AverageDiskReports(uuid, t, reports, 0.3)...)
+	b.networkRecords = append(b.networkRecords, utils.AverageNetworkReports(uuid, t, reports, 0.3)...)
+}
 
+func (b *reportBatch) save() error {
 	// 批量插入数据库前去重（client与time共同构成唯一键）
 	db := dbcore.GetDBInstance()
 
-	if len(records) > 0 {
+	if len(b.records) > 0 {
 		unique := make(map[string]models.Record)
-		for _, rec := range records {
+		for _, rec := range b.records {
 			key := rec.Client + "_" + strconv.FormatInt(rec.Time.ToTime().Unix(), 10)
 			unique[key] = rec
 		}
@@ -109,17 +170,17 @@ func SaveClientReportToDB() error {
 		for _, rec := range unique {
 			deduped = append(deduped, rec)
 		}
-		if err := db.Model(&models.Record{}).Create(&deduped).Error; err != nil {
+		if err := recordsdb.RecordMany(deduped); err != nil {
 			log.Printf("Failed to save records to database: %v", err)
 			return err
 		}
 	}
 
 	// 批量插入GPU记录
-	if len(gpuRecords) > 0 {
+	if len(b.gpuRecords) > 0 {
 		// GPU记录也需要去重，防止重复插入
 		gpuUnique := make(map[string]models.GPURecord)
-		for _, rec := range gpuRecords {
+		for _, rec := range b.gpuRecords {
 			key := rec.Client + "_" + strconv.Itoa(rec.DeviceIndex) + "_" + strconv.FormatInt(rec.Time.ToTime().Unix(), 10)
 			gpuUnique[key] = rec
 		}
@@ -133,6 +194,30 @@ func SaveClientReportToDB() error {
 		}
 	}
 
+	// 批量插入温度传感器记录
+	if len(b.tempRecords) > 0 {
+		if err := db.Create(&b.tempRecords).Error; err != nil {
+			log.Printf("Failed to save temperature records to database: %v", err)
+			return err
+		}
+	}
+
+	// 批量插入挂载点与块设备记录
+	if len(b.diskRecords) > 0 {
+		if err := db.Create(&b.diskRecords).Error; err != nil {
+			log.Printf("Failed to save disk records to database: %v", err)
+			return err
+		}
+	}
+
+	// 批量插入网卡记录
+	if len(b.networkRecords) > 0 {
+		if err := db.Create(&b.networkRecords).Error; err != nil {
+			log.Printf("Failed to save network records to database: %v", err)
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -178,3 +263,30 @@ func isApiKeyValid(apiKey string) bool {
 	}
 	return apiKey == "Bearer "+cfg.ApiKey
 }
+
+// VerifyApiToken 校验 Authorization: Bearer <token> 形式的 API 令牌，无效时返回 nil
+func VerifyApiToken(auth, ip string) *models.ApiToken {
+	raw := strings.TrimPrefix(auth, "Bearer ")
+	if raw == "" || raw == auth {
+		return nil
+	}
+	token, err := apitokens.VerifyToken(raw, ip)
+	if err != nil {
+		return nil
+	}
+	return token
+}
+
+// VerifyApiTokenScope 供 JSON-RPC 等旁路鉴权使用，判断令牌是否具备任一权限范围
+func VerifyApiTokenScope(auth, ip string, scopes ...string) bool {
+	token := VerifyApiToken(auth, ip)
+	if token == nil {
+		return false
+	}
+	for _, s := range scopes {
+		if token.HasScope(s) {
+			return true
+		}
+	}
+	return false
+}
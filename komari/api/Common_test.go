@@ -0,0 +1,24 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/komari-monitor/komari/common"
+	"github.com/komari-monitor/komari/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatestReportBeforeAverage(t *testing.T) {
+	now := time.Now()
+	reports := []common.Report{
+		{UpdatedAt: now.Add(-20 * time.Second), Connections: common.ConnectionsReport{UDP: 5}},
+		{UpdatedAt: now, Connections: common.ConnectionsReport{UDP: 1}},
+		{UpdatedAt: now.Add(-40 * time.Second), Connections: common.ConnectionsReport{UDP: 3}},
+	}
+	// 聚合会原地排序，须在此之前取出最新的上报
+	latest := latestReport(reports)
+	utils.AverageReport("a", now, reports, 0.3)
+	assert.Equal(t, now, latest.UpdatedAt)
+	assert.Equal(t, 1, latest.Connections.UDP)
+}
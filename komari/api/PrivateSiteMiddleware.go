@@ -5,6 +5,7 @@ import (
 
 	"github.com/komari-monitor/komari/database/accounts"
 	"github.com/komari-monitor/komari/database/config"
+	"github.com/komari-monitor/komari/database/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,6 +21,7 @@ var (
 		"/api/oauth_callback",
 		"/api/version",
 		"/api/recent",
+		"/api/metrics",  // 由 GetMetrics 自行鉴权
 		"/api/admin",    // 由AdminAuthMiddleware处理
 		"/api/clients/", // 由TokenAuthMiddleware处理
 	}
@@ -29,7 +31,7 @@ func PrivateSiteMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// API key authentication
 		apiKey := c.GetHeader("Authorization")
-		if isApiKeyValid(apiKey) {
+		if isApiKeyValid(apiKey) || VerifyApiTokenScope(apiKey, c.ClientIP(), models.TokenScopeAdmin, models.TokenScopeRead) {
 			c.Set("api_key", apiKey)
 			c.Next()
 			return
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/komari-monitor/komari/database/clients"
+	"github.com/komari-monitor/komari/database/models"
+)
+
+// RequireRole 要求当前用户至少具备指定角色，需在 AdminAuthMiddleware 之后使用
+//
+// API 令牌不按角色判断：管理员路由要求 admin 权限，其余路由要求此前的 RequireScope 已放行
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := CurrentApiToken(c); token != nil {
+			if !tokenAllowed(c, token, role == models.RoleAdmin) {
+				RespondError(c, http.StatusForbidden, "Permission denied.")
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+		if models.RoleLevel(CurrentRole(c)) < models.RoleLevel(role) {
+			RespondError(c, http.StatusForbidden, "Permission denied.")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRoleForWrite 读请求只要求登录，写请求要求至少具备指定角色
+func RequireRoleForWrite(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := CurrentApiToken(c); token != nil {
+			if !tokenAllowed(c, token, false) {
+				RespondError(c, http.StatusForbidden, "Permission denied.")
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+		required := role
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			required = models.RoleReadOnly
+		}
+		if models.RoleLevel(CurrentRole(c)) < models.RoleLevel(required) {
+			RespondError(c, http.StatusForbidden, "Permission denied.")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireScope 判断 API 令牌是否具备任一权限范围，结果供后续的角色中间件使用；会话请求不受影响
+//
+// read 权限只放行 GET/HEAD 请求。同一请求链上后出现的 RequireScope 会覆盖之前的结果
+func RequireScope(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := CurrentApiToken(c)
+		if token == nil {
+			c.Next()
+			return
+		}
+		isRead := c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead
+		granted := false
+		for _, scope := range scopes {
+			if scope == models.TokenScopeRead && !isRead {
+				continue
+			}
+			if token.HasScope(scope) {
+				granted = true
+				break
+			}
+		}
+		c.Set("token_scope_granted", granted)
+		c.Next()
+	}
+}
+
+func tokenAllowed(c *gin.Context, token *models.ApiToken, adminOnly bool) bool {
+	if token.HasScope(models.TokenScopeAdmin) {
+		// 限定节点的令牌不能访问管理员路由，否则可借此创建不受限的令牌或修改全局设置，与 JSON-RPC 的判断一致
+		return !adminOnly || len(token.Clients) == 0
+	}
+	return !adminOnly && c.GetBool("token_scope_granted")
+}
+
+// CurrentApiToken 返回当前请求使用的 API 令牌，会话与旧版 ApiKey 请求返回 nil
+func CurrentApiToken(c *gin.Context) *models.ApiToken {
+	if v, ok := c.Get("api_token"); ok {
+		if t, ok := v.(*models.ApiToken); ok {
+			return t
+		}
+	}
+	return nil
+}
+
+// CurrentRole 返回当前请求的角色；API Key 视为管理员
+func CurrentRole(c *gin.Context) string {
+	if role, ok := c.Get("role"); ok {
+		if r, ok := role.(string); ok {
+			return r
+		}
+	}
+	return ""
+}
+
+// CurrentUser 返回当前登录用户，API Key 请求返回 nil
+func CurrentUser(c *gin.Context) *models.User {
+	if v, ok := c.Get("user"); ok {
+		if u, ok := v.(*models.User); ok {
+			return u
+		}
+	}
+	return nil
+}
+
+// ClientScope 返回当前用户的节点可见性判断函数，管理员与未限制范围的用户始终返回 true
+func ClientScope(c *gin.Context) func(uuid string) bool {
+	if token := CurrentApiToken(c); token != nil {
+		return token.CanAccessClient
+	}
+	user := CurrentUser(c)
+	if user == nil || !user.IsScoped() {
+		return func(string) bool { return true }
+	}
+	allowed := make(map[string]bool)
+	if list, err := clients.GetAllClientBasicInfo(); err == nil {
+		for i := range list {
+			if user.CanAccessClient(&list[i]) {
+				allowed[list[i].UUID] = true
+			}
+		}
+	}
+	return func(uuid string) bool { return allowed[uuid] }
+}
+
+// CanAccessClients 判断当前用户是否可以操作全部指定节点
+func CanAccessClients(c *gin.Context, uuids ...string) bool {
+	inScope := ClientScope(c)
+	for _, uuid := range uuids {
+		if !inScope(uuid) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsClientScoped 判断当前用户或 API 令牌是否限制了可操作的节点
+func IsClientScoped(c *gin.Context) bool {
+	if token := CurrentApiToken(c); token != nil {
+		return len(token.Clients) > 0
+	}
+	user := CurrentUser(c)
+	return user != nil && user.IsScoped()
+}
+
+// TargetScope 返回节点列表的判断函数：列表中的节点须全部可见。
+// 空列表在脚本、告警等配置中表示全部节点，只允许不受节点范围限制的调用者
+func TargetScope(c *gin.Context) func(uuids []string) bool {
+	if !IsClientScoped(c) {
+		return func([]string) bool { return true }
+	}
+	inScope := ClientScope(c)
+	return func(uuids []string) bool {
+		if len(uuids) == 0 {
+			return false
+		}
+		for _, uuid := range uuids {
+			if !inScope(uuid) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// CanAccessTargets 同 TargetScope，用于单次判断
+func CanAccessTargets(c *gin.Context, uuids ...string) bool {
+	return TargetScope(c)(uuids)
+}
+
+// FilterByTargets 只保留节点列表在当前用户范围内的配置项，判断规则同 TargetScope
+func FilterByTargets[T any](c *gin.Context, items []T, targets func(T) []string) []T {
+	if !IsClientScoped(c) {
+		return items
+	}
+	inScope := TargetScope(c)
+	list := make([]T, 0, len(items))
+	for _, item := range items {
+		if inScope(targets(item)) {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// CanAccessItems 判断 ids 对应的配置项是否都存在且在当前用户范围内，不受限的调用者不加载配置
+func CanAccessItems[T any](c *gin.Context, load func() ([]T, error), id func(T) uint, targets func(T) []string, ids ...uint) bool {
+	if !IsClientScoped(c) {
+		return true
+	}
+	items, err := load()
+	if err != nil {
+		return false
+	}
+	allowed := make(map[uint]bool, len(items))
+	for _, item := range FilterByTargets(c, items, targets) {
+		allowed[id(item)] = true
+	}
+	for _, i := range ids {
+		if !allowed[i] {
+			return false
+		}
+	}
+	return true
+}
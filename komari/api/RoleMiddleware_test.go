@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/komari-monitor/komari/database/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTargetScope(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("api_token", &models.ApiToken{Clients: models.StringArray{"a", "b"}})
+
+	assert.True(t, IsClientScoped(c))
+	assert.True(t, CanAccessTargets(c, "a", "b"))
+	assert.False(t, CanAccessTargets(c, "a", "c"))
+	// 空列表表示全部节点
+	assert.False(t, CanAccessTargets(c))
+
+	type item struct {
+		id      uint
+		clients []string
+	}
+	items := []item{{1, []string{"a"}}, {2, nil}, {3, []string{"b", "c"}}}
+	targets := func(i item) []string { return i.clients }
+	filtered := FilterByTargets(c, items, targets)
+	assert.Equal(t, []item{{1, []string{"a"}}}, filtered)
+
+	load := func() ([]item, error) { return items, nil }
+	id := func(i item) uint { return i.id }
+	assert.True(t, CanAccessItems(c, load, id, targets, 1))
+	assert.False(t, CanAccessItems(c, load, id, targets, 1, 2))
+	assert.False(t, CanAccessItems(c, load, id, targets, 4), "missing items are denied")
+
+	unscoped, _ := gin.CreateTestContext(httptest.NewRecorder())
+	unscoped.Set("api_token", &models.ApiToken{})
+	assert.False(t, IsClientScoped(unscoped))
+	assert.True(t, CanAccessTargets(unscoped))
+	assert.Len(t, FilterByTargets(unscoped, items, targets), 3)
+}
+
+func TestTokenAllowed(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	admin := &models.ApiToken{Scopes: models.StringArray{models.TokenScopeAdmin}}
+	assert.True(t, tokenAllowed(c, admin, true))
+	assert.True(t, tokenAllowed(c, admin, false))
+
+	// 限定节点的 admin 令牌不能访问管理员路由
+	scoped := &models.ApiToken{Scopes: models.StringArray{models.TokenScopeAdmin}, Clients: models.StringArray{"a"}}
+	assert.False(t, tokenAllowed(c, scoped, true))
+	assert.True(t, tokenAllowed(c, scoped, false))
+}
+
+func TestRequireRoleEmptyRole(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("role", "")
+	RequireRole(models.RoleReadOnly)(c)
+	assert.True(t, c.IsAborted(), "an empty role must not pass any role check")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,119 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/komari-monitor/komari/api"
+	"github.com/komari-monitor/komari/database/auditlog"
+	"github.com/komari-monitor/komari/database/config"
+	"github.com/komari-monitor/komari/utils/backup"
+)
+
+// ListScheduledBackups 列出各存储位置中的定时备份及最近一次执行结果
+func ListScheduledBackups(c *gin.Context) {
+	cfg, err := config.Get()
+	if err != nil {
+		api.RespondError(c, http.StatusInternalServerError, "Failed to get config: "+err.Error())
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Minute)
+	defer cancel()
+	items, err := backup.List(ctx, cfg)
+	resp := gin.H{
+		"items":  items,
+		"status": backup.GetStatus(),
+	}
+	if err != nil {
+		// 部分存储不可用时仍返回可用部分
+		resp["error"] = err.Error()
+	}
+	api.RespondSuccess(c, resp)
+}
+
+// RunScheduledBackup 立即在后台执行一次备份
+func RunScheduledBackup(c *gin.Context) {
+	cfg, err := config.Get()
+	if err != nil {
+		api.RespondError(c, http.StatusInternalServerError, "Failed to get config: "+err.Error())
+		return
+	}
+	if _, err := backup.Storages(cfg); err != nil {
+		api.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if cfg.BackupPassphrase == "" {
+		api.RespondError(c, http.StatusBadRequest, "Backup passphrase is required")
+		return
+	}
+	if backup.GetStatus().Running {
+		api.RespondError(c, http.StatusConflict, backup.ErrRunning.Error())
+		return
+	}
+	go func() {
+		if _, err := backup.Run(context.Background(), cfg); err != nil && !errors.Is(err, backup.ErrRunning) {
+			auditlog.EventLog("error", fmt.Sprintf("Manual backup failed: %v", err))
+		}
+	}()
+	uuid, _ := c.Get("uuid")
+	auditlog.Log(c.ClientIP(), uuid.(string), "run backup", "info")
+	api.RespondSuccess(c, nil)
+}
+
+// RestoreScheduledBackup 从存储位置下载并解密备份，校验后重启服务恢复
+// 接受数据类型：
+// - storage: string (local / s3)
+// - name: string (备份文件名)
+func RestoreScheduledBackup(c *gin.Context) {
+	var req struct {
+		Storage string `json:"storage" binding:"required"`
+		Name    string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.RespondError(c, http.StatusBadRequest, "Invalid or missing request body: "+err.Error())
+		return
+	}
+	if !restoreMutex.TryLock() {
+		api.RespondError(c, http.StatusConflict, "Another restore operation is already in progress")
+		return
+	}
+	defer restoreMutex.Unlock()
+	cfg, err := config.Get()
+	if err != nil {
+		api.RespondError(c, http.StatusInternalServerError, "Failed to get config: "+err.Error())
+		return
+	}
+
+	tempDir, err := os.MkdirTemp("", "backup-restore-*")
+	if err != nil {
+		api.RespondError(c, http.StatusInternalServerError, fmt.Sprintf("Error creating temporary directory: %v", err))
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	tempFilePath := filepath.Join(tempDir, "backup.zip")
+	if err := backup.Fetch(c.Request.Context(), cfg, req.Storage, req.Name, tempFilePath); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, backup.ErrDecrypt) {
+			status = http.StatusBadRequest
+		}
+		api.RespondError(c, status, "Failed to fetch backup: "+err.Error())
+		return
+	}
+	if status, err := stageBackupZip(tempFilePath); err != nil {
+		api.RespondError(c, status, err.Error())
+		return
+	}
+
+	uuid, _ := c.Get("uuid")
+	auditlog.Log(c.ClientIP(), uuid.(string), "restore backup: "+req.Storage+"/"+req.Name, "warn")
+	api.RespondSuccess(c, gin.H{
+		"message": "Backup restored successfully. The service will restart and apply the backup.",
+	})
+	restartToApplyBackup()
+}
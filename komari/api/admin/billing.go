@@ -0,0 +1,242 @@
+package admin
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/komari-monitor/komari/api"
+	"github.com/komari-monitor/komari/database/auditlog"
+	"github.com/komari-monitor/komari/database/billing"
+	"github.com/komari-monitor/komari/database/clients"
+	"github.com/komari-monitor/komari/database/config"
+	"github.com/komari-monitor/komari/database/models"
+	"gorm.io/gorm"
+)
+
+func billingConverter(c *gin.Context) (*billing.Converter, bool) {
+	cfg, err := config.Get()
+	if err != nil {
+		api.RespondError(c, http.StatusInternalServerError, "Failed to get config: "+err.Error())
+		return nil, false
+	}
+	conv, err := billing.NewConverter(cfg)
+	if err != nil {
+		api.RespondError(c, http.StatusBadRequest, "Invalid billing_exchange_rates: "+err.Error())
+		return nil, false
+	}
+	return conv, true
+}
+
+func billingReport(c *gin.Context) (*billing.Converter, []models.Client, bool) {
+	conv, ok := billingConverter(c)
+	if !ok {
+		return nil, nil, false
+	}
+	list, err := clients.GetAllClientBasicInfo()
+	if err != nil {
+		api.RespondError(c, http.StatusInternalServerError, "Failed to list clients: "+err.Error())
+		return nil, nil, false
+	}
+	return conv, list, true
+}
+
+func parseLedgerFilter(c *gin.Context) (billing.LedgerFilter, error) {
+	filter := billing.LedgerFilter{Client: c.Query("client")}
+	var err error
+	if s := c.Query("start"); s != "" {
+		if filter.From, err = time.Parse(time.RFC3339, s); err != nil {
+			return filter, fmt.Errorf("invalid start parameter")
+		}
+	}
+	if s := c.Query("end"); s != "" {
+		if filter.To, err = time.Parse(time.RFC3339, s); err != nil {
+			return filter, fmt.Errorf("invalid end parameter")
+		}
+	}
+	return filter, nil
+}
+
+// GetBillingSummary 各节点、各分组的月均费用及基准货币合计
+func GetBillingSummary(c *gin.Context) {
+	conv, list, ok := billingReport(c)
+	if !ok {
+		return
+	}
+	api.RespondSuccess(c, billing.BuildReport(list, conv, time.Now()))
+}
+
+// GetBillingRenewals 续费日历
+// 查询参数：days 未来天数（默认 30，最多 366）
+func GetBillingRenewals(c *gin.Context) {
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+	conv, list, ok := billingReport(c)
+	if !ok {
+		return
+	}
+	api.RespondSuccess(c, billing.Renewals(list, conv, time.Now(), days))
+}
+
+// GetBillingLedger 历史支出账本
+// 查询参数：client、start、end（RFC3339）
+func GetBillingLedger(c *gin.Context) {
+	filter, err := parseLedgerFilter(c)
+	if err != nil {
+		api.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	conv, ok := billingConverter(c)
+	if !ok {
+		return
+	}
+	records, err := billing.ListRecords(filter)
+	if err != nil {
+		api.RespondError(c, http.StatusInternalServerError, "Failed to list billing records: "+err.Error())
+		return
+	}
+	api.RespondSuccess(c, gin.H{"records": records, "summary": billing.SummarizeLedger(records, conv)})
+}
+
+// AddBillingRecord 手动补录一条支出
+func AddBillingRecord(c *gin.Context) {
+	var record models.BillingRecord
+	if err := c.ShouldBindJSON(&record); err != nil {
+		api.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	// 填写了节点时补全名称与分组
+	if record.Client != "" && record.ClientName == "" {
+		client, err := clients.GetClientByUUID(record.Client)
+		if err != nil {
+			api.RespondError(c, http.StatusBadRequest, "Client not found")
+			return
+		}
+		record.ClientName = client.Name
+		if record.Group == "" {
+			record.Group = client.Group
+		}
+	}
+	record.Source = billing.SourceManual
+	if err := billing.AddRecord(&record); err != nil {
+		api.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), fmt.Sprintf("add billing record:%d %s %.2f%s", record.ID, record.ClientName, record.Amount, record.Currency), "info")
+	api.RespondSuccess(c, record)
+}
+
+// POST body: id uint
+func DeleteBillingRecord(c *gin.Context) {
+	var req struct {
+		ID uint `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := billing.DeleteRecord(req.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			api.RespondError(c, http.StatusNotFound, "Billing record not found")
+			return
+		}
+		api.RespondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), fmt.Sprintf("delete billing record:%d", req.ID), "warn")
+	api.RespondSuccess(c, nil)
+}
+
+// ExportBilling 导出 CSV
+// 查询参数：type=nodes|renewals|ledger（默认 nodes），其余参数与对应接口相同
+func ExportBilling(c *gin.Context) {
+	kind := c.DefaultQuery("type", "nodes")
+	var rows [][]string
+	switch kind {
+	case "nodes":
+		conv, list, ok := billingReport(c)
+		if !ok {
+			return
+		}
+		report := billing.BuildReport(list, conv, time.Now())
+		rows = append(rows, []string{"uuid", "name", "group", "price", "currency", "billing_cycle", "auto_renewal", "expired_at", "one_time", "monthly", "monthly_" + conv.Base})
+		for _, n := range report.Nodes {
+			expiredAt := ""
+			if n.ExpiredAt != nil {
+				expiredAt = n.ExpiredAt.ToTime().Format(time.RFC3339)
+			}
+			rows = append(rows, []string{n.UUID, n.Name, n.Group, formatAmount(n.Price), n.Currency, strconv.Itoa(n.BillingCycle),
+				strconv.FormatBool(n.AutoRenewal), expiredAt, strconv.FormatBool(n.OneTime), formatAmount(n.Monthly), convertedAmount(n.MonthlyBase, n.Converted)})
+		}
+	case "renewals":
+		days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+		conv, list, ok := billingReport(c)
+		if !ok {
+			return
+		}
+		rows = append(rows, []string{"date", "days_left", "uuid", "name", "group", "amount", "currency", "amount_" + conv.Base, "auto_renewal", "projected"})
+		for _, r := range billing.Renewals(list, conv, time.Now(), days) {
+			rows = append(rows, []string{r.Date.ToTime().Format(time.RFC3339), strconv.Itoa(r.DaysLeft), r.UUID, r.Name, r.Group,
+				formatAmount(r.Amount), r.Currency, convertedAmount(r.AmountBase, r.Converted), strconv.FormatBool(r.AutoRenewal), strconv.FormatBool(r.Projected)})
+		}
+	case "ledger":
+		filter, err := parseLedgerFilter(c)
+		if err != nil {
+			api.RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		conv, ok := billingConverter(c)
+		if !ok {
+			return
+		}
+		records, err := billing.ListRecords(filter)
+		if err != nil {
+			api.RespondError(c, http.StatusInternalServerError, "Failed to list billing records: "+err.Error())
+			return
+		}
+		rows = append(rows, []string{"id", "paid_at", "client", "client_name", "group", "amount", "currency", "amount_" + conv.Base, "billing_cycle", "period_start", "period_end", "source", "remark"})
+		for _, r := range records {
+			base, converted := conv.Convert(r.Amount, r.Currency)
+			rows = append(rows, []string{strconv.FormatUint(uint64(r.ID), 10), r.PaidAt.ToTime().Format(time.RFC3339), r.Client, r.ClientName, r.Group,
+				formatAmount(r.Amount), r.Currency, convertedAmount(base, converted), strconv.Itoa(r.BillingCycle),
+				formatOptionalTime(r.PeriodStart), formatOptionalTime(r.PeriodEnd), r.Source, r.Remark})
+		}
+	default:
+		api.RespondError(c, http.StatusBadRequest, "Invalid type, expected nodes, renewals or ledger")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"komari-billing-%s-%s.csv\"", kind, time.Now().Format("20060102-150405")))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	// BOM 便于 Excel 正确识别 UTF-8
+	c.Writer.WriteString("\xEF\xBB\xBF")
+	w := csv.NewWriter(c.Writer)
+	if err := w.WriteAll(rows); err != nil {
+		// 响应头已发送，只能记录错误
+		log.Printf("Failed to write billing export: %v", err)
+	}
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// 缺少汇率时留空，避免与 0 混淆
+func convertedAmount(v float64, converted bool) string {
+	if !converted {
+		return ""
+	}
+	return formatAmount(v)
+}
+
+func formatOptionalTime(t models.LocalTime) string {
+	if t.ToTime().Year() < 2 {
+		return ""
+	}
+	return t.ToTime().Format(time.RFC3339)
+}
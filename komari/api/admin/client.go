@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/komari-monitor/komari/api"
 	"github.com/komari-monitor/komari/database/auditlog"
 	"github.com/komari-monitor/komari/database/clients"
 	"github.com/komari-monitor/komari/database/records"
@@ -45,6 +46,10 @@ func EditClient(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Invalid or missing UUID"})
 		return
 	}
+	if !api.CanAccessClients(c, uuid) {
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "Client not found"})
+		return
+	}
 	if v, ok := req["started_at"].(string); ok && strings.TrimSpace(v) == "" {
 		req["started_at"] = nil
 	}
@@ -98,6 +103,10 @@ func GetClient(c *gin.Context) {
 		})
 		return
 	}
+	if !api.CanAccessClients(c, uuid) {
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "Client not found"})
+		return
+	}
 
 	result, err := clients.GetClientByUUID(uuid)
 	if err != nil {
@@ -117,6 +126,14 @@ func ListClients(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": err.Error()})
 		return
 	}
+	inScope := api.ClientScope(c)
+	filtered := cls[:0]
+	for _, cli := range cls {
+		if inScope(cli.UUID) {
+			filtered = append(filtered, cli)
+		}
+	}
+	cls = filtered
 
 	c.JSON(http.StatusOK, cls)
 }
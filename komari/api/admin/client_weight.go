@@ -14,6 +14,14 @@ func OrderWeight(c *gin.Context) {
 		api.RespondError(c, 400, "Invalid or missing request body: "+err.Error())
 		return
 	}
+	uuids := make([]string, 0, len(req))
+	for uuid := range req {
+		uuids = append(uuids, uuid)
+	}
+	if !api.CanAccessClients(c, uuids...) {
+		api.RespondError(c, 403, "Permission denied for some clients")
+		return
+	}
 	db := dbcore.GetDBInstance()
 	for uuid, weight := range req {
 		err := db.Model(&models.Client{}).Where("uuid = ?", uuid).Update("weight", weight).Error
@@ -0,0 +1,89 @@
+package admin
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/komari-monitor/komari/api"
+	"github.com/komari-monitor/komari/database/auditlog"
+	"github.com/komari-monitor/komari/database/configcode"
+)
+
+// 配置文档大小上限
+const maxConfigCodeSize = 16 << 20
+
+// ExportConfigCode 导出声明式配置文档
+// 查询参数：format=yaml|json（默认 yaml）
+func ExportConfigCode(c *gin.Context) {
+	format := c.DefaultQuery("format", "yaml")
+	doc, err := configcode.Export()
+	if err != nil {
+		api.RespondError(c, http.StatusInternalServerError, "Failed to export config: "+err.Error())
+		return
+	}
+	data, err := configcode.Marshal(doc, format)
+	if err != nil {
+		api.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	contentType, ext := "application/yaml", "yaml"
+	if format == "json" {
+		contentType, ext = "application/json", "json"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"komari-config-%s.%s\"", time.Now().Format("20060102-150405"), ext))
+	c.Data(http.StatusOK, contentType+"; charset=utf-8", data)
+}
+
+// ApplyConfigCode 比较或应用配置文档，请求体为 YAML 或 JSON
+// 查询参数：
+// - dry_run=true 仅返回差异
+// - prune=true 删除文档中未列出的条目（节点与 LG 工具设置除外）
+func ApplyConfigCode(c *gin.Context) {
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxConfigCodeSize+1))
+	if err != nil {
+		api.RespondError(c, http.StatusBadRequest, "Failed to read request body: "+err.Error())
+		return
+	}
+	if len(data) > maxConfigCodeSize {
+		api.RespondError(c, http.StatusRequestEntityTooLarge, "Document too large")
+		return
+	}
+	doc, err := configcode.Parse(data)
+	if err != nil {
+		api.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	dryRun := c.Query("dry_run") == "true"
+	prune := c.Query("prune") == "true"
+
+	var changes []configcode.Change
+	if dryRun {
+		changes, err = configcode.Plan(doc, prune)
+	} else {
+		changes, err = configcode.Apply(doc, prune)
+	}
+	summary := configcode.Summarize(changes)
+	if !dryRun && len(changes) > 0 {
+		uuid, _ := c.Get("uuid")
+		msg := fmt.Sprintf("apply config-as-code (%d create, %d update, %d delete)", summary.Create, summary.Update, summary.Delete)
+		level := "warn"
+		if err != nil {
+			msg += ", failed: " + err.Error()
+			level = "error"
+		}
+		auditlog.Log(c.ClientIP(), uuid.(string), msg, level)
+	}
+	if err != nil {
+		api.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	api.RespondSuccess(c, gin.H{
+		"dry_run": dryRun,
+		"prune":   prune,
+		"summary": summary,
+		"changes": changes,
+	})
+}
@@ -1,11 +1,9 @@
 package admin
 
 import (
-	"encoding/json"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/gorilla/websocket"
 	"github.com/komari-monitor/komari/api"
 	"github.com/komari-monitor/komari/database/auditlog"
 	"github.com/komari-monitor/komari/database/models"
@@ -14,13 +12,25 @@ import (
 	"github.com/komari-monitor/komari/ws"
 )
 
+// 离线节点默认排队时长与上限
+const (
+	defaultQueueDuration = 24 * time.Hour
+	maxQueueDuration     = 30 * 24 * time.Hour
+)
+
 // 接受数据类型：
 // - command: string
 // - clients: []string (客户端 UUID 列表)
+// - queue_offline: bool (离线节点排队，上线后下发)
+// - deadline: string (RFC3339，排队截止时间，默认 24 小时后)
+// - timeout: int (执行超时秒数，0 表示不限制)
 func Exec(c *gin.Context) {
 	var req struct {
-		Command string   `json:"command" binding:"required"`
-		Clients []string `json:"clients" binding:"required"`
+		Command      string   `json:"command" binding:"required"`
+		Clients      []string `json:"clients" binding:"required"`
+		QueueOffline bool     `json:"queue_offline"`
+		Deadline     string   `json:"deadline"`
+		Timeout      int      `json:"timeout"`
 	}
 	var onlineClients []string
 	var offlineClients []string
@@ -37,36 +47,59 @@ func Exec(c *gin.Context) {
 	// 	// 	return
 	// 	// }
 	// }
+	if req.Timeout < 0 {
+		api.RespondError(c, 400, "Timeout must not be negative")
+		return
+	}
+	if !api.CanAccessClients(c, req.Clients...) {
+		api.RespondError(c, 403, "Permission denied for some clients")
+		return
+	}
 	for _, uuid := range req.Clients {
-		if client := ws.GetConnectedClients()[uuid]; client != nil {
+		if client := ws.GetMessageWriter(uuid); client != nil {
 			onlineClients = append(onlineClients, uuid)
 		} else {
 			offlineClients = append(offlineClients, uuid)
 		}
 	}
-	if len(onlineClients) == 0 {
+	deadline := time.Now().Add(defaultQueueDuration)
+	if req.QueueOffline && req.Deadline != "" {
+		t, err := time.Parse(time.RFC3339, req.Deadline)
+		if err != nil {
+			api.RespondError(c, 400, "Invalid deadline: "+err.Error())
+			return
+		}
+		if !t.After(time.Now()) || t.After(time.Now().Add(maxQueueDuration)) {
+			api.RespondError(c, 400, "Deadline must be within the next 30 days")
+			return
+		}
+		deadline = t
+	}
+	if len(onlineClients) == 0 && (!req.QueueOffline || len(offlineClients) == 0) {
 		api.RespondError(c, 400, "No clients connected")
 		return
 	}
 	taskId := utils.GenerateRandomString(16)
-	if err := tasks.CreateTask(taskId, append(onlineClients, offlineClients...), req.Command); err != nil {
+	if err := tasks.CreateTask(taskId, append(onlineClients, offlineClients...), req.Command, req.Timeout); err != nil {
 		api.RespondError(c, 500, "Failed to create task: "+err.Error())
 		return
 	}
-	for _, uuid := range onlineClients {
-		var send struct {
-			Message string `json:"message"`
-			Command string `json:"command"`
-			TaskId  string `json:"task_id"`
+	if req.QueueOffline {
+		if err := tasks.QueueTaskResults(taskId, offlineClients, deadline); err != nil {
+			api.RespondError(c, 500, "Failed to queue task: "+err.Error())
+			return
 		}
-		send.Message = "exec"
-		send.Command = req.Command
-		send.TaskId = taskId
-
-		payload, _ := json.Marshal(send)
-		client := ws.GetConnectedClients()[uuid]
+		// 判断在线状态后刚好上线的节点，补一次下发
+		for _, uuid := range offlineClients {
+			if w := ws.GetMessageWriter(uuid); w != nil {
+				go api.DeliverQueuedTasks(uuid, w)
+			}
+		}
+	}
+	for _, uuid := range onlineClients {
+		client := ws.GetMessageWriter(uuid)
 		if client != nil {
-			if err := client.WriteMessage(websocket.TextMessage, payload); err != nil {
+			if err := api.SendExecTask(client, taskId, req.Command, req.Timeout); err != nil {
 				api.RespondError(c, 400, "Client connection is broke: "+uuid)
 				return
 			}
@@ -77,6 +110,15 @@ func Exec(c *gin.Context) {
 	}
 	uuid, _ := c.Get("uuid")
 	auditlog.Log(c.ClientIP(), uuid.(string), "REC, task id: "+taskId, "warn")
+	if req.QueueOffline {
+		api.RespondSuccess(c, gin.H{
+			"task_id":  taskId,
+			"clients":  onlineClients,
+			"queued":   offlineClients,
+			"deadline": models.FromTime(deadline),
+		})
+		return
+	}
 	api.RespondSuccess(c, gin.H{
 		"task_id": taskId,
 		"clients": onlineClients,
@@ -45,5 +45,25 @@ func GetLogs(c *gin.Context) {
 		api.RespondError(c, 500, "Failed to retrieve logs: "+err.Error())
 		return
 	}
-	api.RespondSuccess(c, gin.H{"logs": logs, "total": total})
+	// 附带操作者用户名，便于区分多用户操作
+	uuidSet := make(map[string]struct{})
+	for _, l := range logs {
+		if l.UUID != "" {
+			uuidSet[l.UUID] = struct{}{}
+		}
+	}
+	users := make(map[string]string, len(uuidSet))
+	if len(uuidSet) > 0 {
+		uuids := make([]string, 0, len(uuidSet))
+		for u := range uuidSet {
+			uuids = append(uuids, u)
+		}
+		var list []models.User
+		if err := db.Select("uuid", "username").Where("uuid IN ?", uuids).Find(&list).Error; err == nil {
+			for _, u := range list {
+				users[u.UUID] = u.Username
+			}
+		}
+	}
+	api.RespondSuccess(c, gin.H{"logs": logs, "total": total, "users": users})
 }
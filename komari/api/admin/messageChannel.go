@@ -0,0 +1,151 @@
+package admin
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/komari-monitor/komari/api"
+	"github.com/komari-monitor/komari/database"
+	"github.com/komari-monitor/komari/database/auditlog"
+	"github.com/komari-monitor/komari/database/models"
+	"github.com/komari-monitor/komari/utils/messageSender"
+	"github.com/komari-monitor/komari/utils/messageSender/factory"
+)
+
+// GET /api/admin/settings/message-channel
+func ListMessageChannels(c *gin.Context) {
+	list, err := database.GetAllMessageChannels()
+	if err != nil {
+		api.RespondError(c, 500, "Failed to retrieve message channels: "+err.Error())
+		return
+	}
+	api.RespondSuccess(c, list)
+}
+
+// POST /api/admin/settings/message-channel，id 为 0 时新建
+func SaveMessageChannel(c *gin.Context) {
+	// 未提供 enabled 时默认启用；不放在 gorm default 中，否则显式的 false 会被默认值覆盖
+	channel := models.MessageChannel{Enabled: true}
+	if err := c.ShouldBindJSON(&channel); err != nil {
+		api.RespondError(c, 400, "Invalid configuration: "+err.Error())
+		return
+	}
+	channel.Name = strings.TrimSpace(channel.Name)
+	if channel.Name == "" {
+		api.RespondError(c, 400, "Channel name is required")
+		return
+	}
+	if _, exists := factory.GetConstructor(channel.Provider); !exists {
+		api.RespondError(c, 404, "Provider not found: "+channel.Provider)
+		return
+	}
+	if channel.Addition == "" {
+		channel.Addition = "{}"
+	}
+	if err := database.SaveMessageChannel(&channel); err != nil {
+		api.RespondError(c, 500, "Failed to save message channel: "+err.Error())
+		return
+	}
+	if err := messageSender.ReloadChannels(); err != nil {
+		api.RespondError(c, 500, "Failed to reload message channels: "+err.Error())
+		return
+	}
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), "save message channel:"+channel.Name, "info")
+	api.RespondSuccess(c, gin.H{"id": channel.ID})
+}
+
+// POST /api/admin/settings/message-channel/delete
+func DeleteMessageChannels(c *gin.Context) {
+	var req struct {
+		ID []uint `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.RespondError(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+	if err := database.DeleteMessageChannels(req.ID); err != nil {
+		api.RespondError(c, 500, "Failed to delete message channels: "+err.Error())
+		return
+	}
+	if err := messageSender.ReloadChannels(); err != nil {
+		api.RespondError(c, 500, "Failed to reload message channels: "+err.Error())
+		return
+	}
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), "delete message channels", "info")
+	api.RespondSuccess(c, nil)
+}
+
+// POST /api/admin/settings/message-channel/test body: name
+func TestMessageChannel(c *gin.Context) {
+	var req struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.RespondError(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+	if err := messageSender.TestChannel(req.Name); err != nil {
+		api.RespondError(c, 500, "Failed to send message: "+err.Error())
+		return
+	}
+	api.RespondSuccess(c, nil)
+}
+
+// GET /api/admin/settings/message-route
+func ListMessageRoutes(c *gin.Context) {
+	list, err := database.GetAllMessageRoutes()
+	if err != nil {
+		api.RespondError(c, 500, "Failed to retrieve message routes: "+err.Error())
+		return
+	}
+	api.RespondSuccess(c, list)
+}
+
+// POST /api/admin/settings/message-route，id 为 0 时新建
+func SaveMessageRoute(c *gin.Context) {
+	route := models.MessageRoute{Enabled: true}
+	if err := c.ShouldBindJSON(&route); err != nil {
+		api.RespondError(c, 400, "Invalid configuration: "+err.Error())
+		return
+	}
+	if len(route.Channels) == 0 {
+		api.RespondError(c, 400, "At least one channel is required")
+		return
+	}
+	for _, field := range []*models.StringArray{&route.Events, &route.Groups, &route.Tags, &route.Severities} {
+		if *field == nil {
+			*field = models.StringArray{}
+		}
+	}
+	if err := database.SaveMessageRoute(&route); err != nil {
+		api.RespondError(c, 500, "Failed to save message route: "+err.Error())
+		return
+	}
+	if err := messageSender.ReloadChannels(); err != nil {
+		api.RespondError(c, 500, "Failed to reload message routes: "+err.Error())
+		return
+	}
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), "save message route:"+route.Name, "info")
+	api.RespondSuccess(c, gin.H{"id": route.ID})
+}
+
+// POST /api/admin/settings/message-route/delete
+func DeleteMessageRoutes(c *gin.Context) {
+	var req struct {
+		ID []uint `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.RespondError(c, 400, "Invalid request: "+err.Error())
+		return
+	}
+	if err := database.DeleteMessageRoutes(req.ID); err != nil {
+		api.RespondError(c, 500, "Failed to delete message routes: "+err.Error())
+		return
+	}
+	if err := messageSender.ReloadChannels(); err != nil {
+		api.RespondError(c, 500, "Failed to reload message routes: "+err.Error())
+		return
+	}
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), "delete message routes", "info")
+	api.RespondSuccess(c, nil)
+}
@@ -33,6 +33,10 @@ func AddLoadNotification(c *gin.Context) {
 		api.RespondError(c, http.StatusBadRequest, "Ratio must be between 0 and 1")
 		return
 	}
+	if !api.CanAccessTargets(c, req.Clients...) {
+		api.RespondError(c, http.StatusForbidden, "Permission denied for some clients")
+		return
+	}
 
 	if taskID, err := notification.AddLoadNotification(req.Clients, req.Name, req.Metric, req.Threshold, req.Ratio, req.Interval); err != nil {
 		api.RespondError(c, http.StatusInternalServerError, err.Error())
@@ -50,6 +54,10 @@ func DeleteLoadNotification(c *gin.Context) {
 		api.RespondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if !canAccessLoadNotifications(c, req.ID...) {
+		api.RespondError(c, http.StatusForbidden, "Permission denied for some notifications")
+		return
+	}
 
 	if err := notification.DeleteLoadNotification(req.ID); err != nil {
 		api.RespondError(c, http.StatusInternalServerError, err.Error())
@@ -68,6 +76,22 @@ func EditLoadNotification(c *gin.Context) {
 		api.RespondError(c, http.StatusBadRequest, "Invalid request data")
 		return
 	}
+	ids := make([]uint, 0, len(req.Notifications))
+	for _, n := range req.Notifications {
+		if n == nil {
+			api.RespondError(c, http.StatusBadRequest, "Invalid request data")
+			return
+		}
+		if len(n.Clients) > 0 && !api.CanAccessTargets(c, n.Clients...) {
+			api.RespondError(c, http.StatusForbidden, "Permission denied for some clients")
+			return
+		}
+		ids = append(ids, n.Id)
+	}
+	if !canAccessLoadNotifications(c, ids...) {
+		api.RespondError(c, http.StatusForbidden, "Permission denied for some notifications")
+		return
+	}
 
 	if err := notification.EditLoadNotification(req.Notifications); err != nil {
 		api.RespondError(c, http.StatusInternalServerError, err.Error())
@@ -86,5 +110,13 @@ func GetAllLoadNotifications(c *gin.Context) {
 		return
 	}
 
-	api.RespondSuccess(c, notifications)
+	api.RespondSuccess(c, api.FilterByTargets(c, notifications, loadNotificationClients))
+}
+
+func loadNotificationID(n models.LoadNotification) uint          { return n.Id }
+func loadNotificationClients(n models.LoadNotification) []string { return n.Clients }
+
+// canAccessLoadNotifications 节点受限的用户只能管理节点全部在其范围内的负载通知
+func canAccessLoadNotifications(c *gin.Context, ids ...uint) bool {
+	return api.CanAccessItems(c, notification.GetAllLoadNotifications, loadNotificationID, loadNotificationClients, ids...)
 }
@@ -0,0 +1,78 @@
+package notification
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/komari-monitor/komari/api"
+	"github.com/komari-monitor/komari/database/auditlog"
+	"github.com/komari-monitor/komari/database/maintenance"
+	"github.com/komari-monitor/komari/database/models"
+)
+
+func GetMaintenanceWindows(c *gin.Context) {
+	list, err := maintenance.List()
+	if err != nil {
+		api.RespondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	api.RespondSuccess(c, api.FilterByTargets(c, list, maintenanceTargets))
+}
+
+// POST body: models.MaintenanceWindow，id 为 0 时新建
+func SaveMaintenanceWindow(c *gin.Context) {
+	// 未提供 enabled 时默认启用；不放在 gorm default 中，否则显式的 false 会被默认值覆盖
+	w := models.MaintenanceWindow{Enabled: true}
+	if err := c.ShouldBindJSON(&w); err != nil {
+		api.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !api.TargetScope(c)(maintenanceTargets(w)) || (w.ID != 0 && !canAccessMaintenanceWindows(c, w.ID)) {
+		api.RespondError(c, http.StatusForbidden, "Permission denied.")
+		return
+	}
+	action := "update"
+	if w.ID == 0 {
+		action = "create"
+	}
+	if err := maintenance.Save(&w); err != nil {
+		api.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), fmt.Sprintf("%s maintenance window:%s (%d)", action, w.Name, w.ID), "info")
+	api.RespondSuccess(c, gin.H{"id": w.ID})
+}
+
+// POST body: id []uint
+func DeleteMaintenanceWindows(c *gin.Context) {
+	var req struct {
+		ID []uint `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !canAccessMaintenanceWindows(c, req.ID...) {
+		api.RespondError(c, http.StatusForbidden, "Permission denied.")
+		return
+	}
+	if err := maintenance.Delete(req.ID); err != nil {
+		api.RespondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), fmt.Sprintf("delete maintenance windows:%v", req.ID), "info")
+	api.RespondSuccess(c, nil)
+}
+
+// maintenanceTargets 窗口按分组或标签匹配时可能覆盖任意节点，按全部节点处理
+func maintenanceTargets(w models.MaintenanceWindow) []string {
+	if len(w.Groups) > 0 || len(w.Tags) > 0 {
+		return nil
+	}
+	return w.Clients
+}
+
+func canAccessMaintenanceWindows(c *gin.Context, ids ...uint) bool {
+	return api.CanAccessItems(c, maintenance.List, func(w models.MaintenanceWindow) uint { return w.ID }, maintenanceTargets, ids...)
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/komari-monitor/komari/api"
 	"github.com/komari-monitor/komari/database/dbcore"
 	"github.com/komari-monitor/komari/database/models"
+	"github.com/komari-monitor/komari/database/notification"
 	"gorm.io/gorm/clause"
 )
 
@@ -15,6 +16,10 @@ func EnableOfflineNotification(c *gin.Context) {
 		api.RespondError(c, 400, "Invalid request body: "+err.Error())
 		return
 	}
+	if !api.CanAccessClients(c, uuids...) {
+		api.RespondError(c, 403, "Permission denied for some clients")
+		return
+	}
 	var notifications []models.OfflineNotification
 	for _, uuid := range uuids {
 		notifications = append(notifications, models.OfflineNotification{
@@ -43,6 +48,10 @@ func DisableOfflineNotification(c *gin.Context) {
 		api.RespondError(c, 400, "Invalid request body: "+err.Error())
 		return
 	}
+	if !api.CanAccessClients(c, uuids...) {
+		api.RespondError(c, 403, "Permission denied for some clients")
+		return
+	}
 	var notifications []models.OfflineNotification
 	for _, uuid := range uuids {
 		notifications = append(notifications, models.OfflineNotification{
@@ -83,14 +92,12 @@ func EditOfflineNotification(c *gin.Context) {
 			api.RespondError(c, 400, "GracePeriod must be a positive integer")
 			return
 		}
+		if !api.CanAccessClients(c, noti.Client) {
+			api.RespondError(c, 403, "Permission denied for some clients")
+			return
+		}
 	}
-	err := dbcore.GetDBInstance().Model(&models.OfflineNotification{}).
-		Clauses(clause.OnConflict{
-			Columns:   []clause.Column{{Name: "client"}},
-			DoUpdates: clause.AssignmentColumns([]string{"enable", "grace_period"}),
-		}).
-		Select("*").
-		Create(notifications).Error
+	err := notification.UpsertOfflineNotifications(notifications)
 	if err != nil {
 		api.RespondError(c, 500, "Failed to edit offline notifications: "+err.Error())
 		return
@@ -105,5 +112,12 @@ func ListOfflineNotifications(c *gin.Context) {
 		api.RespondError(c, 500, "Failed to list offline notifications: "+err.Error())
 		return
 	}
-	api.RespondSuccess(c, notifications)
+	inScope := api.ClientScope(c)
+	list := make([]models.OfflineNotification, 0, len(notifications))
+	for _, noti := range notifications {
+		if inScope(noti.Client) {
+			list = append(list, noti)
+		}
+	}
+	api.RespondSuccess(c, list)
 }
@@ -0,0 +1,136 @@
+package notification
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/komari-monitor/komari/api"
+	"github.com/komari-monitor/komari/database/auditlog"
+	"github.com/komari-monitor/komari/database/models"
+	"github.com/komari-monitor/komari/database/notification"
+)
+
+func GetAllAlertRules(c *gin.Context) {
+	rules, err := notification.GetAllAlertRules()
+	if err != nil {
+		api.RespondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	api.RespondSuccess(c, api.FilterByTargets(c, rules, alertRuleClients))
+}
+
+// POST body: models.AlertRule
+func AddAlertRule(c *gin.Context) {
+	// 未提供的字段使用默认值；不放在 gorm default 中，否则显式的 false 会被默认值覆盖
+	rule := models.AlertRule{Enabled: true, NotifyRecovery: true, Duration: 300}
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		api.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	rule.ID = 0
+	if !api.CanAccessTargets(c, rule.Clients...) {
+		api.RespondError(c, http.StatusForbidden, "Permission denied for some clients")
+		return
+	}
+	if err := notification.AddAlertRule(&rule); err != nil {
+		api.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), "create alert rule:"+rule.Name, "info")
+	api.RespondSuccess(c, gin.H{"id": rule.ID})
+}
+
+// POST body: models.AlertRule（需携带 id）
+func EditAlertRule(c *gin.Context) {
+	var rule models.AlertRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		api.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if rule.ID == 0 {
+		api.RespondError(c, http.StatusBadRequest, "id is required")
+		return
+	}
+	if !api.CanAccessTargets(c, rule.Clients...) {
+		api.RespondError(c, http.StatusForbidden, "Permission denied for some clients")
+		return
+	}
+	if !canAccessAlertRules(c, rule.ID) {
+		api.RespondError(c, http.StatusForbidden, "Permission denied for some alert rules")
+		return
+	}
+	if err := notification.EditAlertRule(&rule); err != nil {
+		api.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), "edit alert rule:"+rule.Name, "info")
+	api.RespondSuccess(c, nil)
+}
+
+// POST body: id []uint
+func DeleteAlertRule(c *gin.Context) {
+	var req struct {
+		ID []uint `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !canAccessAlertRules(c, req.ID...) {
+		api.RespondError(c, http.StatusForbidden, "Permission denied for some alert rules")
+		return
+	}
+	if err := notification.DeleteAlertRule(req.ID); err != nil {
+		api.RespondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), "delete alert rules", "info")
+	api.RespondSuccess(c, nil)
+}
+
+func alertRuleID(r models.AlertRule) uint          { return r.ID }
+func alertRuleClients(r models.AlertRule) []string { return r.Clients }
+
+// canAccessAlertRules 节点受限的用户只能管理节点全部在其范围内的告警规则
+func canAccessAlertRules(c *gin.Context, ids ...uint) bool {
+	return api.CanAccessItems(c, notification.GetAllAlertRules, alertRuleID, alertRuleClients, ids...)
+}
+
+// GET ?active=true&limit=100
+func GetAlertIncidents(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	list, err := notification.GetAlertIncidents(c.Query("active") == "true", limit)
+	if err != nil {
+		api.RespondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	inScope := api.ClientScope(c)
+	filtered := make([]models.AlertIncident, 0, len(list))
+	for _, inc := range list {
+		if inScope(inc.Client) {
+			filtered = append(filtered, inc)
+		}
+	}
+	api.RespondSuccess(c, filtered)
+}
+
+// POST /api/admin/notification/rule/incidents/:id/ack
+func AckAlertIncident(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		api.RespondError(c, http.StatusBadRequest, "Invalid incident ID")
+		return
+	}
+	inc, err := notification.GetAlertIncident(uint(id))
+	if err != nil || !api.CanAccessClients(c, inc.Client) {
+		api.RespondError(c, http.StatusNotFound, "Incident not found")
+		return
+	}
+	if err := notification.AckAlertIncident(uint(id), c.GetString("uuid")); err != nil {
+		api.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), "acknowledge alert incident:"+c.Param("id"), "info")
+	api.RespondSuccess(c, nil)
+}
@@ -0,0 +1,90 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/komari-monitor/komari/cmd/flags"
+	"github.com/komari-monitor/komari/database/models"
+	"github.com/komari-monitor/komari/database/notification"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlertRuleHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	flags.DatabaseType = "sqlite"
+	flags.DatabaseFile = filepath.Join(t.TempDir(), "komari.db")
+
+	r := gin.New()
+	// 携带 X-Scope 时模拟限定节点的令牌
+	r.Use(func(c *gin.Context) {
+		if scope := c.GetHeader("X-Scope"); scope != "" {
+			c.Set("api_token", &models.ApiToken{Clients: models.StringArray{scope}})
+		}
+	})
+	r.GET("/rules", GetAllAlertRules)
+	r.POST("/rules/add", AddAlertRule)
+	r.POST("/rules/edit", EditAlertRule)
+	r.POST("/rules/delete", DeleteAlertRule)
+	do := func(method, path, scope string, body any) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		if scope != "" {
+			req.Header.Set("X-Scope", scope)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	conditions := []map[string]any{{"metric": "cpu", "operator": ">", "threshold": 90}}
+
+	// 未提供的字段使用默认值
+	w := do(http.MethodPost, "/rules/add", "", map[string]any{"name": "all", "conditions": conditions})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = do(http.MethodPost, "/rules/add", "a", map[string]any{"name": "a", "clients": []string{"a"}, "conditions": conditions})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	rules, err := notification.GetAllAlertRules()
+	assert.NoError(t, err)
+	if !assert.Len(t, rules, 2) {
+		return
+	}
+	all, scoped := rules[0], rules[1]
+	assert.True(t, all.Enabled)
+	assert.True(t, all.NotifyRecovery)
+	assert.Equal(t, 300, all.Duration)
+
+	// 显式的 false / 0 在编辑后保留
+	edit := map[string]any{"id": all.ID, "name": "all", "enabled": false, "notify_recovery": false, "duration": 0, "conditions": conditions}
+	w = do(http.MethodPost, "/rules/edit", "", edit)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	rules, _ = notification.GetAllAlertRules()
+	assert.False(t, rules[0].Enabled)
+	assert.False(t, rules[0].NotifyRecovery)
+	assert.Equal(t, 0, rules[0].Duration)
+
+	// 限定节点的调用方只能看到并管理范围内的规则
+	w = do(http.MethodGet, "/rules", "a", nil)
+	var resp struct {
+		Data []models.AlertRule `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	if assert.Len(t, resp.Data, 1) {
+		assert.Equal(t, scoped.ID, resp.Data[0].ID)
+	}
+	edit["enabled"] = true
+	w = do(http.MethodPost, "/rules/edit", "a", edit)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	edit["clients"] = []string{"a"}
+	w = do(http.MethodPost, "/rules/edit", "a", edit)
+	assert.Equal(t, http.StatusForbidden, w.Code, "a rule for all nodes cannot be taken over")
+	w = do(http.MethodPost, "/rules/delete", "a", map[string]any{"id": []uint{all.ID}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = do(http.MethodPost, "/rules/delete", "a", map[string]any{"id": []uint{scoped.ID}})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
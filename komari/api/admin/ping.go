@@ -69,6 +69,10 @@ func AddPingTask(c *gin.Context) {
 			api.RespondError(c, http.StatusBadRequest, "interval must be greater than 0")
 			return
 		}
+		if !api.CanAccessTargets(c, clients...) {
+			api.RespondError(c, http.StatusForbidden, "Permission denied for some clients")
+			return
+		}
 		modelTasks = append(modelTasks, models.PingTask{
 			Clients:  clients,
 			Name:     strings.TrimSpace(item.Name),
@@ -100,6 +104,10 @@ func DeletePingTask(c *gin.Context) {
 		api.RespondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if !canAccessPingTasks(c, req.ID...) {
+		api.RespondError(c, http.StatusForbidden, "Permission denied for some tasks")
+		return
+	}
 
 	if err := tasks.DeletePingTask(req.ID); err != nil {
 		api.RespondError(c, http.StatusInternalServerError, err.Error())
@@ -118,6 +126,23 @@ func EditPingTask(c *gin.Context) {
 		api.RespondError(c, http.StatusBadRequest, "Invalid request data")
 		return
 	}
+	ids := make([]uint, 0, len(req.Tasks))
+	for _, task := range req.Tasks {
+		if task == nil {
+			api.RespondError(c, http.StatusBadRequest, "Invalid request data")
+			return
+		}
+		// 未修改节点时 Clients 为空
+		if len(task.Clients) > 0 && !api.CanAccessTargets(c, task.Clients...) {
+			api.RespondError(c, http.StatusForbidden, "Permission denied for some clients")
+			return
+		}
+		ids = append(ids, task.Id)
+	}
+	if !canAccessPingTasks(c, ids...) {
+		api.RespondError(c, http.StatusForbidden, "Permission denied for some tasks")
+		return
+	}
 
 	if err := tasks.EditPingTask(req.Tasks); err != nil {
 		api.RespondError(c, http.StatusInternalServerError, err.Error())
@@ -136,14 +161,27 @@ func GetAllPingTasks(c *gin.Context) {
 		return
 	}
 
-	api.RespondSuccess(c, tasks)
+	api.RespondSuccess(c, api.FilterByTargets(c, tasks, pingTaskClients))
 }
 
 // ClearPingRecords 清空延迟检测历史数据
 func ClearPingRecords(c *gin.Context) {
+	// 清空的是全部节点的数据
+	if api.IsClientScoped(c) {
+		api.RespondError(c, http.StatusForbidden, "Permission denied.")
+		return
+	}
 	if err := tasks.DeleteAllPingRecords(); err != nil {
 		api.RespondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 	api.RespondSuccess(c, nil)
 }
+
+func pingTaskID(t models.PingTask) uint          { return t.Id }
+func pingTaskClients(t models.PingTask) []string { return t.Clients }
+
+// canAccessPingTasks 节点受限的用户只能管理节点全部在其范围内的任务
+func canAccessPingTasks(c *gin.Context, ids ...uint) bool {
+	return api.CanAccessItems(c, tasks.GetAllPingTasks, pingTaskID, pingTaskClients, ids...)
+}
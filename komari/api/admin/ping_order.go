@@ -26,6 +26,14 @@ func OrderPingTask(c *gin.Context) {
 		}
 		weights[uint(id)] = weight
 	}
+	ids := make([]uint, 0, len(weights))
+	for id := range weights {
+		ids = append(ids, id)
+	}
+	if !canAccessPingTasks(c, ids...) {
+		api.RespondError(c, 403, "Permission denied for some tasks")
+		return
+	}
 
 	if err := tasks.OrderPingTasks(weights); err != nil {
 		api.RespondError(c, 500, "Failed to update ping task order: "+err.Error())
@@ -25,6 +25,7 @@ type scriptPayload struct {
 	CronExpr         string                        `json:"cron_expr"`
 	TriggerName      string                        `json:"trigger_name"`
 	MessageType      string                        `json:"message_type"`
+	RunnerClient     string                        `json:"runner_client"`
 	DependsOnScripts []uint                        `json:"depends_on_scripts"`
 	DependsOnFolders []uint                        `json:"depends_on_folders"`
 }
@@ -47,11 +48,45 @@ func toModelScript(req *scriptPayload) *models.Script {
 		CronExpr:         req.CronExpr,
 		TriggerName:      req.TriggerName,
 		MessageType:      req.MessageType,
+		RunnerClient:     req.RunnerClient,
 		DependsOnScripts: models.UIntArray(req.DependsOnScripts),
 		DependsOnFolders: models.UIntArray(req.DependsOnFolders),
 	}
 }
 
+// scriptTargets 脚本可能运行的节点；Clients 为空时定时与事件脚本会在全部节点上运行
+func scriptTargets(clients []string, runner string) []string {
+	if len(clients) == 0 {
+		return nil
+	}
+	targets := append([]string{}, clients...)
+	if runner != "" {
+		targets = append(targets, runner)
+	}
+	return targets
+}
+
+// scopedScripts 只保留当前用户可管理的脚本
+func scopedScripts(c *gin.Context, scripts []models.Script) []models.Script {
+	return api.FilterByTargets(c, scripts, func(s models.Script) []string {
+		return scriptTargets(s.Clients, s.RunnerClient)
+	})
+}
+
+// getScopedScript 获取脚本，超出当前用户节点范围时视为不存在
+func getScopedScript(c *gin.Context, id uint) (*models.Script, bool) {
+	s, err := scriptdb.GetScriptByID(id)
+	if err != nil {
+		api.RespondError(c, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	if !api.CanAccessTargets(c, scriptTargets(s.Clients, s.RunnerClient)...) {
+		api.RespondError(c, http.StatusNotFound, "Script not found")
+		return nil, false
+	}
+	return s, true
+}
+
 func GetScriptStructure(c *gin.Context) {
 	folders, err := scriptdb.GetAllFolders()
 	if err != nil {
@@ -65,7 +100,7 @@ func GetScriptStructure(c *gin.Context) {
 	}
 	api.RespondSuccess(c, gin.H{
 		"folders": folders,
-		"scripts": scripts,
+		"scripts": scopedScripts(c, scripts),
 	})
 }
 
@@ -120,7 +155,7 @@ func GetScripts(c *gin.Context) {
 		api.RespondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
-	api.RespondSuccess(c, list)
+	api.RespondSuccess(c, scopedScripts(c, list))
 }
 
 func AddScript(c *gin.Context) {
@@ -132,6 +167,10 @@ func AddScript(c *gin.Context) {
 	if req.MessageType == "" {
 		req.MessageType = "script"
 	}
+	if !api.CanAccessTargets(c, scriptTargets(req.Clients, req.RunnerClient)...) {
+		api.RespondError(c, http.StatusForbidden, "Permission denied for some clients")
+		return
+	}
 	model := toModelScript(&req)
 	if err := scriptdb.CreateScript(model); err != nil {
 		api.RespondError(c, http.StatusInternalServerError, err.Error())
@@ -151,6 +190,16 @@ func EditScript(c *gin.Context) {
 	}
 	var modelsToUpdate []*models.Script
 	for _, item := range req.Scripts {
+		if item == nil {
+			continue
+		}
+		if _, ok := getScopedScript(c, item.ID); !ok {
+			return
+		}
+		if !api.CanAccessTargets(c, scriptTargets(item.Clients, item.RunnerClient)...) {
+			api.RespondError(c, http.StatusForbidden, "Permission denied for some clients")
+			return
+		}
 		modelsToUpdate = append(modelsToUpdate, toModelScript(item))
 	}
 	if err := scriptdb.UpdateScripts(modelsToUpdate); err != nil {
@@ -169,6 +218,9 @@ func DeleteScript(c *gin.Context) {
 		api.RespondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if _, ok := getScopedScript(c, req.ID); !ok {
+		return
+	}
 	if err := scriptdb.DeleteScript(req.ID); err != nil {
 		api.RespondError(c, http.StatusInternalServerError, err.Error())
 		return
@@ -200,6 +252,10 @@ func ExecuteScript(c *gin.Context) {
 		api.RespondError(c, http.StatusBadRequest, "no clients specified")
 		return
 	}
+	if !api.CanAccessClients(c, targets...) {
+		api.RespondError(c, http.StatusForbidden, "Permission denied for some clients")
+		return
+	}
 	execID, err := scriptdb.DispatchScript(s, targets, "manual", req.Params)
 	if err != nil {
 		api.RespondError(c, http.StatusInternalServerError, err.Error())
@@ -231,6 +287,10 @@ func StopScript(c *gin.Context) {
 		api.RespondError(c, http.StatusBadRequest, "no clients specified")
 		return
 	}
+	if !api.CanAccessClients(c, targets...) {
+		api.RespondError(c, http.StatusForbidden, "Permission denied for some clients")
+		return
+	}
 	payload := map[string]interface{}{
 		"message":   "script_stop",
 		"script_id": req.ScriptID,
@@ -274,6 +334,10 @@ func ForceStopScript(c *gin.Context) {
 		api.RespondError(c, http.StatusBadRequest, "no clients specified")
 		return
 	}
+	if !api.CanAccessClients(c, targets...) {
+		api.RespondError(c, http.StatusForbidden, "Permission denied for some clients")
+		return
+	}
 	// 立即标记状态并推送停止（在线）；离线的加入待发送列表
 	online := ws.GetConnectedClients()
 	sent := 0
@@ -302,6 +366,9 @@ func GetScriptHistory(c *gin.Context) {
 		return
 	}
 	sid, _ := strconv.Atoi(scriptIDStr)
+	if _, ok := getScopedScript(c, uint(sid)); !ok {
+		return
+	}
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 	history, err := scriptdb.ListHistory(uint(sid), limit, offset)
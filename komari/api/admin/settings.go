@@ -5,6 +5,7 @@ import (
 
 	"github.com/komari-monitor/komari/api"
 	"github.com/komari-monitor/komari/database/auditlog"
+	"github.com/komari-monitor/komari/database/billing"
 	"github.com/komari-monitor/komari/database/config"
 	"github.com/komari-monitor/komari/database/models"
 	"github.com/komari-monitor/komari/database/records"
@@ -41,6 +42,19 @@ func EditSettings(c *gin.Context) {
 		return
 	}
 
+	// 汇率格式错误会使账单统计全部失败，保存前校验
+	if v, ok := cfg["billing_exchange_rates"]; ok && v != nil {
+		rates, isString := v.(string)
+		if !isString {
+			api.RespondError(c, 400, "Invalid billing_exchange_rates: expected a string")
+			return
+		}
+		if _, err := billing.ParseRates(rates); err != nil {
+			api.RespondError(c, 400, "Invalid billing_exchange_rates: "+err.Error())
+			return
+		}
+	}
+
 	cfg["id"] = 1 // Only one record
 	if err := config.Update(cfg); err != nil {
 		api.RespondError(c, 500, "Failed to update settings: "+err.Error())
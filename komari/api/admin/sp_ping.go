@@ -79,6 +79,10 @@ func AddSPPingTask(c *gin.Context) {
 			api.RespondError(c, http.StatusBadRequest, "type is required")
 			return
 		}
+		if !api.CanAccessTargets(c, clients...) {
+			api.RespondError(c, http.StatusForbidden, "Permission denied for some clients")
+			return
+		}
 		modelTasks = append(modelTasks, models.SPPingTask{
 			Clients:     clients,
 			Name:        strings.TrimSpace(item.Name),
@@ -112,6 +116,10 @@ func DeleteSPPingTask(c *gin.Context) {
 		api.RespondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if !canAccessSPPingTasks(c, req.ID...) {
+		api.RespondError(c, http.StatusForbidden, "Permission denied for some tasks")
+		return
+	}
 	if err := tasks.DeleteSPPingTask(req.ID); err != nil {
 		api.RespondError(c, http.StatusInternalServerError, err.Error())
 		return
@@ -127,6 +135,22 @@ func EditSPPingTask(c *gin.Context) {
 		api.RespondError(c, http.StatusBadRequest, "Invalid request data")
 		return
 	}
+	ids := make([]uint, 0, len(req.Tasks))
+	for _, task := range req.Tasks {
+		if task == nil {
+			api.RespondError(c, http.StatusBadRequest, "Invalid request data")
+			return
+		}
+		if len(task.Clients) > 0 && !api.CanAccessTargets(c, task.Clients...) {
+			api.RespondError(c, http.StatusForbidden, "Permission denied for some clients")
+			return
+		}
+		ids = append(ids, task.Id)
+	}
+	if !canAccessSPPingTasks(c, ids...) {
+		api.RespondError(c, http.StatusForbidden, "Permission denied for some tasks")
+		return
+	}
 	if err := tasks.EditSPPingTask(req.Tasks); err != nil {
 		api.RespondError(c, http.StatusInternalServerError, err.Error())
 		return
@@ -140,11 +164,15 @@ func GetAllSPPingTasks(c *gin.Context) {
 		api.RespondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
-	api.RespondSuccess(c, ts)
+	api.RespondSuccess(c, api.FilterByTargets(c, ts, spPingTaskClients))
 }
 
 // ClearSPPingRecords 清空历史数据
 func ClearSPPingRecords(c *gin.Context) {
+	if api.IsClientScoped(c) {
+		api.RespondError(c, http.StatusForbidden, "Permission denied.")
+		return
+	}
 	if err := tasks.DeleteAllSPPingRecords(); err != nil {
 		api.RespondError(c, http.StatusInternalServerError, err.Error())
 		return
@@ -160,9 +188,24 @@ func OrderSPPingTask(c *gin.Context) {
 		api.RespondError(c, http.StatusBadRequest, "Invalid request data")
 		return
 	}
+	ids := make([]uint, 0, len(req.Weights))
+	for id := range req.Weights {
+		ids = append(ids, id)
+	}
+	if !canAccessSPPingTasks(c, ids...) {
+		api.RespondError(c, http.StatusForbidden, "Permission denied for some tasks")
+		return
+	}
 	if err := tasks.OrderSPPingTasks(req.Weights); err != nil {
 		api.RespondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 	api.RespondSuccess(c, nil)
 }
+
+func spPingTaskID(t models.SPPingTask) uint          { return t.Id }
+func spPingTaskClients(t models.SPPingTask) []string { return t.Clients }
+
+func canAccessSPPingTasks(c *gin.Context, ids ...uint) bool {
+	return api.CanAccessItems(c, tasks.GetAllSPPingTasks, spPingTaskID, spPingTaskClients, ids...)
+}
@@ -1,12 +1,9 @@
 package admin
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
-	"net"
 	"net/http"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -16,8 +13,8 @@ import (
 	"github.com/komari-monitor/komari/api"
 	"github.com/komari-monitor/komari/database/auditlog"
 	"github.com/komari-monitor/komari/database/clients"
-	"github.com/komari-monitor/komari/database/credentials"
 	"github.com/komari-monitor/komari/database/models"
+	"github.com/komari-monitor/komari/utils/sshjob"
 	"golang.org/x/crypto/ssh"
 	"gorm.io/gorm"
 )
@@ -124,94 +121,11 @@ func (s *sshInstallSession) subscribe() chan string {
 }
 
 func buildSSHClient(target sshTarget) (*ssh.Client, *models.Credential, error) {
-	if target.Port <= 0 {
-		target.Port = 22
-	}
-	cred, err := credentials.Get(target.CredentialID)
-	if err != nil {
-		return nil, nil, err
-	}
-	secret, err := credentials.RevealSecret(cred.ID)
-	if err != nil {
-		return nil, nil, err
-	}
-	passphrase, err := credentials.RevealPassphrase(cred.ID)
-	if err != nil {
-		return nil, nil, err
-	}
-	var auth ssh.AuthMethod
-	switch cred.Type {
-	case models.CredentialTypePassword:
-		auth = ssh.Password(secret)
-	case models.CredentialTypeKey:
-		var signer ssh.Signer
-		if strings.TrimSpace(passphrase) != "" {
-			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(secret), []byte(passphrase))
-		} else {
-			signer, err = ssh.ParsePrivateKey([]byte(secret))
-		}
-		if err != nil {
-			return nil, nil, fmt.Errorf("invalid private key: %w", err)
-		}
-		auth = ssh.PublicKeys(signer)
-	default:
-		return nil, nil, fmt.Errorf("unsupported credential type: %s", cred.Type)
-	}
-
-	cfg := &ssh.ClientConfig{
-		User:            cred.Username,
-		Auth:            []ssh.AuthMethod{auth},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         10 * time.Second,
-	}
-	addr := net.JoinHostPort(target.Host, strconv.Itoa(target.Port))
-	client, err := ssh.Dial("tcp", addr, cfg)
-	if err != nil {
-		return nil, nil, err
-	}
-	return client, cred, nil
+	return sshjob.Dial(sshjob.Target{Host: target.Host, Port: target.Port, CredentialID: target.CredentialID})
 }
 
 func runSSHCommand(client *ssh.Client, cmd string, onLine func(string)) error {
-	sess, err := client.NewSession()
-	if err != nil {
-		return err
-	}
-	defer sess.Close()
-
-	stdout, err := sess.StdoutPipe()
-	if err != nil {
-		return err
-	}
-	stderr, err := sess.StderrPipe()
-	if err != nil {
-		return err
-	}
-
-	if err := sess.Start(cmd); err != nil {
-		return err
-	}
-
-	wg := sync.WaitGroup{}
-	wg.Add(2)
-	go func() {
-		defer wg.Done()
-		sc := bufio.NewScanner(stdout)
-		for sc.Scan() {
-			onLine(sc.Text())
-		}
-	}()
-	go func() {
-		defer wg.Done()
-		sc := bufio.NewScanner(stderr)
-		for sc.Scan() {
-			onLine(sc.Text())
-		}
-	}()
-
-	err = sess.Wait()
-	wg.Wait()
-	return err
+	return sshjob.Run(client, cmd, nil, onLine)
 }
 
 func TestSSHConnection(c *gin.Context) {
@@ -351,7 +265,7 @@ func StartSSHInstall(c *gin.Context) {
 			}
 
 			// 通过面板脚本执行安装（依赖远端可访问面板）
-			cmd = fmt.Sprintf("bash <(curl -fsSL %s/api/public/install.sh) %s", endpoint, shellQuoteArgs(args))
+			cmd = fmt.Sprintf("bash <(curl -fsSL %s/api/public/install.sh) %s", endpoint, sshjob.QuoteArgs(args))
 		}
 		s.appendLog("[INFO] Running: " + cmd)
 		err = runSSHCommand(client, cmd, func(line string) {
@@ -440,28 +354,35 @@ func GetSSHInstallStatus(c *gin.Context) {
 	api.RespondSuccess(c, gin.H{"done": done, "error": errStr})
 }
 
-func sseEscape(s string) string {
-	// SSE 每行以 data: 开头，这里只需避免出现 \r
-	return strings.ReplaceAll(s, "\r", "")
+// ListSSHKnownHosts 列出首次连接时记录的 SSH 主机密钥
+func ListSSHKnownHosts(c *gin.Context) {
+	list, err := sshjob.ListKnownHosts()
+	if err != nil {
+		api.RespondError(c, http.StatusInternalServerError, "Failed to list SSH known hosts: "+err.Error())
+		return
+	}
+	api.RespondSuccess(c, list)
 }
 
-func shellQuoteArgs(args []string) string {
-	quoted := make([]string, 0, len(args))
-	for _, a := range args {
-		quoted = append(quoted, shellQuote(a))
+// DeleteSSHKnownHost 删除记录的主机密钥，用于节点重装等主机密钥变化的情况，下次连接时重新记录
+// 查询参数：address，格式为 host:port
+func DeleteSSHKnownHost(c *gin.Context) {
+	address := c.Query("address")
+	if err := sshjob.ForgetHostKey(address); err != nil {
+		if IsSSHErrNotFound(err) {
+			api.RespondError(c, http.StatusNotFound, "SSH known host not found")
+			return
+		}
+		api.RespondError(c, http.StatusInternalServerError, err.Error())
+		return
 	}
-	return strings.Join(quoted, " ")
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), "delete ssh known host:"+address, "warn")
+	api.RespondSuccess(c, nil)
 }
 
-func shellQuote(s string) string {
-	// 单引号安全引用：' -> '\'' 
-	if s == "" {
-		return "''"
-	}
-	if !strings.ContainsAny(s, " \t\n'\"\\$`") {
-		return s
-	}
-	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+func sseEscape(s string) string {
+	// SSE 每行以 data: 开头，这里只需避免出现 \r
+	return strings.ReplaceAll(s, "\r", "")
 }
 
 func IsSSHErrNotFound(err error) bool {
@@ -0,0 +1,164 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/komari-monitor/komari/api"
+	"github.com/komari-monitor/komari/database/auditlog"
+	"github.com/komari-monitor/komari/utils/sshjob"
+	"gorm.io/gorm"
+)
+
+// StartSSHJob 通过 SSH 在多个节点上批量执行命令或安装脚本
+// clients 留空表示所有已配置 SSH 的节点
+func StartSSHJob(c *gin.Context) {
+	var req struct {
+		Clients     []string `json:"clients"`
+		Kind        string   `json:"kind" binding:"required"` // command / script
+		Command     string   `json:"command"`
+		Script      string   `json:"script"`
+		Endpoint    string   `json:"endpoint"`
+		Args        []string `json:"args"`
+		Concurrency int      `json:"concurrency"`
+		TimeoutSec  int      `json:"timeout_sec"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.RespondError(c, http.StatusBadRequest, "请求格式错误: "+err.Error())
+		return
+	}
+	job, err := sshjob.Start(sshjob.Options{
+		Clients:     req.Clients,
+		Kind:        req.Kind,
+		Command:     req.Command,
+		Script:      req.Script,
+		Endpoint:    strings.TrimRight(strings.TrimSpace(req.Endpoint), "/"),
+		Args:        req.Args,
+		Concurrency: req.Concurrency,
+		Timeout:     time.Duration(req.TimeoutSec) * time.Second,
+		UserUUID:    c.GetString("uuid"),
+		RequesterIp: c.ClientIP(),
+	})
+	if err != nil {
+		api.RespondError(c, http.StatusBadRequest, "创建任务失败: "+err.Error())
+		return
+	}
+	target := job.Command
+	if job.Kind == sshjob.KindScript {
+		target = strings.TrimSpace(job.Script + " " + job.Command)
+	}
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), fmt.Sprintf("ssh job start:%s, nodes:%d, %s:%s", job.ID, job.Total, job.Kind, target), "warn")
+	api.RespondSuccess(c, job)
+}
+
+// ListSSHJobs 列出批量任务历史
+// 查询参数：status、limit、offset
+func ListSSHJobs(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	list, total, err := sshjob.List(sshjob.Filter{
+		Status: c.Query("status"),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		api.RespondError(c, http.StatusInternalServerError, "Failed to list SSH jobs: "+err.Error())
+		return
+	}
+	api.RespondSuccess(c, gin.H{"jobs": list, "total": total})
+}
+
+// GetSSHJob 获取任务及各节点的执行结果
+func GetSSHJob(c *gin.Context) {
+	job, targets, err := sshjob.Get(c.Param("id"))
+	if err != nil {
+		respondSSHJobError(c, err)
+		return
+	}
+	api.RespondSuccess(c, gin.H{"job": job, "targets": targets})
+}
+
+// StreamSSHJob 以 SSE 推送任务的节点日志与状态，可用 ?client= 只看单个节点
+// 事件类型为 log / status / done，data 为 JSON
+func StreamSSHJob(c *gin.Context) {
+	events, ch, unsubscribe, err := sshjob.Subscribe(c.Param("id"))
+	if err != nil {
+		respondSSHJobError(c, err)
+		return
+	}
+	defer unsubscribe()
+	client := c.Query("client")
+
+	c.Writer.Header().Set("Content-Type", "text/event-stream")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+
+	write := func(ev sshjob.Event) {
+		if client != "" && ev.Client != "" && ev.Client != client {
+			return
+		}
+		data, _ := json.Marshal(ev)
+		fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", ev.Type, data)
+	}
+	// 先发历史
+	for _, ev := range events {
+		write(ev)
+	}
+	c.Writer.Flush()
+	if ch == nil {
+		return
+	}
+
+	notify := c.Request.Context().Done()
+	for {
+		select {
+		case <-notify:
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			write(ev)
+			c.Writer.Flush()
+		}
+	}
+}
+
+// CancelSSHJob 取消运行中的任务
+func CancelSSHJob(c *gin.Context) {
+	id := c.Param("id")
+	if err := sshjob.Cancel(id); err != nil {
+		api.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), "ssh job cancel:"+id, "warn")
+	api.RespondSuccess(c, nil)
+}
+
+// DeleteSSHJob 删除已结束的任务记录
+func DeleteSSHJob(c *gin.Context) {
+	id := c.Param("id")
+	if err := sshjob.Delete(id); err != nil {
+		respondSSHJobError(c, err)
+		return
+	}
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), "delete ssh job:"+id, "info")
+	api.RespondSuccess(c, nil)
+}
+
+func respondSSHJobError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		api.RespondError(c, http.StatusNotFound, "SSH job not found")
+	case errors.Is(err, sshjob.ErrRunning):
+		api.RespondError(c, http.StatusBadRequest, err.Error())
+	default:
+		api.RespondError(c, http.StatusInternalServerError, err.Error())
+	}
+}
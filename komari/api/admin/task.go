@@ -3,7 +3,10 @@ package admin
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/komari-monitor/komari/api"
+	"github.com/komari-monitor/komari/database/auditlog"
+	"github.com/komari-monitor/komari/database/models"
 	"github.com/komari-monitor/komari/database/tasks"
+	"github.com/komari-monitor/komari/ws"
 )
 
 func GetTasks(c *gin.Context) {
@@ -12,8 +15,12 @@ func GetTasks(c *gin.Context) {
 		api.RespondError(c, 500, "Failed to retrieve tasks: "+err.Error())
 		return
 	}
+	inScope := api.ClientScope(c)
 	var responseTasks []gin.H
 	for _, t := range dbTasks {
+		if !anyInScope(t.Clients, inScope) {
+			continue
+		}
 		results, err := tasks.GetTaskResultsByTaskId(t.TaskId)
 		if err != nil {
 			api.RespondError(c, 500, "Failed to retrieve task results: "+err.Error())
@@ -22,12 +29,18 @@ func GetTasks(c *gin.Context) {
 
 		var filteredResults []gin.H
 		for _, r := range results {
+			if !inScope(r.Client) {
+				continue
+			}
 			filteredResults = append(filteredResults, gin.H{
-				"client":      r.Client,
-				"result":      r.Result,
-				"exit_code":   r.ExitCode,
-				"finished_at": r.FinishedAt,
-				"created_at":  r.CreatedAt,
+				"client":       r.Client,
+				"result":       r.Result,
+				"exit_code":    r.ExitCode,
+				"finished_at":  r.FinishedAt,
+				"created_at":   r.CreatedAt,
+				"status":       r.Status,
+				"deadline":     r.Deadline,
+				"delivered_at": r.DeliveredAt,
 			})
 		}
 
@@ -35,6 +48,7 @@ func GetTasks(c *gin.Context) {
 			"task_id": t.TaskId,
 			"clients": t.Clients,
 			"command": t.Command,
+			"timeout": t.Timeout,
 			"results": filteredResults,
 		})
 	}
@@ -56,6 +70,11 @@ func GetTaskById(c *gin.Context) {
 		api.RespondError(c, 404, "Task not found")
 		return
 	}
+	inScope := api.ClientScope(c)
+	if !anyInScope(task.Clients, inScope) {
+		api.RespondError(c, 404, "Task not found")
+		return
+	}
 	results, err := tasks.GetTaskResultsByTaskId(taskId)
 	if err != nil {
 		api.RespondError(c, 500, "Failed to retrieve task results: "+err.Error())
@@ -63,18 +82,25 @@ func GetTaskById(c *gin.Context) {
 	}
 	var filteredResults []gin.H
 	for _, r := range results {
+		if !inScope(r.Client) {
+			continue
+		}
 		filteredResults = append(filteredResults, gin.H{
-			"client":      r.Client,
-			"result":      r.Result,
-			"exit_code":   r.ExitCode,
-			"finished_at": r.FinishedAt,
-			"created_at":  r.CreatedAt,
+			"client":       r.Client,
+			"result":       r.Result,
+			"exit_code":    r.ExitCode,
+			"finished_at":  r.FinishedAt,
+			"created_at":   r.CreatedAt,
+			"status":       r.Status,
+			"deadline":     r.Deadline,
+			"delivered_at": r.DeliveredAt,
 		})
 	}
 	api.RespondSuccess(c, gin.H{
 		"task_id": task.TaskId,
 		"clients": task.Clients,
 		"command": task.Command,
+		"timeout": task.Timeout,
 		"results": filteredResults,
 	})
 }
@@ -85,6 +111,10 @@ func GetTasksByClientId(c *gin.Context) {
 		api.RespondError(c, 400, "Client ID is required")
 		return
 	}
+	if !api.CanAccessClients(c, clientId) {
+		api.RespondError(c, 404, "No tasks found for this client")
+		return
+	}
 	tasks, err := tasks.GetTasksByClientId(clientId)
 	if err != nil {
 		api.RespondError(c, 500, "Failed to retrieve tasks: "+err.Error())
@@ -104,6 +134,10 @@ func GetSpecificTaskResult(c *gin.Context) {
 		api.RespondError(c, 400, "Task ID and Client ID are required")
 		return
 	}
+	if !api.CanAccessClients(c, clientId) {
+		api.RespondError(c, 404, "No result found for this task and client")
+		return
+	}
 	result, err := tasks.GetSpecificTaskResult(taskId, clientId)
 	if err != nil {
 		api.RespondError(c, 500, "Failed to retrieve task result: "+err.Error())
@@ -128,6 +162,14 @@ func GetTaskResultsByTaskId(c *gin.Context) {
 		api.RespondError(c, 500, "Failed to retrieve task results: "+err.Error())
 		return
 	}
+	inScope := api.ClientScope(c)
+	filtered := results[:0]
+	for _, r := range results {
+		if inScope(r.Client) {
+			filtered = append(filtered, r)
+		}
+	}
+	results = filtered
 	if len(results) == 0 {
 		api.RespondError(c, 404, "No results found for this task")
 		return
@@ -141,6 +183,10 @@ func GetAllTaskResultByUUID(c *gin.Context) {
 		api.RespondError(c, 400, "Client ID is required")
 		return
 	}
+	if !api.CanAccessClients(c, clientId) {
+		api.RespondError(c, 404, "No tasks found for this client")
+		return
+	}
 	results, err := tasks.GetAllTasksResultByUUID(clientId)
 	if err != nil {
 		api.RespondError(c, 500, "Failed to retrieve tasks: "+err.Error())
@@ -152,3 +198,94 @@ func GetAllTaskResultByUUID(c *gin.Context) {
 	}
 	api.RespondSuccess(c, results)
 }
+
+func anyInScope(uuids []string, inScope func(string) bool) bool {
+	for _, uuid := range uuids {
+		if inScope(uuid) {
+			return true
+		}
+	}
+	return false
+}
+
+// CancelTask 取消任务：排队中的直接标记为已取消，已下发的通知节点终止命令，
+// 节点会上报取消前的输出
+// 接受数据类型：
+// - clients: []string (可选，默认为任务的全部节点)
+func CancelTask(c *gin.Context) {
+	taskId := c.Param("task_id")
+	var req struct {
+		Clients []string `json:"clients"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			api.RespondError(c, 400, "Invalid request body: "+err.Error())
+			return
+		}
+	}
+	task, err := tasks.GetTaskByTaskId(taskId)
+	if err != nil {
+		api.RespondError(c, 404, "Task not found")
+		return
+	}
+	inScope := api.ClientScope(c)
+	if !anyInScope(task.Clients, inScope) {
+		api.RespondError(c, 404, "Task not found")
+		return
+	}
+	targets := req.Clients
+	if len(targets) == 0 {
+		for _, uuid := range task.Clients {
+			if inScope(uuid) {
+				targets = append(targets, uuid)
+			}
+		}
+	} else if !api.CanAccessClients(c, targets...) {
+		api.RespondError(c, 403, "Permission denied for some clients")
+		return
+	}
+	results, err := tasks.GetTaskResultsByTaskId(taskId)
+	if err != nil {
+		api.RespondError(c, 500, "Failed to retrieve task results: "+err.Error())
+		return
+	}
+	statusOf := make(map[string]string, len(results))
+	for _, r := range results {
+		statusOf[r.Client] = r.Status
+	}
+
+	var cancelled, signalled, skipped []string
+	for _, uuid := range targets {
+		switch statusOf[uuid] {
+		case models.TaskStatusPending:
+			ok, err := tasks.CancelPendingTask(taskId, uuid)
+			if err != nil {
+				api.RespondError(c, 500, "Failed to cancel task: "+err.Error())
+				return
+			}
+			if ok {
+				cancelled = append(cancelled, uuid)
+				continue
+			}
+			skipped = append(skipped, uuid)
+		case models.TaskStatusDelivered:
+			w := ws.GetMessageWriter(uuid)
+			if w == nil || api.SendExecCancel(w, taskId) != nil {
+				skipped = append(skipped, uuid)
+				continue
+			}
+			signalled = append(signalled, uuid)
+		default:
+			// 已结束或不属于该任务
+			skipped = append(skipped, uuid)
+		}
+	}
+	uuid, _ := c.Get("uuid")
+	auditlog.Log(c.ClientIP(), uuid.(string), "cancel task, task id: "+taskId, "warn")
+	api.RespondSuccess(c, gin.H{
+		"task_id":   taskId,
+		"cancelled": cancelled,
+		"signalled": signalled,
+		"skipped":   skipped,
+	})
+}
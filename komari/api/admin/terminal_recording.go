@@ -0,0 +1,98 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/komari-monitor/komari/api"
+	"github.com/komari-monitor/komari/database/auditlog"
+	"github.com/komari-monitor/komari/utils/termrec"
+	"gorm.io/gorm"
+)
+
+// 超过该大小的录制需下载后回放
+const maxReplaySize = 64 << 20
+
+// ListTerminalRecordings 列出终端录制
+// 查询参数：client、user、limit、offset
+func ListTerminalRecordings(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	list, total, err := termrec.List(termrec.Filter{
+		Client:   c.Query("client"),
+		UserUUID: c.Query("user"),
+		Limit:    limit,
+		Offset:   offset,
+	})
+	if err != nil {
+		api.RespondError(c, http.StatusInternalServerError, "Failed to list terminal recordings: "+err.Error())
+		return
+	}
+	api.RespondSuccess(c, gin.H{"recordings": list, "total": total})
+}
+
+// GetTerminalRecording 获取录制信息，id 与审计日志中的 terminal id 一致
+func GetTerminalRecording(c *gin.Context) {
+	record, err := termrec.Get(c.Param("id"))
+	if err != nil {
+		respondRecordingError(c, err)
+		return
+	}
+	api.RespondSuccess(c, record)
+}
+
+// DownloadTerminalRecording 下载 asciicast 文件
+func DownloadTerminalRecording(c *gin.Context) {
+	record, f, err := termrec.Open(c.Param("id"))
+	if err != nil {
+		respondRecordingError(c, err)
+		return
+	}
+	defer f.Close()
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), "download terminal recording:"+record.ID, "info")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"terminal-%s.cast\"", record.ID))
+	c.DataFromReader(http.StatusOK, -1, "application/x-asciicast", f, nil)
+}
+
+// ReplayTerminalRecording 返回解析后的文件头与事件，供前端按时间轴回放
+func ReplayTerminalRecording(c *gin.Context) {
+	record, f, err := termrec.Open(c.Param("id"))
+	if err != nil {
+		respondRecordingError(c, err)
+		return
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil && info.Size() > maxReplaySize {
+		api.RespondError(c, http.StatusRequestEntityTooLarge, "Recording too large, please download it instead")
+		return
+	}
+	header, events, err := termrec.ReadCast(f)
+	if err != nil {
+		api.RespondError(c, http.StatusInternalServerError, "Failed to read recording: "+err.Error())
+		return
+	}
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), "replay terminal recording:"+record.ID, "info")
+	api.RespondSuccess(c, gin.H{"recording": record, "header": header, "events": events})
+}
+
+// DeleteTerminalRecording 删除录制
+func DeleteTerminalRecording(c *gin.Context) {
+	id := c.Param("id")
+	if err := termrec.Delete(id); err != nil {
+		respondRecordingError(c, err)
+		return
+	}
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), "delete terminal recording:"+id, "warn")
+	api.RespondSuccess(c, nil)
+}
+
+func respondRecordingError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		api.RespondError(c, http.StatusNotFound, "Recording not found")
+		return
+	}
+	api.RespondError(c, http.StatusInternalServerError, err.Error())
+}
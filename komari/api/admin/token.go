@@ -0,0 +1,69 @@
+package admin
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/komari-monitor/komari/api"
+	"github.com/komari-monitor/komari/database/apitokens"
+	"github.com/komari-monitor/komari/database/auditlog"
+	"github.com/komari-monitor/komari/database/models"
+)
+
+// GET /api/admin/token
+func ListApiTokens(c *gin.Context) {
+	list, err := apitokens.ListTokens()
+	if err != nil {
+		api.RespondError(c, 500, "Failed to retrieve tokens: "+err.Error())
+		return
+	}
+	api.RespondSuccess(c, list)
+}
+
+// POST /api/admin/token/add
+func CreateApiToken(c *gin.Context) {
+	var req struct {
+		Name      string            `json:"name" binding:"required"`
+		Scopes    []string          `json:"scopes" binding:"required"`
+		Clients   []string          `json:"clients"`
+		ExpiresAt *models.LocalTime `json:"expires_at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.RespondError(c, 400, "Invalid or missing request body: "+err.Error())
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		api.RespondError(c, 400, "Name is required")
+		return
+	}
+	raw, token, err := apitokens.CreateToken(req.Name, req.Scopes, req.Clients, req.ExpiresAt, c.GetString("uuid"))
+	if err != nil {
+		api.RespondError(c, 400, "Failed to create token: "+err.Error())
+		return
+	}
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), "create api token:"+token.Name+", scopes:"+strings.Join(token.Scopes, ","), "warn")
+	// 明文令牌只返回这一次
+	api.RespondSuccess(c, gin.H{"token": raw, "info": token})
+}
+
+// POST /api/admin/token/:id/remove
+func RemoveApiToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		api.RespondError(c, 400, "Invalid token ID")
+		return
+	}
+	token, err := apitokens.GetTokenByID(uint(id))
+	if err != nil {
+		api.RespondError(c, 404, "Token not found")
+		return
+	}
+	if err := apitokens.DeleteToken(token.ID); err != nil {
+		api.RespondError(c, 500, "Failed to remove token: "+err.Error())
+		return
+	}
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), "revoke api token:"+token.Name, "warn")
+	api.RespondSuccess(c, nil)
+}
@@ -0,0 +1,94 @@
+package admin
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/komari-monitor/komari/api"
+	"github.com/komari-monitor/komari/database/clients"
+	"github.com/komari-monitor/komari/database/config"
+	"github.com/komari-monitor/komari/utils/tunnel"
+)
+
+// CreateTunnel 打开到节点本机端口的隧道
+// POST body: client, port, mode=proxy|listener（默认 proxy）, duration 存活分钟数（默认且最多为站点设置的上限）
+func CreateTunnel(c *gin.Context) {
+	var req struct {
+		Client   string `json:"client" binding:"required"`
+		Port     int    `json:"port" binding:"required"`
+		Mode     string `json:"mode"`
+		Duration int    `json:"duration"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err := clients.GetClientByUUID(req.Client); err != nil || !api.CanAccessClients(c, req.Client) {
+		api.RespondError(c, http.StatusBadRequest, "Client not found")
+		return
+	}
+	cfg, err := config.Get()
+	if err != nil {
+		api.RespondError(c, http.StatusInternalServerError, "Failed to get config: "+err.Error())
+		return
+	}
+	duration := cfg.TunnelMaxDuration
+	if req.Duration > 0 && req.Duration < duration {
+		duration = req.Duration
+	}
+	t, err := tunnel.Open(tunnel.Options{
+		Client:      req.Client,
+		Port:        req.Port,
+		Mode:        req.Mode,
+		UserUUID:    c.GetString("uuid"),
+		RequesterIp: c.ClientIP(),
+		ListenHost:  cfg.TunnelListenHost,
+		Duration:    time.Duration(duration) * time.Minute,
+		IdleTimeout: time.Duration(cfg.TunnelIdleTimeout) * time.Minute,
+	})
+	if err != nil {
+		api.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	api.RespondSuccess(c, t.Info())
+}
+
+// ListTunnels 列出当前隧道
+func ListTunnels(c *gin.Context) {
+	list := []tunnel.Info{}
+	for _, info := range tunnel.List() {
+		if api.CanAccessClients(c, info.Client) {
+			list = append(list, info)
+		}
+	}
+	api.RespondSuccess(c, list)
+}
+
+// CloseTunnel 关闭隧道
+func CloseTunnel(c *gin.Context) {
+	t := accessibleTunnel(c)
+	if t == nil {
+		return
+	}
+	t.Close("closed by " + c.GetString("uuid"))
+	api.RespondSuccess(c, nil)
+}
+
+// ProxyTunnel 通过隧道反向代理 HTTP 请求，路径为 /api/admin/tunnel/:id/proxy/*path
+func ProxyTunnel(c *gin.Context) {
+	t := accessibleTunnel(c)
+	if t == nil {
+		return
+	}
+	t.ServeProxy(c.Writer, c.Request, c.Param("path"))
+}
+
+func accessibleTunnel(c *gin.Context) *tunnel.Tunnel {
+	t := tunnel.Get(c.Param("id"))
+	if t == nil || !api.CanAccessClients(c, t.Info().Client) {
+		api.RespondError(c, http.StatusNotFound, "Tunnel not found")
+		return nil
+	}
+	return t
+}
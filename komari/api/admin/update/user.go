@@ -5,6 +5,7 @@ import (
 	"github.com/komari-monitor/komari/api"
 	"github.com/komari-monitor/komari/database/accounts"
 	"github.com/komari-monitor/komari/database/auditlog"
+	"github.com/komari-monitor/komari/database/models"
 )
 
 func UpdateUser(c *gin.Context) {
@@ -18,6 +19,11 @@ func UpdateUser(c *gin.Context) {
 		api.RespondError(c, 400, "Invalid or missing request body: "+err.Error())
 		return
 	}
+	// 非管理员只能修改自己的账户
+	if api.CurrentRole(c) != models.RoleAdmin && req.Uuid != c.GetString("uuid") {
+		api.RespondError(c, 403, "Permission denied.")
+		return
+	}
 	if req.Password == nil && req.Name == nil {
 		api.RespondError(c, 400, "At least one field (username or password) must be provided")
 		return
@@ -1,7 +1,6 @@
 package admin
 
 import (
-	"archive/zip"
 	"fmt"
 	"io"
 	"log"
@@ -14,6 +13,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/komari-monitor/komari/api"
+	"github.com/komari-monitor/komari/utils/backup"
 )
 
 // 只有一个备份恢复操作在进行
@@ -42,12 +42,6 @@ func UploadBackup(c *gin.Context) {
 		return
 	}
 
-	// 确保data目录存在
-	if err := os.MkdirAll("./data", 0755); err != nil {
-		api.RespondError(c, http.StatusInternalServerError, fmt.Sprintf("Error creating data directory: %v", err))
-		return
-	}
-
 	// 创建临时文件保存上传的zip（先校验，再落地到固定位置）
 	tempFile, err := os.CreateTemp("", "backup-upload-*.zip")
 	if err != nil {
@@ -66,25 +60,37 @@ func UploadBackup(c *gin.Context) {
 	}
 	tempFile.Close() // 关闭文件以便后续操作
 
-	// 基础校验：检查是否包含标记文件
-	if zr, err := zip.OpenReader(tempFilePath); err == nil {
-		hasMarkup := false
-		for _, f := range zr.File {
-			if f.Name == "komari-backup-markup" {
-				hasMarkup = true
-				break
-			}
-		}
-		zr.Close()
-		if !hasMarkup {
-			api.RespondError(c, http.StatusBadRequest, "Invalid backup file: missing komari-backup-markup file")
-			return
-		}
-	} else {
-		api.RespondError(c, http.StatusInternalServerError, fmt.Sprintf("Error opening zip file: %v", err))
+	if status, err := stageBackupZip(tempFilePath); err != nil {
+		api.RespondError(c, status, err.Error())
 		return
 	}
 
+	// 返回：已保存备份，重启后将自动恢复
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Backup uploaded successfully. The service will restart and apply the backup.",
+		"path":    "./data/backup.zip",
+	})
+
+	restartToApplyBackup()
+}
+
+// stageBackupZip 校验备份包并放到 ./data/backup.zip，服务重启时自动恢复；出错时返回对应的 HTTP 状态码
+func stageBackupZip(tempFilePath string) (int, error) {
+	// 确保data目录存在
+	if err := os.MkdirAll("./data", 0755); err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("Error creating data directory: %v", err)
+	}
+
+	// 基础校验：检查是否包含标记文件
+	hasMarkup, err := backup.HasMarkup(tempFilePath)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("Error opening zip file: %v", err)
+	}
+	if !hasMarkup {
+		return http.StatusBadRequest, fmt.Errorf("Invalid backup file: missing komari-backup-markup file")
+	}
+
 	// 将校验通过的临时文件移动到固定路径 ./data/backup.zip
 	finalPath := filepath.Join(".", "data", "backup.zip")
 	// 如存在旧文件，先删除
@@ -93,30 +99,23 @@ func UploadBackup(c *gin.Context) {
 		// fallback：拷贝
 		in, err2 := os.Open(tempFilePath)
 		if err2 != nil {
-			api.RespondError(c, http.StatusInternalServerError, fmt.Sprintf("Error preparing backup file: %v", err))
-			return
+			return http.StatusInternalServerError, fmt.Errorf("Error preparing backup file: %v", err)
 		}
 		defer in.Close()
 		out, err2 := os.Create(finalPath)
 		if err2 != nil {
-			api.RespondError(c, http.StatusInternalServerError, fmt.Sprintf("Error creating target backup file: %v", err2))
-			return
+			return http.StatusInternalServerError, fmt.Errorf("Error creating target backup file: %v", err2)
 		}
 		if _, err2 = io.Copy(out, in); err2 != nil {
 			out.Close()
-			api.RespondError(c, http.StatusInternalServerError, fmt.Sprintf("Error writing target backup file: %v", err2))
-			return
+			return http.StatusInternalServerError, fmt.Errorf("Error writing target backup file: %v", err2)
 		}
 		out.Close()
 	}
+	return http.StatusOK, nil
+}
 
-	// 返回：已保存备份，重启后将自动恢复
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "success",
-		"message": "Backup uploaded successfully. The service will restart and apply the backup.",
-		"path":    "./data/backup.zip",
-	})
-
+func restartToApplyBackup() {
 	go func() {
 		log.Println("Backup uploaded, restarting service in 2 seconds to apply on startup...")
 		time.Sleep(2 * time.Second)
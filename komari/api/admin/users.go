@@ -0,0 +1,111 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/komari-monitor/komari/api"
+	"github.com/komari-monitor/komari/database/accounts"
+	"github.com/komari-monitor/komari/database/auditlog"
+	"github.com/komari-monitor/komari/database/models"
+)
+
+func ListUsers(c *gin.Context) {
+	users, err := accounts.ListUsers()
+	if err != nil {
+		api.RespondError(c, 500, "Failed to retrieve users: "+err.Error())
+		return
+	}
+	api.RespondSuccess(c, users)
+}
+
+func AddUser(c *gin.Context) {
+	var req struct {
+		Username    string `json:"username" binding:"required"`
+		Password    string `json:"password" binding:"required"`
+		Role        string `json:"role" binding:"required"`
+		ScopeGroups string `json:"scope_groups"`
+		ScopeTags   string `json:"scope_tags"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.RespondError(c, 400, "Invalid or missing request body: "+err.Error())
+		return
+	}
+	if len(req.Username) < 3 {
+		api.RespondError(c, 400, "Username must be at least 3 characters long")
+		return
+	}
+	if len(req.Password) < 6 {
+		api.RespondError(c, 400, "Password must be at least 6 characters long")
+		return
+	}
+	if !models.IsValidRole(req.Role) {
+		api.RespondError(c, 400, "Invalid role: "+req.Role)
+		return
+	}
+	user, err := accounts.CreateAccountWithRole(req.Username, req.Password, req.Role, req.ScopeGroups, req.ScopeTags)
+	if err != nil {
+		api.RespondError(c, 500, "Failed to create user: "+err.Error())
+		return
+	}
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), "create user:"+user.Username+", role:"+user.Role, "warn")
+	api.RespondSuccess(c, gin.H{"uuid": user.UUID})
+}
+
+func EditUser(c *gin.Context) {
+	target := c.Param("uuid")
+	var req struct {
+		Role        string `json:"role" binding:"required"`
+		ScopeGroups string `json:"scope_groups"`
+		ScopeTags   string `json:"scope_tags"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.RespondError(c, 400, "Invalid or missing request body: "+err.Error())
+		return
+	}
+	if !models.IsValidRole(req.Role) {
+		api.RespondError(c, 400, "Invalid role: "+req.Role)
+		return
+	}
+	user, err := accounts.GetUserByUUID(target)
+	if err != nil {
+		api.RespondError(c, 404, "User not found")
+		return
+	}
+	// 至少保留一个管理员
+	if user.Role == models.RoleAdmin && req.Role != models.RoleAdmin {
+		if count, err := accounts.CountAdmins(); err != nil || count <= 1 {
+			api.RespondError(c, 400, "Cannot demote the last admin")
+			return
+		}
+	}
+	if err := accounts.UpdateUserRole(target, req.Role, req.ScopeGroups, req.ScopeTags); err != nil {
+		api.RespondError(c, 500, "Failed to update user: "+err.Error())
+		return
+	}
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), "edit user:"+user.Username+", role:"+req.Role, "warn")
+	api.RespondSuccess(c, nil)
+}
+
+func RemoveUser(c *gin.Context) {
+	target := c.Param("uuid")
+	if c.GetString("uuid") == target {
+		api.RespondError(c, 400, "Cannot remove the current user")
+		return
+	}
+	user, err := accounts.GetUserByUUID(target)
+	if err != nil {
+		api.RespondError(c, 404, "User not found")
+		return
+	}
+	if user.Role == models.RoleAdmin {
+		if count, err := accounts.CountAdmins(); err != nil || count <= 1 {
+			api.RespondError(c, 400, "Cannot remove the last admin")
+			return
+		}
+	}
+	if err := accounts.DeleteAccountByUUID(target); err != nil {
+		api.RespondError(c, 500, "Failed to remove user: "+err.Error())
+		return
+	}
+	auditlog.Log(c.ClientIP(), c.GetString("uuid"), "remove user:"+user.Username, "warn")
+	api.RespondSuccess(c, nil)
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"sort"
+	"time"
+
+	"github.com/komari-monitor/komari/common"
+	"github.com/komari-monitor/komari/database/config"
+	"github.com/komari-monitor/komari/database/dbcore"
+	"github.com/komari-monitor/komari/database/models"
+)
+
+// 允许的客户端时钟超前量，超出的补发数据视为无效
+const backfillClockSkew = time.Minute
+
+// SaveHistoricalReports 保存 Agent 断线期间缓存的上报。
+// 按原始采集时间分钟聚合后写入，已有记录的分钟会被跳过，避免与实时数据重复；返回实际写入的分钟数
+func SaveHistoricalReports(uuid string, reports []common.Report) (int, error) {
+	cfg, err := config.Get()
+	if err != nil {
+		return 0, err
+	}
+	if !cfg.RecordEnabled || len(reports) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	oldest := now.Add(-time.Duration(cfg.RecordPreserveTime) * time.Hour)
+	// 以 Unix 分钟为键：Agent 时间带有其自身的时区偏移，与库中按面板时区存储的时间无法直接比较
+	groups := make(map[int64][]common.Report)
+	for _, r := range reports {
+		if r.UpdatedAt.IsZero() || r.UpdatedAt.After(now.Add(backfillClockSkew)) || r.UpdatedAt.Before(oldest) {
+			continue
+		}
+		minute := r.UpdatedAt.Unix() / 60
+		groups[minute] = append(groups[minute], r)
+	}
+	if len(groups) == 0 {
+		return 0, nil
+	}
+
+	minutes := make([]int64, 0, len(groups))
+	for m := range groups {
+		minutes = append(minutes, m)
+	}
+	sort.Slice(minutes, func(i, j int) bool { return minutes[i] < minutes[j] })
+
+	// 查询这段时间内已有的记录
+	var existing []models.Record
+	db := dbcore.GetDBInstance()
+	from := models.FromTime(time.Unix(minutes[0]*60, 0))
+	to := models.FromTime(time.Unix((minutes[len(minutes)-1]+1)*60, 0))
+	if err := db.Select("time").Where("client = ? AND time >= ? AND time < ?", uuid, from, to).
+		Find(&existing).Error; err != nil {
+		return 0, err
+	}
+	covered := make(map[int64]bool, len(existing))
+	// 当前这一分钟由实时上报负责
+	covered[now.Unix()/60] = true
+	for _, rec := range existing {
+		covered[rec.Time.ToTime().Unix()/60] = true
+	}
+
+	batch := &reportBatch{}
+	saved := 0
+	for _, m := range minutes {
+		if covered[m] {
+			continue
+		}
+		group := groups[m]
+		sort.Slice(group, func(i, j int) bool { return group[i].UpdatedAt.Before(group[j].UpdatedAt) })
+		batch.add(uuid, group[len(group)-1].UpdatedAt, group)
+		saved++
+	}
+	if saved == 0 {
+		return 0, nil
+	}
+	return saved, batch.save()
+}
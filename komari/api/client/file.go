@@ -0,0 +1,40 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/komari-monitor/komari/api"
+	"github.com/komari-monitor/komari/database/clients"
+	"github.com/komari-monitor/komari/ws"
+)
+
+// Agent WS: /api/clients/file
+func EstablishFileConnection(c *gin.Context) {
+	sessionID := c.Query("id")
+	api.FileSessionsMutex.Lock()
+	session, exists := api.FileSessions[sessionID]
+	api.FileSessionsMutex.Unlock()
+	if !exists || session == nil || session.Agent != nil {
+		c.JSON(http.StatusNotFound, gin.H{"status": "error", "error": "Session not found"})
+		return
+	}
+	// 只允许目标节点建立连接
+	if uuid, err := clients.GetClientUUIDByToken(c.Query("token")); err != nil || uuid != session.UUID {
+		c.JSON(http.StatusForbidden, gin.H{"status": "error", "error": "Session does not belong to this client"})
+		return
+	}
+	conn, err := ws.UpgradeRequest(c, func(r *http.Request) bool { return true })
+	if err != nil {
+		return
+	}
+	api.FileSessionsMutex.Lock()
+	if session.Agent != nil {
+		api.FileSessionsMutex.Unlock()
+		conn.Close()
+		return
+	}
+	session.Agent = conn
+	api.FileSessionsMutex.Unlock()
+	go api.ForwardFileTransfer(sessionID)
+}
@@ -137,6 +137,7 @@ func WebSocketReport(c *gin.Context) {
 			})
 		}
 	}
+	go api.DeliverQueuedTasks(uuid, conn)
 	go notifier.OnlineNotification(uuid, conn.ID)
 	defer func() {
 		ws.DeleteClientConditionally(uuid, conn)
@@ -187,6 +188,18 @@ func processMessage(conn *ws.SafeConn, message []byte, uuid string) {
 			return
 		}
 		ws.SetLatestReport(uuid, &report)
+	case "report_history":
+		// Agent 断线期间缓存的上报，updated_at 为原始采集时间，只入库不更新实时状态
+		var reqBody struct {
+			Reports []common.Report `json:"reports"`
+		}
+		if err := json.Unmarshal(message, &reqBody); err != nil {
+			conn.WriteJSON(gin.H{"status": "error", "error": "Invalid report history format"})
+			return
+		}
+		if _, err := api.SaveHistoricalReports(uuid, reqBody.Reports); err != nil {
+			log.Printf("Failed to save report history for %s: %v", uuid, err)
+		}
 	case "ping_result":
 		var reqBody struct {
 			PingTaskID uint      `json:"task_id"`
@@ -254,6 +267,28 @@ func processMessage(conn *ws.SafeConn, message []byte, uuid string) {
 			Samples:    sampleJSON,
 		}
 		_ = tasks.SaveSPPingRecord(&rec)
+	case "task_output":
+		// 远程命令的增量输出，仅实时转发，完整结果由 Agent 结束后上报
+		var reqBody struct {
+			TaskID string `json:"task_id"`
+			Stream string `json:"stream"`
+			Data   string `json:"data"`
+			Time   string `json:"time"`
+		}
+		if err := json.Unmarshal(message, &reqBody); err != nil || reqBody.TaskID == "" {
+			conn.WriteJSON(gin.H{"status": "error", "error": "Invalid task output format"})
+			return
+		}
+		if reqBody.Time == "" {
+			reqBody.Time = time.Now().Format(time.RFC3339)
+		}
+		jsonRpc.PublishTaskOutput(jsonRpc.TaskOutputEvent{
+			TaskID:     reqBody.TaskID,
+			ClientUUID: uuid,
+			Stream:     reqBody.Stream,
+			Data:       reqBody.Data,
+			Time:       reqBody.Time,
+		})
 	case "script_log":
 		var reqBody struct {
 			ScriptID uint   `json:"script_id"`
@@ -21,13 +21,24 @@ func TaskResult(c *gin.Context) {
 		Result     string    `json:"result" binding:"required"`
 		ExitCode   int       `json:"exit_code"`
 		FinishedAt time.Time `json:"finished_at" binding:"required"`
+		Status     string    `json:"status"` // cancelled / timeout，为空表示正常结束
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(400, gin.H{"status": "error", "message": "Invalid request"})
 		return
 	}
 
-	if err := tasks.SaveTaskResult(req.TaskId, clientId, req.Result, req.ExitCode, models.FromTime(req.FinishedAt)); err != nil {
+	status := models.TaskStatusFinished
+	switch req.Status {
+	case "":
+	case models.TaskStatusCancelled, models.TaskStatusTimeout:
+		status = req.Status
+	default:
+		c.JSON(400, gin.H{"status": "error", "message": "Invalid task status"})
+		return
+	}
+
+	if err := tasks.SaveTaskResultWithStatus(req.TaskId, clientId, req.Result, req.ExitCode, models.FromTime(req.FinishedAt), status); err != nil {
 		c.JSON(500, gin.H{"status": "error", "message": "Failed to update task result: " + err.Error()})
 		return
 	}
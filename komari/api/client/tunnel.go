@@ -0,0 +1,26 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/komari-monitor/komari/database/clients"
+	"github.com/komari-monitor/komari/utils/tunnel"
+	"github.com/komari-monitor/komari/ws"
+)
+
+// Agent WS: /api/clients/tunnel
+func EstablishTunnelConnection(c *gin.Context) {
+	uuid, err := clients.GetClientUUIDByToken(c.Query("token"))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"status": "error", "error": "Invalid token"})
+		return
+	}
+	conn, err := ws.UpgradeRequest(c, func(r *http.Request) bool { return true })
+	if err != nil {
+		return
+	}
+	if err := tunnel.Accept(c.Query("id"), uuid, conn); err != nil {
+		conn.Close()
+	}
+}
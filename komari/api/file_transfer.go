@@ -0,0 +1,217 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/komari-monitor/komari/database/auditlog"
+	"github.com/komari-monitor/komari/database/clients"
+	"github.com/komari-monitor/komari/database/config"
+	"github.com/komari-monitor/komari/utils"
+	"github.com/komari-monitor/komari/ws"
+)
+
+// FileSession 浏览器与 Agent 之间的文件传输通道
+//
+// 协议：文本帧为 JSON 请求/响应，按 id 对应；binary 帧为上传或下载的数据块。
+// 请求 op 支持 list、stat、download、upload、mkdir、remove、rename，
+// 上传/下载通过 offset 断点续传，由 Agent 逐个处理请求
+type FileSession struct {
+	UUID        string
+	UserUUID    string
+	Browser     *websocket.Conn
+	Agent       *websocket.Conn
+	RequesterIp string
+	MaxSize     int64 // 单个文件大小上限（字节），0 表示不限制
+}
+
+var FileSessionsMutex = &sync.Mutex{}
+var FileSessions = make(map[string]*FileSession)
+
+// fileRequest 浏览器发出的请求，服务端仅解析用于审计与限制
+type fileRequest struct {
+	ID     int64  `json:"id"`
+	Op     string `json:"op"`
+	Path   string `json:"path"`
+	Target string `json:"target,omitempty"`
+	Offset int64  `json:"offset,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+}
+
+func RequestFileTransfer(c *gin.Context) {
+	uuid := c.Param("uuid")
+	_, err := clients.GetClientByUUID(uuid)
+	if err != nil || !CanAccessClients(c, uuid) {
+		RespondError(c, http.StatusBadRequest, "Client not found")
+		return
+	}
+	if !websocket.IsWebSocketUpgrade(c.Request) {
+		RespondError(c, http.StatusBadRequest, "Require WebSocket upgrade")
+		return
+	}
+	cfg, err := config.Get()
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Failed to get config: "+err.Error())
+		return
+	}
+	upgrader := websocket.Upgrader{
+		CheckOrigin: ws.CheckOrigin,
+	}
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	id := utils.GenerateRandomString(32)
+	session := &FileSession{
+		UUID:        uuid,
+		UserUUID:    c.GetString("uuid"),
+		Browser:     conn,
+		RequesterIp: c.ClientIP(),
+		MaxSize:     int64(cfg.FileTransferMaxSize) << 20,
+	}
+	FileSessionsMutex.Lock()
+	FileSessions[id] = session
+	FileSessionsMutex.Unlock()
+	removeSession := func() {
+		FileSessionsMutex.Lock()
+		delete(FileSessions, id)
+		FileSessionsMutex.Unlock()
+	}
+
+	agentConn := ws.GetMessageWriter(uuid)
+	if agentConn == nil {
+		conn.WriteJSON(gin.H{"op": "error", "error": "Client offline"})
+		conn.Close()
+		removeSession()
+		return
+	}
+	err = agentConn.WriteJSON(gin.H{
+		"message":    "file",
+		"request_id": id,
+		"max_size":   session.MaxSize,
+	})
+	if err != nil {
+		conn.Close()
+		removeSession()
+		return
+	}
+	time.AfterFunc(30*time.Second, func() {
+		FileSessionsMutex.Lock()
+		defer FileSessionsMutex.Unlock()
+		if session.Agent == nil {
+			conn.WriteJSON(gin.H{"op": "error", "error": "Agent connection timeout"})
+			conn.Close()
+			delete(FileSessions, id)
+		}
+	})
+}
+
+// ForwardFileTransfer 在浏览器与 Agent 之间转发文件传输消息
+func ForwardFileTransfer(id string) {
+	FileSessionsMutex.Lock()
+	session, exists := FileSessions[id]
+	FileSessionsMutex.Unlock()
+	if !exists || session == nil || session.Agent == nil || session.Browser == nil {
+		return
+	}
+	auditlog.Log(session.RequesterIp, session.UserUUID, "established, file session id:"+id+", client:"+session.UUID, "file")
+	established := time.Now()
+	// 服务端拒绝请求时也会写浏览器连接，需与转发串行
+	var browserMu sync.Mutex
+	writeBrowser := func(messageType int, data []byte) error {
+		browserMu.Lock()
+		defer browserMu.Unlock()
+		return session.Browser.WriteMessage(messageType, data)
+	}
+	errChan := make(chan error, 2)
+
+	go func() {
+		for {
+			messageType, data, err := session.Browser.ReadMessage()
+			if err != nil {
+				errChan <- err
+				return
+			}
+			if messageType == websocket.TextMessage {
+				var req fileRequest
+				if err := json.Unmarshal(data, &req); err != nil {
+					continue
+				}
+				if reason := checkFileRequest(session, req); reason != "" {
+					resp, _ := json.Marshal(gin.H{"id": req.ID, "op": req.Op, "ok": false, "error": reason})
+					if err := writeBrowser(websocket.TextMessage, resp); err != nil {
+						errChan <- err
+						return
+					}
+					continue
+				}
+				auditFileRequest(session, id, req)
+			}
+			if err := session.Agent.WriteMessage(messageType, data); err != nil {
+				errChan <- err
+				return
+			}
+		}
+	}()
+
+	go func() {
+		for {
+			messageType, data, err := session.Agent.ReadMessage()
+			if err != nil {
+				errChan <- err
+				return
+			}
+			if err := writeBrowser(messageType, data); err != nil {
+				errChan <- err
+				return
+			}
+		}
+	}()
+
+	if err := <-errChan; err != nil && !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		log.Println("File session closed:", err)
+	}
+	session.Agent.Close()
+	session.Browser.Close()
+	auditlog.Log(session.RequesterIp, session.UserUUID, "disconnected, file session id:"+id+", duration:"+time.Since(established).String(), "file")
+	FileSessionsMutex.Lock()
+	delete(FileSessions, id)
+	FileSessionsMutex.Unlock()
+}
+
+// checkFileRequest 服务端限制，返回拒绝原因
+func checkFileRequest(session *FileSession, req fileRequest) string {
+	switch req.Op {
+	case "list", "stat", "download", "upload", "mkdir", "remove", "rename":
+	default:
+		return "unsupported operation: " + req.Op
+	}
+	if req.Op == "upload" && session.MaxSize > 0 && req.Size > session.MaxSize {
+		return fmt.Sprintf("file exceeds size limit of %d MB", session.MaxSize>>20)
+	}
+	return ""
+}
+
+func auditFileRequest(session *FileSession, id string, req fileRequest) {
+	var msg string
+	switch req.Op {
+	case "download":
+		msg = fmt.Sprintf("download %s (offset %d)", req.Path, req.Offset)
+	case "upload":
+		msg = fmt.Sprintf("upload %s (%d bytes, offset %d)", req.Path, req.Size, req.Offset)
+	case "mkdir", "remove":
+		msg = req.Op + " " + req.Path
+	case "rename":
+		msg = fmt.Sprintf("rename %s -> %s", req.Path, req.Target)
+	default:
+		// 浏览目录不记录
+		return
+	}
+	auditlog.Log(session.RequesterIp, session.UserUUID, msg+", file session id:"+id+", client:"+session.UUID, "file")
+}
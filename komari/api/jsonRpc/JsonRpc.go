@@ -11,6 +11,7 @@ import (
 	"sync"
 
 	"github.com/gin-gonic/gin"
+	"github.com/komari-monitor/komari/api"
 	"github.com/komari-monitor/komari/database/accounts"
 	"github.com/komari-monitor/komari/database/clients"
 	"github.com/komari-monitor/komari/database/config"
@@ -40,6 +41,7 @@ func OnRpcRequest(c *gin.Context) {
 		meta := buildContextMeta(c, permissionGroup)
 		defer conn.Close()
 		defer clearScriptLogConn(conn)
+		defer clearTaskOutputConn(conn)
 		for {
 			var req rpc.JsonRpcRequest
 			err := conn.ReadJSON(&req)
@@ -58,6 +60,9 @@ func OnRpcRequest(c *gin.Context) {
 				conn.WriteJSON(jerr.ResponseWithID(req.ID))
 				continue
 			}
+			if handled := handleTaskOutputRPC(conn, &req, meta); handled {
+				continue
+			}
 			if handled := handleScriptLogRPC(conn, &req, permissionGroup); handled {
 				continue
 			}
@@ -129,17 +134,38 @@ func detectPermissionGroup(c *gin.Context, cfg models.Config) string {
 		permissionGroup = "client"
 	}
 	if session_token, _ := c.Cookie("session_token"); session_token != "" {
-		if _, err := accounts.GetUserBySession(session_token); err == nil {
-			permissionGroup = "admin"
+		if user, err := accounts.GetUserBySession(session_token); err == nil {
+			// 非管理员账户归入 user 组，只能访问公共方法，节点按范围过滤
+			if user.Role == models.RoleAdmin {
+				permissionGroup = "admin"
+			} else {
+				permissionGroup = "user"
+			}
 		}
 	}
 	apiKey := c.GetHeader("Authorization")
-	if apiKey == "Bearer "+cfg.ApiKey {
+	if cfg.ApiKey != "" && apiKey == "Bearer "+cfg.ApiKey {
 		permissionGroup = "admin"
+	} else if token := api.VerifyApiToken(apiKey, c.ClientIP()); token != nil {
+		c.Set("api_token", token)
+		permissionGroup = tokenPermissionGroup(token)
 	}
 	return permissionGroup
 }
 
+// tokenPermissionGroup 按令牌的权限范围与节点限制映射权限分组：
+// 不限节点的 admin 令牌为 admin；限制了节点的 admin 令牌与 read 令牌归入 user 组，节点按令牌范围过滤
+func tokenPermissionGroup(token *models.ApiToken) string {
+	switch {
+	case token.HasScope(models.TokenScopeAdmin) && len(token.Clients) == 0:
+		return "admin"
+	case token.HasScope(models.TokenScopeAdmin), token.HasScope(models.TokenScopeRead):
+		return "user"
+	default:
+		return "guest"
+	}
+}
+
 // buildContextMeta 从 gin.Context 构建 *rpc.ContextMeta
 func buildContextMeta(c *gin.Context, permissionGroup string) *rpc.ContextMeta {
 	meta := &rpc.ContextMeta{Permission: permissionGroup}
@@ -165,6 +191,12 @@ func buildContextMeta(c *gin.Context, permissionGroup string) *rpc.ContextMeta {
 			meta.UserUUID = user.UUID
 		}
 	}
+	// API 令牌请求以令牌为身份
+	if token := api.CurrentApiToken(c); token != nil {
+		meta.ApiToken = token
+		meta.User = nil
+		meta.UserUUID = token.Actor()
+	}
 	meta.RemoteIP = c.ClientIP()
 	meta.UserAgent = c.GetHeader("User-Agent")
 	return meta
@@ -0,0 +1,36 @@
+package jsonRpc
+
+import (
+	"testing"
+
+	"github.com/komari-monitor/komari/database/models"
+	"github.com/komari-monitor/komari/utils/rpc"
+)
+
+func TestTokenPermissionGroup(t *testing.T) {
+	cases := []struct {
+		token models.ApiToken
+		want  string
+	}{
+		{models.ApiToken{Scopes: models.StringArray{models.TokenScopeAdmin}}, "admin"},
+		{models.ApiToken{Scopes: models.StringArray{models.TokenScopeAdmin}, Clients: models.StringArray{"a"}}, "user"},
+		{models.ApiToken{Scopes: models.StringArray{models.TokenScopeRead}}, "user"},
+		{models.ApiToken{Scopes: models.StringArray{models.TokenScopePing}}, "guest"},
+	}
+	for _, tc := range cases {
+		if got := tokenPermissionGroup(&tc.token); got != tc.want {
+			t.Errorf("scopes %v clients %v: got %s, want %s", tc.token.Scopes, tc.token.Clients, got, tc.want)
+		}
+	}
+}
+
+func TestInvisibleClientsForToken(t *testing.T) {
+	meta := &rpc.ContextMeta{
+		Permission: "user",
+		ApiToken:   &models.ApiToken{Scopes: models.StringArray{models.TokenScopeRead}, Clients: models.StringArray{"a"}},
+	}
+	hidden := invisibleClients(meta, []models.Client{{UUID: "a", Hidden: true}, {UUID: "b"}})
+	if hidden["a"] || !hidden["b"] {
+		t.Fatalf("unexpected hidden set: %v", hidden)
+	}
+}
@@ -0,0 +1,465 @@
+package jsonRpc
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/komari-monitor/komari/database/clients"
+	"github.com/komari-monitor/komari/database/models"
+	recordsdb "github.com/komari-monitor/komari/database/records"
+	"github.com/komari-monitor/komari/utils/rpc"
+)
+
+func init() {
+	Register("getFleetStats", getFleetStats)
+	Register("getTopNodes", getTopNodes)
+}
+
+// fleetSelector 按分组、标签或 uuid 列表选取节点，条件之间为“且”，均为空时选取全部可见节点
+type fleetSelector struct {
+	Group string   `json:"group"`
+	Tag   string   `json:"tag"`
+	UUIDs []string `json:"uuids"`
+	Hours int      `json:"hours"` // time window in hours; default 1 if start/end not provided
+	Start string   `json:"start"` // RFC3339 start time (optional)
+	End   string   `json:"end"`   // RFC3339 end time (optional)
+}
+
+// selectFleet 返回命中的节点（已按调用者权限过滤）与时间窗口
+func selectFleet(meta *rpc.ContextMeta, sel fleetSelector) ([]models.Client, time.Time, time.Time, *rpc.JsonRpcError) {
+	startTime, endTime, jerr := parseTimeWindow(sel.Hours, sel.Start, sel.End)
+	if jerr != nil {
+		return nil, startTime, endTime, jerr
+	}
+	cinfo, err := clients.GetAllClientBasicInfo()
+	if err != nil {
+		return nil, startTime, endTime, rpc.MakeError(rpc.InternalError, "Failed to get client info", err.Error())
+	}
+	hidden := invisibleClients(meta, cinfo)
+	wanted := make(map[string]bool, len(sel.UUIDs))
+	for _, u := range sel.UUIDs {
+		wanted[u] = true
+	}
+	nodes := make([]models.Client, 0, len(cinfo))
+	for _, c := range cinfo {
+		if hidden[c.UUID] {
+			continue
+		}
+		if sel.Group != "" && c.Group != sel.Group {
+			continue
+		}
+		if sel.Tag != "" && !hasTag(c.Tags, sel.Tag) {
+			continue
+		}
+		if len(wanted) > 0 && !wanted[c.UUID] {
+			continue
+		}
+		nodes = append(nodes, c)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Weight != nodes[j].Weight {
+			return nodes[i].Weight < nodes[j].Weight
+		}
+		return nodes[i].UUID < nodes[j].UUID
+	})
+	return nodes, startTime, endTime, nil
+}
+
+func hasTag(tags, tag string) bool {
+	for _, t := range strings.Split(tags, ";") {
+		if strings.TrimSpace(t) == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// fleetRecords 取窗口内命中节点的负载记录
+func fleetRecords(nodes []models.Client, start, end time.Time) ([]models.Record, error) {
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+	uuid := ""
+	if len(nodes) == 1 {
+		uuid = nodes[0].UUID
+	}
+	recs, err := getLoadRecordsCombined(uuid, start, end)
+	if err != nil {
+		return nil, err
+	}
+	in := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		in[n.UUID] = true
+	}
+	filtered := recs[:0]
+	for _, r := range recs {
+		if in[r.Client] {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered, nil
+}
+
+// 可用于聚合与排序的负载指标，百分比类指标已换算为 0-100
+var fleetMetrics = map[string]func(r *models.Record) (float64, bool){
+	"cpu":         func(r *models.Record) (float64, bool) { return float64(r.Cpu), true },
+	"gpu":         func(r *models.Record) (float64, bool) { return float64(r.Gpu), true },
+	"ram":         func(r *models.Record) (float64, bool) { return ratioPercent(r.Ram, r.RamTotal) },
+	"swap":        func(r *models.Record) (float64, bool) { return ratioPercent(r.Swap, r.SwapTotal) },
+	"disk":        func(r *models.Record) (float64, bool) { return ratioPercent(r.Disk, r.DiskTotal) },
+	"load":        func(r *models.Record) (float64, bool) { return float64(r.Load), true },
+	"temp":        func(r *models.Record) (float64, bool) { return float64(r.Temp), r.Temp > 0 },
+	"net_in":      func(r *models.Record) (float64, bool) { return float64(r.NetIn), true },
+	"net_out":     func(r *models.Record) (float64, bool) { return float64(r.NetOut), true },
+	"net":         func(r *models.Record) (float64, bool) { return float64(r.NetIn + r.NetOut), true },
+	"process":     func(r *models.Record) (float64, bool) { return float64(r.Process), true },
+	"connections": func(r *models.Record) (float64, bool) { return float64(r.Connections + r.ConnectionsUdp), true },
+}
+
+func ratioPercent(used, total int64) (float64, bool) {
+	if total <= 0 {
+		return 0, false
+	}
+	return float64(used) / float64(total) * 100, true
+}
+
+// percentileFloat 对已排序的切片做线性插值取百分位，与 ping 统计的算法一致
+func percentileFloat(sorted []float64, pct float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	if pct <= 0 {
+		return sorted[0]
+	}
+	if pct >= 1 {
+		return sorted[len(sorted)-1]
+	}
+	pos := float64(len(sorted)-1) * pct
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	if lo == hi {
+		return sorted[lo]
+	}
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(pos-float64(lo))
+}
+
+type fleetDistribution struct {
+	Avg float64 `json:"avg"`
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+func distribution(values []float64) fleetDistribution {
+	if len(values) == 0 {
+		return fleetDistribution{}
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+	return fleetDistribution{
+		Avg: sum / float64(len(sorted)),
+		P50: percentileFloat(sorted, 0.50),
+		P95: percentileFloat(sorted, 0.95),
+		P99: percentileFloat(sorted, 0.99),
+		Max: sorted[len(sorted)-1],
+	}
+}
+
+// fleetPoint 一个时间桶内的全体节点聚合值，带宽与内存为各节点之和
+type fleetPoint struct {
+	Time     models.LocalTime `json:"time"`
+	Nodes    int              `json:"nodes"` // 该时间桶内有数据的节点数
+	CpuAvg   float64          `json:"cpu_avg"`
+	CpuP95   float64          `json:"cpu_p95"`
+	CpuMax   float64          `json:"cpu_max"`
+	LoadAvg  float64          `json:"load_avg"`
+	Ram      int64            `json:"ram"`
+	RamTotal int64            `json:"ram_total"`
+	NetIn    int64            `json:"net_in"`
+	NetOut   int64            `json:"net_out"`
+}
+
+type fleetAggregate struct {
+	Series    []fleetPoint
+	Reporting int
+	Cpu       fleetDistribution
+	Load      fleetDistribution
+	NetIn     fleetDistribution
+	NetOut    fleetDistribution
+}
+
+// aggregateFleet 将记录按 step 分桶：每个节点在桶内先取均值，避免上报频率不同的节点权重不一，
+// 再在节点之间求和（带宽、内存）或取均值与百分位（CPU、负载）。
+// 整体分布基于各节点在各时间桶的均值，带宽分布基于各时间桶的全体总和
+func aggregateFleet(recs []models.Record, start time.Time, step time.Duration) fleetAggregate {
+	type acc struct {
+		n                            int
+		cpu, load                    float64
+		ram, ramTotal, netIn, netOut int64
+	}
+	buckets := map[int64]map[string]*acc{}
+	reporting := map[string]bool{}
+	for i := range recs {
+		r := &recs[i]
+		idx := int64(r.Time.ToTime().Sub(start) / step)
+		if idx < 0 {
+			idx = 0
+		}
+		nodes := buckets[idx]
+		if nodes == nil {
+			nodes = map[string]*acc{}
+			buckets[idx] = nodes
+		}
+		a := nodes[r.Client]
+		if a == nil {
+			a = &acc{}
+			nodes[r.Client] = a
+		}
+		a.n++
+		a.cpu += float64(r.Cpu)
+		a.load += float64(r.Load)
+		a.ram += r.Ram
+		a.ramTotal += r.RamTotal
+		a.netIn += r.NetIn
+		a.netOut += r.NetOut
+		reporting[r.Client] = true
+	}
+	keys := make([]int64, 0, len(buckets))
+	for k := range buckets {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	out := fleetAggregate{Series: make([]fleetPoint, 0, len(keys)), Reporting: len(reporting)}
+	var cpuAll, loadAll, netInAll, netOutAll []float64
+	for _, k := range keys {
+		p := fleetPoint{Time: models.FromTime(start.Add(time.Duration(k) * step)), Nodes: len(buckets[k])}
+		cpus := make([]float64, 0, len(buckets[k]))
+		for _, a := range buckets[k] {
+			n := float64(a.n)
+			cpu := a.cpu / n
+			cpus = append(cpus, cpu)
+			loadAll = append(loadAll, a.load/n)
+			p.LoadAvg += a.load / n
+			p.Ram += a.ram / int64(a.n)
+			p.RamTotal += a.ramTotal / int64(a.n)
+			p.NetIn += a.netIn / int64(a.n)
+			p.NetOut += a.netOut / int64(a.n)
+		}
+		d := distribution(cpus)
+		p.CpuAvg, p.CpuP95, p.CpuMax = d.Avg, d.P95, d.Max
+		p.LoadAvg /= float64(p.Nodes)
+		cpuAll = append(cpuAll, cpus...)
+		netInAll = append(netInAll, float64(p.NetIn))
+		netOutAll = append(netOutAll, float64(p.NetOut))
+		out.Series = append(out.Series, p)
+	}
+	out.Cpu = distribution(cpuAll)
+	out.Load = distribution(loadAll)
+	out.NetIn = distribution(netInAll)
+	out.NetOut = distribution(netOutAll)
+	return out
+}
+
+// fleetStep 按期望点数计算分桶宽度，不小于 1 分钟
+func fleetStep(start, end time.Time, points int) time.Duration {
+	if points <= 0 {
+		points = 360
+	}
+	step := end.Sub(start) / time.Duration(points)
+	if step < time.Minute {
+		step = time.Minute
+	}
+	return step.Round(time.Minute)
+}
+
+// getFleetStats 返回选中节点的整体统计与聚合时序
+func getFleetStats(ctx context.Context, req *rpc.JsonRpcRequest) (any, *rpc.JsonRpcError) {
+	meta := rpc.MetaFromContext(ctx)
+	var params struct {
+		fleetSelector
+		Points int `json:"points"` // number of series points; default 360
+	}
+	req.BindParams(&params)
+	nodes, startTime, endTime, jerr := selectFleet(meta, params.fleetSelector)
+	if jerr != nil {
+		return nil, jerr
+	}
+	recs, err := fleetRecords(nodes, startTime, endTime)
+	if err != nil {
+		return nil, rpc.MakeError(rpc.InternalError, "Failed to fetch records", err.Error())
+	}
+	step := fleetStep(startTime, endTime, params.Points)
+	agg := aggregateFleet(recs, startTime, step)
+
+	// 流量按各节点自己的结算日取当前周期
+	resetDays := make(map[string]int, len(nodes))
+	uuids := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		resetDays[n.UUID] = n.TrafficResetDay
+		uuids = append(uuids, n.UUID)
+	}
+	ledger, err := recordsdb.GetCurrentTraffic(resetDays, time.Now())
+	if err != nil {
+		return nil, rpc.MakeError(rpc.InternalError, "Failed to fetch traffic ledger", err.Error())
+	}
+	type trafficSum struct {
+		Up    int64 `json:"up"`
+		Down  int64 `json:"down"`
+		Total int64 `json:"total"`
+	}
+	var traffic trafficSum
+	for _, l := range ledger {
+		traffic.Up += l.Up
+		traffic.Down += l.Down
+	}
+	traffic.Total = traffic.Up + traffic.Down
+
+	return struct {
+		Nodes     []string          `json:"nodes"`
+		Reporting int               `json:"reporting"`
+		Cpu       fleetDistribution `json:"cpu"`
+		Load      fleetDistribution `json:"load"`
+		NetIn     fleetDistribution `json:"net_in"`
+		NetOut    fleetDistribution `json:"net_out"`
+		Traffic   trafficSum        `json:"traffic"`
+		Step      int               `json:"step"` // seconds per series point
+		Series    []fleetPoint      `json:"series"`
+		From      models.LocalTime  `json:"from"`
+		To        models.LocalTime  `json:"to"`
+	}{
+		Nodes:     uuids,
+		Reporting: agg.Reporting,
+		Cpu:       agg.Cpu,
+		Load:      agg.Load,
+		NetIn:     agg.NetIn,
+		NetOut:    agg.NetOut,
+		Traffic:   traffic,
+		Step:      int(step / time.Second),
+		Series:    agg.Series,
+		From:      models.FromTime(startTime),
+		To:        models.FromTime(endTime),
+	}, nil
+}
+
+// getTopNodes 按指标在窗口内的聚合值对节点排序，返回前 N 个
+func getTopNodes(ctx context.Context, req *rpc.JsonRpcRequest) (any, *rpc.JsonRpcError) {
+	meta := rpc.MetaFromContext(ctx)
+	var params struct {
+		fleetSelector
+		Metric string `json:"metric"` // cpu|gpu|ram|swap|disk|load|temp|net_in|net_out|net|process|connections|traffic|traffic_up|traffic_down
+		Agg    string `json:"agg"`    // avg|max|p95|latest; default avg; ignored for traffic metrics
+		Limit  int    `json:"limit"`  // default 10, -1 unlimited
+		Order  string `json:"order"`  // desc|asc; default desc
+	}
+	req.BindParams(&params)
+	if params.Metric == "" {
+		params.Metric = "cpu"
+	}
+	if params.Agg == "" {
+		params.Agg = "avg"
+	}
+	if params.Limit == 0 {
+		params.Limit = 10
+	}
+	switch params.Agg {
+	case "avg", "max", "p95", "latest":
+	default:
+		return nil, rpc.MakeError(rpc.InvalidParams, "Invalid agg, expected avg|max|p95|latest", params.Agg)
+	}
+	nodes, startTime, endTime, jerr := selectFleet(meta, params.fleetSelector)
+	if jerr != nil {
+		return nil, jerr
+	}
+
+	values := make(map[string]float64, len(nodes))
+	if strings.HasPrefix(params.Metric, "traffic") {
+		resetDays := make(map[string]int, len(nodes))
+		for _, n := range nodes {
+			resetDays[n.UUID] = n.TrafficResetDay
+		}
+		ledger, err := recordsdb.GetCurrentTraffic(resetDays, time.Now())
+		if err != nil {
+			return nil, rpc.MakeError(rpc.InternalError, "Failed to fetch traffic ledger", err.Error())
+		}
+		for _, n := range nodes {
+			l := ledger[n.UUID]
+			switch params.Metric {
+			case "traffic":
+				values[n.UUID] = float64(l.Up + l.Down)
+			case "traffic_up":
+				values[n.UUID] = float64(l.Up)
+			case "traffic_down":
+				values[n.UUID] = float64(l.Down)
+			default:
+				return nil, rpc.MakeError(rpc.InvalidParams, "Invalid metric", params.Metric)
+			}
+		}
+	} else {
+		extract, ok := fleetMetrics[params.Metric]
+		if !ok {
+			return nil, rpc.MakeError(rpc.InvalidParams, "Invalid metric", params.Metric)
+		}
+		recs, err := fleetRecords(nodes, startTime, endTime)
+		if err != nil {
+			return nil, rpc.MakeError(rpc.InternalError, "Failed to fetch records", err.Error())
+		}
+		sort.Slice(recs, func(i, j int) bool { return recs[i].Time.ToTime().Before(recs[j].Time.ToTime()) })
+		samples := make(map[string][]float64, len(nodes))
+		for i := range recs {
+			if v, ok := extract(&recs[i]); ok {
+				samples[recs[i].Client] = append(samples[recs[i].Client], v)
+			}
+		}
+		for uuid, s := range samples {
+			switch params.Agg {
+			case "latest":
+				values[uuid] = s[len(s)-1]
+			case "max":
+				values[uuid] = distribution(s).Max
+			case "p95":
+				values[uuid] = distribution(s).P95
+			default:
+				values[uuid] = distribution(s).Avg
+			}
+		}
+	}
+
+	type item struct {
+		UUID  string  `json:"uuid"`
+		Name  string  `json:"name"`
+		Group string  `json:"group"`
+		Value float64 `json:"value"`
+	}
+	items := make([]item, 0, len(values))
+	for _, n := range nodes {
+		if v, ok := values[n.UUID]; ok {
+			items = append(items, item{UUID: n.UUID, Name: n.Name, Group: n.Group, Value: v})
+		}
+	}
+	asc := params.Order == "asc"
+	sort.SliceStable(items, func(i, j int) bool {
+		if asc {
+			return items[i].Value < items[j].Value
+		}
+		return items[i].Value > items[j].Value
+	})
+	if params.Limit > 0 && len(items) > params.Limit {
+		items = items[:params.Limit]
+	}
+	return struct {
+		Metric string           `json:"metric"`
+		Agg    string           `json:"agg"`
+		Items  []item           `json:"items"`
+		From   models.LocalTime `json:"from"`
+		To     models.LocalTime `json:"to"`
+	}{Metric: params.Metric, Agg: params.Agg, Items: items, From: models.FromTime(startTime), To: models.FromTime(endTime)}, nil
+}
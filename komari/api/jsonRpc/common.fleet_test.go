@@ -0,0 +1,44 @@
+package jsonRpc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/komari-monitor/komari/database/models"
+)
+
+func TestAggregateFleet(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(min int) models.LocalTime { return models.FromTime(start.Add(time.Duration(min) * time.Minute)) }
+	recs := []models.Record{
+		// a 在第一个桶上报两次，按均值计入
+		{Client: "a", Time: at(0), Cpu: 10, NetIn: 100},
+		{Client: "a", Time: at(1), Cpu: 30, NetIn: 300},
+		{Client: "b", Time: at(2), Cpu: 60, NetIn: 1000},
+		{Client: "b", Time: at(6), Cpu: 80, NetIn: 500},
+	}
+	agg := aggregateFleet(recs, start, 5*time.Minute)
+	if agg.Reporting != 2 || len(agg.Series) != 2 {
+		t.Fatalf("unexpected aggregate: %+v", agg)
+	}
+	first := agg.Series[0]
+	if first.Nodes != 2 || first.CpuAvg != 40 || first.CpuMax != 60 || first.NetIn != 1200 {
+		t.Fatalf("unexpected first bucket: %+v", first)
+	}
+	if second := agg.Series[1]; second.Nodes != 1 || second.NetIn != 500 || !second.Time.ToTime().Equal(start.Add(5*time.Minute)) {
+		t.Fatalf("unexpected second bucket: %+v", second)
+	}
+	if agg.Cpu.Max != 80 || agg.NetIn.Max != 1200 {
+		t.Fatalf("unexpected distribution: cpu %+v net_in %+v", agg.Cpu, agg.NetIn)
+	}
+}
+
+func TestPercentileFloat(t *testing.T) {
+	sorted := []float64{1, 2, 3, 4}
+	if v := percentileFloat(sorted, 0.5); v != 2.5 {
+		t.Fatalf("p50 = %v", v)
+	}
+	if v := percentileFloat(sorted, 1); v != 4 {
+		t.Fatalf("p100 = %v", v)
+	}
+}
@@ -13,7 +13,6 @@ import (
 	"github.com/komari-monitor/komari/database"
 	"github.com/komari-monitor/komari/database/clients"
 	"github.com/komari-monitor/komari/database/config"
-	"github.com/komari-monitor/komari/database/dbcore"
 	"github.com/komari-monitor/komari/database/models"
 	"github.com/komari-monitor/komari/database/tasks"
 	"github.com/komari-monitor/komari/utils"
@@ -231,10 +230,16 @@ func getNodes(ctx context.Context, req *rpc.JsonRpcRequest) (any, *rpc.JsonRpcEr
 
 	cfg, _ := config.Get()
 	if meta.Permission != "admin" {
-		// 过滤 Hidden 节点并隐藏敏感字段
+		// 过滤不可见节点并隐藏敏感字段
+		hidden := invisibleClients(meta, cinfo)
 		filtered := make([]models.Client, 0, len(cinfo))
 		for _, node := range cinfo {
-			if node.Hidden { // 非 admin 不显示隐藏节点
+			if hidden[node.UUID] {
+				continue
+			}
+			node.Token = ""
+			if meta.Permission == "user" { // 已登录的非管理员账户保留 IP 与备注
+				filtered = append(filtered, node)
 				continue
 			}
 			if cfg.SendIpAddrToGuest {
@@ -251,7 +256,6 @@ func getNodes(ctx context.Context, req *rpc.JsonRpcRequest) (any, *rpc.JsonRpcEr
 
 			node.Remark = ""
 			node.Version = ""
-			node.Token = ""
 			filtered = append(filtered, node)
 		}
 		cinfo = filtered
@@ -295,18 +299,13 @@ func getNodesLatestStatus(ctx context.Context, req *rpc.JsonRpcRequest) (any, *r
 		onlineSet[uuid] = true
 	}
 
-	// Hidden 过滤
+	// 不可见节点过滤
 	if meta.Permission != "admin" {
 		cinfo, err := clients.GetAllClientBasicInfo()
 		if err != nil {
 			return nil, rpc.MakeError(rpc.InternalError, "Failed to get client info", err.Error())
 		}
-		hidden := make(map[string]bool, len(cinfo))
-		for _, c := range cinfo {
-			if c.Hidden {
-				hidden[c.UUID] = true
-			}
-		}
+		hidden := invisibleClients(meta, cinfo)
 		for uuid := range latest {
 			if hidden[uuid] {
 				delete(latest, uuid)
@@ -418,12 +417,28 @@ func getMe(ctx context.Context, _ *rpc.JsonRpcRequest) (any, *rpc.JsonRpcError)
 		SSOType      string `json:"sso_type"`
 		Username     string `json:"username"`
 		UUID         string `json:"uuid"`
+		Role         string `json:"role,omitempty"`
 	}
 
 	meta := rpc.MetaFromContext(ctx)
 
 	switch meta.Permission {
-	case "admin":
+	case "admin", "user":
+		if meta.ApiToken != nil {
+			resp.LoggedIn = true
+			resp.Role = models.RoleReadOnly
+			if meta.Permission == "admin" {
+				resp.Role = models.RoleAdmin
+			}
+			resp.UUID = meta.UserUUID
+			return resp, nil
+		}
+		if meta.User == nil { // API Key
+			resp.LoggedIn = true
+			resp.Role = models.RoleAdmin
+			return resp, nil
+		}
+		resp.Role = meta.User.Role
 		resp.TwoFAEnabled = meta.User.TwoFactor != ""
 		resp.LoggedIn = true
 		resp.SSOId = meta.User.SSOID
@@ -469,23 +484,13 @@ func getNodeRecentStatus(ctx context.Context, req *rpc.JsonRpcRequest) (any, *rp
 		return nil, rpc.MakeError(rpc.InvalidParams, "UUID is required", params)
 	}
 	meta := rpc.MetaFromContext(ctx)
-	// 登录状态检查
-	isLogin := false
-	if meta.Permission == "admin" {
-		isLogin = true
-	}
-
-	// 仅在未登录时需要 Hidden 信息做过滤
-	hiddenMap := map[string]bool{}
-	if !isLogin {
-		var hiddenClients []models.Client
-		db := dbcore.GetDBInstance()
-		_ = db.Select("uuid").Where("hidden = ?", true).Find(&hiddenClients).Error
-		for _, cli := range hiddenClients {
-			hiddenMap[cli.UUID] = true
+	// 管理员可查看全部节点，其余请求过滤隐藏节点与范围外节点
+	if meta.Permission != "admin" {
+		cinfo, err := clients.GetAllClientBasicInfo()
+		if err != nil {
+			return nil, rpc.MakeError(rpc.InternalError, "Failed to get client info", err.Error())
 		}
-
-		if hiddenMap[params.UUID] {
+		if invisibleClients(meta, cinfo)[params.UUID] {
 			return nil, rpc.MakeError(rpc.InvalidParams, "UUID is required", params) //防止未登录用户获取隐藏客户端数据
 		}
 	}
@@ -556,3 +561,30 @@ func getNodeRecentStatus(ctx context.Context, req *rpc.JsonRpcRequest) (any, *rp
 	resp.Count = len(resp.Records)
 	return resp, nil
 }
+
+// invisibleClients 返回当前请求不可见的节点：
+// 访客与 Agent 看不到隐藏节点；非管理员账户可以看到隐藏节点，但受节点范围限制
+func invisibleClients(meta *rpc.ContextMeta, cinfo []models.Client) map[string]bool {
+	hidden := make(map[string]bool)
+	if meta.Permission == "admin" {
+		return hidden
+	}
+	for i := range cinfo {
+		if meta.Permission == "user" {
+			if meta.ApiToken != nil {
+				if !meta.ApiToken.CanAccessClient(cinfo[i].UUID) {
+					hidden[cinfo[i].UUID] = true
+				}
+				continue
+			}
+			if meta.User == nil || !meta.User.CanAccessClient(&cinfo[i]) {
+				hidden[cinfo[i].UUID] = true
+			}
+			continue
+		}
+		if cinfo[i].Hidden {
+			hidden[cinfo[i].UUID] = true
+		}
+	}
+	return hidden
+}
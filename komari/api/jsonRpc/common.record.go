@@ -22,7 +22,7 @@ func init() {
 func getRecords(ctx context.Context, req *rpc.JsonRpcRequest) (any, *rpc.JsonRpcError) {
 	meta := rpc.MetaFromContext(ctx)
 	var params struct {
-		Type     string `json:"type"`      // "load" | "ping"; default "load"
+		Type     string `json:"type"`      // "load" | "ping" | "disk" | "network" | "traffic"; default "load"
 		UUID     string `json:"uuid"`      // client uuid; empty = all clients
 		Hours    int    `json:"hours"`     // time window in hours; default 1 if start/end not provided
 		Start    string `json:"start"`     // RFC3339 start time (optional)
@@ -37,39 +37,12 @@ func getRecords(ctx context.Context, req *rpc.JsonRpcRequest) (any, *rpc.JsonRpc
 	if params.Type == "" {
 		params.Type = "load"
 	}
-	// parse time window
-	var startTime, endTime time.Time
-	if params.Start != "" || params.End != "" {
-		// allow partial: missing end means now
-		var err error
-		if params.End == "" {
-			endTime = time.Now()
-		} else {
-			endTime, err = time.Parse(time.RFC3339, params.End)
-			if err != nil {
-				return nil, rpc.MakeError(rpc.InvalidParams, "Invalid end time", params.End)
-			}
-		}
-		if params.Start == "" {
-			// default to 1 hour before end
-			startTime = endTime.Add(-1 * time.Hour)
-		} else {
-			start, err := time.Parse(time.RFC3339, params.Start)
-			if err != nil {
-				return nil, rpc.MakeError(rpc.InvalidParams, "Invalid start time", params.Start)
-			}
-			startTime = start
-		}
-	} else {
-		hours := params.Hours
-		if hours <= 0 {
-			hours = 1 // default 1 hour
-		}
-		endTime = time.Now()
-		startTime = endTime.Add(-time.Duration(hours) * time.Hour)
+	startTime, endTime, jerr := parseTimeWindow(params.Hours, params.Start, params.End)
+	if jerr != nil {
+		return nil, jerr
 	}
 
-	// Hidden filtering for non-admin
+	// Hidden / scope filtering for non-admin
 	isAdmin := meta.Permission == "admin"
 	hidden := map[string]bool{}
 	if !isAdmin {
@@ -77,11 +50,7 @@ func getRecords(ctx context.Context, req *rpc.JsonRpcRequest) (any, *rpc.JsonRpc
 		if err != nil {
 			return nil, rpc.MakeError(rpc.InternalError, "Failed to get client info", err.Error())
 		}
-		for _, c := range cinfo {
-			if c.Hidden {
-				hidden[c.UUID] = true
-			}
-		}
+		hidden = invisibleClients(meta, cinfo)
 		if params.UUID != "" && hidden[params.UUID] {
 			return nil, rpc.MakeError(rpc.InvalidParams, "UUID not found", params.UUID)
 		}
@@ -189,6 +158,106 @@ func getRecords(ctx context.Context, req *rpc.JsonRpcRequest) (any, *rpc.JsonRpc
 			To      models.LocalTime           `json:"to"`
 		}{Count: total, Records: grouped, From: models.FromTime(startTime), To: models.FromTime(endTime)}, nil
 
+	case "disk":
+		// per-mount usage and per-device I/O, only for a single client
+		if params.UUID == "" {
+			return nil, rpc.MakeError(rpc.InvalidParams, "uuid is required for type=disk", nil)
+		}
+		recs, err := recordsdb.GetDiskRecordsByClientAndTime(params.UUID, startTime, endTime)
+		if err != nil {
+			return nil, rpc.MakeError(rpc.InternalError, "Failed to fetch disk records", err.Error())
+		}
+		mounts := make(map[string][]models.DiskRecord)
+		io := make(map[string][]models.DiskRecord)
+		for _, r := range recs {
+			if r.Kind == models.DiskRecordIO {
+				io[r.Name] = append(io[r.Name], r)
+			} else {
+				mounts[r.Name] = append(mounts[r.Name], r)
+			}
+		}
+		maxCount := params.MaxCount
+		if maxCount == 0 {
+			maxCount = 4000
+		}
+		total := 0
+		for _, series := range []map[string][]models.DiskRecord{mounts, io} {
+			for name, arr := range series {
+				sort.Slice(arr, func(i, j int) bool { return arr[i].Time.ToTime().Before(arr[j].Time.ToTime()) })
+				if maxCount != -1 && len(recs) > maxCount {
+					k := len(arr) * maxCount / len(recs)
+					if k < 1 {
+						k = 1
+					}
+					arr = downsampleRecords(arr, k)
+				}
+				series[name] = arr
+				total += len(arr)
+			}
+		}
+		return struct {
+			Count  int                            `json:"count"`
+			Mounts map[string][]models.DiskRecord `json:"mounts"`
+			IO     map[string][]models.DiskRecord `json:"io"`
+			From   models.LocalTime               `json:"from"`
+			To     models.LocalTime               `json:"to"`
+		}{Count: total, Mounts: mounts, IO: io, From: models.FromTime(startTime), To: models.FromTime(endTime)}, nil
+
+	case "network":
+		// per-interface speed and counters, only for a single client
+		if params.UUID == "" {
+			return nil, rpc.MakeError(rpc.InvalidParams, "uuid is required for type=network", nil)
+		}
+		recs, err := recordsdb.GetNetworkRecordsByClientAndTime(params.UUID, startTime, endTime)
+		if err != nil {
+			return nil, rpc.MakeError(rpc.InternalError, "Failed to fetch network records", err.Error())
+		}
+		grouped := make(map[string][]models.NetworkRecord)
+		for _, r := range recs {
+			grouped[r.Interface] = append(grouped[r.Interface], r)
+		}
+		maxCount := params.MaxCount
+		if maxCount == 0 {
+			maxCount = 4000
+		}
+		total := 0
+		for name, arr := range grouped {
+			sort.Slice(arr, func(i, j int) bool { return arr[i].Time.ToTime().Before(arr[j].Time.ToTime()) })
+			if maxCount != -1 && len(recs) > maxCount {
+				k := len(arr) * maxCount / len(recs)
+				if k < 1 {
+					k = 1
+				}
+				arr = downsampleRecords(arr, k)
+			}
+			grouped[name] = arr
+			total += len(arr)
+		}
+		return struct {
+			Count   int                               `json:"count"`
+			Records map[string][]models.NetworkRecord `json:"records"`
+			From    models.LocalTime                  `json:"from"`
+			To      models.LocalTime                  `json:"to"`
+		}{Count: total, Records: grouped, From: models.FromTime(startTime), To: models.FromTime(endTime)}, nil
+
+	case "traffic":
+		// server-side monthly traffic ledger; time window is ignored, maxCount limits the number of periods
+		if params.UUID == "" {
+			return nil, rpc.MakeError(rpc.InvalidParams, "uuid is required for type=traffic", nil)
+		}
+		limit := params.MaxCount
+		if limit == 0 {
+			limit = 12
+		}
+		ledger, err := recordsdb.GetTrafficLedger(params.UUID, limit)
+		if err != nil {
+			return nil, rpc.MakeError(rpc.InternalError, "Failed to fetch traffic ledger", err.Error())
+		}
+		return struct {
+			Count   int                    `json:"count"`
+			Records []models.TrafficLedger `json:"records"`
+		}{Count: len(ledger), Records: ledger}, nil
+
 	case "ping":
 		taskId := params.TaskID
 		if taskId == 0 {
@@ -509,6 +578,37 @@ func getRecords(ctx context.Context, req *rpc.JsonRpcRequest) (any, *rpc.JsonRpc
 
 // ---------- helpers for load records ----------
 
+// parseTimeWindow 解析 start/end（RFC3339），缺省时取最近 hours 小时（默认 1）
+func parseTimeWindow(hours int, start, end string) (time.Time, time.Time, *rpc.JsonRpcError) {
+	var startTime, endTime time.Time
+	if start == "" && end == "" {
+		if hours <= 0 {
+			hours = 1 // default 1 hour
+		}
+		endTime = time.Now()
+		return endTime.Add(-time.Duration(hours) * time.Hour), endTime, nil
+	}
+	// allow partial: missing end means now
+	var err error
+	if end == "" {
+		endTime = time.Now()
+	} else {
+		endTime, err = time.Parse(time.RFC3339, end)
+		if err != nil {
+			return startTime, endTime, rpc.MakeError(rpc.InvalidParams, "Invalid end time", end)
+		}
+	}
+	if start == "" {
+		// default to 1 hour before end
+		return endTime.Add(-1 * time.Hour), endTime, nil
+	}
+	startTime, err = time.Parse(time.RFC3339, start)
+	if err != nil {
+		return startTime, endTime, rpc.MakeError(rpc.InvalidParams, "Invalid start time", start)
+	}
+	return startTime, endTime, nil
+}
+
 // getLoadRecordsCombined fetches records for a client or all clients within a time range,
 // combining recent short-term table and long-term table with 15-min grouping for recent part.
 func getLoadRecordsCombined(uuid string, start, end time.Time) ([]models.Record, error) {
@@ -717,6 +817,32 @@ func downsampleFlatRecords(in []flatRecord, k int) []flatRecord {
 	return out
 }
 
+// downsampleRecords 在序列上等间距取 k 个点，保留首尾
+func downsampleRecords[T any](in []T, k int) []T {
+	n := len(in)
+	if k <= 0 || n == 0 {
+		return []T{}
+	}
+	if k >= n {
+		return in
+	}
+	out := make([]T, 0, k)
+	if k == 1 {
+		out = append(out, in[n-1])
+		return out
+	}
+	for i := 0; i < k; i++ {
+		idx := int(math.Round(float64(i) * float64(n-1) / float64(k-1)))
+		if idx < 0 {
+			idx = 0
+		} else if idx >= n {
+			idx = n - 1
+		}
+		out = append(out, in[idx])
+	}
+	return out
+}
+
 // flatRecord is a projection used when load_type is specified.
 type flatRecord struct {
 	Client         string           `json:"client"`
@@ -44,7 +44,7 @@ func handleScriptLogRPC(conn *ws.SafeConn, req *rpc.JsonRpcRequest, permissionGr
 	if req == nil {
 		return false
 	}
-	if permissionGroup != "admin" {
+	if permissionGroup != "admin" && permissionGroup != "user" {
 		if req.HasID() {
 			conn.WriteJSON(rpc.ErrorResponse(req.ID, rpc.Unavailable, "Unauthorized", nil))
 		}
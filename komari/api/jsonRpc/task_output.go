@@ -0,0 +1,126 @@
+package jsonRpc
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/komari-monitor/komari/database/clients"
+	"github.com/komari-monitor/komari/database/models"
+	"github.com/komari-monitor/komari/utils/rpc"
+	"github.com/komari-monitor/komari/ws"
+)
+
+// TaskOutputEvent 远程命令执行过程中的增量输出
+type TaskOutputEvent struct {
+	TaskID     string `json:"task_id"`
+	ClientUUID string `json:"client_uuid"`
+	Stream     string `json:"stream"` // stdout / stderr
+	Data       string `json:"data"`
+	Time       string `json:"time"`
+}
+
+// 订阅者对应的账户，管理员为 nil，非管理员账户推送时按节点范围过滤
+var (
+	taskOutputSubs   = make(map[string]map[*ws.SafeConn]*models.User)
+	taskOutputSubsMu sync.RWMutex
+)
+
+func clearTaskOutputConn(conn *ws.SafeConn) {
+	taskOutputSubsMu.Lock()
+	defer taskOutputSubsMu.Unlock()
+	for taskID, conns := range taskOutputSubs {
+		delete(conns, conn)
+		if len(conns) == 0 {
+			delete(taskOutputSubs, taskID)
+		}
+	}
+}
+
+func handleTaskOutputRPC(conn *ws.SafeConn, req *rpc.JsonRpcRequest, meta *rpc.ContextMeta) bool {
+	if req == nil {
+		return false
+	}
+	switch req.Method {
+	case "task_output.subscribe", "admin:task_output.subscribe",
+		"task_output.unsubscribe", "admin:task_output.unsubscribe":
+	default:
+		return false
+	}
+	var user *models.User
+	switch meta.Permission {
+	case "admin":
+	case "user":
+		// 与执行命令一致，至少需要操作员角色
+		if meta.User == nil || models.RoleLevel(meta.User.Role) < models.RoleLevel(models.RoleOperator) {
+			conn.WriteJSON(rpc.ErrorResponse(req.ID, rpc.Unavailable, "Unauthorized", nil))
+			return true
+		}
+		user = meta.User
+	default:
+		conn.WriteJSON(rpc.ErrorResponse(req.ID, rpc.Unavailable, "Unauthorized", nil))
+		return true
+	}
+	taskID, err := parseTaskOutputParams(req.Params)
+	if err != nil {
+		conn.WriteJSON(rpc.ErrorResponse(req.ID, rpc.InvalidParams, err.Error(), nil))
+		return true
+	}
+	taskOutputSubsMu.Lock()
+	switch req.Method {
+	case "task_output.subscribe", "admin:task_output.subscribe":
+		if _, ok := taskOutputSubs[taskID]; !ok {
+			taskOutputSubs[taskID] = make(map[*ws.SafeConn]*models.User)
+		}
+		taskOutputSubs[taskID][conn] = user
+	default:
+		if conns, ok := taskOutputSubs[taskID]; ok {
+			delete(conns, conn)
+			if len(conns) == 0 {
+				delete(taskOutputSubs, taskID)
+			}
+		}
+	}
+	taskOutputSubsMu.Unlock()
+	conn.WriteJSON(rpc.SuccessResponse(req.ID, "ok"))
+	return true
+}
+
+func parseTaskOutputParams(params any) (string, error) {
+	m, ok := params.(map[string]any)
+	if !ok {
+		return "", errors.New("invalid params")
+	}
+	v, ok := m["task_id"]
+	if !ok || v == nil {
+		return "", errors.New("task_id required")
+	}
+	taskID := fmt.Sprint(v)
+	if taskID == "" {
+		return "", errors.New("task_id required")
+	}
+	return taskID, nil
+}
+
+// PublishTaskOutput 将节点上报的命令输出推送给订阅了该任务的连接
+func PublishTaskOutput(evt TaskOutputEvent) {
+	notification := rpc.NewNotification("admin:task_output.event", evt)
+	taskOutputSubsMu.RLock()
+	defer taskOutputSubsMu.RUnlock()
+	var client *models.Client
+	for conn, user := range taskOutputSubs[evt.TaskID] {
+		if user != nil && user.IsScoped() {
+			if client == nil {
+				c, err := clients.GetClientBasicInfo(evt.ClientUUID)
+				if err != nil {
+					continue
+				}
+				client = &c
+			}
+			if !user.CanAccessClient(client) {
+				continue
+			}
+		}
+		_ = conn.WriteJSON(notification)
+	}
+}
@@ -0,0 +1,258 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/komari-monitor/komari/database/clients"
+	"github.com/komari-monitor/komari/database/config"
+	"github.com/komari-monitor/komari/database/models"
+	"github.com/komari-monitor/komari/database/tasks"
+	"github.com/komari-monitor/komari/utils/metrics"
+	"github.com/komari-monitor/komari/ws"
+)
+
+// 只导出最近窗口内的探测结果，避免已停用任务的陈旧数据一直存在
+const (
+	metricsPingWindow   = 10 * time.Minute
+	metricsSPPingWindow = 2 * time.Hour
+)
+
+// GetMetrics 以 Prometheus 文本格式导出节点最新上报与延迟探测结果，/api/metrics
+func GetMetrics(c *gin.Context) {
+	cfg, err := config.Get()
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Failed to get configuration.")
+		return
+	}
+	if !cfg.MetricsEnabled {
+		RespondError(c, http.StatusNotFound, "Metrics exporter is disabled.")
+		return
+	}
+	authorized, token := isMetricsAuthorized(c, cfg)
+	if !authorized {
+		RespondError(c, http.StatusUnauthorized, "Unauthorized.")
+		return
+	}
+
+	allClients, err := clients.GetAllClientBasicInfo()
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "Failed to retrieve client information: "+err.Error())
+		return
+	}
+	// 限定节点的令牌只导出其范围内的节点，探测结果按 nodeLabels 随之过滤
+	clientList := make([]models.Client, 0, len(allClients))
+	for _, cli := range allClients {
+		if token == nil || token.CanAccessClient(cli.UUID) {
+			clientList = append(clientList, cli)
+		}
+	}
+	nodeLabels := make(map[string]metrics.Labels, len(clientList))
+	for _, cli := range clientList {
+		nodeLabels[cli.UUID] = metrics.Labels{
+			"uuid":   cli.UUID,
+			"name":   cli.Name,
+			"group":  cli.Group,
+			"region": cli.Region,
+		}
+	}
+
+	reg := metrics.NewRegistry()
+	writeNodeMetrics(reg, clientList, nodeLabels)
+	if err := writePingMetrics(reg, nodeLabels); err != nil {
+		RespondError(c, http.StatusInternalServerError, "Failed to retrieve ping records: "+err.Error())
+		return
+	}
+	if err := writeSPPingMetrics(reg, nodeLabels); err != nil {
+		RespondError(c, http.StatusInternalServerError, "Failed to retrieve sp ping records: "+err.Error())
+		return
+	}
+
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", metrics.ContentType)
+	reg.WriteTo(c.Writer)
+}
+
+// isMetricsAuthorized 接受 API Key、具备 read 权限的 API 令牌，或 Bearer/查询参数形式的抓取令牌；
+// 使用 API 令牌时一并返回该令牌，用于限定导出的节点
+func isMetricsAuthorized(c *gin.Context, cfg models.Config) (bool, *models.ApiToken) {
+	auth := c.GetHeader("Authorization")
+	if isApiKeyValid(auth) {
+		return true, nil
+	}
+	if apiToken := VerifyApiToken(auth, c.ClientIP()); apiToken != nil && (apiToken.HasScope(models.TokenScopeAdmin) || apiToken.HasScope(models.TokenScopeRead)) {
+		return true, apiToken
+	}
+	if cfg.MetricsToken == "" {
+		return false, nil
+	}
+	token := strings.TrimPrefix(auth, "Bearer ")
+	if token == "" {
+		token = c.Query("token")
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(cfg.MetricsToken)) == 1, nil
+}
+
+func withLabels(base metrics.Labels, extra metrics.Labels) metrics.Labels {
+	l := make(metrics.Labels, len(base)+len(extra))
+	for k, v := range base {
+		l[k] = v
+	}
+	for k, v := range extra {
+		l[k] = v
+	}
+	return l
+}
+
+func writeNodeMetrics(reg *metrics.Registry, clientList []models.Client, nodeLabels map[string]metrics.Labels) {
+	online := make(map[string]bool)
+	for _, uuid := range ws.GetAllOnlineUUIDs() {
+		online[uuid] = true
+	}
+	for _, cli := range clientList {
+		v := 0.0
+		if online[cli.UUID] {
+			v = 1
+		}
+		reg.Gauge("komari_node_up", "Whether the node is currently connected (1) or not (0).", nodeLabels[cli.UUID], v)
+	}
+
+	for uuid, r := range ws.GetLatestReport() {
+		labels, ok := nodeLabels[uuid]
+		if !ok || r == nil {
+			continue
+		}
+		reg.Gauge("komari_node_cpu_usage_percent", "CPU usage in percent.", labels, r.CPU.Usage)
+		reg.Gauge("komari_node_memory_used_bytes", "Used memory in bytes.", labels, float64(r.Ram.Used))
+		reg.Gauge("komari_node_memory_total_bytes", "Total memory in bytes.", labels, float64(r.Ram.Total))
+		reg.Gauge("komari_node_swap_used_bytes", "Used swap in bytes.", labels, float64(r.Swap.Used))
+		reg.Gauge("komari_node_swap_total_bytes", "Total swap in bytes.", labels, float64(r.Swap.Total))
+		reg.Gauge("komari_node_load1", "1-minute load average.", labels, r.Load.Load1)
+		reg.Gauge("komari_node_load5", "5-minute load average.", labels, r.Load.Load5)
+		reg.Gauge("komari_node_load15", "15-minute load average.", labels, r.Load.Load15)
+		reg.Gauge("komari_node_disk_used_bytes", "Used disk space in bytes.", labels, float64(r.Disk.Used))
+		reg.Gauge("komari_node_disk_total_bytes", "Total disk space in bytes.", labels, float64(r.Disk.Total))
+		reg.Gauge("komari_node_network_receive_bytes_per_second", "Current download speed in bytes per second.", labels, float64(r.Network.Down))
+		reg.Gauge("komari_node_network_transmit_bytes_per_second", "Current upload speed in bytes per second.", labels, float64(r.Network.Up))
+		reg.Counter("komari_node_network_receive_bytes_total", "Total bytes received as reported by the agent.", labels, float64(r.Network.TotalDown))
+		reg.Counter("komari_node_network_transmit_bytes_total", "Total bytes sent as reported by the agent.", labels, float64(r.Network.TotalUp))
+		reg.Gauge("komari_node_connections", "Number of open connections.", withLabels(labels, metrics.Labels{"protocol": "tcp"}), float64(r.Connections.TCP))
+		reg.Gauge("komari_node_connections", "Number of open connections.", withLabels(labels, metrics.Labels{"protocol": "udp"}), float64(r.Connections.UDP))
+		reg.Gauge("komari_node_processes", "Number of running processes.", labels, float64(r.Process))
+		reg.Gauge("komari_node_uptime_seconds", "Node uptime in seconds.", labels, float64(r.Uptime))
+		reg.Gauge("komari_node_last_report_timestamp_seconds", "Unix time of the latest report.", labels, float64(r.UpdatedAt.Unix()))
+		if r.GPU != nil {
+			reg.Gauge("komari_node_gpu_average_usage_percent", "Average GPU usage across all devices in percent.", labels, r.GPU.AverageUsage)
+			for idx, gpu := range r.GPU.DetailedInfo {
+				gl := withLabels(labels, metrics.Labels{"device": fmt.Sprintf("%d", idx), "device_name": gpu.Name})
+				reg.Gauge("komari_node_gpu_usage_percent", "GPU utilization in percent.", gl, gpu.Utilization)
+				reg.Gauge("komari_node_gpu_memory_used_bytes", "Used GPU memory in bytes.", gl, float64(gpu.MemoryUsed))
+				reg.Gauge("komari_node_gpu_memory_total_bytes", "Total GPU memory in bytes.", gl, float64(gpu.MemoryTotal))
+				reg.Gauge("komari_node_gpu_temperature_celsius", "GPU temperature in degrees Celsius.", gl, float64(gpu.Temperature))
+			}
+		}
+		if r.Temperature != nil {
+			reg.Gauge("komari_node_temperature_celsius", "CPU package temperature, or the hottest sensor when unavailable, in degrees Celsius.", labels, r.Temperature.Primary())
+			for _, sensor := range r.Temperature.Sensors {
+				reg.Gauge("komari_node_sensor_temperature_celsius", "Hardware sensor temperature in degrees Celsius.", withLabels(labels, metrics.Labels{"sensor": sensor.Name}), sensor.Temperature)
+			}
+		}
+	}
+}
+
+func writePingMetrics(reg *metrics.Registry, nodeLabels map[string]metrics.Labels) error {
+	pingTasks, err := tasks.GetAllPingTasks()
+	if err != nil {
+		return err
+	}
+	taskMap := make(map[uint]models.PingTask, len(pingTasks))
+	for _, t := range pingTasks {
+		taskMap[t.Id] = t
+	}
+	records, err := tasks.GetLatestPingRecords(time.Now().Add(-metricsPingWindow))
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		labels, ok := nodeLabels[r.Client]
+		if !ok {
+			continue
+		}
+		t, ok := taskMap[r.TaskId]
+		if !ok {
+			continue
+		}
+		pl := withLabels(labels, metrics.Labels{
+			"task_id":   fmt.Sprintf("%d", t.Id),
+			"task_name": t.Name,
+			"type":      t.Type,
+			"target":    t.Target,
+		})
+		success := 0.0
+		if r.Value >= 0 {
+			success = 1
+			reg.Gauge("komari_ping_latency_milliseconds", "Latest ping latency in milliseconds.", pl, float64(r.Value))
+		}
+		reg.Gauge("komari_ping_success", "Whether the latest ping succeeded (1) or was lost (0).", pl, success)
+		reg.Gauge("komari_ping_timestamp_seconds", "Unix time of the latest ping result.", pl, float64(r.Time.ToTime().Unix()))
+	}
+	return nil
+}
+
+func writeSPPingMetrics(reg *metrics.Registry, nodeLabels map[string]metrics.Labels) error {
+	spTasks, err := tasks.GetAllSPPingTasks()
+	if err != nil {
+		return err
+	}
+	taskMap := make(map[uint]models.SPPingTask, len(spTasks))
+	for _, t := range spTasks {
+		taskMap[t.Id] = t
+	}
+	records, err := tasks.GetLatestSPPingRecords(time.Now().Add(-metricsSPPingWindow))
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		labels, ok := nodeLabels[r.Client]
+		if !ok {
+			continue
+		}
+		t, ok := taskMap[r.TaskId]
+		if !ok {
+			continue
+		}
+		sl := withLabels(labels, metrics.Labels{
+			"task_id":   fmt.Sprintf("%d", t.Id),
+			"task_name": t.Name,
+			"type":      t.Type,
+			"target":    t.Target,
+		})
+		// 延迟为 -1 表示整轮全部丢包，此时不输出延迟分位
+		if r.Median >= 0 {
+			for _, q := range []struct {
+				quantile string
+				value    float64
+			}{
+				{"0", r.Min},
+				{"0.1", r.P10},
+				{"0.5", r.Median},
+				{"0.9", r.P90},
+				{"1", r.Max},
+			} {
+				reg.Gauge("komari_sp_ping_latency_milliseconds", "Latency distribution of the latest SmokePing round in milliseconds.", withLabels(sl, metrics.Labels{"quantile": q.quantile}), q.value)
+			}
+		}
+		lossRatio := 0.0
+		if r.Total > 0 {
+			lossRatio = float64(r.Loss) / float64(r.Total)
+		}
+		reg.Gauge("komari_sp_ping_loss_ratio", "Packet loss ratio of the latest SmokePing round (0-1).", sl, lossRatio)
+		reg.Gauge("komari_sp_ping_sent_packets", "Packets sent in the latest SmokePing round.", sl, float64(r.Total))
+		reg.Gauge("komari_sp_ping_timestamp_seconds", "Unix time of the latest SmokePing round.", sl, float64(r.Time.ToTime().Unix()))
+	}
+	return nil
+}
@@ -125,6 +125,21 @@ func GetRecordsByUUID(c *gin.Context) {
 		}
 	}
 
+	// 按传感器附加温度历史
+	if loadType == "" || loadType == "all" || loadType == "temp" {
+		tempRecords, err := records.GetTemperatureRecordsByClientAndTime(uuid, time.Now().Add(-time.Duration(hoursInt)*time.Hour), time.Now())
+		if err == nil && len(tempRecords) > 0 {
+			sensors := make(map[string][]gin.H)
+			for _, r := range tempRecords {
+				sensors[r.Sensor] = append(sensors[r.Sensor], gin.H{"time": r.Time, "temperature": r.Temperature})
+			}
+			response["temperature_sensors"] = sensors
+			response["has_temperature_data"] = true
+		} else {
+			response["has_temperature_data"] = false
+		}
+	}
+
 	api.RespondSuccess(c, response)
 }
 
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"log"
+	"time"
+
+	"github.com/komari-monitor/komari/database/models"
+	"github.com/komari-monitor/komari/database/tasks"
+	"github.com/komari-monitor/komari/ws"
+)
+
+// SendExecTask 向节点下发命令，消息格式与 Agent 的 exec 协议一致；timeout 为 0 时不限制执行时间
+func SendExecTask(w ws.MessageWriter, taskId, command string, timeout int) error {
+	return w.WriteJSON(struct {
+		Message string `json:"message"`
+		Command string `json:"command"`
+		TaskId  string `json:"task_id"`
+		Timeout int    `json:"timeout_sec,omitempty"`
+	}{Message: "exec", Command: command, TaskId: taskId, Timeout: timeout})
+}
+
+// SendExecCancel 通知节点终止正在执行的命令
+func SendExecCancel(w ws.MessageWriter, taskId string) error {
+	return w.WriteJSON(struct {
+		Message string `json:"message"`
+		TaskId  string `json:"task_id"`
+	}{Message: "exec_cancel", TaskId: taskId})
+}
+
+// DeliverQueuedTasks 节点上线后下发排队中的命令，已过截止时间的标记为过期
+func DeliverQueuedTasks(uuid string, w ws.MessageWriter) {
+	if w == nil {
+		return
+	}
+	pending, err := tasks.GetPendingTaskResults(uuid)
+	if err != nil {
+		log.Printf("Failed to load queued tasks for %s: %v", uuid, err)
+		return
+	}
+	now := time.Now()
+	for _, r := range pending {
+		if r.Deadline != nil && r.Deadline.ToTime().Before(now) {
+			continue // 由 ExpirePendingTasks 统一处理
+		}
+		task, err := tasks.GetTaskByTaskId(r.TaskId)
+		if err != nil {
+			log.Printf("Failed to load queued task %s: %v", r.TaskId, err)
+			continue
+		}
+		ok, err := tasks.MarkTaskDelivered(r.TaskId, uuid)
+		if err != nil || !ok {
+			continue
+		}
+		if err := SendExecTask(w, task.TaskId, task.Command, task.Timeout); err != nil {
+			// 连接已断开，放回队列等待下次上线
+			_ = tasks.QueueTaskResults(r.TaskId, []string{uuid}, deadlineOrDefault(r.Deadline))
+			log.Printf("Failed to deliver queued task %s to %s: %v", r.TaskId, uuid, err)
+			return
+		}
+		log.Printf("Delivered queued task %s to %s", r.TaskId, uuid)
+	}
+}
+
+func deadlineOrDefault(deadline *models.LocalTime) time.Time {
+	if deadline == nil {
+		return time.Now().Add(24 * time.Hour)
+	}
+	return deadline.ToTime()
+}
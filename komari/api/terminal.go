@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/json"
 	"log"
 	"net/http"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/komari-monitor/komari/database/auditlog"
 	"github.com/komari-monitor/komari/database/clients"
 	"github.com/komari-monitor/komari/utils"
+	"github.com/komari-monitor/komari/utils/termrec"
 	"github.com/komari-monitor/komari/ws"
 )
 
@@ -17,7 +19,7 @@ func RequestTerminal(c *gin.Context) {
 	uuid := c.Param("uuid")
 	user_uuid, _ := c.Get("uuid")
 	_, err := clients.GetClientByUUID(uuid)
-	if err != nil {
+	if err != nil || !CanAccessClients(c, uuid) {
 		c.JSON(400, gin.H{
 			"status":  "error",
 			"message": "Client not found",
@@ -61,7 +63,8 @@ func RequestTerminal(c *gin.Context) {
 		return nil
 	})
 
-	if ws.GetConnectedClients()[uuid] == nil {
+	agentConn := ws.GetMessageWriter(uuid)
+	if agentConn == nil {
 		conn.WriteMessage(1, []byte("Client offline!\n被控端离线!"))
 		conn.Close()
 		TerminalSessionsMutex.Lock()
@@ -69,7 +72,7 @@ func RequestTerminal(c *gin.Context) {
 		TerminalSessionsMutex.Unlock()
 		return
 	}
-	err = ws.GetConnectedClients()[uuid].WriteJSON(gin.H{
+	err = agentConn.WriteJSON(gin.H{
 		"message":    "terminal",
 		"request_id": id,
 	})
@@ -103,8 +106,17 @@ func ForwardTerminal(id string) {
 	if !exists || session == nil || session.Agent == nil || session.Browser == nil {
 		return
 	}
-	auditlog.Log(session.RequesterIp, session.UserUUID, "established, terminal id:"+id, "terminal")
+	recording := termrec.Start(id, session.UUID, session.UserUUID, session.RequesterIp)
+	if recording != nil {
+		auditlog.Log(session.RequesterIp, session.UserUUID, "established, terminal id:"+id+", recording:/api/admin/terminal/recordings/"+id+"/replay", "terminal")
+	} else {
+		auditlog.Log(session.RequesterIp, session.UserUUID, "established, terminal id:"+id, "terminal")
+	}
 	established_time := time.Now()
+	session.mu.Lock()
+	session.establishedAt = established_time
+	session.recording = recording
+	session.mu.Unlock()
 	errChan := make(chan error, 1)
 
 	go func() {
@@ -115,15 +127,16 @@ func ForwardTerminal(id string) {
 				return
 			}
 
+			recordTerminalInput(recording, messageType, data)
 			if messageType == websocket.TextMessage {
 				if session.Agent != nil && string(data[0:1]) == "{" {
-					err = session.Agent.WriteMessage(websocket.TextMessage, data)
+					err = session.writeAgent(websocket.TextMessage, data)
 				} else if session.Agent != nil {
-					err = session.Agent.WriteMessage(websocket.BinaryMessage, data)
+					err = session.writeAgent(websocket.BinaryMessage, data)
 				}
 			} else if session.Agent != nil {
 				// 二进制消息，原样传递
-				err = session.Agent.WriteMessage(websocket.BinaryMessage, data)
+				err = session.writeAgent(websocket.BinaryMessage, data)
 			}
 
 			if err != nil {
@@ -140,6 +153,8 @@ func ForwardTerminal(id string) {
 				errChan <- err
 				return
 			}
+			recording.Output(data)
+			session.broadcast(data)
 			if session.Browser != nil {
 				err = session.Browser.WriteMessage(websocket.BinaryMessage, data)
 				if err != nil {
@@ -159,9 +174,37 @@ func ForwardTerminal(id string) {
 	if session.Browser != nil {
 		session.Browser.Close()
 	}
+	recording.Finish()
 	disconnect_time := time.Now()
 	auditlog.Log(session.RequesterIp, session.UserUUID, "disconnected, terminal id:"+id+", duration:"+disconnect_time.Sub(established_time).String(), "terminal")
 	TerminalSessionsMutex.Lock()
 	delete(TerminalSessions, id)
 	TerminalSessionsMutex.Unlock()
+	session.closeViewers()
+}
+
+// recordTerminalInput 解析浏览器消息并写入录制，格式与 Agent 端一致：
+// JSON 文本为 resize/input 指令，其余内容为原始输入
+func recordTerminalInput(recording *termrec.Session, messageType int, data []byte) {
+	if recording == nil || len(data) == 0 {
+		return
+	}
+	if messageType == websocket.TextMessage && data[0] == '{' {
+		var cmd struct {
+			Type  string `json:"type"`
+			Cols  int    `json:"cols"`
+			Rows  int    `json:"rows"`
+			Input string `json:"input"`
+		}
+		if err := json.Unmarshal(data, &cmd); err == nil {
+			switch cmd.Type {
+			case "resize":
+				recording.Resize(cmd.Cols, cmd.Rows)
+			case "input":
+				recording.Input([]byte(cmd.Input))
+			}
+			return
+		}
+	}
+	recording.Input(data)
 }
@@ -0,0 +1,277 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/komari-monitor/komari/database/accounts"
+	"github.com/komari-monitor/komari/database/auditlog"
+	"github.com/komari-monitor/komari/utils"
+	"github.com/komari-monitor/komari/ws"
+)
+
+const (
+	// 为后加入的观察者保留的最近输出
+	terminalHistorySize = 64 * 1024
+	// 观察者发送队列长度，跟不上输出时断开该观察者，避免拖慢会话
+	terminalViewerQueue = 256
+)
+
+// TerminalViewer 附加到共享终端的浏览器
+type TerminalViewer struct {
+	ID          string    `json:"id"`
+	UserUUID    string    `json:"user_uuid"`
+	Username    string    `json:"username"`
+	RequesterIp string    `json:"requester_ip"`
+	CanInput    bool      `json:"can_input"`
+	JoinedAt    time.Time `json:"joined_at"`
+}
+
+type terminalViewer struct {
+	TerminalViewer
+	conn      *websocket.Conn
+	send      chan []byte
+	closeOnce sync.Once
+}
+
+func (v *terminalViewer) close() {
+	v.closeOnce.Do(func() { close(v.send) })
+}
+
+func (v *terminalViewer) writeLoop() {
+	defer v.conn.Close()
+	for data := range v.send {
+		v.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		if err := v.conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+			// 继续消费直到队列关闭，避免广播阻塞
+			for range v.send {
+			}
+			return
+		}
+	}
+}
+
+// writeAgent 串行写入 Agent 连接
+func (s *TerminalSession) writeAgent(messageType int, data []byte) error {
+	s.agentMu.Lock()
+	defer s.agentMu.Unlock()
+	if s.Agent == nil {
+		return nil
+	}
+	return s.Agent.WriteMessage(messageType, data)
+}
+
+// broadcast 将 Agent 输出分发给所有观察者并保存到历史
+func (s *TerminalSession) broadcast(data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history = append(s.history, data...)
+	if over := len(s.history) - terminalHistorySize; over > 0 {
+		s.history = append(s.history[:0], s.history[over:]...)
+	}
+	for id, v := range s.viewers {
+		select {
+		case v.send <- data:
+		default:
+			delete(s.viewers, id)
+			v.close()
+		}
+	}
+}
+
+func (s *TerminalSession) removeViewer(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.viewers[id]
+	if ok {
+		delete(s.viewers, id)
+		v.close()
+	}
+	return ok
+}
+
+// closeViewers 会话结束时断开所有观察者
+func (s *TerminalSession) closeViewers() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, v := range s.viewers {
+		select {
+		case v.send <- []byte("\r\n[session closed]\r\n"):
+		default:
+		}
+		delete(s.viewers, id)
+		v.close()
+	}
+}
+
+// TerminalSessionInfo 终端会话及其参与者
+type TerminalSessionInfo struct {
+	ID            string           `json:"id"`
+	Client        string           `json:"client"`
+	UserUUID      string           `json:"user_uuid"`
+	Username      string           `json:"username"`
+	RequesterIp   string           `json:"requester_ip"`
+	Established   bool             `json:"established"`
+	EstablishedAt *time.Time       `json:"established_at"`
+	Recording     bool             `json:"recording"`
+	Viewers       []TerminalViewer `json:"viewers"`
+}
+
+// ListTerminalSessions 列出当前可访问节点上的终端会话及已附加的观察者
+// 查询参数：client 仅列出指定节点
+func ListTerminalSessions(c *gin.Context) {
+	client := c.Query("client")
+	TerminalSessionsMutex.Lock()
+	sessions := make(map[string]*TerminalSession, len(TerminalSessions))
+	for id, s := range TerminalSessions {
+		if s != nil && (client == "" || s.UUID == client) {
+			sessions[id] = s
+		}
+	}
+	TerminalSessionsMutex.Unlock()
+
+	names := map[string]string{}
+	username := func(uuid string) string {
+		if name, ok := names[uuid]; ok {
+			return name
+		}
+		user, _ := accounts.GetUserByUUID(uuid)
+		names[uuid] = user.Username
+		return user.Username
+	}
+
+	list := []TerminalSessionInfo{}
+	for id, s := range sessions {
+		if !CanAccessClients(c, s.UUID) {
+			continue
+		}
+		info := TerminalSessionInfo{
+			ID:          id,
+			Client:      s.UUID,
+			UserUUID:    s.UserUUID,
+			RequesterIp: s.RequesterIp,
+			Viewers:     []TerminalViewer{},
+		}
+		s.mu.Lock()
+		if !s.establishedAt.IsZero() {
+			at := s.establishedAt
+			info.Established, info.EstablishedAt = true, &at
+		}
+		info.Recording = s.recording != nil
+		for _, v := range s.viewers {
+			info.Viewers = append(info.Viewers, v.TerminalViewer)
+		}
+		s.mu.Unlock()
+		info.Username = username(info.UserUUID)
+		for i := range info.Viewers {
+			info.Viewers[i].Username = username(info.Viewers[i].UserUUID)
+		}
+		sort.Slice(info.Viewers, func(i, j int) bool { return info.Viewers[i].JoinedAt.Before(info.Viewers[j].JoinedAt) })
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	RespondSuccess(c, list)
+}
+
+// AttachTerminal 以观察者身份附加到已建立的终端会话（WebSocket）
+// 查询参数：mode=read（默认，只读）| write（可输入，不会改变终端尺寸）
+func AttachTerminal(c *gin.Context) {
+	id := c.Param("id")
+	mode := c.DefaultQuery("mode", "read")
+	if mode != "read" && mode != "write" {
+		RespondError(c, http.StatusBadRequest, "Invalid mode, expected read or write")
+		return
+	}
+	TerminalSessionsMutex.Lock()
+	session, exists := TerminalSessions[id]
+	TerminalSessionsMutex.Unlock()
+	if !exists || session == nil || !CanAccessClients(c, session.UUID) {
+		RespondError(c, http.StatusNotFound, "Terminal session not found")
+		return
+	}
+	session.mu.Lock()
+	established := !session.establishedAt.IsZero()
+	session.mu.Unlock()
+	if !established {
+		RespondError(c, http.StatusConflict, "Terminal session is not established yet")
+		return
+	}
+	if !websocket.IsWebSocketUpgrade(c.Request) {
+		RespondError(c, http.StatusBadRequest, "Require WebSocket upgrade")
+		return
+	}
+	upgrader := websocket.Upgrader{
+		CheckOrigin: ws.CheckOrigin,
+	}
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+
+	viewer := &terminalViewer{
+		TerminalViewer: TerminalViewer{
+			ID:          utils.GenerateRandomString(16),
+			UserUUID:    c.GetString("uuid"),
+			RequesterIp: c.ClientIP(),
+			CanInput:    mode == "write",
+			JoinedAt:    time.Now(),
+		},
+		conn: conn,
+		send: make(chan []byte, terminalViewerQueue),
+	}
+	session.mu.Lock()
+	if session.viewers == nil {
+		session.viewers = map[string]*terminalViewer{}
+	}
+	if len(session.history) > 0 {
+		viewer.send <- append([]byte(nil), session.history...)
+	}
+	session.viewers[viewer.ID] = viewer
+	session.mu.Unlock()
+	go viewer.writeLoop()
+
+	// 会话可能在升级期间结束，此时 closeViewers 已执行过
+	TerminalSessionsMutex.Lock()
+	_, alive := TerminalSessions[id]
+	TerminalSessionsMutex.Unlock()
+	if !alive {
+		session.removeViewer(viewer.ID)
+		return
+	}
+
+	auditlog.Log(viewer.RequesterIp, viewer.UserUUID, fmt.Sprintf("attached (%s), terminal id:%s, viewer:%s", mode, id, viewer.ID), "terminal")
+	joined := time.Now()
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		if !viewer.CanInput || len(data) == 0 {
+			continue
+		}
+		if messageType == websocket.TextMessage && data[0] == '{' {
+			var cmd struct {
+				Type string `json:"type"`
+			}
+			// 终端尺寸以发起者为准
+			if json.Unmarshal(data, &cmd) == nil && cmd.Type != "input" {
+				continue
+			}
+			err = session.writeAgent(websocket.TextMessage, data)
+		} else {
+			err = session.writeAgent(websocket.BinaryMessage, data)
+		}
+		recordTerminalInput(session.recording, messageType, data)
+		if err != nil {
+			break
+		}
+	}
+	session.removeViewer(viewer.ID)
+	auditlog.Log(viewer.RequesterIp, viewer.UserUUID, fmt.Sprintf("detached, terminal id:%s, viewer:%s, duration:%s", id, viewer.ID, time.Since(joined).String()), "terminal")
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTerminalBroadcast(t *testing.T) {
+	fast := &terminalViewer{send: make(chan []byte, terminalViewerQueue)}
+	slow := &terminalViewer{send: make(chan []byte, 1)}
+	session := &TerminalSession{viewers: map[string]*terminalViewer{"fast": fast, "slow": slow}}
+
+	session.broadcast([]byte("a"))
+	session.broadcast([]byte("b"))
+	// 队列已满的观察者被移除
+	assert.Contains(t, session.viewers, "fast")
+	assert.NotContains(t, session.viewers, "slow")
+	assert.Len(t, fast.send, 2)
+
+	session.broadcast(bytes.Repeat([]byte("x"), terminalHistorySize))
+	assert.Len(t, session.history, terminalHistorySize)
+	assert.Equal(t, byte('x'), session.history[0])
+
+	session.closeViewers()
+	assert.Empty(t, session.viewers)
+	var received [][]byte
+	for data := range fast.send {
+		received = append(received, data)
+	}
+	assert.Len(t, received, 4, "queued output and close notice are delivered before the channel closes")
+}
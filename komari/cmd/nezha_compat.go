@@ -11,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/komari-monitor/komari/api"
 	apiClient "github.com/komari-monitor/komari/api/client"
 	"github.com/komari-monitor/komari/common"
 	"github.com/komari-monitor/komari/database/auditlog"
@@ -213,23 +214,31 @@ func (s *nezhaCompatServer) ReportSystemState(stream proto.NezhaService_ReportSy
 	}
 }
 
-// RequestTask: do not dispatch tasks, just drain results to avoid Unimplemented
+// RequestTask: 下发 exec/ping/终端任务，并将 TaskResult 写回对应记录
 func (s *nezhaCompatServer) RequestTask(stream proto.NezhaService_RequestTaskServer) error {
 	ctx := stream.Context()
-	uuid, _, err := getAuth(ctx)
+	uuid, secret, err := getAuth(ctx)
 	if err != nil {
 		return err
 	}
+	if err := verifyNezhaClient(uuid, secret); err != nil {
+		return err
+	}
 	ticker := time.NewTicker(10 * time.Second)
 	defer ticker.Stop()
 	connID := time.Now().UnixNano()
 	ws.SetPresence(uuid, connID, true)
 	defer ws.SetPresence(uuid, connID, false)
+	// 注册下行通道，供 Exec / Ping 调度 / 终端请求使用
+	writer := newNezhaTaskWriter(uuid, stream)
+	ws.SetCompatWriter(uuid, writer)
+	defer ws.DeleteCompatWriterConditionally(uuid, writer)
+	go api.DeliverQueuedTasks(uuid, writer)
 	// receive results in background
 	recvErr := make(chan error, 1)
 	go func() {
 		for {
-			_, rerr := stream.Recv()
+			result, rerr := stream.Recv()
 			if rerr == io.EOF {
 				recvErr <- nil
 				return
@@ -240,6 +249,7 @@ func (s *nezhaCompatServer) RequestTask(stream proto.NezhaService_RequestTaskSer
 			}
 			// refresh presence TTL when result received
 			ws.KeepAlivePresence(uuid, connID, 30*time.Second)
+			writer.handleResult(result)
 		}
 	}()
 	// send heartbeat tasks periodically
@@ -250,14 +260,51 @@ func (s *nezhaCompatServer) RequestTask(stream proto.NezhaService_RequestTaskSer
 		case err := <-recvErr:
 			return err
 		case <-ticker.C:
-			if err := stream.Send(&proto.Task{}); err != nil {
+			if err := writer.send(&proto.Task{}); err != nil {
 				return err
 			}
 		}
 	}
 }
 
-// Unimplemented methods intentionally left as default (IOStream, ReportGeoIP)
+// IOStream: 终端数据流，首帧为 0xff05ff05 + StreamID（即 Komari 终端会话 ID）
+func (s *nezhaCompatServer) IOStream(stream proto.NezhaService_IOStreamServer) error {
+	ctx := stream.Context()
+	uuid, secret, err := getAuth(ctx)
+	if err != nil {
+		return err
+	}
+	if err := verifyNezhaClient(uuid, secret); err != nil {
+		return err
+	}
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	data := first.GetData()
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0x05 || data[2] != 0xff || data[3] != 0x05 {
+		return errors.New("invalid stream header")
+	}
+	streamID := string(data[4:])
+
+	api.TerminalSessionsMutex.Lock()
+	session, exists := api.TerminalSessions[streamID]
+	if !exists || session == nil || session.Browser == nil || session.Agent != nil || session.UUID != uuid {
+		api.TerminalSessionsMutex.Unlock()
+		return errors.New("terminal session not found")
+	}
+	conn := newNezhaTerminalConn(stream)
+	session.Agent = conn
+	api.TerminalSessionsMutex.Unlock()
+
+	go api.ForwardTerminal(streamID)
+	// 返回即结束流，需等待会话关闭
+	select {
+	case <-conn.closed:
+	case <-ctx.Done():
+	}
+	return nil
+}
 
 // upsertClientFromHost maps Host into models.Client and upserts by UUID
 func upsertClientFromHost(uuid, secret string, h *proto.Host) error {
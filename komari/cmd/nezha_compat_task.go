@@ -0,0 +1,313 @@
+package cmd
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/komari-monitor/komari/compat/nezha/proto"
+	"github.com/komari-monitor/komari/database/dbcore"
+	"github.com/komari-monitor/komari/database/models"
+	"github.com/komari-monitor/komari/database/tasks"
+)
+
+// Nezha Agent 任务类型，取值与 nezha agent 保持一致
+const (
+	nezhaTaskTypeHTTPGet      = 1
+	nezhaTaskTypeICMPPing     = 2
+	nezhaTaskTypeTCPPing      = 3
+	nezhaTaskTypeCommand      = 4
+	nezhaTaskTypeTerminalGRPC = 8
+)
+
+// nezhaPendingTTL 未回报结果的任务映射保留时长
+const nezhaPendingTTL = 10 * time.Minute
+
+type nezhaPendingTask struct {
+	kind    string // exec / ping / sp_ping
+	taskID  string // exec 任务 ID
+	probeID uint   // ping / sp_ping 任务 ID
+	created time.Time
+}
+
+// nezhaTaskWriter 将 Komari 下行消息翻译为 Nezha Task，并把 TaskResult 写回对应记录
+type nezhaTaskWriter struct {
+	uuid    string
+	stream  proto.NezhaService_RequestTaskServer
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]nezhaPendingTask
+}
+
+func newNezhaTaskWriter(uuid string, stream proto.NezhaService_RequestTaskServer) *nezhaTaskWriter {
+	return &nezhaTaskWriter{
+		uuid:    uuid,
+		stream:  stream,
+		pending: make(map[uint64]nezhaPendingTask),
+	}
+}
+
+func (w *nezhaTaskWriter) send(t *proto.Task) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stream.Send(t)
+}
+
+// dispatch 分配任务 ID 并记录映射，结果回来时据此找到 Komari 侧的任务
+func (w *nezhaTaskWriter) dispatch(p nezhaPendingTask, taskType uint64, data string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := time.Now()
+	for id, old := range w.pending {
+		if now.Sub(old.created) > nezhaPendingTTL {
+			delete(w.pending, id)
+		}
+	}
+	w.nextID++
+	id := w.nextID
+	p.created = now
+	w.pending[id] = p
+	if err := w.stream.Send(&proto.Task{Id: id, Type: taskType, Data: data}); err != nil {
+		delete(w.pending, id)
+		return err
+	}
+	return nil
+}
+
+// WriteJSON 实现 ws.MessageWriter，消息格式与 Agent WebSocket 下行消息一致
+func (w *nezhaTaskWriter) WriteJSON(v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var msg struct {
+		Message      string `json:"message"`
+		Command      string `json:"command"`
+		TaskID       string `json:"task_id"`
+		PingTaskID   uint   `json:"ping_task_id"`
+		PingType     string `json:"ping_type"`
+		PingTarget   string `json:"ping_target"`
+		SPPingTaskID uint   `json:"sp_ping_task_id"`
+		SPPingType   string `json:"sp_ping_type"`
+		SPPingTarget string `json:"sp_ping_target"`
+		RequestID    string `json:"request_id"`
+		TimeoutSec   int    `json:"timeout_sec"`
+	}
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return err
+	}
+	switch msg.Message {
+	case "exec":
+		if err := w.dispatch(nezhaPendingTask{kind: "exec", taskID: msg.TaskID}, nezhaTaskTypeCommand, msg.Command); err != nil {
+			return err
+		}
+		if msg.TimeoutSec > 0 {
+			taskID, timeout := msg.TaskID, msg.TimeoutSec
+			time.AfterFunc(time.Duration(timeout)*time.Second, func() {
+				w.abandonExec(taskID, models.TaskStatusTimeout, fmt.Sprintf("[Task timed out after %ds; the nezha agent cannot terminate the command]", timeout))
+			})
+		}
+		return nil
+	case "exec_cancel":
+		if !w.abandonExec(msg.TaskID, models.TaskStatusCancelled, "[Task cancelled; the nezha agent cannot terminate the command]") {
+			return fmt.Errorf("task %s is not running", msg.TaskID)
+		}
+		return nil
+	case "ping":
+		taskType, err := nezhaProbeTaskType(msg.PingType)
+		if err != nil {
+			return err
+		}
+		return w.dispatch(nezhaPendingTask{kind: "ping", probeID: msg.PingTaskID}, taskType, msg.PingTarget)
+	case "sp_ping":
+		// Nezha Agent 每个任务只回报一个延迟值，按单样本记录
+		taskType, err := nezhaProbeTaskType(msg.SPPingType)
+		if err != nil {
+			return err
+		}
+		return w.dispatch(nezhaPendingTask{kind: "sp_ping", probeID: msg.SPPingTaskID}, taskType, msg.SPPingTarget)
+	case "terminal":
+		data, _ := json.Marshal(map[string]string{"StreamID": msg.RequestID})
+		return w.send(&proto.Task{Type: nezhaTaskTypeTerminalGRPC, Data: string(data)})
+	default:
+		return fmt.Errorf("message %q is not supported by nezha agent", msg.Message)
+	}
+}
+
+// abandonExec Nezha Agent 不支持终止命令，只能停止等待其结果：记录为取消或超时，之后回报的结果将被忽略。
+// 返回 false 表示任务已结束
+func (w *nezhaTaskWriter) abandonExec(taskID, status, note string) bool {
+	w.mu.Lock()
+	found := false
+	for id, p := range w.pending {
+		if p.kind == "exec" && p.taskID == taskID {
+			delete(w.pending, id)
+			found = true
+		}
+	}
+	w.mu.Unlock()
+	if !found {
+		return false
+	}
+	_ = tasks.SaveTaskResultWithStatus(taskID, w.uuid, note, -1, models.FromTime(time.Now()), status)
+	return true
+}
+
+func nezhaProbeTaskType(probeType string) (uint64, error) {
+	switch probeType {
+	case "icmp":
+		return nezhaTaskTypeICMPPing, nil
+	case "tcp":
+		return nezhaTaskTypeTCPPing, nil
+	case "http":
+		return nezhaTaskTypeHTTPGet, nil
+	}
+	return 0, fmt.Errorf("ping type %q is not supported by nezha agent", probeType)
+}
+
+// handleResult 将 TaskResult 写回任务结果、Ping 记录或 SP Ping 记录
+func (w *nezhaTaskWriter) handleResult(r *proto.TaskResult) {
+	if r == nil || r.GetId() == 0 {
+		return
+	}
+	w.mu.Lock()
+	p, ok := w.pending[r.GetId()]
+	delete(w.pending, r.GetId())
+	w.mu.Unlock()
+	if !ok {
+		return
+	}
+	now := models.FromTime(time.Now())
+	switch p.kind {
+	case "exec":
+		exitCode := 0
+		if !r.GetSuccessful() {
+			exitCode = 1
+		}
+		_ = tasks.SaveTaskResult(p.taskID, w.uuid, r.GetData(), exitCode, now)
+	case "ping":
+		value := -1
+		if r.GetSuccessful() {
+			value = int(math.Round(float64(r.GetDelay())))
+		}
+		_ = tasks.SavePingRecord(models.PingRecord{Client: w.uuid, TaskId: p.probeID, Value: value, Time: now})
+	case "sp_ping":
+		sample, loss := -1.0, 1
+		if r.GetSuccessful() {
+			sample, loss = float64(r.GetDelay()), 0
+		}
+		samples, _ := json.Marshal([]float64{sample})
+		rec := models.SPPingRecord{
+			Client:  w.uuid,
+			TaskId:  p.probeID,
+			Time:    now,
+			Pings:   1,
+			Loss:    loss,
+			Total:   1,
+			Samples: samples,
+		}
+		if meta, _ := tasks.GetSPPingTaskByID(p.probeID); meta != nil {
+			rec.Step = meta.Step
+		}
+		_ = tasks.SaveSPPingRecord(&rec)
+	}
+}
+
+// verifyNezhaClient 校验 Agent 凭据。ReportSystemState 自动创建的节点没有令牌，
+// 任何人都能以其 UUID 上报，需管理员分配令牌后才能接收任务与终端
+func verifyNezhaClient(uuid, secret string) error {
+	var client models.Client
+	if err := dbcore.GetDBInstance().Where("uuid = ?", uuid).First(&client).Error; err != nil {
+		return errors.New("unauthorized: unknown client")
+	}
+	if client.Token == "" {
+		return errors.New("unauthorized: client has no token assigned")
+	}
+	if subtle.ConstantTimeCompare([]byte(client.Token), []byte(secret)) != 1 {
+		return errors.New("unauthorized: token mismatch")
+	}
+	return nil
+}
+
+// nezhaTerminalConn 将 Nezha IOStream 适配为 api.TerminalConn
+//
+// 下行帧首字节：0 为终端输入，1 为窗口大小（JSON）
+type nezhaTerminalConn struct {
+	stream    proto.NezhaService_IOStreamServer
+	sendMu    sync.Mutex
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newNezhaTerminalConn(stream proto.NezhaService_IOStreamServer) *nezhaTerminalConn {
+	return &nezhaTerminalConn{stream: stream, closed: make(chan struct{})}
+}
+
+func (c *nezhaTerminalConn) ReadMessage() (int, []byte, error) {
+	for {
+		data, err := c.stream.Recv()
+		if err != nil {
+			return 0, nil, err
+		}
+		// 空帧为 Agent 保活
+		if len(data.GetData()) == 0 {
+			continue
+		}
+		return websocket.BinaryMessage, data.GetData(), nil
+	}
+}
+
+// WriteMessage 接收浏览器消息，文本 JSON 按 Komari 终端协议解析 resize/input
+func (c *nezhaTerminalConn) WriteMessage(messageType int, data []byte) error {
+	if messageType == websocket.TextMessage && len(data) > 0 && data[0] == '{' {
+		var cmd struct {
+			Type  string `json:"type"`
+			Cols  int    `json:"cols,omitempty"`
+			Rows  int    `json:"rows,omitempty"`
+			Input string `json:"input,omitempty"`
+		}
+		if err := json.Unmarshal(data, &cmd); err == nil {
+			switch cmd.Type {
+			case "resize":
+				if cmd.Cols <= 0 || cmd.Rows <= 0 {
+					return nil
+				}
+				size, _ := json.Marshal(struct {
+					Cols uint32
+					Rows uint32
+				}{uint32(cmd.Cols), uint32(cmd.Rows)})
+				return c.send(append([]byte{1}, size...))
+			case "input":
+				if cmd.Input == "" {
+					return nil
+				}
+				return c.send(append([]byte{0}, cmd.Input...))
+			}
+			return nil
+		}
+	}
+	return c.send(append([]byte{0}, data...))
+}
+
+func (c *nezhaTerminalConn) send(frame []byte) error {
+	select {
+	case <-c.closed:
+		return io.ErrClosedPipe
+	default:
+	}
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	return c.stream.Send(&proto.IOStreamData{Data: frame})
+}
+
+// Close 结束 IOStream 调用，Agent 侧随之关闭终端
+func (c *nezhaTerminalConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
@@ -40,12 +40,17 @@ import (
 	"github.com/komari-monitor/komari/database/tasks"
 	"github.com/komari-monitor/komari/public"
 	"github.com/komari-monitor/komari/utils"
+	"github.com/komari-monitor/komari/utils/backup"
 	"github.com/komari-monitor/komari/utils/cloudflared"
 	"github.com/komari-monitor/komari/utils/geoip"
 	logutil "github.com/komari-monitor/komari/utils/log"
 	"github.com/komari-monitor/komari/utils/messageSender"
 	"github.com/komari-monitor/komari/utils/notifier"
 	"github.com/komari-monitor/komari/utils/oauth"
+	"github.com/komari-monitor/komari/utils/recordsink"
+	"github.com/komari-monitor/komari/utils/sshjob"
+	"github.com/komari-monitor/komari/utils/termrec"
+	"github.com/komari-monitor/komari/utils/tunnel"
 	"github.com/spf13/cobra"
 )
 
@@ -85,6 +90,7 @@ func RunServer() {
 	if err := installscripts.EnsureDefaults(); err != nil {
 		log.Fatalf("Failed to init install scripts: %v", err)
 	}
+	sshjob.MarkInterrupted()
 	lg.EnsureAuthorizationIndexes()
 	if err := security.EnsureSecurityConfig(); err != nil {
 		log.Fatalf("Failed to init security config: %v", err)
@@ -102,6 +108,8 @@ func RunServer() {
 	go geoip.InitGeoIp()
 	go DoScheduledWork()
 	go messageSender.Initialize()
+	// 事件触发脚本
+	messageSender.OnEvent(scriptsched.HandleEvent)
 	// oidcInit
 	go oauth.Initialize()
 
@@ -114,7 +122,24 @@ func RunServer() {
 		}()
 	}
 
+	if err := recordsink.Reload(conf); err != nil {
+		log.Printf("Failed to initialize record sink: %v", err)
+	}
+	if err := backup.Reload(conf); err != nil {
+		log.Printf("Failed to initialize scheduled backup: %v", err)
+	}
+
 	config.Subscribe(func(event config.ConfigEvent) {
+		if event.New.RecordSinkType != event.Old.RecordSinkType || event.New.RecordSinkURL != event.Old.RecordSinkURL || event.New.RecordSinkToken != event.Old.RecordSinkToken {
+			if err := recordsink.Reload(event.New); err != nil {
+				auditlog.EventLog("error", fmt.Sprintf("Failed to reload record sink: %v", err))
+			}
+		}
+		if backup.ConfigChanged(event.Old, event.New) {
+			if err := backup.Reload(event.New); err != nil {
+				auditlog.EventLog("error", fmt.Sprintf("Failed to reload scheduled backup: %v", err))
+			}
+		}
 		if event.New.OAuthProvider != event.Old.OAuthProvider {
 			oidcProvider, err := database.GetOidcConfigByName(event.New.OAuthProvider)
 			if err != nil {
@@ -208,6 +233,7 @@ func RunServer() {
 	r.GET("/api/logout", api.Logout)
 	r.GET("/api/version", api.GetVersion)
 	r.GET("/api/recent/:uuid", api.GetClientRecentRecords)
+	r.GET("/api/metrics", api.GetMetrics)
 	// looking-glass
 	r.GET("/api/lg/public-nodes", api.GetPublicLgNodes)
 	r.POST("/api/lg/verify-code", api.VerifyLgCode)
@@ -235,6 +261,8 @@ func RunServer() {
 		tokenAuthrized.POST("/uploadBasicInfo", client.UploadBasicInfo)
 		tokenAuthrized.POST("/report", client.UploadReport)
 		tokenAuthrized.GET("/terminal", client.EstablishConnection)
+		tokenAuthrized.GET("/file", client.EstablishFileConnection)
+		tokenAuthrized.GET("/tunnel", client.EstablishTunnelConnection)
 		tokenAuthrized.GET("/lg", client.ClientLgWS)
 		tokenAuthrized.POST("/task/result", client.TaskResult)
 		tokenAuthrized.GET("/update", client.GetAgentUpdate)
@@ -247,10 +275,27 @@ func RunServer() {
 	// #region 管理员
 	adminAuthrized := r.Group("/api/admin", api.AdminAuthMiddleware())
 	{
-		adminAuthrized.GET("/download/backup", admin.DownloadBackup)
-		adminAuthrized.POST("/upload/backup", admin.UploadBackup)
+		// 角色：readonly 只读，operator 可执行节点操作，admin 可修改站点设置与账户
+		// API 令牌按权限范围放行：RequireScope 需放在角色中间件之前
+		adminOnly := api.RequireRole(models.RoleAdmin)
+		operatorWrite := api.RequireRoleForWrite(models.RoleOperator)
+		anyUser := api.RequireRole(models.RoleReadOnly)
+
+		adminAuthrized.GET("/download/backup", adminOnly, admin.DownloadBackup)
+		adminAuthrized.POST("/upload/backup", adminOnly, admin.UploadBackup)
+		backupGroup := adminAuthrized.Group("/backup/scheduled", adminOnly)
+		{
+			backupGroup.GET("", admin.ListScheduledBackups)
+			backupGroup.POST("/run", admin.RunScheduledBackup)
+			backupGroup.POST("/restore", admin.RestoreScheduledBackup)
+		}
+		configCodeGroup := adminAuthrized.Group("/config-as-code", adminOnly)
+		{
+			configCodeGroup.GET("/export", admin.ExportConfigCode)
+			configCodeGroup.POST("/apply", admin.ApplyConfigCode)
+		}
 		// test
-		testGroup := adminAuthrized.Group("/test")
+		testGroup := adminAuthrized.Group("/test", adminOnly)
 		{
 			testGroup.GET("/geoip", test.TestGeoIp)
 			testGroup.POST("/sendMessage", test.TestSendMessage)
@@ -258,23 +303,24 @@ func RunServer() {
 		// update
 		updateGroup := adminAuthrized.Group("/update")
 		{
-			updateGroup.POST("/mmdb", update.UpdateMmdbGeoIP)
-			updateGroup.POST("/user", update.UpdateUser)
-			updateGroup.PUT("/favicon", update.UploadFavicon)
-			updateGroup.POST("/favicon", update.DeleteFavicon)
+			updateGroup.POST("/mmdb", adminOnly, update.UpdateMmdbGeoIP)
+			updateGroup.POST("/user", anyUser, update.UpdateUser)
+			updateGroup.PUT("/favicon", adminOnly, update.UploadFavicon)
+			updateGroup.POST("/favicon", adminOnly, update.DeleteFavicon)
 		}
 		// tasks
-		taskGroup := adminAuthrized.Group("/task")
+		taskGroup := adminAuthrized.Group("/task", api.RequireScope(models.TokenScopeRead, models.TokenScopeExec), operatorWrite)
 		{
 			taskGroup.GET("/all", admin.GetTasks)
 			taskGroup.POST("/exec", admin.Exec)
 			taskGroup.GET("/:task_id", admin.GetTaskById)
 			taskGroup.GET("/:task_id/result", admin.GetTaskResultsByTaskId)
 			taskGroup.GET("/:task_id/result/:uuid", admin.GetSpecificTaskResult)
+			taskGroup.POST("/:task_id/cancel", admin.CancelTask)
 			taskGroup.GET("/client/:uuid", admin.GetTasksByClientId)
 		}
 		// settings
-		settingsGroup := adminAuthrized.Group("/settings")
+		settingsGroup := adminAuthrized.Group("/settings", adminOnly)
 		{
 			settingsGroup.GET("/", admin.GetSettings)
 			settingsGroup.POST("/", admin.EditSettings)
@@ -282,14 +328,21 @@ func RunServer() {
 			settingsGroup.GET("/oidc", admin.GetOidcProvider)
 			settingsGroup.POST("/message-sender", admin.SetMessageSenderProvider)
 			settingsGroup.GET("/message-sender", admin.GetMessageSenderProvider)
-		}
-		securityGroup := adminAuthrized.Group("/security")
+			settingsGroup.GET("/message-channel", admin.ListMessageChannels)
+			settingsGroup.POST("/message-channel", admin.SaveMessageChannel)
+			settingsGroup.POST("/message-channel/delete", admin.DeleteMessageChannels)
+			settingsGroup.POST("/message-channel/test", admin.TestMessageChannel)
+			settingsGroup.GET("/message-route", admin.ListMessageRoutes)
+			settingsGroup.POST("/message-route", admin.SaveMessageRoute)
+			settingsGroup.POST("/message-route/delete", admin.DeleteMessageRoutes)
+		}
+		securityGroup := adminAuthrized.Group("/security", adminOnly)
 		{
 			securityGroup.GET("", admin.GetSecurityConfig)
 			securityGroup.POST("", admin.UpdateSecurityConfig)
 		}
 		// themes
-		themeGroup := adminAuthrized.Group("/theme")
+		themeGroup := adminAuthrized.Group("/theme", adminOnly)
 		{
 			themeGroup.PUT("/upload", admin.UploadTheme)
 			themeGroup.GET("/list", admin.ListThemes)
@@ -298,7 +351,7 @@ func RunServer() {
 			themeGroup.POST("/update", admin.UpdateTheme)
 			themeGroup.POST("/settings", admin.UpdateThemeSettings)
 		}
-		agentVersionGroup := adminAuthrized.Group("/agent-version")
+		agentVersionGroup := adminAuthrized.Group("/agent-version", adminOnly)
 		{
 			// 兼容是否携带尾部斜杠，避免 POST multipart 在 307 重定向下卡住
 			agentVersionGroup.GET("", admin.ListAgentVersions)
@@ -311,12 +364,12 @@ func RunServer() {
 			agentVersionGroup.DELETE("/:id/package/:package_id", admin.DeleteAgentPackage)
 			agentVersionGroup.GET("/:id/package/:package_id/download", admin.DownloadAgentPackage)
 		}
-		installScriptGroup := adminAuthrized.Group("/install-script")
+		installScriptGroup := adminAuthrized.Group("/install-script", adminOnly)
 		{
 			installScriptGroup.GET("/", admin.ListInstallScripts)
 			installScriptGroup.POST("/:name", admin.UpdateInstallScript)
 		}
-		credentialGroup := adminAuthrized.Group("/credential")
+		credentialGroup := adminAuthrized.Group("/credential", adminOnly)
 		{
 			credentialGroup.GET("/", admin.ListCredentials)
 			credentialGroup.POST("/", admin.CreateCredential)
@@ -324,55 +377,109 @@ func RunServer() {
 			credentialGroup.DELETE("/:id", admin.DeleteCredential)
 			credentialGroup.GET("/:id/reveal", admin.RevealCredentialSecret)
 		}
-		sshGroup := adminAuthrized.Group("/ssh")
+		billingGroup := adminAuthrized.Group("/billing", adminOnly)
+		{
+			billingGroup.GET("/summary", admin.GetBillingSummary)
+			billingGroup.GET("/renewals", admin.GetBillingRenewals)
+			billingGroup.GET("/ledger", admin.GetBillingLedger)
+			billingGroup.POST("/ledger", admin.AddBillingRecord)
+			billingGroup.POST("/ledger/delete", admin.DeleteBillingRecord)
+			billingGroup.GET("/export", admin.ExportBilling)
+		}
+		terminalSessionGroup := adminAuthrized.Group("/terminal/sessions", api.RequireScope(models.TokenScopeTerminal), api.RequireRole(models.RoleOperator))
+		{
+			terminalSessionGroup.GET("", api.ListTerminalSessions)
+			terminalSessionGroup.GET("/:id/attach", api.AttachTerminal)
+		}
+		tunnelGroup := adminAuthrized.Group("/tunnel", api.RequireScope(models.TokenScopeTerminal), api.RequireRole(models.RoleOperator))
+		{
+			tunnelGroup.GET("", admin.ListTunnels)
+			tunnelGroup.POST("", admin.CreateTunnel)
+			tunnelGroup.DELETE("/:id", admin.CloseTunnel)
+			tunnelGroup.Any("/:id/proxy/*path", admin.ProxyTunnel)
+		}
+		terminalRecordingGroup := adminAuthrized.Group("/terminal/recordings", adminOnly)
+		{
+			terminalRecordingGroup.GET("", admin.ListTerminalRecordings)
+			terminalRecordingGroup.GET("/:id", admin.GetTerminalRecording)
+			terminalRecordingGroup.GET("/:id/download", admin.DownloadTerminalRecording)
+			terminalRecordingGroup.GET("/:id/replay", admin.ReplayTerminalRecording)
+			terminalRecordingGroup.DELETE("/:id", admin.DeleteTerminalRecording)
+		}
+		sshGroup := adminAuthrized.Group("/ssh", adminOnly)
 		{
 			sshGroup.POST("/test", admin.TestSSHConnection)
 			sshGroup.POST("/install", admin.StartSSHInstall)
 			sshGroup.GET("/install/:id/stream", admin.StreamSSHInstall)
 			sshGroup.GET("/install/:id", admin.GetSSHInstallStatus)
+			sshGroup.GET("/jobs", admin.ListSSHJobs)
+			sshGroup.POST("/jobs", admin.StartSSHJob)
+			sshGroup.GET("/jobs/:id", admin.GetSSHJob)
+			sshGroup.GET("/jobs/:id/stream", admin.StreamSSHJob)
+			sshGroup.POST("/jobs/:id/cancel", admin.CancelSSHJob)
+			sshGroup.DELETE("/jobs/:id", admin.DeleteSSHJob)
+			sshGroup.GET("/known-hosts", admin.ListSSHKnownHosts)
+			sshGroup.DELETE("/known-hosts", admin.DeleteSSHKnownHost)
 		}
 		// clients
-		clientGroup := adminAuthrized.Group("/client")
+		clientGroup := adminAuthrized.Group("/client", api.RequireScope(models.TokenScopeRead), operatorWrite)
 		{
-			clientGroup.POST("/add", admin.AddClient)
+			clientGroup.POST("/add", adminOnly, admin.AddClient)
 			clientGroup.GET("/list", admin.ListClients)
 			clientGroup.GET("/:uuid", admin.GetClient)
 			clientGroup.POST("/:uuid/edit", admin.EditClient)
-			clientGroup.POST("/:uuid/remove", admin.RemoveClient)
-			clientGroup.GET("/:uuid/token", admin.GetClientToken)
+			clientGroup.POST("/:uuid/remove", adminOnly, admin.RemoveClient)
+			clientGroup.GET("/:uuid/token", adminOnly, admin.GetClientToken)
 			clientGroup.POST("/order", admin.OrderWeight)
 			// client terminal
-			clientGroup.GET("/:uuid/terminal", api.RequestTerminal)
+			clientGroup.GET("/:uuid/terminal", api.RequireScope(models.TokenScopeTerminal), api.RequireRole(models.RoleOperator), api.RequestTerminal)
+			// client file transfer
+			clientGroup.GET("/:uuid/files", api.RequireScope(models.TokenScopeTerminal), api.RequireRole(models.RoleOperator), api.RequestFileTransfer)
 		}
 
 		// records
-		recordGroup := adminAuthrized.Group("/record")
+		recordGroup := adminAuthrized.Group("/record", adminOnly)
 		{
 			recordGroup.POST("/clear", admin.ClearRecord)
 			recordGroup.POST("/clear/all", admin.ClearAllRecords)
 		}
 		// oauth2
-		oauth2Group := adminAuthrized.Group("/oauth2")
+		oauth2Group := adminAuthrized.Group("/oauth2", anyUser)
 		{
 			oauth2Group.GET("/bind", admin.BindingExternalAccount)
 			oauth2Group.POST("/unbind", admin.UnbindExternalAccount)
 		}
-		sessionGroup := adminAuthrized.Group("/session")
+		sessionGroup := adminAuthrized.Group("/session", adminOnly)
 		{
 			sessionGroup.GET("/get", admin.GetSessions)
 			sessionGroup.POST("/remove", admin.DeleteSession)
 			sessionGroup.POST("/remove/all", admin.DeleteAllSession)
 		}
-		two_factorGroup := adminAuthrized.Group("/2fa")
+		// 账户管理
+		userGroup := adminAuthrized.Group("/user", adminOnly)
+		{
+			userGroup.GET("", admin.ListUsers)
+			userGroup.POST("/add", admin.AddUser)
+			userGroup.POST("/:uuid/edit", admin.EditUser)
+			userGroup.POST("/:uuid/remove", admin.RemoveUser)
+		}
+		// API 令牌
+		tokenGroup := adminAuthrized.Group("/token", adminOnly)
+		{
+			tokenGroup.GET("", admin.ListApiTokens)
+			tokenGroup.POST("/add", admin.CreateApiToken)
+			tokenGroup.POST("/:id/remove", admin.RemoveApiToken)
+		}
+		two_factorGroup := adminAuthrized.Group("/2fa", anyUser)
 		{
 			two_factorGroup.GET("/generate", admin.Generate2FA)
 			two_factorGroup.POST("/enable", admin.Enable2FA)
 			two_factorGroup.POST("/disable", admin.Disable2FA)
 		}
-		adminAuthrized.GET("/logs", log_api.GetLogs)
+		adminAuthrized.GET("/logs", adminOnly, log_api.GetLogs)
 
 		// clipboard
-		clipboardGroup := adminAuthrized.Group("/clipboard")
+		clipboardGroup := adminAuthrized.Group("/clipboard", operatorWrite)
 		{
 			clipboardGroup.GET("/:id", clipboard.GetClipboard)
 			clipboardGroup.GET("", clipboard.ListClipboard)
@@ -382,7 +489,7 @@ func RunServer() {
 			clipboardGroup.POST("/:id/remove", clipboard.DeleteClipboard)
 		}
 
-		notificationGroup := adminAuthrized.Group("/notification")
+		notificationGroup := adminAuthrized.Group("/notification", api.RequireScope(models.TokenScopeRead), operatorWrite)
 		{
 			// offline notifications
 			notificationGroup.GET("/offline", notification.ListOfflineNotifications)
@@ -396,9 +503,26 @@ func RunServer() {
 				loadAlertGroup.POST("/delete", notification.DeleteLoadNotification)
 				loadAlertGroup.POST("/edit", notification.EditLoadNotification)
 			}
+			// 组合告警规则
+			ruleGroup := notificationGroup.Group("/rule")
+			{
+				ruleGroup.GET("", notification.GetAllAlertRules)
+				ruleGroup.POST("/add", notification.AddAlertRule)
+				ruleGroup.POST("/edit", notification.EditAlertRule)
+				ruleGroup.POST("/delete", notification.DeleteAlertRule)
+				ruleGroup.GET("/incidents", notification.GetAlertIncidents)
+				ruleGroup.POST("/incidents/:id/ack", notification.AckAlertIncident)
+			}
+			// 维护窗口
+			maintenanceGroup := notificationGroup.Group("/maintenance")
+			{
+				maintenanceGroup.GET("", notification.GetMaintenanceWindows)
+				maintenanceGroup.POST("", notification.SaveMaintenanceWindow)
+				maintenanceGroup.POST("/delete", notification.DeleteMaintenanceWindows)
+			}
 		}
 
-		pingTaskGroup := adminAuthrized.Group("/ping")
+		pingTaskGroup := adminAuthrized.Group("/ping", api.RequireScope(models.TokenScopeRead, models.TokenScopePing), operatorWrite)
 		{
 			pingTaskGroup.GET("/", admin.GetAllPingTasks)
 			pingTaskGroup.POST("/add", admin.AddPingTask)
@@ -408,7 +532,7 @@ func RunServer() {
 			pingTaskGroup.POST("/clear", admin.ClearPingRecords)
 
 		}
-		spPingGroup := adminAuthrized.Group("/sp-ping")
+		spPingGroup := adminAuthrized.Group("/sp-ping", api.RequireScope(models.TokenScopeRead, models.TokenScopePing), operatorWrite)
 		{
 			spPingGroup.GET("/", admin.GetAllSPPingTasks)
 			spPingGroup.POST("/add", admin.AddSPPingTask)
@@ -418,7 +542,7 @@ func RunServer() {
 			spPingGroup.POST("/clear", admin.ClearSPPingRecords)
 		}
 		// scripts
-		scriptGroup := adminAuthrized.Group("/script")
+		scriptGroup := adminAuthrized.Group("/script", operatorWrite)
 		{
 			scriptGroup.GET("/structure", admin.GetScriptStructure)
 			scriptGroup.POST("/folder/add", admin.AddScriptFolder)
@@ -438,7 +562,7 @@ func RunServer() {
 		}
 
 		// looking-glass 管理
-		lgGroup := adminAuthrized.Group("/lg")
+		lgGroup := adminAuthrized.Group("/lg", adminOnly)
 		{
 			lgGroup.GET("/authorization", admin.ListLgAuthorizations)
 			lgGroup.POST("/authorization", admin.CreateLgAuthorization)
@@ -510,6 +634,9 @@ func DoScheduledWork() {
 	tasks.ReloadPingSchedule()
 	tasks.ReloadSPPingSchedule()
 	d_notification.ReloadLoadNotificationSchedule()
+	if err := d_notification.ReloadAlertRuleSchedule(); err != nil {
+		log.Printf("Failed to load alert rules: %v", err)
+	}
 	scriptsched.ReloadScriptSchedule()
 	ticker := time.NewTicker(time.Minute * 30)
 	minute := time.NewTicker(60 * time.Second)
@@ -527,8 +654,11 @@ func DoScheduledWork() {
 			_ = tasks.AggregateSPPingRecords(cfg.SpRecordPreserveHours)
 			_ = tasks.CleanupSPPingRecords(cfg.SpRecordPreserveHours)
 			auditlog.RemoveOldLogs()
+			termrec.RemoveOldRecordings()
+			sshjob.RemoveOldJobs()
 		case <-minute.C:
 			api.SaveClientReportToDB()
+			_ = tasks.ExpirePendingTasks(time.Now())
 			if !cfg.RecordEnabled {
 				records.DeleteAll()
 				tasks.DeleteAllPingRecords()
@@ -543,6 +673,9 @@ func DoScheduledWork() {
 
 func OnShutdown() {
 	auditlog.Log("", "", "server is shutting down", "info")
+	recordsink.Close()
+	backup.Stop()
+	tunnel.CloseAll()
 	cloudflared.Kill()
 }
 
@@ -61,20 +61,24 @@ type IPAddress struct {
 	Ipv6 string `json:"ipv6"`
 }
 type Report struct {
-	UUID        string            `json:"uuid,omitempty"`
-	CPU         CPUReport         `json:"cpu"`
-	Ram         RamReport         `json:"ram"`
-	Swap        RamReport         `json:"swap"`
-	Load        LoadReport        `json:"load"`
-	Disk        DiskReport        `json:"disk"`
-	Network     NetworkReport     `json:"network"`
-	Connections ConnectionsReport `json:"connections"`
-	GPU         *GPUDetailReport  `json:"gpu,omitempty"` // 新增GPU详细信息
-	Uptime      int64             `json:"uptime"`
-	Process     int               `json:"process"`
-	Message     string            `json:"message"`
-	Method      string            `json:"method,omitempty"`
-	UpdatedAt   time.Time         `json:"updated_at"`
+	UUID        string             `json:"uuid,omitempty"`
+	CPU         CPUReport          `json:"cpu"`
+	Ram         RamReport          `json:"ram"`
+	Swap        RamReport          `json:"swap"`
+	Load        LoadReport         `json:"load"`
+	Disk        DiskReport         `json:"disk"`
+	Mounts      []MountReport      `json:"mounts,omitempty"`
+	DiskIO      []DiskIOReport     `json:"disk_io,omitempty"`
+	Network     NetworkReport      `json:"network"`
+	Interfaces  []InterfaceReport  `json:"interfaces,omitempty"`
+	Connections ConnectionsReport  `json:"connections"`
+	GPU         *GPUDetailReport   `json:"gpu,omitempty"` // 新增GPU详细信息
+	Temperature *TemperatureReport `json:"temperature,omitempty"`
+	Uptime      int64              `json:"uptime"`
+	Process     int                `json:"process"`
+	Message     string             `json:"message"`
+	Method      string             `json:"method,omitempty"`
+	UpdatedAt   time.Time          `json:"updated_at"`
 }
 
 type CPUReport struct {
@@ -100,6 +104,30 @@ type GPUDeviceInfo struct {
 	Temperature  int     `json:"temperature"`   // GPU温度(°C)
 }
 
+// TemperatureReport 硬件温度信息
+type TemperatureReport struct {
+	Package float64             `json:"package"` // CPU 封装温度(°C)，无法识别时为 0
+	Max     float64             `json:"max"`     // 所有传感器中的最高温度(°C)
+	Sensors []TemperatureSensor `json:"sensors"`
+}
+
+// TemperatureSensor 单个传感器读数
+type TemperatureSensor struct {
+	Name        string  `json:"name"`        // 如 coretemp/Package id 0、thermal_zone0
+	Temperature float64 `json:"temperature"` // 温度(°C)
+}
+
+// Primary 记录到 Record.Temp 的代表温度：优先 CPU 封装温度，否则取最高值
+func (t *TemperatureReport) Primary() float64 {
+	if t == nil {
+		return 0
+	}
+	if t.Package > 0 {
+		return t.Package
+	}
+	return t.Max
+}
+
 // 保持向后兼容的旧GPUReport结构
 type GPUReport struct {
 	Name  string  `json:"name,omitempty"`
@@ -122,6 +150,26 @@ type DiskReport struct {
 	Used  int64 `json:"used"`
 }
 
+// MountReport 单个挂载点的容量与 inode 使用情况
+type MountReport struct {
+	Mountpoint  string `json:"mountpoint"`
+	Device      string `json:"device"`
+	Fstype      string `json:"fstype"`
+	Total       int64  `json:"total"`
+	Used        int64  `json:"used"`
+	InodesTotal int64  `json:"inodes_total"`
+	InodesUsed  int64  `json:"inodes_used"`
+}
+
+// DiskIOReport 单个块设备的读写速率
+type DiskIOReport struct {
+	Device     string  `json:"device"`
+	ReadBytes  int64   `json:"read_bytes"`  // 字节/秒
+	WriteBytes int64   `json:"write_bytes"` // 字节/秒
+	ReadIOPS   float64 `json:"read_iops"`
+	WriteIOPS  float64 `json:"write_iops"`
+}
+
 type NetworkReport struct {
 	Up        int64 `json:"up"`
 	Down      int64 `json:"down"`
@@ -129,6 +177,15 @@ type NetworkReport struct {
 	TotalDown int64 `json:"totalDown"`
 }
 
+// InterfaceReport 单个网卡的速率与系统累计计数器，计数器在重启后归零
+type InterfaceReport struct {
+	Name      string `json:"name"`
+	Up        int64  `json:"up"`
+	Down      int64  `json:"down"`
+	TotalUp   int64  `json:"totalUp"`
+	TotalDown int64  `json:"totalDown"`
+}
+
 type ConnectionsReport struct {
 	TCP int `json:"tcp"`
 	UDP int `json:"udp"`
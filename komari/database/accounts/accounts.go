@@ -62,6 +62,7 @@ func CreateAccount(username, passwd string) (user models.User, err error) {
 		UUID:     uuid.New().String(),
 		Username: username,
 		Passwd:   hashedPassword,
+		Role:     models.RoleAdmin,
 	}
 	err = db.Create(&user).Error
 	if err != nil {
@@ -70,6 +71,81 @@ func CreateAccount(username, passwd string) (user models.User, err error) {
 	return user, nil
 }
 
+// CreateAccountWithRole 创建指定角色与节点范围的账户
+func CreateAccountWithRole(username, passwd, role, scopeGroups, scopeTags string) (user models.User, err error) {
+	if !models.IsValidRole(role) {
+		return models.User{}, fmt.Errorf("invalid role: %s", role)
+	}
+	db := dbcore.GetDBInstance()
+	user = models.User{
+		UUID:        uuid.New().String(),
+		Username:    username,
+		Passwd:      hashPasswd(passwd),
+		Role:        role,
+		ScopeGroups: scopeGroups,
+		ScopeTags:   scopeTags,
+	}
+	if err = db.Create(&user).Error; err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// ListUsers 返回全部账户，不含密码与 2FA 密钥
+func ListUsers() ([]models.User, error) {
+	var users []models.User
+	if err := dbcore.GetDBInstance().Order("created_at asc").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for i := range users {
+		users[i].Passwd = ""
+		users[i].TwoFactor = ""
+	}
+	return users, nil
+}
+
+// UpdateUserRole 修改账户角色与节点范围
+func UpdateUserRole(uuid, role, scopeGroups, scopeTags string) error {
+	if !models.IsValidRole(role) {
+		return fmt.Errorf("invalid role: %s", role)
+	}
+	result := dbcore.GetDBInstance().Model(&models.User{}).Where("uuid = ?", uuid).Updates(map[string]interface{}{
+		"role":         role,
+		"scope_groups": scopeGroups,
+		"scope_tags":   scopeTags,
+		"updated_at":   time.Now(),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user not found: %s", uuid)
+	}
+	return nil
+}
+
+// CountAdmins 统计管理员数量
+func CountAdmins() (int64, error) {
+	var count int64
+	err := dbcore.GetDBInstance().Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&count).Error
+	return count, err
+}
+
+// DeleteAccountByUUID 删除账户及其全部会话
+func DeleteAccountByUUID(uuid string) error {
+	if err := DeleteSessionsByUUID(uuid); err != nil {
+		return err
+	}
+	result := dbcore.GetDBInstance().Where("uuid = ?", uuid).Delete(&models.User{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user not found: %s", uuid)
+	}
+	return nil
+}
+
 func DeleteAccountByUsername(username string) (err error) {
 	db := dbcore.GetDBInstance()
 	err = db.Where("username = ?", username).Delete(&models.User{}).Error
@@ -99,6 +175,7 @@ func CreateDefaultAdminAccount() (username, passwd string, err error) {
 		UUID:      uuid.New().String(),
 		Username:  username,
 		Passwd:    hashedPassword,
+		Role:      models.RoleAdmin,
 		SSOID:     "",
 		CreatedAt: models.FromTime(time.Now()),
 		UpdatedAt: models.FromTime(time.Now()),
@@ -177,7 +254,7 @@ func UpdateUser(uuid string, name, password, sso_type *string) error {
 		return err
 	}
 	if password != nil {
-		DeleteAllSessions()
+		DeleteSessionsByUUID(uuid)
 	}
 	return nil
 }
@@ -109,6 +109,11 @@ func DeleteAllSessions() error {
 	return nil
 }
 
+// DeleteSessionsByUUID 删除指定用户的全部会话
+func DeleteSessionsByUUID(uuid string) error {
+	return dbcore.GetDBInstance().Where("uuid = ?", uuid).Delete(&models.Session{}).Error
+}
+
 func UpdateLatestOnline(session string) error {
 	db := dbcore.GetDBInstance()
 	return db.Model(&models.Session{}).Where("session = ?", session).Update("latest_online", time.Now()).Error
@@ -0,0 +1,112 @@
+package apitokens
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/komari-monitor/komari/database/dbcore"
+	"github.com/komari-monitor/komari/database/models"
+	"github.com/komari-monitor/komari/utils"
+)
+
+// TokenPrefix 令牌统一前缀，便于与旧版 ApiKey 及 Agent Token 区分
+const TokenPrefix = "kmt_"
+
+// lastUsedInterval 最近使用时间的最小写入间隔，避免每个请求都写库
+const lastUsedInterval = time.Minute
+
+var ErrInvalidToken = errors.New("invalid api token")
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateToken 创建令牌，明文只在此时返回一次
+func CreateToken(name string, scopes, clients []string, expiresAt *models.LocalTime, createdBy string) (string, *models.ApiToken, error) {
+	if len(scopes) == 0 {
+		return "", nil, errors.New("at least one scope is required")
+	}
+	for _, s := range scopes {
+		if !models.IsValidTokenScope(s) {
+			return "", nil, fmt.Errorf("invalid scope: %s", s)
+		}
+	}
+	// admin 权限可管理令牌与全局设置，无法限定在部分节点内
+	if len(clients) > 0 && slices.Contains(scopes, models.TokenScopeAdmin) {
+		return "", nil, errors.New("admin scope cannot be limited to clients")
+	}
+	if clients == nil {
+		clients = []string{}
+	}
+	raw := TokenPrefix + utils.GenerateRandomString(40)
+	token := &models.ApiToken{
+		Name:      name,
+		TokenHash: hashToken(raw),
+		Prefix:    raw[:len(TokenPrefix)+6],
+		Scopes:    scopes,
+		Clients:   clients,
+		ExpiresAt: expiresAt,
+		CreatedBy: createdBy,
+	}
+	if err := dbcore.GetDBInstance().Create(token).Error; err != nil {
+		return "", nil, err
+	}
+	return raw, token, nil
+}
+
+// ListTokens 列出全部令牌（不含哈希）
+func ListTokens() ([]models.ApiToken, error) {
+	var list []models.ApiToken
+	if err := dbcore.GetDBInstance().Order("id desc").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func GetTokenByID(id uint) (*models.ApiToken, error) {
+	var token models.ApiToken
+	if err := dbcore.GetDBInstance().First(&token, id).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// DeleteToken 吊销令牌
+func DeleteToken(id uint) error {
+	result := dbcore.GetDBInstance().Delete(&models.ApiToken{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("token not found")
+	}
+	return nil
+}
+
+// VerifyToken 校验明文令牌，未过期时返回令牌并刷新最近使用信息
+func VerifyToken(raw, ip string) (*models.ApiToken, error) {
+	if len(raw) <= len(TokenPrefix) || raw[:len(TokenPrefix)] != TokenPrefix {
+		return nil, ErrInvalidToken
+	}
+	var token models.ApiToken
+	if err := dbcore.GetDBInstance().Where("token_hash = ?", hashToken(raw)).First(&token).Error; err != nil {
+		return nil, ErrInvalidToken
+	}
+	if token.IsExpired() {
+		return nil, ErrInvalidToken
+	}
+	now := time.Now()
+	if token.LastUsedAt == nil || now.Sub(token.LastUsedAt.ToTime()) > lastUsedInterval || token.LastUsedIP != ip {
+		used := models.FromTime(now)
+		dbcore.GetDBInstance().Model(&models.ApiToken{}).Where("id = ?", token.ID).
+			UpdateColumns(map[string]interface{}{"last_used_at": used, "last_used_ip": ip})
+		token.LastUsedAt = &used
+		token.LastUsedIP = ip
+	}
+	return &token, nil
+}
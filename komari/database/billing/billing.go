@@ -0,0 +1,125 @@
+package billing
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/komari-monitor/komari/database/models"
+)
+
+// 平均每月天数，用于把任意周期折算为月均费用
+const daysPerMonth = 365.25 / 12
+
+// AdvanceByCycle 按账单周期推进到期时间
+//
+// 常见周期（月、季、半年、年、两年、三年、五年）按自然月/年推进，其余按天数推进
+func AdvanceByCycle(t time.Time, cycle int) time.Time {
+	switch {
+	case cycle >= 27 && cycle <= 32:
+		return t.AddDate(0, 1, 0)
+	case cycle >= 87 && cycle <= 95:
+		return t.AddDate(0, 3, 0)
+	case cycle >= 175 && cycle <= 185:
+		return t.AddDate(0, 6, 0)
+	case cycle >= 360 && cycle <= 370:
+		return t.AddDate(1, 0, 0)
+	case cycle >= 720 && cycle <= 750:
+		return t.AddDate(2, 0, 0)
+	case cycle >= 1080 && cycle <= 1150:
+		return t.AddDate(3, 0, 0)
+	case cycle >= 1800 && cycle <= 1850:
+		return t.AddDate(5, 0, 0)
+	default:
+		return t.AddDate(0, 0, cycle)
+	}
+}
+
+// HasExpiry 是否设置了到期时间（0002 年之前视为未设置）
+func HasExpiry(client models.Client) bool {
+	return client.ExpiredAt.ToTime().Year() >= 2
+}
+
+// IsOneTime 到期时间超过当前时间 100 年视为长期/一次性账单
+func IsOneTime(client models.Client, now time.Time) bool {
+	return HasExpiry(client) && client.ExpiredAt.ToTime().After(now.AddDate(100, 0, 0))
+}
+
+// MonthlyCost 节点的月均费用（原币），免费、一次性或未设置周期的节点为 0
+func MonthlyCost(client models.Client, now time.Time) float64 {
+	if client.Price <= 0 || client.BillingCycle <= 0 || IsOneTime(client, now) {
+		return 0
+	}
+	return round2(client.Price * daysPerMonth / float64(client.BillingCycle))
+}
+
+// ParseRates 解析汇率配置
+//
+// 每行或逗号分隔一项，格式为 货币=汇率，表示 1 单位该货币折合多少基准货币
+func ParseRates(s string) (map[string]float64, error) {
+	rates := map[string]float64{}
+	for _, item := range strings.FieldsFunc(s, func(r rune) bool { return r == '\n' || r == ',' || r == ';' }) {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		currency, value, ok := strings.Cut(item, "=")
+		currency = strings.TrimSpace(currency)
+		if !ok || currency == "" {
+			return nil, fmt.Errorf("invalid exchange rate %q, expected currency=rate", item)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || rate <= 0 || math.IsInf(rate, 0) {
+			return nil, fmt.Errorf("invalid exchange rate for %s: %q", currency, strings.TrimSpace(value))
+		}
+		rates[currency] = rate
+	}
+	return rates, nil
+}
+
+// Converter 按基准货币折算金额
+type Converter struct {
+	Base  string
+	Rates map[string]float64
+}
+
+// NewConverter 从配置构建折算器
+func NewConverter(cfg models.Config) (*Converter, error) {
+	rates, err := ParseRates(cfg.BillingExchangeRates)
+	if err != nil {
+		return nil, err
+	}
+	base := strings.TrimSpace(cfg.BillingBaseCurrency)
+	if base == "" {
+		base = "$"
+	}
+	return &Converter{Base: base, Rates: rates}, nil
+}
+
+// Currency 规范化货币写法，留空视为基准货币
+func (c *Converter) Currency(currency string) string {
+	currency = strings.TrimSpace(currency)
+	if currency == "" {
+		return c.Base
+	}
+	return currency
+}
+
+// Convert 折算为基准货币，缺少汇率时返回 false
+func (c *Converter) Convert(amount float64, currency string) (float64, bool) {
+	currency = c.Currency(currency)
+	if currency == c.Base {
+		return amount, true
+	}
+	rate, ok := c.Rates[currency]
+	if !ok {
+		return 0, false
+	}
+	return round2(amount * rate), true
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,85 @@
+package billing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/komari-monitor/komari/database/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRates(t *testing.T) {
+	rates, err := ParseRates("¥=0.14, €=1.08\nHKD = 0.128\n\n")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{"¥": 0.14, "€": 1.08, "HKD": 0.128}, rates)
+
+	for _, s := range []string{"¥", "=1", "¥=abc", "¥=0", "¥=-1"} {
+		_, err = ParseRates(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestMonthlyCost(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := models.Client{Price: 120, BillingCycle: 365, ExpiredAt: models.FromTime(now.AddDate(0, 1, 0))}
+	assert.Equal(t, 10.01, MonthlyCost(client, now))
+
+	client.BillingCycle = 30
+	assert.Equal(t, 121.75, MonthlyCost(client, now))
+
+	client.Price = -1
+	assert.Equal(t, 0.0, MonthlyCost(client, now), "free")
+
+	client.Price = 100
+	client.ExpiredAt = models.FromTime(now.AddDate(200, 0, 0))
+	assert.Equal(t, 0.0, MonthlyCost(client, now), "one-time")
+}
+
+func TestBuildReport(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	conv := &Converter{Base: "$", Rates: map[string]float64{"¥": 0.5}}
+	report := BuildReport([]models.Client{
+		{UUID: "a", Group: "hk", Price: 10, BillingCycle: 30, Currency: "$"},
+		{UUID: "b", Group: "hk", Price: 20, BillingCycle: 30, Currency: "¥"},
+		{UUID: "c", Group: "jp", Price: 30, BillingCycle: 30, Currency: "€"},
+	}, conv, now)
+
+	assert.InDelta(t, 20.29, report.MonthlyTotal, 0.011)
+	assert.Equal(t, []string{"€"}, report.Unconverted)
+	assert.Equal(t, map[string]float64{"$": 10.15, "¥": 20.29, "€": 30.44}, report.ByCurrency)
+	if assert.Len(t, report.Groups, 2) {
+		assert.Equal(t, "hk", report.Groups[0].Group)
+		assert.Equal(t, 2, report.Groups[0].Nodes)
+		assert.Equal(t, 0.0, report.Groups[1].MonthlyBase)
+	}
+}
+
+func TestRenewals(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	conv := &Converter{Base: "$"}
+	list := Renewals([]models.Client{
+		{UUID: "auto", Price: 5, BillingCycle: 30, AutoRenewal: true, ExpiredAt: models.FromTime(now.AddDate(0, 0, 10))},
+		{UUID: "manual", Price: 50, BillingCycle: 365, ExpiredAt: models.FromTime(now.AddDate(0, 0, 20))},
+		{UUID: "overdue", Price: 1, BillingCycle: 30, ExpiredAt: models.FromTime(now.AddDate(0, 0, -3))},
+		{UUID: "stale", Price: 1, BillingCycle: 30, AutoRenewal: true, ExpiredAt: models.FromTime(now.AddDate(-1, 0, 0))},
+		{UUID: "unset", Price: 1, BillingCycle: 30},
+		{UUID: "lifetime", Price: 1, BillingCycle: 30, ExpiredAt: models.FromTime(now.AddDate(200, 0, 0))},
+	}, conv, now, 45)
+
+	var ids []string
+	for _, r := range list {
+		ids = append(ids, r.UUID)
+	}
+	assert.Equal(t, []string{"overdue", "stale", "auto", "manual", "stale", "auto"}, ids)
+	assert.Equal(t, -3, list[0].DaysLeft)
+	assert.False(t, list[2].Projected)
+	assert.True(t, list[5].Projected)
+	assert.Equal(t, now.AddDate(0, 1, 10), list[5].Date.ToTime())
+}
+
+func TestAdvanceByCycle(t *testing.T) {
+	base := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, base.AddDate(0, 1, 0), AdvanceByCycle(base, 30))
+	assert.Equal(t, base.AddDate(1, 0, 0), AdvanceByCycle(base, 365))
+	assert.Equal(t, base.AddDate(0, 0, 7), AdvanceByCycle(base, 7))
+}
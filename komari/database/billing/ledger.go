@@ -0,0 +1,147 @@
+package billing
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/komari-monitor/komari/database/dbcore"
+	"github.com/komari-monitor/komari/database/models"
+	"gorm.io/gorm"
+)
+
+const (
+	SourceAutoRenewal = "auto_renewal"
+	SourceManual      = "manual"
+)
+
+// LedgerFilter 账本查询条件，零值表示不限
+type LedgerFilter struct {
+	Client string
+	From   time.Time
+	To     time.Time
+}
+
+// ListRecords 按支付时间倒序列出账本记录
+func ListRecords(filter LedgerFilter) ([]models.BillingRecord, error) {
+	db := dbcore.GetDBInstance().Model(&models.BillingRecord{})
+	if filter.Client != "" {
+		db = db.Where("client = ?", filter.Client)
+	}
+	// 按 LocalTime 的存储格式绑定，直接传 time.Time 在 SQLite 上会按字符串错误比较
+	if !filter.From.IsZero() {
+		db = db.Where("paid_at >= ?", models.FromTime(filter.From.In(models.GetAppLocation())))
+	}
+	if !filter.To.IsZero() {
+		db = db.Where("paid_at < ?", models.FromTime(filter.To.In(models.GetAppLocation())))
+	}
+	records := []models.BillingRecord{}
+	err := db.Order("paid_at DESC, id DESC").Find(&records).Error
+	return records, err
+}
+
+// AddRecord 写入一条账本记录，未填写的支付时间与来源使用默认值
+func AddRecord(record *models.BillingRecord) error {
+	record.ID = 0
+	record.Currency = strings.TrimSpace(record.Currency)
+	if record.Amount < 0 {
+		return fmt.Errorf("amount must not be negative")
+	}
+	if record.Currency == "" {
+		return fmt.Errorf("currency is required")
+	}
+	if record.PaidAt.ToTime().Year() < 2 {
+		record.PaidAt = models.Now()
+	}
+	if record.Source == "" {
+		record.Source = SourceManual
+	}
+	return dbcore.GetDBInstance().Create(record).Error
+}
+
+// DeleteRecord 删除账本记录
+func DeleteRecord(id uint) error {
+	result := dbcore.GetDBInstance().Delete(&models.BillingRecord{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RecordRenewal 记录一次自动续费的支出，免费节点不记录
+func RecordRenewal(client models.Client, periodStart, periodEnd time.Time) error {
+	if client.Price <= 0 {
+		return nil
+	}
+	return AddRecord(&models.BillingRecord{
+		Client:       client.UUID,
+		ClientName:   client.Name,
+		Group:        client.Group,
+		Amount:       client.Price,
+		Currency:     client.Currency,
+		BillingCycle: client.BillingCycle,
+		PeriodStart:  models.FromTime(periodStart),
+		PeriodEnd:    models.FromTime(periodEnd),
+		Source:       SourceAutoRenewal,
+	})
+}
+
+// MonthSpend 某月的支出合计
+type MonthSpend struct {
+	Month      string             `json:"month"` // YYYY-MM（服务器时区）
+	Total      float64            `json:"total"` // 基准货币
+	ByCurrency map[string]float64 `json:"by_currency"`
+}
+
+// LedgerSummary 账本汇总
+type LedgerSummary struct {
+	BaseCurrency string             `json:"base_currency"`
+	Total        float64            `json:"total"`
+	ByCurrency   map[string]float64 `json:"by_currency"`
+	ByMonth      []MonthSpend       `json:"by_month"` // 按月份升序
+	Unconverted  []string           `json:"unconverted"`
+}
+
+// SummarizeLedger 按月份与货币汇总账本记录
+func SummarizeLedger(records []models.BillingRecord, conv *Converter) LedgerSummary {
+	summary := LedgerSummary{
+		BaseCurrency: conv.Base,
+		ByCurrency:   map[string]float64{},
+		ByMonth:      []MonthSpend{},
+		Unconverted:  []string{},
+	}
+	months := map[string]*MonthSpend{}
+	unconverted := map[string]bool{}
+	loc := models.GetAppLocation()
+	for _, r := range records {
+		currency := conv.Currency(r.Currency)
+		month := r.PaidAt.ToTime().In(loc).Format("2006-01")
+		m, ok := months[month]
+		if !ok {
+			m = &MonthSpend{Month: month, ByCurrency: map[string]float64{}}
+			months[month] = m
+		}
+		m.ByCurrency[currency] = round2(m.ByCurrency[currency] + r.Amount)
+		summary.ByCurrency[currency] = round2(summary.ByCurrency[currency] + r.Amount)
+		amount, ok := conv.Convert(r.Amount, currency)
+		if !ok {
+			unconverted[currency] = true
+			continue
+		}
+		m.Total = round2(m.Total + amount)
+		summary.Total = round2(summary.Total + amount)
+	}
+	for _, m := range months {
+		summary.ByMonth = append(summary.ByMonth, *m)
+	}
+	sort.Slice(summary.ByMonth, func(i, j int) bool { return summary.ByMonth[i].Month < summary.ByMonth[j].Month })
+	for currency := range unconverted {
+		summary.Unconverted = append(summary.Unconverted, currency)
+	}
+	sort.Strings(summary.Unconverted)
+	return summary
+}
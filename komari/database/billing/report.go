@@ -0,0 +1,183 @@
+package billing
+
+import (
+	"sort"
+	"time"
+
+	"github.com/komari-monitor/komari/database/models"
+)
+
+// NodeCost 单个节点的费用
+type NodeCost struct {
+	UUID         string            `json:"uuid"`
+	Name         string            `json:"name"`
+	Group        string            `json:"group"`
+	Price        float64           `json:"price"`
+	Currency     string            `json:"currency"`
+	BillingCycle int               `json:"billing_cycle"`
+	AutoRenewal  bool              `json:"auto_renewal"`
+	ExpiredAt    *models.LocalTime `json:"expired_at"`
+	OneTime      bool              `json:"one_time"`     // 长期/一次性账单，不计入月均
+	Monthly      float64           `json:"monthly"`      // 月均费用（原币）
+	MonthlyBase  float64           `json:"monthly_base"` // 月均费用（基准货币），缺少汇率时为 0
+	Converted    bool              `json:"converted"`
+}
+
+// GroupCost 分组汇总
+type GroupCost struct {
+	Group       string             `json:"group"`
+	Nodes       int                `json:"nodes"`
+	MonthlyBase float64            `json:"monthly_base"`
+	Monthly     map[string]float64 `json:"monthly"` // 按原币分别合计
+}
+
+// Report 费用报表
+type Report struct {
+	BaseCurrency string             `json:"base_currency"`
+	Nodes        []NodeCost         `json:"nodes"`
+	Groups       []GroupCost        `json:"groups"`
+	MonthlyTotal float64            `json:"monthly_total"` // 基准货币
+	YearlyTotal  float64            `json:"yearly_total"`  // 基准货币
+	ByCurrency   map[string]float64 `json:"by_currency"`   // 按原币的月均合计
+	Unconverted  []string           `json:"unconverted"`   // 缺少汇率、未计入基准货币合计的货币
+}
+
+// BuildReport 计算各节点、各分组的月均费用并折算为基准货币
+func BuildReport(clients []models.Client, conv *Converter, now time.Time) Report {
+	report := Report{
+		BaseCurrency: conv.Base,
+		Nodes:        make([]NodeCost, 0, len(clients)),
+		Groups:       []GroupCost{},
+		ByCurrency:   map[string]float64{},
+		Unconverted:  []string{},
+	}
+	groups := map[string]*GroupCost{}
+	unconverted := map[string]bool{}
+	for _, client := range clients {
+		currency := conv.Currency(client.Currency)
+		node := NodeCost{
+			UUID:         client.UUID,
+			Name:         client.Name,
+			Group:        client.Group,
+			Price:        client.Price,
+			Currency:     currency,
+			BillingCycle: client.BillingCycle,
+			AutoRenewal:  client.AutoRenewal,
+			OneTime:      IsOneTime(client, now),
+			Monthly:      MonthlyCost(client, now),
+		}
+		if HasExpiry(client) {
+			expiredAt := client.ExpiredAt
+			node.ExpiredAt = &expiredAt
+		}
+		node.MonthlyBase, node.Converted = conv.Convert(node.Monthly, currency)
+		if !node.Converted && node.Monthly > 0 {
+			unconverted[currency] = true
+		}
+		report.Nodes = append(report.Nodes, node)
+
+		g, ok := groups[client.Group]
+		if !ok {
+			g = &GroupCost{Group: client.Group, Monthly: map[string]float64{}}
+			groups[client.Group] = g
+		}
+		g.Nodes++
+		if node.Monthly > 0 {
+			g.Monthly[currency] = round2(g.Monthly[currency] + node.Monthly)
+			report.ByCurrency[currency] = round2(report.ByCurrency[currency] + node.Monthly)
+		}
+		g.MonthlyBase = round2(g.MonthlyBase + node.MonthlyBase)
+		report.MonthlyTotal = round2(report.MonthlyTotal + node.MonthlyBase)
+	}
+	report.YearlyTotal = round2(report.MonthlyTotal * 12)
+	for _, g := range groups {
+		report.Groups = append(report.Groups, *g)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		if report.Groups[i].MonthlyBase != report.Groups[j].MonthlyBase {
+			return report.Groups[i].MonthlyBase > report.Groups[j].MonthlyBase
+		}
+		return report.Groups[i].Group < report.Groups[j].Group
+	})
+	for currency := range unconverted {
+		report.Unconverted = append(report.Unconverted, currency)
+	}
+	sort.Strings(report.Unconverted)
+	return report
+}
+
+// Renewal 续费日历中的一次续费
+type Renewal struct {
+	UUID        string           `json:"uuid"`
+	Name        string           `json:"name"`
+	Group       string           `json:"group"`
+	Date        models.LocalTime `json:"date"`
+	DaysLeft    int              `json:"days_left"` // 负数表示已过期
+	Amount      float64          `json:"amount"`
+	Currency    string           `json:"currency"`
+	AmountBase  float64          `json:"amount_base"`
+	Converted   bool             `json:"converted"`
+	AutoRenewal bool             `json:"auto_renewal"`
+	Projected   bool             `json:"projected"` // 按自动续费推算的后续周期
+}
+
+// 续费日历最多展示的天数
+const MaxRenewalDays = 366
+
+// 已过期但未续费的节点在日历中保留的天数
+const overdueDays = 30
+
+// Renewals 列出未来 days 天内的续费，自动续费的节点会按周期推算多次
+func Renewals(clients []models.Client, conv *Converter, now time.Time, days int) []Renewal {
+	if days <= 0 {
+		days = 30
+	}
+	if days > MaxRenewalDays {
+		days = MaxRenewalDays
+	}
+	start, end := now.AddDate(0, 0, -overdueDays), now.AddDate(0, 0, days)
+	result := []Renewal{}
+	for _, client := range clients {
+		if !HasExpiry(client) || IsOneTime(client, now) {
+			continue
+		}
+		currency := conv.Currency(client.Currency)
+		amount := client.Price
+		if amount < 0 {
+			amount = 0
+		}
+		amountBase, converted := conv.Convert(amount, currency)
+		auto := client.AutoRenewal && client.BillingCycle > 0
+
+		t := client.ExpiredAt.ToTime()
+		// 与自动续费一致：过期超过 30 天的节点以当前时间为基准续费
+		if auto && t.Before(start) {
+			t = now
+		}
+		for i := 0; !t.After(end) && i <= MaxRenewalDays; i++ {
+			if !t.Before(start) {
+				result = append(result, Renewal{
+					UUID:        client.UUID,
+					Name:        client.Name,
+					Group:       client.Group,
+					Date:        models.FromTime(t),
+					DaysLeft:    int(t.Sub(now).Hours() / 24),
+					Amount:      amount,
+					Currency:    currency,
+					AmountBase:  amountBase,
+					Converted:   converted,
+					AutoRenewal: client.AutoRenewal,
+					Projected:   i > 0,
+				})
+			}
+			if !auto {
+				break
+			}
+			t = AdvanceByCycle(t, client.BillingCycle)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Date.ToTime().Before(result[j].Date.ToTime())
+	})
+	return result
+}
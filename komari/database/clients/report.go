@@ -180,7 +180,7 @@ func SaveClientReport(clientUUID string, report common.Report) (err error) {
 		Swap:           report.Swap.Used,
 		SwapTotal:      report.Swap.Total,
 		Load:           float32(report.Load.Load1), // 使用 Load1 作为主要负载指标
-		Temp:           0,                          // Report 未提供 TEMP，设为 0（与原 nil 行为类似）
+		Temp:           float32(report.Temperature.Primary()),
 		Disk:           report.Disk.Used,
 		DiskTotal:      report.Disk.Total,
 		NetIn:          report.Network.Down,
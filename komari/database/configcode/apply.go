@@ -0,0 +1,459 @@
+package configcode
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/komari-monitor/komari/database/clients"
+	"github.com/komari-monitor/komari/database/dbcore"
+	"github.com/komari-monitor/komari/database/lg"
+	"github.com/komari-monitor/komari/database/models"
+	"github.com/komari-monitor/komari/database/notification"
+	"github.com/komari-monitor/komari/database/script"
+	"github.com/komari-monitor/komari/database/tasks"
+)
+
+var applyMu sync.Mutex
+
+// Apply 将文档应用到数据库并返回实际执行的变更。
+// 各分组依次调用对应的 database 包完成写入，中途失败时已完成的部分不会回滚，
+// 修正文档后再次应用即可收敛到期望状态
+func Apply(doc Document, prune bool) ([]Change, error) {
+	applyMu.Lock()
+	defer applyMu.Unlock()
+
+	current, err := load()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := plan(&doc, current, prune)
+	if err != nil || len(changes) == 0 {
+		return changes, err
+	}
+	a := &applier{doc: &doc, cur: current, changes: changes}
+	for _, step := range []func() error{
+		a.clients, a.pingTasks, a.spPingTasks, a.loadNotifications, a.offlineNotifications,
+		a.scripts, a.lgAuthorizations, a.lgToolSettings,
+	} {
+		if err := step(); err != nil {
+			return changes, err
+		}
+	}
+	return changes, nil
+}
+
+type applier struct {
+	doc     *Document
+	cur     *snapshot
+	changes []Change
+}
+
+func (a *applier) deletedIDs(kind string, ids map[string]uint) []uint {
+	var out []uint
+	for _, c := range byAction(a.changes, kind, ActionDelete) {
+		out = append(out, ids[c.Key])
+	}
+	return out
+}
+
+func (a *applier) clients() error {
+	changed := changedKeys(a.changes, KindClient)
+	for _, spec := range a.doc.Clients {
+		c, ok := changed[spec.UUID]
+		if !ok {
+			continue
+		}
+		columns := spec.columns()
+		updates := map[string]interface{}{"uuid": spec.UUID}
+		for field := range c.Fields {
+			updates[field] = columns[field]
+		}
+		if err := clients.SaveClient(updates); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// columns 返回已设置字段对应的数据库列
+func (s ClientSpec) columns() map[string]interface{} {
+	m := map[string]interface{}{}
+	set := func(col string, ok bool, v interface{}) {
+		if ok {
+			m[col] = v
+		}
+	}
+	timeValue := func(v *string) interface{} {
+		if *v == "" {
+			return nil
+		}
+		return models.FromTime(parseTime(*v))
+	}
+	set("name", s.Name != nil, deref(s.Name))
+	set("group", s.Group != nil, deref(s.Group))
+	if s.Tags != nil {
+		m["tags"] = strings.Join(*s.Tags, ";")
+	}
+	set("weight", s.Weight != nil, deref(s.Weight))
+	set("hidden", s.Hidden != nil, deref(s.Hidden))
+	set("remark", s.Remark != nil, deref(s.Remark))
+	set("public_remark", s.PublicRemark != nil, deref(s.PublicRemark))
+	set("price", s.Price != nil, deref(s.Price))
+	set("billing_cycle", s.BillingCycle != nil, deref(s.BillingCycle))
+	set("auto_renewal", s.AutoRenewal != nil, deref(s.AutoRenewal))
+	set("currency", s.Currency != nil, deref(s.Currency))
+	if s.StartedAt != nil {
+		m["started_at"] = timeValue(s.StartedAt)
+	}
+	if s.ExpiredAt != nil {
+		m["expired_at"] = timeValue(s.ExpiredAt)
+	}
+	set("traffic_limit", s.TrafficLimit != nil, deref(s.TrafficLimit))
+	set("traffic_limit_type", s.TrafficLimitType != nil, deref(s.TrafficLimitType))
+	set("traffic_reset_day", s.TrafficResetDay != nil, deref(s.TrafficResetDay))
+	return m
+}
+
+func deref[T any](v *T) T {
+	var zero T
+	if v == nil {
+		return zero
+	}
+	return *v
+}
+
+// onlyWeight 变更是否只涉及排序权重
+func onlyWeight(c Change) bool {
+	_, ok := c.Fields["weight"]
+	return ok && len(c.Fields) == 1
+}
+
+func (a *applier) pingTasks() error {
+	changed := changedKeys(a.changes, KindPingTask)
+	var creates []models.PingTask
+	var edits []*models.PingTask
+	for _, t := range a.doc.PingTasks {
+		c, ok := changed[t.Name]
+		if !ok {
+			continue
+		}
+		model := models.PingTask{Name: t.Name, Clients: t.Clients, Type: t.Type, Target: t.Target, Interval: t.Interval}
+		if c.Action == ActionCreate {
+			creates = append(creates, model)
+		} else if !onlyWeight(c) {
+			model.Id = a.cur.pingIDs[t.Name]
+			edits = append(edits, &model)
+		}
+	}
+	ids, err := tasks.AddPingTasks(creates)
+	if err != nil {
+		return err
+	}
+	for i, id := range ids {
+		a.cur.pingIDs[creates[i].Name] = id
+	}
+	if len(edits) > 0 {
+		if err := tasks.EditPingTask(edits); err != nil {
+			return err
+		}
+	}
+	// 新建任务的权重由 AddPingTasks 自动分配，统一按文档覆盖
+	weights := map[uint]int{}
+	for _, t := range a.doc.PingTasks {
+		if _, ok := changed[t.Name]; ok {
+			weights[a.cur.pingIDs[t.Name]] = t.Weight
+		}
+	}
+	if len(weights) > 0 {
+		if err := tasks.OrderPingTasks(weights); err != nil {
+			return err
+		}
+	}
+	if ids := a.deletedIDs(KindPingTask, a.cur.pingIDs); len(ids) > 0 {
+		return tasks.DeletePingTask(ids)
+	}
+	return nil
+}
+
+func (a *applier) spPingTasks() error {
+	changed := changedKeys(a.changes, KindSPPingTask)
+	var creates []models.SPPingTask
+	var edits []*models.SPPingTask
+	for _, t := range a.doc.SPPingTasks {
+		c, ok := changed[t.Name]
+		if !ok {
+			continue
+		}
+		model := models.SPPingTask{
+			Name:        t.Name,
+			Clients:     t.Clients,
+			Type:        t.Type,
+			Target:      t.Target,
+			Step:        t.Step,
+			Pings:       t.Pings,
+			TimeoutMS:   t.TimeoutMS,
+			PayloadSize: t.PayloadSize,
+		}
+		if c.Action == ActionCreate {
+			creates = append(creates, model)
+		} else if !onlyWeight(c) {
+			model.Id = a.cur.spIDs[t.Name]
+			edits = append(edits, &model)
+		}
+	}
+	ids, err := tasks.AddSPPingTasks(creates)
+	if err != nil {
+		return err
+	}
+	for i, id := range ids {
+		a.cur.spIDs[creates[i].Name] = id
+	}
+	if len(edits) > 0 {
+		if err := tasks.EditSPPingTask(edits); err != nil {
+			return err
+		}
+	}
+	weights := map[uint]int{}
+	for _, t := range a.doc.SPPingTasks {
+		if _, ok := changed[t.Name]; ok {
+			weights[a.cur.spIDs[t.Name]] = t.Weight
+		}
+	}
+	if len(weights) > 0 {
+		if err := tasks.OrderSPPingTasks(weights); err != nil {
+			return err
+		}
+	}
+	if ids := a.deletedIDs(KindSPPingTask, a.cur.spIDs); len(ids) > 0 {
+		return tasks.DeleteSPPingTask(ids)
+	}
+	return nil
+}
+
+func (a *applier) loadNotifications() error {
+	changed := changedKeys(a.changes, KindLoadNotification)
+	var edits []*models.LoadNotification
+	for _, n := range a.doc.LoadNotifications {
+		c, ok := changed[n.Name]
+		if !ok {
+			continue
+		}
+		if c.Action == ActionCreate {
+			if _, err := notification.AddLoadNotification(n.Clients, n.Name, n.Metric, n.Threshold, n.Ratio, n.Interval); err != nil {
+				return err
+			}
+			continue
+		}
+		edits = append(edits, &models.LoadNotification{
+			Id:        a.cur.loadIDs[n.Name],
+			Name:      n.Name,
+			Clients:   n.Clients,
+			Metric:    n.Metric,
+			Threshold: n.Threshold,
+			Ratio:     n.Ratio,
+			Interval:  n.Interval,
+		})
+	}
+	if len(edits) > 0 {
+		if err := notification.EditLoadNotification(edits); err != nil {
+			return err
+		}
+	}
+	if ids := a.deletedIDs(KindLoadNotification, a.cur.loadIDs); len(ids) > 0 {
+		return notification.DeleteLoadNotification(ids)
+	}
+	return nil
+}
+
+func (a *applier) offlineNotifications() error {
+	changed := changedKeys(a.changes, KindOfflineNotification)
+	var upserts []models.OfflineNotification
+	for _, n := range a.doc.OfflineNotifications {
+		if _, ok := changed[n.Client]; ok {
+			upserts = append(upserts, models.OfflineNotification{Client: n.Client, Enable: n.Enable, GracePeriod: n.GracePeriod})
+		}
+	}
+	if err := notification.UpsertOfflineNotifications(upserts); err != nil {
+		return err
+	}
+	var deletes []string
+	for _, c := range byAction(a.changes, KindOfflineNotification, ActionDelete) {
+		deletes = append(deletes, c.Key)
+	}
+	return notification.DeleteOfflineNotifications(deletes)
+}
+
+// scripts 先建目录（父目录优先），再建脚本并回填依赖，最后删除脚本与目录（子目录优先）
+func (a *applier) scripts() error {
+	folderChanges := changedKeys(a.changes, KindScriptFolder)
+	scriptChanges := changedKeys(a.changes, KindScript)
+	folderDeletes := byAction(a.changes, KindScriptFolder, ActionDelete)
+	scriptDeletes := byAction(a.changes, KindScript, ActionDelete)
+	if len(folderChanges)+len(scriptChanges)+len(folderDeletes)+len(scriptDeletes) == 0 {
+		return nil
+	}
+	defer script.ReloadScriptSchedule()
+
+	folders := make(map[string]ScriptFolderSpec, len(a.doc.ScriptFolders))
+	var paths []string
+	for _, f := range a.doc.ScriptFolders {
+		if _, ok := folderChanges[f.Path]; ok {
+			folders[f.Path] = f
+			paths = append(paths, f.Path)
+		}
+	}
+	sortByDepth(paths, false)
+	for _, p := range paths {
+		f := folders[p]
+		name, parent := p, (*uint)(nil)
+		if i := strings.LastIndex(p, "/"); i >= 0 {
+			id := a.cur.folderIDs[p[:i]]
+			name, parent = p[i+1:], &id
+		}
+		model := &models.ScriptFolder{ID: a.cur.folderIDs[p], Name: name, ParentID: parent, Icon: f.Icon, Order: f.Order}
+		if folderChanges[p].Action == ActionCreate {
+			if err := script.AddFolder(model); err != nil {
+				return err
+			}
+			a.cur.folderIDs[p] = model.ID
+		} else if err := script.UpdateFolder(model); err != nil {
+			return err
+		}
+	}
+
+	var specs []ScriptSpec
+	for _, s := range a.doc.Scripts {
+		if c, ok := scriptChanges[s.Key()]; ok {
+			specs = append(specs, s)
+			if c.Action == ActionCreate {
+				model := a.scriptModel(s)
+				model.DependsOnScripts, model.DependsOnFolders = models.UIntArray{}, models.UIntArray{}
+				if err := script.CreateScript(model); err != nil {
+					return err
+				}
+				a.cur.scriptIDs[s.Key()] = model.ID
+			}
+		}
+	}
+	// 依赖可能指向本次新建的脚本，全部建好后再统一写入
+	var updates []*models.Script
+	for _, s := range specs {
+		model := a.scriptModel(s)
+		model.ID = a.cur.scriptIDs[s.Key()]
+		model.ClientStatus = a.cur.scripts[s.Key()].ClientStatus
+		updates = append(updates, model)
+	}
+	if err := script.UpdateScripts(updates); err != nil {
+		return err
+	}
+
+	for _, c := range scriptDeletes {
+		if err := script.DeleteScript(a.cur.scriptIDs[c.Key]); err != nil {
+			return err
+		}
+	}
+	paths = paths[:0]
+	for _, c := range folderDeletes {
+		paths = append(paths, c.Key)
+	}
+	sortByDepth(paths, true)
+	for _, p := range paths {
+		if err := script.DeleteFolder(a.cur.folderIDs[p]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *applier) scriptModel(s ScriptSpec) *models.Script {
+	model := &models.Script{
+		Name:             s.Name,
+		Order:            s.Order,
+		Enabled:          s.Enabled,
+		Clients:          s.Clients,
+		ScriptBody:       s.ScriptBody,
+		TimeoutSec:       s.TimeoutSec,
+		TriggerKind:      s.TriggerKind,
+		CronExpr:         s.CronExpr,
+		TriggerName:      s.TriggerName,
+		MessageType:      s.MessageType,
+		RunnerClient:     s.RunnerClient,
+		DependsOnScripts: models.UIntArray{},
+		DependsOnFolders: models.UIntArray{},
+	}
+	if s.Folder != "" {
+		id := a.cur.folderIDs[s.Folder]
+		model.FolderID = &id
+	}
+	for _, dep := range s.DependsOnScripts {
+		model.DependsOnScripts = append(model.DependsOnScripts, a.cur.scriptIDs[dep])
+	}
+	for _, dep := range s.DependsOnFolders {
+		model.DependsOnFolders = append(model.DependsOnFolders, a.cur.folderIDs[dep])
+	}
+	return model
+}
+
+func (a *applier) lgAuthorizations() error {
+	changed := changedKeys(a.changes, KindLgAuthorization)
+	for _, s := range a.doc.LgAuthorizations {
+		c, ok := changed[s.Name]
+		if !ok {
+			continue
+		}
+		model := &models.LgAuthorization{
+			Name:     s.Name,
+			Remark:   s.Remark,
+			Mode:     s.Mode,
+			Code:     s.Code,
+			Nodes:    s.Nodes,
+			Tools:    s.Tools,
+			MaxUsage: s.MaxUsage,
+		}
+		if s.ExpiresAt != "" {
+			t := models.FromTime(parseTime(s.ExpiresAt))
+			model.ExpiresAt = &t
+		}
+		if c.Action == ActionCreate {
+			if err := lg.CreateAuthorization(model); err != nil {
+				return err
+			}
+			continue
+		}
+		model.ID = a.cur.lgIDs[s.Name]
+		if err := lg.UpdateAuthorization(model); err != nil {
+			return err
+		}
+		// UpdateAuthorization 会跳过零值，可清空的字段需要单独写入
+		err := dbcore.GetDBInstance().Model(&models.LgAuthorization{}).Where("id = ?", model.ID).
+			Updates(map[string]interface{}{
+				"remark":     model.Remark,
+				"code":       model.Code,
+				"expires_at": model.ExpiresAt,
+				"max_usage":  model.MaxUsage,
+			}).Error
+		if err != nil {
+			return err
+		}
+	}
+	for _, c := range byAction(a.changes, KindLgAuthorization, ActionDelete) {
+		if err := lg.DeleteAuthorization(a.cur.lgIDs[c.Key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *applier) lgToolSettings() error {
+	changed := changedKeys(a.changes, KindLgToolSetting)
+	var settings []models.LgToolSetting
+	for _, t := range a.doc.LgToolSettings {
+		if _, ok := changed[t.Tool]; ok {
+			settings = append(settings, models.LgToolSetting{Tool: t.Tool, CommandTemplate: t.CommandTemplate, TimeoutSeconds: t.TimeoutSeconds})
+		}
+	}
+	if len(settings) == 0 {
+		return nil
+	}
+	return lg.UpsertToolSettings(settings)
+}
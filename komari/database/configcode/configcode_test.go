@@ -0,0 +1,141 @@
+package configcode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testSnapshot(doc Document) *snapshot {
+	s := &snapshot{
+		doc:       doc,
+		clients:   map[string]bool{"c1": true, "c2": true},
+		folderIDs: map[string]uint{},
+		scriptIDs: map[string]uint{},
+	}
+	for i, f := range doc.ScriptFolders {
+		s.folderIDs[f.Path] = uint(i + 1)
+	}
+	for i, sc := range doc.Scripts {
+		s.scriptIDs[sc.Key()] = uint(i + 1)
+	}
+	return s
+}
+
+func TestParse(t *testing.T) {
+	doc, err := Parse([]byte(`
+version: 1
+ping_tasks:
+  - name: google
+    clients: [c1]
+    target: 8.8.8.8
+scripts: []
+scripts_typo: 1
+`))
+	assert.Error(t, err, "unknown fields should be rejected")
+
+	doc, err = Parse([]byte(`
+ping_tasks:
+  - name: google
+    clients: [c1]
+    target: 8.8.8.8
+scripts: []
+`))
+	assert.NoError(t, err)
+	assert.Len(t, doc.PingTasks, 1)
+	assert.NotNil(t, doc.Scripts, "empty section is present")
+	assert.Nil(t, doc.Clients, "missing section is untouched")
+
+	doc, err = Parse([]byte(`{"version":1,"clients":[{"uuid":"c1","group":"hk"}]}`))
+	assert.NoError(t, err)
+	if assert.Len(t, doc.Clients, 1) {
+		assert.Equal(t, "hk", *doc.Clients[0].Group)
+		assert.Nil(t, doc.Clients[0].Name)
+	}
+
+	_, err = Parse([]byte("version: 99\n"))
+	assert.Error(t, err)
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	name := "node"
+	doc := Document{
+		Version: Version,
+		Clients: []ClientSpec{{UUID: "c1", Name: &name}},
+		Scripts: []ScriptSpec{{Name: "hello", ScriptBody: "echo 1\necho 2\n", Clients: []string{}}},
+	}
+	for _, format := range []string{"yaml", "json"} {
+		data, err := Marshal(doc, format)
+		assert.NoError(t, err)
+		parsed, err := Parse(data)
+		assert.NoError(t, err, format)
+		assert.Equal(t, doc.Scripts[0].ScriptBody, parsed.Scripts[0].ScriptBody, format)
+		assert.Equal(t, "node", *parsed.Clients[0].Name, format)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	group, tags, started := "hk", []string{"a", "b"}, "2025-01-01T00:00:00Z"
+	current := testSnapshot(Document{
+		Clients: []ClientSpec{{UUID: "c1", Group: &group, Tags: &tags, StartedAt: &started}},
+		PingTasks: []PingTaskSpec{
+			{Name: "keep", Clients: []string{"c1"}, Type: "icmp", Target: "1.1.1.1", Interval: 60},
+			{Name: "old", Clients: []string{}, Type: "icmp", Target: "2.2.2.2", Interval: 60, Weight: 1},
+		},
+		ScriptFolders: []ScriptFolderSpec{{Path: "ops"}},
+		Scripts:       []ScriptSpec{{Folder: "ops", Name: "a", Clients: []string{}, DependsOnScripts: []string{}, DependsOnFolders: []string{}, MessageType: "script"}},
+	})
+
+	// 与当前状态一致（依赖默认值补齐）时不产生变更
+	newGroup := "jp"
+	desired := Document{
+		Clients:   []ClientSpec{{UUID: "c1", Group: &newGroup}},
+		PingTasks: []PingTaskSpec{{Name: "keep", Clients: []string{"c1"}, Target: "1.1.1.1"}},
+		Scripts: []ScriptSpec{
+			{Folder: "ops", Name: "a"},
+			{Name: "b", DependsOnScripts: []string{"ops/a"}},
+		},
+	}
+	changes, err := plan(&desired, current, false)
+	assert.NoError(t, err)
+	if assert.Len(t, changes, 2) {
+		assert.Equal(t, Change{Kind: KindClient, Key: "c1", Action: ActionUpdate,
+			Fields: map[string]FieldChange{"group": {From: "hk", To: "jp"}}}, changes[0])
+		assert.Equal(t, KindScript, changes[1].Kind)
+		assert.Equal(t, "b", changes[1].Key)
+		assert.Equal(t, ActionCreate, changes[1].Action)
+	}
+
+	desired = Document{PingTasks: []PingTaskSpec{{Name: "keep", Clients: []string{"c1"}, Target: "1.1.1.1"}}}
+	changes, err = plan(&desired, current, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{{Kind: KindPingTask, Key: "old", Action: ActionDelete}}, changes)
+
+	desired = Document{Clients: []ClientSpec{{UUID: "missing"}}}
+	_, err = plan(&desired, current, false)
+	assert.Error(t, err)
+
+	desired = Document{PingTasks: []PingTaskSpec{{Name: "x"}, {Name: "x"}}}
+	_, err = plan(&desired, current, false)
+	assert.Error(t, err)
+
+	desired = Document{Scripts: []ScriptSpec{{Folder: "missing", Name: "a"}}}
+	_, err = plan(&desired, current, false)
+	assert.Error(t, err)
+
+	// prune 时依赖必须在文档中
+	desired = Document{Scripts: []ScriptSpec{{Name: "b", DependsOnScripts: []string{"ops/a"}}}}
+	_, err = plan(&desired, current, true)
+	assert.Error(t, err)
+}
+
+func TestNormalizeTime(t *testing.T) {
+	v, err := normalizeTime("2025-01-01T08:00:00+08:00")
+	assert.NoError(t, err)
+	assert.Equal(t, "2025-01-01T00:00:00Z", v)
+	v, err = normalizeTime("")
+	assert.NoError(t, err)
+	assert.Equal(t, "", v)
+	_, err = normalizeTime("tomorrow")
+	assert.Error(t, err)
+}
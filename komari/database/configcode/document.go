@@ -0,0 +1,216 @@
+// Package configcode 以声明式文档（YAML/JSON）导出与导入面板配置，
+// 支持先比较差异（dry-run）再幂等地应用。
+package configcode
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/komari-monitor/komari/database/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Version 当前文档格式版本
+const Version = 1
+
+// Document 配置文档。未出现的分组保持不变；出现但为空列表的分组视为“期望为空”，
+// 仅在 prune 模式下才会删除数据库中多余的条目
+type Document struct {
+	Version              int                       `yaml:"version" json:"version"`
+	Clients              []ClientSpec              `yaml:"clients" json:"clients"`
+	PingTasks            []PingTaskSpec            `yaml:"ping_tasks" json:"ping_tasks"`
+	SPPingTasks          []SPPingTaskSpec          `yaml:"sp_ping_tasks" json:"sp_ping_tasks"`
+	LoadNotifications    []LoadNotificationSpec    `yaml:"load_notifications" json:"load_notifications"`
+	OfflineNotifications []OfflineNotificationSpec `yaml:"offline_notifications" json:"offline_notifications"`
+	ScriptFolders        []ScriptFolderSpec        `yaml:"script_folders" json:"script_folders"`
+	Scripts              []ScriptSpec              `yaml:"scripts" json:"scripts"`
+	LgAuthorizations     []LgAuthorizationSpec     `yaml:"lg_authorizations" json:"lg_authorizations"`
+	LgToolSettings       []LgToolSettingSpec       `yaml:"lg_tool_settings" json:"lg_tool_settings"`
+}
+
+// ClientSpec 节点元数据，以 uuid 匹配已有节点，只更新出现的字段，不会新建或删除节点
+type ClientSpec struct {
+	UUID             string    `yaml:"uuid" json:"uuid"`
+	Name             *string   `yaml:"name,omitempty" json:"name,omitempty"`
+	Group            *string   `yaml:"group,omitempty" json:"group,omitempty"`
+	Tags             *[]string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Weight           *int      `yaml:"weight,omitempty" json:"weight,omitempty"`
+	Hidden           *bool     `yaml:"hidden,omitempty" json:"hidden,omitempty"`
+	Remark           *string   `yaml:"remark,omitempty" json:"remark,omitempty"`
+	PublicRemark     *string   `yaml:"public_remark,omitempty" json:"public_remark,omitempty"`
+	Price            *float64  `yaml:"price,omitempty" json:"price,omitempty"`
+	BillingCycle     *int      `yaml:"billing_cycle,omitempty" json:"billing_cycle,omitempty"`
+	AutoRenewal      *bool     `yaml:"auto_renewal,omitempty" json:"auto_renewal,omitempty"`
+	Currency         *string   `yaml:"currency,omitempty" json:"currency,omitempty"`
+	StartedAt        *string   `yaml:"started_at,omitempty" json:"started_at,omitempty"` // RFC3339，空串表示未设置
+	ExpiredAt        *string   `yaml:"expired_at,omitempty" json:"expired_at,omitempty"`
+	TrafficLimit     *int64    `yaml:"traffic_limit,omitempty" json:"traffic_limit,omitempty"`
+	TrafficLimitType *string   `yaml:"traffic_limit_type,omitempty" json:"traffic_limit_type,omitempty"`
+	TrafficResetDay  *int      `yaml:"traffic_reset_day,omitempty" json:"traffic_reset_day,omitempty"`
+}
+
+// PingTaskSpec 以 name 匹配
+type PingTaskSpec struct {
+	Name     string   `yaml:"name" json:"name"`
+	Clients  []string `yaml:"clients" json:"clients"`
+	Type     string   `yaml:"type" json:"type"`
+	Target   string   `yaml:"target" json:"target"`
+	Interval int      `yaml:"interval" json:"interval"`
+	Weight   int      `yaml:"weight" json:"weight"`
+}
+
+// SPPingTaskSpec 以 name 匹配
+type SPPingTaskSpec struct {
+	Name        string   `yaml:"name" json:"name"`
+	Clients     []string `yaml:"clients" json:"clients"`
+	Type        string   `yaml:"type" json:"type"`
+	Target      string   `yaml:"target" json:"target"`
+	Step        int      `yaml:"step" json:"step"`
+	Pings       int      `yaml:"pings" json:"pings"`
+	TimeoutMS   int      `yaml:"timeout_ms" json:"timeout_ms"`
+	PayloadSize int      `yaml:"payload_size" json:"payload_size"`
+	Weight      int      `yaml:"weight" json:"weight"`
+}
+
+// LoadNotificationSpec 以 name 匹配
+type LoadNotificationSpec struct {
+	Name      string   `yaml:"name" json:"name"`
+	Clients   []string `yaml:"clients" json:"clients"`
+	Metric    string   `yaml:"metric" json:"metric"`
+	Threshold float32  `yaml:"threshold" json:"threshold"`
+	Ratio     float32  `yaml:"ratio" json:"ratio"`
+	Interval  int      `yaml:"interval" json:"interval"`
+}
+
+// OfflineNotificationSpec 以 client 匹配
+type OfflineNotificationSpec struct {
+	Client      string `yaml:"client" json:"client"`
+	Enable      bool   `yaml:"enable" json:"enable"`
+	GracePeriod int    `yaml:"grace_period" json:"grace_period"`
+}
+
+// ScriptFolderSpec 以路径匹配，路径由各级目录名以 / 连接
+type ScriptFolderSpec struct {
+	Path  string `yaml:"path" json:"path"`
+	Icon  string `yaml:"icon" json:"icon"`
+	Order int    `yaml:"order" json:"order"`
+}
+
+// ScriptSpec 以“目录路径/名称”匹配，依赖同样以路径引用
+type ScriptSpec struct {
+	Folder           string   `yaml:"folder" json:"folder"`
+	Name             string   `yaml:"name" json:"name"`
+	Order            int      `yaml:"order" json:"order"`
+	Enabled          bool     `yaml:"enabled" json:"enabled"`
+	Clients          []string `yaml:"clients" json:"clients"`
+	TimeoutSec       int      `yaml:"timeout_sec" json:"timeout_sec"`
+	TriggerKind      string   `yaml:"trigger_kind" json:"trigger_kind"`
+	CronExpr         string   `yaml:"cron_expr" json:"cron_expr"`
+	TriggerName      string   `yaml:"trigger_name" json:"trigger_name"`
+	MessageType      string   `yaml:"message_type" json:"message_type"`
+	RunnerClient     string   `yaml:"runner_client" json:"runner_client"`
+	DependsOnScripts []string `yaml:"depends_on_scripts" json:"depends_on_scripts"`
+	DependsOnFolders []string `yaml:"depends_on_folders" json:"depends_on_folders"`
+	ScriptBody       string   `yaml:"script_body" json:"script_body"`
+}
+
+// LgAuthorizationSpec 以 name 匹配，使用次数不在文档中管理
+type LgAuthorizationSpec struct {
+	Name      string   `yaml:"name" json:"name"`
+	Remark    string   `yaml:"remark" json:"remark"`
+	Mode      string   `yaml:"mode" json:"mode"`
+	Code      string   `yaml:"code" json:"code"`
+	Nodes     []string `yaml:"nodes" json:"nodes"`
+	Tools     []string `yaml:"tools" json:"tools"`
+	ExpiresAt string   `yaml:"expires_at" json:"expires_at"` // RFC3339，空串表示不过期
+	MaxUsage  *int     `yaml:"max_usage" json:"max_usage"`
+}
+
+// LgToolSettingSpec 以 tool 匹配，只更新不删除
+type LgToolSettingSpec struct {
+	Tool            string `yaml:"tool" json:"tool"`
+	CommandTemplate string `yaml:"command_template" json:"command_template"`
+	TimeoutSeconds  int    `yaml:"timeout_seconds" json:"timeout_seconds"`
+}
+
+// Key 返回脚本的匹配键
+func (s ScriptSpec) Key() string {
+	return joinPath(s.Folder, s.Name)
+}
+
+func joinPath(folder, name string) string {
+	if folder == "" {
+		return name
+	}
+	return folder + "/" + name
+}
+
+// Parse 解析 YAML 或 JSON 文档，未知字段视为错误以便发现拼写问题
+func Parse(data []byte) (Document, error) {
+	var doc Document
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return doc, errors.New("empty document")
+		}
+		return doc, fmt.Errorf("invalid document: %v", err)
+	}
+	if doc.Version > Version {
+		return doc, fmt.Errorf("unsupported document version %d", doc.Version)
+	}
+	return doc, nil
+}
+
+// Marshal 按 format（yaml/json）序列化文档
+func Marshal(doc Document, format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case "", "yaml", "yml":
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case "json":
+		return json.MarshalIndent(doc, "", "  ")
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// formatTime 统一以 UTC RFC3339 表示时间，零值为空串
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// normalizeTime 校验并统一时间字符串，便于与导出结果比较
+func normalizeTime(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, models.GetAppLocation()); err == nil {
+			return formatTime(t), nil
+		}
+	}
+	return "", fmt.Errorf("invalid time %q, expected RFC3339", s)
+}
+
+func parseTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339, s)
+	return t
+}
@@ -0,0 +1,378 @@
+package configcode
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/komari-monitor/komari/database/clients"
+	"github.com/komari-monitor/komari/database/dbcore"
+	"github.com/komari-monitor/komari/database/lg"
+	"github.com/komari-monitor/komari/database/models"
+	"github.com/komari-monitor/komari/database/notification"
+	"github.com/komari-monitor/komari/database/script"
+	"github.com/komari-monitor/komari/database/tasks"
+)
+
+// snapshot 数据库当前状态：导出文档以及各匹配键对应的记录 ID
+type snapshot struct {
+	doc        Document
+	clients    map[string]bool
+	pingIDs    map[string]uint
+	spIDs      map[string]uint
+	loadIDs    map[string]uint
+	lgIDs      map[string]uint
+	folderIDs  map[string]uint
+	scriptIDs  map[string]uint
+	scripts    map[string]models.Script
+	folderPath map[uint]string
+	scriptKey  map[uint]string
+}
+
+// Export 导出当前配置
+func Export() (Document, error) {
+	s, err := load()
+	if err != nil {
+		return Document{}, err
+	}
+	return s.doc, nil
+}
+
+func load() (*snapshot, error) {
+	s := &snapshot{
+		doc:        Document{Version: Version},
+		clients:    map[string]bool{},
+		pingIDs:    map[string]uint{},
+		spIDs:      map[string]uint{},
+		loadIDs:    map[string]uint{},
+		lgIDs:      map[string]uint{},
+		folderIDs:  map[string]uint{},
+		scriptIDs:  map[string]uint{},
+		scripts:    map[string]models.Script{},
+		folderPath: map[uint]string{},
+		scriptKey:  map[uint]string{},
+	}
+	for _, f := range []func() error{
+		s.loadClients, s.loadPingTasks, s.loadSPPingTasks, s.loadLoadNotifications,
+		s.loadOfflineNotifications, s.loadScripts, s.loadLg,
+	} {
+		if err := f(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *snapshot) loadClients() error {
+	list, err := clients.GetAllClientBasicInfo()
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Weight != list[j].Weight {
+			return list[i].Weight < list[j].Weight
+		}
+		return list[i].UUID < list[j].UUID
+	})
+	s.doc.Clients = make([]ClientSpec, 0, len(list))
+	for _, c := range list {
+		s.clients[c.UUID] = true
+		s.doc.Clients = append(s.doc.Clients, clientSpec(c))
+	}
+	return nil
+}
+
+func clientSpec(c models.Client) ClientSpec {
+	tags := splitTags(c.Tags)
+	startedAt := formatTime(c.StartedAt.ToTime())
+	expiredAt := formatTime(c.ExpiredAt.ToTime())
+	return ClientSpec{
+		UUID:             c.UUID,
+		Name:             &c.Name,
+		Group:            &c.Group,
+		Tags:             &tags,
+		Weight:           &c.Weight,
+		Hidden:           &c.Hidden,
+		Remark:           &c.Remark,
+		PublicRemark:     &c.PublicRemark,
+		Price:            &c.Price,
+		BillingCycle:     &c.BillingCycle,
+		AutoRenewal:      &c.AutoRenewal,
+		Currency:         &c.Currency,
+		StartedAt:        &startedAt,
+		ExpiredAt:        &expiredAt,
+		TrafficLimit:     &c.TrafficLimit,
+		TrafficLimitType: &c.TrafficLimitType,
+		TrafficResetDay:  &c.TrafficResetDay,
+	}
+}
+
+func splitTags(tags string) []string {
+	out := []string{}
+	for _, t := range strings.Split(tags, ";") {
+		if t = strings.TrimSpace(t); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func (s *snapshot) loadPingTasks() error {
+	list, err := tasks.GetAllPingTasks()
+	if err != nil {
+		return err
+	}
+	s.doc.PingTasks = make([]PingTaskSpec, 0, len(list))
+	for _, t := range list {
+		if err := addKey(s.pingIDs, "ping task", t.Name, t.Id); err != nil {
+			return err
+		}
+		s.doc.PingTasks = append(s.doc.PingTasks, PingTaskSpec{
+			Name:     t.Name,
+			Clients:  cloneStrings(t.Clients),
+			Type:     t.Type,
+			Target:   t.Target,
+			Interval: t.Interval,
+			Weight:   t.Weight,
+		})
+	}
+	return nil
+}
+
+func (s *snapshot) loadSPPingTasks() error {
+	list, err := tasks.GetAllSPPingTasks()
+	if err != nil {
+		return err
+	}
+	s.doc.SPPingTasks = make([]SPPingTaskSpec, 0, len(list))
+	for _, t := range list {
+		if err := addKey(s.spIDs, "sp ping task", t.Name, t.Id); err != nil {
+			return err
+		}
+		s.doc.SPPingTasks = append(s.doc.SPPingTasks, SPPingTaskSpec{
+			Name:        t.Name,
+			Clients:     cloneStrings(t.Clients),
+			Type:        t.Type,
+			Target:      t.Target,
+			Step:        t.Step,
+			Pings:       t.Pings,
+			TimeoutMS:   t.TimeoutMS,
+			PayloadSize: t.PayloadSize,
+			Weight:      t.Weight,
+		})
+	}
+	return nil
+}
+
+func (s *snapshot) loadLoadNotifications() error {
+	list, err := notification.GetAllLoadNotifications()
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Id < list[j].Id })
+	s.doc.LoadNotifications = make([]LoadNotificationSpec, 0, len(list))
+	for _, n := range list {
+		if err := addKey(s.loadIDs, "load notification", n.Name, n.Id); err != nil {
+			return err
+		}
+		s.doc.LoadNotifications = append(s.doc.LoadNotifications, LoadNotificationSpec{
+			Name:      n.Name,
+			Clients:   cloneStrings(n.Clients),
+			Metric:    n.Metric,
+			Threshold: n.Threshold,
+			Ratio:     n.Ratio,
+			Interval:  n.Interval,
+		})
+	}
+	return nil
+}
+
+func (s *snapshot) loadOfflineNotifications() error {
+	var list []models.OfflineNotification
+	if err := dbcore.GetDBInstance().Order("client asc").Find(&list).Error; err != nil {
+		return err
+	}
+	s.doc.OfflineNotifications = make([]OfflineNotificationSpec, 0, len(list))
+	for _, n := range list {
+		s.doc.OfflineNotifications = append(s.doc.OfflineNotifications, OfflineNotificationSpec{
+			Client:      n.Client,
+			Enable:      n.Enable,
+			GracePeriod: n.GracePeriod,
+		})
+	}
+	return nil
+}
+
+func (s *snapshot) loadScripts() error {
+	folders, err := script.GetAllFolders()
+	if err != nil {
+		return err
+	}
+	byID := make(map[uint]models.ScriptFolder, len(folders))
+	for _, f := range folders {
+		byID[f.ID] = f
+	}
+	var resolve func(id uint, depth int) (string, error)
+	resolve = func(id uint, depth int) (string, error) {
+		if p, ok := s.folderPath[id]; ok {
+			return p, nil
+		}
+		f, ok := byID[id]
+		if !ok || depth > len(folders) {
+			return "", fmt.Errorf("script folder %d has a broken parent chain", id)
+		}
+		if f.Name == "" || strings.Contains(f.Name, "/") {
+			return "", fmt.Errorf("script folder %d has a name that cannot be used in a path: %q", id, f.Name)
+		}
+		p := f.Name
+		// 父目录已被删除的目录视为位于根目录
+		if _, ok := byID[derefUint(f.ParentID)]; ok {
+			parent, err := resolve(*f.ParentID, depth+1)
+			if err != nil {
+				return "", err
+			}
+			p = parent + "/" + f.Name
+		}
+		s.folderPath[id] = p
+		return p, nil
+	}
+	s.doc.ScriptFolders = make([]ScriptFolderSpec, 0, len(folders))
+	for _, f := range folders {
+		p, err := resolve(f.ID, 0)
+		if err != nil {
+			return err
+		}
+		if err := addKey(s.folderIDs, "script folder", p, f.ID); err != nil {
+			return err
+		}
+		s.doc.ScriptFolders = append(s.doc.ScriptFolders, ScriptFolderSpec{Path: p, Icon: f.Icon, Order: f.Order})
+	}
+	sort.SliceStable(s.doc.ScriptFolders, func(i, j int) bool {
+		return s.doc.ScriptFolders[i].Path < s.doc.ScriptFolders[j].Path
+	})
+
+	list, err := script.GetAllScripts()
+	if err != nil {
+		return err
+	}
+	for _, sc := range list {
+		folder := ""
+		if sc.FolderID != nil {
+			// 所属目录已被删除的脚本视为位于根目录
+			folder = s.folderPath[*sc.FolderID]
+		}
+		key := joinPath(folder, sc.Name)
+		if err := addKey(s.scriptIDs, "script", key, sc.ID); err != nil {
+			return err
+		}
+		s.scriptKey[sc.ID] = key
+		s.scripts[key] = sc
+	}
+	s.doc.Scripts = make([]ScriptSpec, 0, len(list))
+	for _, sc := range list {
+		folder := ""
+		if sc.FolderID != nil {
+			folder = s.folderPath[*sc.FolderID]
+		}
+		spec := ScriptSpec{
+			Folder:           folder,
+			Name:             sc.Name,
+			Order:            sc.Order,
+			Enabled:          sc.Enabled,
+			Clients:          cloneStrings(sc.Clients),
+			TimeoutSec:       sc.TimeoutSec,
+			TriggerKind:      sc.TriggerKind,
+			CronExpr:         sc.CronExpr,
+			TriggerName:      sc.TriggerName,
+			MessageType:      sc.MessageType,
+			RunnerClient:     sc.RunnerClient,
+			DependsOnScripts: []string{},
+			DependsOnFolders: []string{},
+			ScriptBody:       sc.ScriptBody,
+		}
+		// 引用已不存在的依赖直接忽略
+		for _, id := range sc.DependsOnScripts {
+			if k, ok := s.scriptKey[id]; ok {
+				spec.DependsOnScripts = append(spec.DependsOnScripts, k)
+			}
+		}
+		for _, id := range sc.DependsOnFolders {
+			if p, ok := s.folderPath[id]; ok {
+				spec.DependsOnFolders = append(spec.DependsOnFolders, p)
+			}
+		}
+		s.doc.Scripts = append(s.doc.Scripts, spec)
+	}
+	sort.SliceStable(s.doc.Scripts, func(i, j int) bool {
+		if s.doc.Scripts[i].Folder != s.doc.Scripts[j].Folder {
+			return s.doc.Scripts[i].Folder < s.doc.Scripts[j].Folder
+		}
+		return s.doc.Scripts[i].Order < s.doc.Scripts[j].Order
+	})
+	return nil
+}
+
+func (s *snapshot) loadLg() error {
+	auths, err := lg.ListAuthorizations(lg.AuthorizationFilter{})
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(auths, func(i, j int) bool { return auths[i].ID < auths[j].ID })
+	s.doc.LgAuthorizations = make([]LgAuthorizationSpec, 0, len(auths))
+	for _, a := range auths {
+		if err := addKey(s.lgIDs, "lg authorization", a.Name, a.ID); err != nil {
+			return err
+		}
+		spec := LgAuthorizationSpec{
+			Name:     a.Name,
+			Remark:   a.Remark,
+			Mode:     a.Mode,
+			Code:     a.Code,
+			Nodes:    cloneStrings(a.Nodes),
+			Tools:    cloneStrings(a.Tools),
+			MaxUsage: a.MaxUsage,
+		}
+		if a.ExpiresAt != nil {
+			spec.ExpiresAt = formatTime(a.ExpiresAt.ToTime())
+		}
+		s.doc.LgAuthorizations = append(s.doc.LgAuthorizations, spec)
+	}
+
+	settings, err := lg.ListToolSettings()
+	if err != nil {
+		return err
+	}
+	s.doc.LgToolSettings = make([]LgToolSettingSpec, 0, len(settings))
+	for _, t := range settings {
+		s.doc.LgToolSettings = append(s.doc.LgToolSettings, LgToolSettingSpec{
+			Tool:            t.Tool,
+			CommandTemplate: t.CommandTemplate,
+			TimeoutSeconds:  t.TimeoutSeconds,
+		})
+	}
+	return nil
+}
+
+func derefUint(v *uint) uint {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+// addKey 记录匹配键，重名时无法一一对应，要求先改名
+func addKey(m map[string]uint, kind, key string, id uint) error {
+	if _, ok := m[key]; ok {
+		return fmt.Errorf("duplicate %s name %q in database, rename it before using config-as-code", kind, key)
+	}
+	m[key] = id
+	return nil
+}
+
+// cloneStrings 将 nil 统一为空切片，保证导出与比较结果一致
+func cloneStrings(v []string) []string {
+	if v == nil {
+		return []string{}
+	}
+	return append([]string{}, v...)
+}
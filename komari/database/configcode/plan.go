@@ -0,0 +1,445 @@
+package configcode
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// 变更动作
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// 分组名称，与文档字段一致
+const (
+	KindClient              = "clients"
+	KindPingTask            = "ping_tasks"
+	KindSPPingTask          = "sp_ping_tasks"
+	KindLoadNotification    = "load_notifications"
+	KindOfflineNotification = "offline_notifications"
+	KindScriptFolder        = "script_folders"
+	KindScript              = "scripts"
+	KindLgAuthorization     = "lg_authorizations"
+	KindLgToolSetting       = "lg_tool_settings"
+)
+
+// FieldChange 单个字段的新旧值
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// Change 单个条目的变更
+type Change struct {
+	Kind   string                 `json:"kind"`
+	Key    string                 `json:"key"`
+	Action string                 `json:"action"`
+	Fields map[string]FieldChange `json:"fields,omitempty"`
+}
+
+// Summary 各动作数量
+type Summary struct {
+	Create int `json:"create"`
+	Update int `json:"update"`
+	Delete int `json:"delete"`
+}
+
+// Summarize 统计变更数量
+func Summarize(changes []Change) Summary {
+	var s Summary
+	for _, c := range changes {
+		switch c.Action {
+		case ActionCreate:
+			s.Create++
+		case ActionUpdate:
+			s.Update++
+		case ActionDelete:
+			s.Delete++
+		}
+	}
+	return s
+}
+
+// entry 参与比较的条目
+type entry struct {
+	key  string
+	spec any
+}
+
+// diff 比较同一分组的当前与期望条目。partial 为 true 时只比较期望中出现的字段，
+// prune 为 true 时删除期望中未列出的条目
+func diff(kind string, current, desired []entry, partial, prune bool) ([]Change, error) {
+	cur := make(map[string]map[string]any, len(current))
+	for _, e := range current {
+		m, err := toMap(e.spec)
+		if err != nil {
+			return nil, err
+		}
+		cur[e.key] = m
+	}
+	var changes []Change
+	seen := make(map[string]bool, len(desired))
+	for _, e := range desired {
+		seen[e.key] = true
+		want, err := toMap(e.spec)
+		if err != nil {
+			return nil, err
+		}
+		have, ok := cur[e.key]
+		if !ok {
+			fields := make(map[string]FieldChange, len(want))
+			for k, v := range want {
+				fields[k] = FieldChange{To: v}
+			}
+			changes = append(changes, Change{Kind: kind, Key: e.key, Action: ActionCreate, Fields: fields})
+			continue
+		}
+		fields := map[string]FieldChange{}
+		for k, v := range want {
+			if !reflect.DeepEqual(have[k], v) {
+				fields[k] = FieldChange{From: have[k], To: v}
+			}
+		}
+		if !partial {
+			for k, v := range have {
+				if _, ok := want[k]; !ok && v != nil {
+					fields[k] = FieldChange{From: v}
+				}
+			}
+		}
+		if len(fields) > 0 {
+			changes = append(changes, Change{Kind: kind, Key: e.key, Action: ActionUpdate, Fields: fields})
+		}
+	}
+	if prune {
+		for _, e := range current {
+			if !seen[e.key] {
+				changes = append(changes, Change{Kind: kind, Key: e.key, Action: ActionDelete})
+			}
+		}
+	}
+	return changes, nil
+}
+
+// toMap 经 JSON 转换为通用结构，数值统一为 float64 以便比较
+func toMap(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func entries[T any](list []T, key func(T) string) []entry {
+	out := make([]entry, 0, len(list))
+	for _, v := range list {
+		out = append(out, entry{key: key(v), spec: v})
+	}
+	return out
+}
+
+// normalize 补齐与数据库默认值一致的字段，并检查匹配键，保证重复应用时不产生差异
+func normalize(doc *Document, current *snapshot, prune bool) error {
+	if doc.Version == 0 {
+		doc.Version = Version
+	}
+	keys := map[string]bool{}
+	unique := func(kind, key string) error {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("%s: name must not be empty", kind)
+		}
+		if keys[kind+"\x00"+key] {
+			return fmt.Errorf("%s: duplicate key %q", kind, key)
+		}
+		keys[kind+"\x00"+key] = true
+		return nil
+	}
+
+	for i := range doc.Clients {
+		c := &doc.Clients[i]
+		if err := unique(KindClient, c.UUID); err != nil {
+			return err
+		}
+		if !current.clients[c.UUID] {
+			return fmt.Errorf("%s: unknown client %q", KindClient, c.UUID)
+		}
+		if c.Tags != nil {
+			tags := splitTags(strings.Join(*c.Tags, ";"))
+			c.Tags = &tags
+		}
+		for _, t := range []*string{c.StartedAt, c.ExpiredAt} {
+			if t == nil {
+				continue
+			}
+			v, err := normalizeTime(*t)
+			if err != nil {
+				return fmt.Errorf("%s %s: %v", KindClient, c.UUID, err)
+			}
+			*t = v
+		}
+	}
+	for i := range doc.PingTasks {
+		t := &doc.PingTasks[i]
+		if err := unique(KindPingTask, t.Name); err != nil {
+			return err
+		}
+		t.Clients = cloneStrings(t.Clients)
+		if t.Type == "" {
+			t.Type = "icmp"
+		}
+		if t.Interval <= 0 {
+			t.Interval = 60
+		}
+	}
+	for i := range doc.SPPingTasks {
+		t := &doc.SPPingTasks[i]
+		if err := unique(KindSPPingTask, t.Name); err != nil {
+			return err
+		}
+		t.Clients = cloneStrings(t.Clients)
+		if t.Type == "" {
+			t.Type = "icmp"
+		}
+		if t.Step <= 0 {
+			t.Step = 300
+		}
+		if t.Pings <= 0 {
+			t.Pings = 20
+		}
+		if t.TimeoutMS <= 0 {
+			t.TimeoutMS = 1000
+		}
+		if t.PayloadSize <= 0 {
+			t.PayloadSize = 56
+		}
+	}
+	for i := range doc.LoadNotifications {
+		n := &doc.LoadNotifications[i]
+		if err := unique(KindLoadNotification, n.Name); err != nil {
+			return err
+		}
+		n.Clients = cloneStrings(n.Clients)
+		if n.Metric == "" {
+			n.Metric = "cpu"
+		}
+		if n.Threshold <= 0 {
+			n.Threshold = 80
+		}
+		if n.Ratio <= 0 {
+			n.Ratio = 0.8
+		}
+		if n.Interval <= 0 {
+			n.Interval = 15
+		}
+	}
+	for i := range doc.OfflineNotifications {
+		n := &doc.OfflineNotifications[i]
+		if err := unique(KindOfflineNotification, n.Client); err != nil {
+			return err
+		}
+		if !current.clients[n.Client] {
+			return fmt.Errorf("%s: unknown client %q", KindOfflineNotification, n.Client)
+		}
+		if n.GracePeriod <= 0 {
+			n.GracePeriod = 180
+		}
+	}
+
+	// 目录与脚本引用可指向文档中或（非 prune 时）数据库中已有的条目
+	folders := map[string]bool{}
+	for i := range doc.ScriptFolders {
+		f := &doc.ScriptFolders[i]
+		f.Path = strings.Trim(f.Path, "/")
+		if err := unique(KindScriptFolder, f.Path); err != nil {
+			return err
+		}
+		for _, seg := range strings.Split(f.Path, "/") {
+			if strings.TrimSpace(seg) == "" {
+				return fmt.Errorf("%s: invalid path %q", KindScriptFolder, f.Path)
+			}
+		}
+		folders[f.Path] = true
+	}
+	folderExists := func(p string) bool {
+		return folders[p] || (doc.ScriptFolders == nil || !prune) && current.folderIDs[p] != 0
+	}
+	for _, f := range doc.ScriptFolders {
+		if i := strings.LastIndex(f.Path, "/"); i >= 0 && !folderExists(f.Path[:i]) {
+			return fmt.Errorf("%s %q: parent folder not found", KindScriptFolder, f.Path)
+		}
+	}
+	scripts := map[string]bool{}
+	for i := range doc.Scripts {
+		s := &doc.Scripts[i]
+		s.Folder = strings.Trim(s.Folder, "/")
+		if strings.TrimSpace(s.Name) == "" {
+			return fmt.Errorf("%s: name must not be empty", KindScript)
+		}
+		if err := unique(KindScript, s.Key()); err != nil {
+			return err
+		}
+		if s.Folder != "" && !folderExists(s.Folder) {
+			return fmt.Errorf("%s %q: folder not found", KindScript, s.Key())
+		}
+		s.Clients = cloneStrings(s.Clients)
+		s.DependsOnScripts = cloneStrings(s.DependsOnScripts)
+		s.DependsOnFolders = cloneStrings(s.DependsOnFolders)
+		if s.MessageType == "" {
+			s.MessageType = "script"
+		}
+		scripts[s.Key()] = true
+	}
+	for _, s := range doc.Scripts {
+		for _, dep := range s.DependsOnScripts {
+			if !scripts[dep] && (prune || current.scriptIDs[dep] == 0) {
+				return fmt.Errorf("%s %q: dependency script %q not found", KindScript, s.Key(), dep)
+			}
+		}
+		for _, dep := range s.DependsOnFolders {
+			if !folderExists(dep) {
+				return fmt.Errorf("%s %q: dependency folder %q not found", KindScript, s.Key(), dep)
+			}
+		}
+	}
+
+	for i := range doc.LgAuthorizations {
+		a := &doc.LgAuthorizations[i]
+		if err := unique(KindLgAuthorization, a.Name); err != nil {
+			return err
+		}
+		a.Mode = strings.ToLower(a.Mode)
+		if a.Mode == "public" {
+			a.Code = ""
+		}
+		a.Nodes = cloneStrings(a.Nodes)
+		a.Tools = cloneStrings(a.Tools)
+		for j, t := range a.Tools {
+			a.Tools[j] = strings.ToLower(t)
+		}
+		v, err := normalizeTime(a.ExpiresAt)
+		if err != nil {
+			return fmt.Errorf("%s %q: %v", KindLgAuthorization, a.Name, err)
+		}
+		a.ExpiresAt = v
+	}
+	for i := range doc.LgToolSettings {
+		t := &doc.LgToolSettings[i]
+		t.Tool = strings.ToLower(t.Tool)
+		if err := unique(KindLgToolSetting, t.Tool); err != nil {
+			return err
+		}
+		if t.TimeoutSeconds <= 0 {
+			t.TimeoutSeconds = 30
+		}
+	}
+	return nil
+}
+
+// plan 计算变更，只比较文档中出现的分组
+func plan(doc *Document, current *snapshot, prune bool) ([]Change, error) {
+	if err := normalize(doc, current, prune); err != nil {
+		return nil, err
+	}
+	cur := current.doc
+	type section struct {
+		kind             string
+		present          bool
+		current, desired []entry
+		partial, prune   bool
+	}
+	sections := []section{
+		{KindClient, doc.Clients != nil,
+			entries(cur.Clients, func(c ClientSpec) string { return c.UUID }),
+			entries(doc.Clients, func(c ClientSpec) string { return c.UUID }), true, false},
+		{KindPingTask, doc.PingTasks != nil,
+			entries(cur.PingTasks, func(t PingTaskSpec) string { return t.Name }),
+			entries(doc.PingTasks, func(t PingTaskSpec) string { return t.Name }), false, prune},
+		{KindSPPingTask, doc.SPPingTasks != nil,
+			entries(cur.SPPingTasks, func(t SPPingTaskSpec) string { return t.Name }),
+			entries(doc.SPPingTasks, func(t SPPingTaskSpec) string { return t.Name }), false, prune},
+		{KindLoadNotification, doc.LoadNotifications != nil,
+			entries(cur.LoadNotifications, func(n LoadNotificationSpec) string { return n.Name }),
+			entries(doc.LoadNotifications, func(n LoadNotificationSpec) string { return n.Name }), false, prune},
+		{KindOfflineNotification, doc.OfflineNotifications != nil,
+			entries(cur.OfflineNotifications, func(n OfflineNotificationSpec) string { return n.Client }),
+			entries(doc.OfflineNotifications, func(n OfflineNotificationSpec) string { return n.Client }), false, prune},
+		{KindScriptFolder, doc.ScriptFolders != nil,
+			entries(cur.ScriptFolders, func(f ScriptFolderSpec) string { return f.Path }),
+			entries(doc.ScriptFolders, func(f ScriptFolderSpec) string { return f.Path }), false, prune},
+		{KindScript, doc.Scripts != nil,
+			entries(cur.Scripts, ScriptSpec.Key),
+			entries(doc.Scripts, ScriptSpec.Key), false, prune},
+		{KindLgAuthorization, doc.LgAuthorizations != nil,
+			entries(cur.LgAuthorizations, func(a LgAuthorizationSpec) string { return a.Name }),
+			entries(doc.LgAuthorizations, func(a LgAuthorizationSpec) string { return a.Name }), false, prune},
+		{KindLgToolSetting, doc.LgToolSettings != nil,
+			entries(cur.LgToolSettings, func(t LgToolSettingSpec) string { return t.Tool }),
+			entries(doc.LgToolSettings, func(t LgToolSettingSpec) string { return t.Tool }), false, false},
+	}
+	changes := []Change{}
+	for _, s := range sections {
+		if !s.present {
+			continue
+		}
+		c, err := diff(s.kind, s.current, s.desired, s.partial, s.prune)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, c...)
+	}
+	return changes, nil
+}
+
+// Plan 计算将文档应用到当前数据库所需的变更，不做任何修改
+func Plan(doc Document, prune bool) ([]Change, error) {
+	current, err := load()
+	if err != nil {
+		return nil, err
+	}
+	return plan(&doc, current, prune)
+}
+
+// byAction 按动作与键筛选某分组的变更
+func byAction(changes []Change, kind, action string) []Change {
+	var out []Change
+	for _, c := range changes {
+		if c.Kind == kind && c.Action == action {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// changedKeys 返回某分组中新建或更新的键
+func changedKeys(changes []Change, kind string) map[string]Change {
+	out := map[string]Change{}
+	for _, c := range changes {
+		if c.Kind == kind && c.Action != ActionDelete {
+			out[c.Key] = c
+		}
+	}
+	return out
+}
+
+// pathDepth 目录层级，用于父目录先于子目录创建、子目录先于父目录删除
+func pathDepth(p string) int {
+	return strings.Count(p, "/")
+}
+
+func sortByDepth(keys []string, desc bool) {
+	sort.SliceStable(keys, func(i, j int) bool {
+		if desc {
+			return pathDepth(keys[i]) > pathDepth(keys[j])
+		}
+		return pathDepth(keys[i]) < pathDepth(keys[j])
+	})
+}
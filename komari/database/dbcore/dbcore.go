@@ -355,6 +355,13 @@ func MergeDatabase(db *gorm.DB) {
 	migrateSPPingLatencyColumns(db)
 }
 
+// migrateEmptyRoles 角色字段出现之前创建的用户均为管理员，显式补齐，空角色不再视为管理员
+func migrateEmptyRoles(db *gorm.DB) {
+	if err := db.Model(&models.User{}).Where("role = '' OR role IS NULL").Update("role", models.RoleAdmin).Error; err != nil {
+		log.Printf("Failed to migrate empty user roles: %v", err)
+	}
+}
+
 func migrateSPPingLatencyColumns(db *gorm.DB) {
 	if db == nil || !db.Migrator().HasTable(&models.SPPingRecord{}) {
 		return
@@ -393,6 +400,13 @@ func migrateSPPingLatencyColumns(db *gorm.DB) {
 				log.Printf("failed to alter sp_ping_records.%s to double: %v", col, err)
 			}
 		}
+	case "postgres":
+		for _, col := range needChange {
+			sql := fmt.Sprintf("ALTER TABLE sp_ping_records ALTER COLUMN %s TYPE DOUBLE PRECISION USING %s::double precision", col, col)
+			if err := db.Exec(sql).Error; err != nil {
+				log.Printf("failed to alter sp_ping_records.%s to double: %v", col, err)
+			}
+		}
 	default:
 		log.Printf("[sp-ping] latency columns detected as integer on %s, skip alter; SQLite will still store floats on INTEGER columns", db.Dialector.Name())
 	}
@@ -483,6 +497,13 @@ func GetDBInstance() *gorm.DB {
 				log.Fatalf("Failed to connect to MySQL database: %v", err)
 			}
 			log.Printf("Using MySQL database: %s@%s:%s/%s", flags.DatabaseUser, flags.DatabaseHost, flags.DatabasePort, flags.DatabaseName)
+		case "postgres", "postgresql":
+			// PostgreSQL 连接
+			instance, err = gorm.Open(newPostgresDialector(), logConfig)
+			if err != nil {
+				log.Fatalf("Failed to connect to PostgreSQL database: %v", err)
+			}
+			log.Printf("Using PostgreSQL database: %s@%s:%s/%s", flags.DatabaseUser, flags.DatabaseHost, flags.DatabasePort, flags.DatabaseName)
 		default:
 			log.Fatalf("Unsupported database type: %s", flags.DatabaseType)
 		}
@@ -490,17 +511,32 @@ func GetDBInstance() *gorm.DB {
 		// 自动迁移模型
 		err = instance.AutoMigrate(
 			&models.User{},
+			&models.ApiToken{},
 			&models.Client{},
+			&models.BillingRecord{},
 			&models.Credential{},
 			&models.InstallScript{},
 			&models.Record{},
 			&models.GPURecord{},
+			&models.TemperatureRecord{},
+			&models.DiskRecord{},
+			&models.NetworkRecord{},
+			&models.TrafficLedger{},
 			&models.LgAuthorization{},
 			&models.LgToolSetting{},
 			&models.Config{},
 			&models.Log{},
+			&models.TerminalRecording{},
+			&models.SSHJob{},
+			&models.SSHJobTarget{},
+			&models.SSHKnownHost{},
 			&models.Clipboard{},
 			&models.LoadNotification{},
+			&models.AlertRule{},
+			&models.AlertIncident{},
+			&models.MaintenanceWindow{},
+			&models.MessageChannel{},
+			&models.MessageRoute{},
 			&models.OfflineNotification{},
 			&models.PingRecord{},
 			&models.PingTask{},
@@ -520,6 +556,7 @@ func GetDBInstance() *gorm.DB {
 		if err != nil {
 			log.Fatalf("Failed to create tables: %v", err)
 		}
+		migrateEmptyRoles(instance)
 		err = instance.Table("records_long_term").AutoMigrate(
 			&models.Record{},
 		)
@@ -532,6 +569,24 @@ func GetDBInstance() *gorm.DB {
 		if err != nil {
 			log.Printf("Failed to create gpu_records_long_term table, it may already exist: %v", err)
 		}
+		err = instance.Table("temperature_records_long_term").AutoMigrate(
+			&models.TemperatureRecord{},
+		)
+		if err != nil {
+			log.Printf("Failed to create temperature_records_long_term table, it may already exist: %v", err)
+		}
+		err = instance.Table("disk_records_long_term").AutoMigrate(
+			&models.DiskRecord{},
+		)
+		if err != nil {
+			log.Printf("Failed to create disk_records_long_term table, it may already exist: %v", err)
+		}
+		err = instance.Table("network_records_long_term").AutoMigrate(
+			&models.NetworkRecord{},
+		)
+		if err != nil {
+			log.Printf("Failed to create network_records_long_term table, it may already exist: %v", err)
+		}
 		err = instance.AutoMigrate(
 			&models.Session{},
 		)
@@ -545,6 +600,8 @@ func GetDBInstance() *gorm.DB {
 		if err != nil {
 			log.Printf("Failed to create Task and TaskResult table, it may already exist: %v", err)
 		}
+		// 表结构就绪后导入备份包中的逻辑备份（MySQL/PostgreSQL）
+		restoreLogicalDump(instance)
 
 	})
 	return instance
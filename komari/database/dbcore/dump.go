@@ -0,0 +1,278 @@
+package dbcore
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// LogicalDumpFile 逻辑备份在备份包中的文件名，恢复时解压到 ./data 下
+const LogicalDumpFile = "komari-dump.jsonl"
+
+const dumpInsertBatch = 200
+
+// dumpLine 逻辑备份的一行：先写表头（Columns），随后每行数据一条（Values）
+type dumpLine struct {
+	Table   string   `json:"table"`
+	Columns []string `json:"columns,omitempty"`
+	Values  []any    `json:"values,omitempty"`
+}
+
+// 无法直接用 JSON 表示的值
+type dumpTime struct {
+	Time time.Time `json:"$time"`
+}
+
+type dumpBytes struct {
+	Bytes string `json:"$bytes"`
+}
+
+// DumpDatabase 将所有表导出为 JSON Lines，用于 MySQL/PostgreSQL 等无法直接复制文件的数据库。
+// 导出在只读事务中进行，保证各表数据一致
+func DumpDatabase(db *gorm.DB, w io.Writer) error {
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		return fmt.Errorf("failed to list tables: %v", err)
+	}
+	sort.Strings(tables)
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range tables {
+			if err := dumpTable(tx, table, enc); err != nil {
+				return fmt.Errorf("failed to dump table %s: %v", table, err)
+			}
+		}
+		return nil
+	}, &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func dumpTable(tx *gorm.DB, table string, enc *json.Encoder) error {
+	rows, err := tx.Table(table).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if err := enc.Encode(dumpLine{Table: table, Columns: columns}); err != nil {
+		return err
+	}
+	values := make([]any, len(columns))
+	ptrs := make([]any, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		line := dumpLine{Table: table, Values: make([]any, len(values))}
+		for i, v := range values {
+			line.Values[i] = encodeDumpValue(v)
+		}
+		if err := enc.Encode(line); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func encodeDumpValue(v any) any {
+	switch val := v.(type) {
+	case time.Time:
+		return dumpTime{Time: val}
+	case []byte:
+		if utf8.Valid(val) {
+			return string(val)
+		}
+		return dumpBytes{Bytes: base64.StdEncoding.EncodeToString(val)}
+	default:
+		return v
+	}
+}
+
+func decodeDumpValue(v any) (any, error) {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(val.String(), 10, 64); err == nil {
+			return i, nil
+		}
+		return strconv.ParseFloat(val.String(), 64)
+	case map[string]any:
+		if s, ok := val["$time"].(string); ok {
+			return time.Parse(time.RFC3339Nano, s)
+		}
+		if s, ok := val["$bytes"].(string); ok {
+			return base64.StdEncoding.DecodeString(s)
+		}
+		return nil, fmt.Errorf("unknown value: %v", val)
+	default:
+		return v, nil
+	}
+}
+
+// RestoreDatabase 导入 DumpDatabase 生成的数据。
+// 备份中出现的表会先清空再写入，未出现的表保持不变；任一步失败时整体回滚
+func RestoreDatabase(db *gorm.DB, r io.Reader) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var restored []string
+	err := withoutForeignKeys(db, func(conn *gorm.DB) error {
+		return restoreTables(conn, dec, &restored)
+	})
+	if err != nil {
+		return err
+	}
+	if db.Dialector.Name() == "postgres" {
+		// 显式写入了自增主键，需要同步序列
+		for _, table := range restored {
+			if !db.Migrator().HasColumn(table, "id") {
+				continue
+			}
+			db.Exec(fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', 'id'), COALESCE((SELECT MAX(id) FROM %s), 0) + 1, false) WHERE pg_get_serial_sequence('%s', 'id') IS NOT NULL",
+				table, db.Statement.Quote(table), table))
+		}
+	}
+	return nil
+}
+
+// restoreTables 在一个事务中逐表清空并写入，任一步失败时整体回滚
+func restoreTables(db *gorm.DB, dec *json.Decoder, restored *[]string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var (
+			table   string
+			columns []string
+			batch   []map[string]any
+			skip    bool
+		)
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			err := tx.Table(table).Create(batch).Error
+			batch = batch[:0]
+			return err
+		}
+		for {
+			var line dumpLine
+			if err := dec.Decode(&line); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return fmt.Errorf("invalid dump: %v", err)
+			}
+			if line.Columns != nil {
+				if err := flush(); err != nil {
+					return fmt.Errorf("failed to restore table %s: %v", table, err)
+				}
+				table, columns = line.Table, line.Columns
+				// 旧版本遗留的表在当前结构中已不存在，跳过
+				skip = !tx.Migrator().HasTable(table)
+				if skip {
+					log.Printf("[restore] table %s does not exist, skipped", table)
+					continue
+				}
+				if err := tx.Exec("DELETE FROM " + tx.Statement.Quote(table)).Error; err != nil {
+					return fmt.Errorf("failed to clear table %s: %v", table, err)
+				}
+				*restored = append(*restored, table)
+				continue
+			}
+			if skip {
+				continue
+			}
+			if line.Table != table || len(line.Values) != len(columns) {
+				return fmt.Errorf("invalid dump row for table %s", line.Table)
+			}
+			row := make(map[string]any, len(columns))
+			for i, col := range columns {
+				v, err := decodeDumpValue(line.Values[i])
+				if err != nil {
+					return fmt.Errorf("invalid value for %s.%s: %v", table, col, err)
+				}
+				row[col] = v
+			}
+			batch = append(batch, row)
+			if len(batch) >= dumpInsertBatch {
+				if err := flush(); err != nil {
+					return fmt.Errorf("failed to restore table %s: %v", table, err)
+				}
+			}
+		}
+		if err := flush(); err != nil {
+			return fmt.Errorf("failed to restore table %s: %v", table, err)
+		}
+		return nil
+	})
+}
+
+// withoutForeignKeys 在同一连接上关闭外键检查后执行 fn：备份按表名顺序恢复，子表可能先于父表写入，
+// 清空父表时也不能级联删除已恢复的子表。PostgreSQL 需要超级用户权限，无权限时保持检查开启
+func withoutForeignKeys(db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	return db.Connection(func(conn *gorm.DB) error {
+		var disable, enable string
+		switch conn.Dialector.Name() {
+		case "sqlite":
+			var on int
+			if err := conn.Raw("PRAGMA foreign_keys").Scan(&on).Error; err != nil || on == 0 {
+				return fn(conn)
+			}
+			// 事务内修改 foreign_keys 无效，必须在事务开始前设置
+			disable, enable = "PRAGMA foreign_keys = OFF", "PRAGMA foreign_keys = ON"
+		case "mysql":
+			disable, enable = "SET FOREIGN_KEY_CHECKS = 0", "SET FOREIGN_KEY_CHECKS = 1"
+		case "postgres":
+			disable, enable = "SET session_replication_role = replica", "RESET session_replication_role"
+		default:
+			return fn(conn)
+		}
+		if err := conn.Exec(disable).Error; err != nil {
+			log.Printf("[restore] failed to disable foreign key checks: %v", err)
+			return fn(conn)
+		}
+		defer conn.Exec(enable)
+		return fn(conn)
+	})
+}
+
+// restoreLogicalDump 恢复备份包中解压出的逻辑备份，成功后删除文件
+func restoreLogicalDump(db *gorm.DB) {
+	path := filepath.Join(".", "data", LogicalDumpFile)
+	f, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[restore] failed to read %s: %v", LogicalDumpFile, err)
+		}
+		return
+	}
+	err = RestoreDatabase(db, bufio.NewReader(f))
+	f.Close()
+	if err != nil {
+		// 保留文件，便于排查后重试
+		log.Printf("[restore] failed to import %s: %v", LogicalDumpFile, err)
+		return
+	}
+	log.Printf("[restore] %s imported", LogicalDumpFile)
+	if err := os.Remove(path); err != nil {
+		log.Printf("[restore] failed to remove %s: %v", LogicalDumpFile, err)
+	}
+}
@@ -0,0 +1,85 @@
+package dbcore
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/komari-monitor/komari/database/models"
+)
+
+func TestDumpAndRestoreDatabase(t *testing.T) {
+	src, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, src.AutoMigrate(&models.Clipboard{}, &models.Log{}))
+	now := time.Now().Truncate(time.Second)
+	assert.NoError(t, src.Create(&models.Clipboard{Name: "hello", Text: "echo 中文", Weight: 3}).Error)
+	assert.NoError(t, src.Create(&models.Log{IP: "127.0.0.1", Message: "login", MsgType: "info", Time: models.FromTime(now)}).Error)
+
+	var buf bytes.Buffer
+	assert.NoError(t, DumpDatabase(src, &buf))
+
+	dst, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, dst.AutoMigrate(&models.Clipboard{}, &models.Log{}))
+	// 恢复前已有的数据会被替换
+	assert.NoError(t, dst.Create(&models.Clipboard{Name: "stale"}).Error)
+	assert.NoError(t, RestoreDatabase(dst, &buf))
+
+	var clips []models.Clipboard
+	assert.NoError(t, dst.Find(&clips).Error)
+	if assert.Len(t, clips, 1) {
+		assert.Equal(t, "hello", clips[0].Name)
+		assert.Equal(t, "echo 中文", clips[0].Text)
+		assert.Equal(t, 3, clips[0].Weight)
+	}
+	var logs []models.Log
+	assert.NoError(t, dst.Find(&logs).Error)
+	if assert.Len(t, logs, 1) {
+		assert.True(t, logs[0].Time.ToTime().Equal(now))
+	}
+}
+
+// 有外键的表按字母序恢复时子表在父表之前，全新库插入与旧库清空都不能触发外键检查或级联删除
+func TestRestoreDatabaseWithForeignKeys(t *testing.T) {
+	tables := []any{&models.Client{}, &models.Task{}, &models.TaskResult{}, &models.PingTask{}, &models.PingRecord{}}
+	open := func() *gorm.DB {
+		db, err := gorm.Open(sqlite.Open(":memory:?_foreign_keys=1"), &gorm.Config{})
+		assert.NoError(t, err)
+		assert.NoError(t, db.AutoMigrate(tables...))
+		return db
+	}
+	src := open()
+	now := time.Now().Truncate(time.Second)
+	assert.NoError(t, src.Create(&models.Client{UUID: "c1", Token: "t"}).Error)
+	assert.NoError(t, src.Create(&models.Task{TaskId: "task1", Command: "uptime"}).Error)
+	assert.NoError(t, src.Create(&models.TaskResult{TaskId: "task1", Client: "c1", Result: "ok", CreatedAt: models.FromTime(now)}).Error)
+	assert.NoError(t, src.Create(&models.PingTask{Id: 1, Name: "p", Target: "1.1.1.1"}).Error)
+	assert.NoError(t, src.Create(&models.PingRecord{Client: "c1", TaskId: 1, Time: models.FromTime(now), Value: 10}).Error)
+	var buf bytes.Buffer
+	assert.NoError(t, DumpDatabase(src, &buf))
+	dump := buf.Bytes()
+
+	check := func(db *gorm.DB) {
+		var results, pings int64
+		db.Model(&models.TaskResult{}).Count(&results)
+		db.Model(&models.PingRecord{}).Count(&pings)
+		assert.Equal(t, int64(1), results)
+		assert.Equal(t, int64(1), pings)
+		var fk int
+		db.Raw("PRAGMA foreign_keys").Scan(&fk)
+		assert.Equal(t, 1, fk, "foreign keys are re-enabled after restore")
+	}
+
+	fresh := open()
+	assert.NoError(t, RestoreDatabase(fresh, bytes.NewReader(dump)))
+	check(fresh)
+
+	// 覆盖已有数据时不能级联清空已恢复的子表
+	assert.NoError(t, RestoreDatabase(fresh, bytes.NewReader(dump)))
+	check(fresh)
+}
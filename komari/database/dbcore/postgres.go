@@ -0,0 +1,56 @@
+package dbcore
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/komari-monitor/komari/cmd/flags"
+	"github.com/komari-monitor/komari/database/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+// postgresDialector 将模型中 MySQL 风格的列类型映射为 PostgreSQL 可用的类型，
+// 避免为每个模型单独维护标签
+type postgresDialector struct {
+	*postgres.Dialector
+}
+
+func newPostgresDialector() gorm.Dialector {
+	return postgresDialector{postgres.Open(postgresDSN()).(*postgres.Dialector)}
+}
+
+// postgresDSN 会话时区与应用时区一致，LocalTime 以无时区字符串写入时才能被正确解释
+func postgresDSN() string {
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(flags.DatabaseUser, flags.DatabasePass),
+		Host:   flags.DatabaseHost + ":" + flags.DatabasePort,
+		Path:   "/" + flags.DatabaseName,
+	}
+	q := url.Values{}
+	q.Set("sslmode", "prefer")
+	q.Set("TimeZone", models.GetAppLocation().String())
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func (d postgresDialector) DataTypeOf(field *schema.Field) string {
+	switch strings.ToLower(string(field.DataType)) {
+	case "longtext", "mediumtext":
+		return "text"
+	case "double":
+		return "double precision"
+	}
+	return d.Dialector.DataTypeOf(field)
+}
+
+func (d postgresDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return postgres.Migrator{Migrator: migrator.Migrator{Config: migrator.Config{
+		DB:                          db,
+		Dialector:                   d,
+		CreateIndexAfterCreateTable: true,
+	}}}
+}
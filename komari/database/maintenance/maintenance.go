@@ -0,0 +1,226 @@
+package maintenance
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/komari-monitor/komari/database/dbcore"
+	"github.com/komari-monitor/komari/database/models"
+	"gorm.io/gorm"
+)
+
+const (
+	RecurrenceOnce   = "once"
+	RecurrenceDaily  = "daily"
+	RecurrenceWeekly = "weekly"
+)
+
+var (
+	cacheMu sync.RWMutex
+	cache   []models.MaintenanceWindow
+	loaded  bool
+)
+
+// Validate 校验窗口配置并补全默认值
+func Validate(w *models.MaintenanceWindow) error {
+	w.Name = strings.TrimSpace(w.Name)
+	if w.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if w.Recurrence == "" {
+		w.Recurrence = RecurrenceOnce
+	}
+	switch w.Recurrence {
+	case RecurrenceOnce:
+		if w.StartAt == nil || w.EndAt == nil || !w.EndAt.ToTime().After(w.StartAt.ToTime()) {
+			return fmt.Errorf("one-off window requires start_at before end_at")
+		}
+	case RecurrenceDaily, RecurrenceWeekly:
+		if _, err := time.Parse("15:04", w.StartTime); err != nil {
+			return fmt.Errorf("start_time must be HH:MM")
+		}
+		if w.Duration <= 0 || w.Duration > 7*24*60 {
+			return fmt.Errorf("duration must be between 1 and 10080 minutes")
+		}
+		if w.Recurrence == RecurrenceWeekly && len(parseWeekdays(w.Weekdays)) == 0 {
+			return fmt.Errorf("weekly window requires weekdays")
+		}
+	default:
+		return fmt.Errorf("invalid recurrence: %s", w.Recurrence)
+	}
+	for _, field := range []*models.StringArray{&w.Clients, &w.Groups, &w.Tags} {
+		if *field == nil {
+			*field = models.StringArray{}
+		}
+	}
+	return nil
+}
+
+func List() ([]models.MaintenanceWindow, error) {
+	var list []models.MaintenanceWindow
+	if err := dbcore.GetDBInstance().Order("id desc").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func Get(id uint) (*models.MaintenanceWindow, error) {
+	var w models.MaintenanceWindow
+	if err := dbcore.GetDBInstance().First(&w, id).Error; err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// Save ID 为 0 时新建，否则整体更新
+func Save(w *models.MaintenanceWindow) error {
+	if err := Validate(w); err != nil {
+		return err
+	}
+	db := dbcore.GetDBInstance()
+	if w.ID == 0 {
+		if err := db.Create(w).Error; err != nil {
+			return err
+		}
+	} else {
+		result := db.Model(&models.MaintenanceWindow{}).Where("id = ?", w.ID).Select("*").Omit("id", "created_at").Updates(w)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+	}
+	return Reload()
+}
+
+func Delete(ids []uint) error {
+	if err := dbcore.GetDBInstance().Where("id IN ?", ids).Delete(&models.MaintenanceWindow{}).Error; err != nil {
+		return err
+	}
+	return Reload()
+}
+
+// Reload 重新加载启用的窗口到内存
+func Reload() error {
+	var list []models.MaintenanceWindow
+	if err := dbcore.GetDBInstance().Where("enabled = ?", true).Find(&list).Error; err != nil {
+		return err
+	}
+	cacheMu.Lock()
+	cache = list
+	loaded = true
+	cacheMu.Unlock()
+	return nil
+}
+
+func enabledWindows() []models.MaintenanceWindow {
+	cacheMu.RLock()
+	if loaded {
+		defer cacheMu.RUnlock()
+		return cache
+	}
+	cacheMu.RUnlock()
+	if err := Reload(); err != nil {
+		log.Printf("Failed to load maintenance windows: %v", err)
+		return nil
+	}
+	cacheMu.RLock()
+	defer cacheMu.RUnlock()
+	return cache
+}
+
+// IsActive 判断节点在指定时间是否处于维护窗口内
+func IsActive(client models.Client, t time.Time) bool {
+	for _, w := range enabledWindows() {
+		if coversClient(w, client) && covers(w, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterActive 去除处于维护窗口内的节点
+func FilterActive(list []models.Client, t time.Time) []models.Client {
+	result := make([]models.Client, 0, len(list))
+	for _, c := range list {
+		if !IsActive(c, t) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func coversClient(w models.MaintenanceWindow, client models.Client) bool {
+	if len(w.Clients) == 0 && len(w.Groups) == 0 && len(w.Tags) == 0 {
+		return true
+	}
+	for _, uuid := range w.Clients {
+		if uuid == client.UUID {
+			return true
+		}
+	}
+	for _, g := range w.Groups {
+		if g != "" && g == client.Group {
+			return true
+		}
+	}
+	for _, tag := range strings.Split(client.Tags, ";") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		for _, wt := range w.Tags {
+			if wt == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// covers 判断时间是否落在窗口内；周期窗口可跨越午夜，因此同时检查前一天开始的窗口
+func covers(w models.MaintenanceWindow, t time.Time) bool {
+	if w.Recurrence == RecurrenceOnce || w.Recurrence == "" {
+		return w.StartAt != nil && w.EndAt != nil && !t.Before(w.StartAt.ToTime()) && t.Before(w.EndAt.ToTime())
+	}
+	if w.StartAt != nil && !w.StartAt.ToTime().IsZero() && t.Before(w.StartAt.ToTime()) {
+		return false
+	}
+	if w.EndAt != nil && !w.EndAt.ToTime().IsZero() && !t.Before(w.EndAt.ToTime()) {
+		return false
+	}
+	clock, err := time.Parse("15:04", w.StartTime)
+	if err != nil {
+		return false
+	}
+	length := time.Duration(w.Duration) * time.Minute
+	weekdays := parseWeekdays(w.Weekdays)
+	// 窗口最长 7 天，回看 7 天内每一天开始的窗口
+	for back := 0; back <= 7; back++ {
+		day := t.AddDate(0, 0, -back)
+		start := time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, t.Location())
+		if w.Recurrence == RecurrenceWeekly && !weekdays[int(start.Weekday())] {
+			continue
+		}
+		if !t.Before(start) && t.Before(start.Add(length)) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseWeekdays(s string) map[int]bool {
+	days := make(map[int]bool)
+	for _, part := range strings.Split(s, ",") {
+		d, err := strconv.Atoi(strings.TrimSpace(part))
+		if err == nil && d >= 0 && d <= 6 {
+			days[d] = true
+		}
+	}
+	return days
+}
@@ -27,3 +27,58 @@ func SaveMessageSenderConfig(config *models.MessageSenderProvider) error {
 	db := dbcore.GetDBInstance()
 	return db.Save(config).Error
 }
+
+func GetAllMessageChannels() ([]models.MessageChannel, error) {
+	db := dbcore.GetDBInstance()
+	var result []models.MessageChannel
+	if err := db.Order("id asc").Find(&result).Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func GetMessageChannelByName(name string) (*models.MessageChannel, error) {
+	db := dbcore.GetDBInstance()
+	var channel models.MessageChannel
+	if err := db.Where("name = ?", name).First(&channel).Error; err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// SaveMessageChannel ID 为 0 时新建，否则整体更新
+func SaveMessageChannel(channel *models.MessageChannel) error {
+	db := dbcore.GetDBInstance()
+	if channel.ID == 0 {
+		return db.Create(channel).Error
+	}
+	return db.Model(&models.MessageChannel{}).Where("id = ?", channel.ID).Select("*").Omit("id", "created_at").Updates(channel).Error
+}
+
+func DeleteMessageChannels(ids []uint) error {
+	db := dbcore.GetDBInstance()
+	return db.Where("id IN ?", ids).Delete(&models.MessageChannel{}).Error
+}
+
+// GetAllMessageRoutes 按 Weight 升序返回路由规则
+func GetAllMessageRoutes() ([]models.MessageRoute, error) {
+	db := dbcore.GetDBInstance()
+	var result []models.MessageRoute
+	if err := db.Order("weight asc, id asc").Find(&result).Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func SaveMessageRoute(route *models.MessageRoute) error {
+	db := dbcore.GetDBInstance()
+	if route.ID == 0 {
+		return db.Create(route).Error
+	}
+	return db.Model(&models.MessageRoute{}).Where("id = ?", route.ID).Select("*").Omit("id", "created_at").Updates(route).Error
+}
+
+func DeleteMessageRoutes(ids []uint) error {
+	db := dbcore.GetDBInstance()
+	return db.Where("id IN ?", ids).Delete(&models.MessageRoute{}).Error
+}
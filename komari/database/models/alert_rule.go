@@ -0,0 +1,86 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+)
+
+// 告警级别
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// AlertRule 组合告警规则：多个条件按 Logic 组合，在 Duration 窗口内聚合评估
+type AlertRule struct {
+	ID             uint            `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name           string          `json:"name" gorm:"type:varchar(255);not null"`
+	Enabled        bool            `json:"enabled"`
+	Clients        StringArray     `json:"clients" gorm:"type:longtext"`               // 为空表示全部节点
+	Logic          string          `json:"logic" gorm:"type:varchar(8);default:'and'"` // and / or
+	Conditions     AlertConditions `json:"conditions" gorm:"type:longtext"`
+	Duration       int             `json:"duration" gorm:"type:int"` // 评估窗口（秒）
+	Severity       string          `json:"severity" gorm:"type:varchar(16);default:'warning'"`
+	Providers      StringArray     `json:"providers" gorm:"type:longtext"` // 发送渠道，为空使用默认通知方式
+	NotifyRecovery bool            `json:"notify_recovery"`
+	Cooldown       int             `json:"cooldown" gorm:"type:int;default:0"` // 持续告警时的重复通知间隔（秒），0 不重复
+	CreatedAt      LocalTime       `json:"created_at"`
+	UpdatedAt      LocalTime       `json:"updated_at"`
+}
+
+// AlertCondition 单个条件
+//
+// Metric 取值：Record 字段（cpu、gpu、ram、swap、disk 为百分比，load、temp、net_in、net_out、
+// net_total_up、net_total_down、process、connections、connections_udp 为原始值），
+// GPU 记录（gpu_usage、gpu_mem、gpu_temp），Ping 记录（ping_latency、ping_loss，需指定 TaskID），
+// 以及 offline（离线为 1）
+type AlertCondition struct {
+	Metric    string  `json:"metric"`
+	Operator  string  `json:"operator"`            // > >= < <= == !=
+	Threshold float64 `json:"threshold"`           // 阈值
+	Aggregate string  `json:"aggregate,omitempty"` // 窗口聚合方式：avg（默认）/ min / max / last
+	TaskID    uint    `json:"task_id,omitempty"`   // Ping 任务 ID
+}
+
+// AlertMetrics 支持的条件指标
+var AlertMetrics = []string{
+	"cpu", "gpu", "ram", "swap", "disk", "load", "temp", "net_in", "net_out",
+	"net_total_up", "net_total_down", "process", "connections", "connections_udp",
+	"gpu_usage", "gpu_mem", "gpu_temp", "ping_latency", "ping_loss", "offline",
+}
+
+func IsValidAlertMetric(metric string) bool {
+	for _, m := range AlertMetrics {
+		if m == metric {
+			return true
+		}
+	}
+	return false
+}
+
+type AlertConditions []AlertCondition
+
+func (ac *AlertConditions) Scan(value interface{}) error {
+	*ac = nil
+	return scanJSON(value, ac, "AlertConditions")
+}
+
+func (ac AlertConditions) Value() (driver.Value, error) {
+	return json.Marshal(ac)
+}
+
+// AlertIncident 规则在某个节点上的一次告警，恢复后记录 ResolvedAt
+type AlertIncident struct {
+	ID           uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	RuleID       uint       `json:"rule_id" gorm:"index"`
+	RuleName     string     `json:"rule_name" gorm:"type:varchar(255)"`
+	Client       string     `json:"client" gorm:"type:varchar(36);index"`
+	Severity     string     `json:"severity" gorm:"type:varchar(16)"`
+	Message      string     `json:"message" gorm:"type:text"`
+	StartedAt    LocalTime  `json:"started_at" gorm:"index"`
+	ResolvedAt   *LocalTime `json:"resolved_at" gorm:"type:timestamp"`
+	LastNotified LocalTime  `json:"last_notified"`
+	AckedAt      *LocalTime `json:"acked_at" gorm:"type:timestamp"` // 确认后不再重复通知，直至恢复
+	AckedBy      string     `json:"acked_by" gorm:"type:varchar(36)"`
+}
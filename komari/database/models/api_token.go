@@ -0,0 +1,73 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+// API Token 权限范围
+const (
+	TokenScopeAdmin    = "admin"    // 全部权限，等同于管理员
+	TokenScopeRead     = "read"     // 只读访问节点、任务、记录与指标
+	TokenScopePing     = "ping"     // 管理 Ping / SP Ping 任务
+	TokenScopeExec     = "exec"     // 下发命令并查看执行结果
+	TokenScopeTerminal = "terminal" // 打开节点终端
+)
+
+var TokenScopes = []string{TokenScopeAdmin, TokenScopeRead, TokenScopePing, TokenScopeExec, TokenScopeTerminal}
+
+// ApiToken 带权限范围与有效期的 API 令牌，数据库只保存令牌的 SHA-256
+type ApiToken struct {
+	ID         uint        `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name       string      `json:"name" gorm:"type:varchar(100);not null"`
+	TokenHash  string      `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	Prefix     string      `json:"prefix" gorm:"type:varchar(16)"` // 令牌前几位，便于辨认
+	Scopes     StringArray `json:"scopes" gorm:"type:longtext;not null"`
+	Clients    StringArray `json:"clients" gorm:"type:longtext;not null"` // 为空表示不限制节点
+	ExpiresAt  *LocalTime  `json:"expires_at" gorm:"type:timestamp"`
+	LastUsedAt *LocalTime  `json:"last_used_at" gorm:"type:timestamp"`
+	LastUsedIP string      `json:"last_used_ip" gorm:"type:varchar(100)"`
+	CreatedBy  string      `json:"created_by" gorm:"type:varchar(36)"`
+	CreatedAt  LocalTime   `json:"created_at"`
+	UpdatedAt  LocalTime   `json:"updated_at"`
+}
+
+func IsValidTokenScope(scope string) bool {
+	for _, s := range TokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *ApiToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *ApiToken) IsExpired() bool {
+	return t.ExpiresAt != nil && !t.ExpiresAt.ToTime().IsZero() && time.Now().After(t.ExpiresAt.ToTime())
+}
+
+// Actor 返回令牌在审计日志等场景中的操作者标识
+func (t *ApiToken) Actor() string {
+	return "token:" + strconv.FormatUint(uint64(t.ID), 10)
+}
+
+// CanAccessClient 令牌未限制节点时全部可见
+func (t *ApiToken) CanAccessClient(uuid string) bool {
+	if len(t.Clients) == 0 {
+		return true
+	}
+	for _, c := range t.Clients {
+		if c == uuid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,20 @@
+package models
+
+// BillingRecord 历史支出账本，自动续费时写入，也可手动补录
+//
+// 冗余保存节点名称、分组与价格，节点删除或改价后账本仍保持原样
+type BillingRecord struct {
+	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Client       string    `json:"client" gorm:"type:varchar(36);index"`
+	ClientName   string    `json:"client_name" gorm:"type:varchar(100)"`
+	Group        string    `json:"group" gorm:"type:varchar(100)"`
+	Amount       float64   `json:"amount"`
+	Currency     string    `json:"currency" gorm:"type:varchar(20);default:'$'"`
+	BillingCycle int       `json:"billing_cycle" gorm:"type:int;default:0"`
+	PeriodStart  LocalTime `json:"period_start" gorm:"type:timestamp"`
+	PeriodEnd    LocalTime `json:"period_end" gorm:"type:timestamp"`
+	PaidAt       LocalTime `json:"paid_at" gorm:"type:timestamp;index"`
+	Source       string    `json:"source" gorm:"type:varchar(16);default:'manual'"` // auto_renewal / manual
+	Remark       string    `json:"remark" gorm:"type:text"`
+	CreatedAt    LocalTime `json:"created_at"`
+}
@@ -40,9 +40,46 @@ type Config struct {
 	RecordEnabled          bool `json:"record_enabled" gorm:"default:true"`          // 是否启用记录功能
 	RecordPreserveTime     int  `json:"record_preserve_time" gorm:"default:720"`     // 记录保留时间，单位小时，默认30天
 	PingRecordPreserveTime int  `json:"ping_record_preserve_time" gorm:"default:24"` // Ping 记录保留时间，单位小时，默认1天
+	// 外部记录汇：完整精度的历史数据额外写入时序数据库
+	RecordSinkType  string `json:"record_sink_type" gorm:"type:varchar(20);default:''"` // 空、prometheus（remote_write）、influxdb
+	RecordSinkURL   string `json:"record_sink_url" gorm:"type:varchar(255);default:''"` // remote_write 地址或 InfluxDB 写入地址
+	RecordSinkToken string `json:"record_sink_token" gorm:"type:varchar(255);default:''"`
 	// SmokePing 风格配置（主题使用）
 	SpRecordPreserveHours int    `json:"sp_record_preserve_hours" gorm:"default:8760"`                        // SP Ping 记录保留时间，单位小时，默认一年
 	SpChartRanges         string `json:"sp_chart_ranges" gorm:"type:varchar(255);default:'3h,30h,10d,360d'"` // 逗号分隔的时间跨度列表
-	CreatedAt              LocalTime
-	UpdatedAt              LocalTime
+	// Prometheus 指标导出
+	MetricsEnabled bool   `json:"metrics_enabled" gorm:"default:false"`              // 是否启用 /api/metrics
+	MetricsToken   string `json:"metrics_token" gorm:"type:varchar(255);default:''"` // 抓取专用令牌，留空时仅接受 API Key
+	// 定时备份：加密后保存到本地目录，可选上传到 S3 兼容存储
+	BackupEnabled     bool   `json:"backup_enabled" gorm:"default:false"`
+	BackupCron        string `json:"backup_cron" gorm:"type:varchar(100);default:'0 3 * * *'"`               // cron 表达式，支持可选的秒字段
+	BackupPassphrase  string `json:"backup_passphrase" gorm:"type:varchar(255);default:''"`                  // 加密口令，丢失后无法恢复
+	BackupKeep        int    `json:"backup_keep" gorm:"default:7"`                                           // 每个存储位置保留的份数
+	BackupLocalDir    string `json:"backup_local_dir" gorm:"type:varchar(255);default:'./backup/scheduled'"` // 留空时不保存到本地
+	BackupS3Endpoint  string `json:"backup_s3_endpoint" gorm:"type:varchar(255);default:''"`                 // 例如 https://s3.amazonaws.com 或 http://minio:9000
+	BackupS3Region    string `json:"backup_s3_region" gorm:"type:varchar(64);default:'us-east-1'"`
+	BackupS3Bucket    string `json:"backup_s3_bucket" gorm:"type:varchar(255);default:''"` // 留空时不上传
+	BackupS3Prefix    string `json:"backup_s3_prefix" gorm:"type:varchar(255);default:''"`
+	BackupS3AccessKey string `json:"backup_s3_access_key" gorm:"type:varchar(255);default:''"`
+	BackupS3SecretKey string `json:"backup_s3_secret_key" gorm:"type:varchar(255);default:''"`
+	BackupS3PathStyle bool   `json:"backup_s3_path_style" gorm:"default:true"` // MinIO 等需要路径风格访问
+	// 账单：节点费用按汇率折算为基准货币后汇总
+	BillingBaseCurrency  string `json:"billing_base_currency" gorm:"type:varchar(20);default:'$'"` // 与节点的货币字段写法一致，例如 $、¥、CNY
+	BillingExchangeRates string `json:"billing_exchange_rates" gorm:"type:text"`                   // 每行或逗号分隔的 货币=汇率，表示 1 单位该货币折合多少基准货币，例如 ¥=0.14
+	// 终端录制：以 asciicast v2 格式保存网页终端会话
+	TerminalRecordEnabled  bool   `json:"terminal_record_enabled" gorm:"default:false"`
+	TerminalRecordInput    bool   `json:"terminal_record_input" gorm:"default:false"`                                        // 同时录制浏览器输入，可能包含密码等敏感内容
+	TerminalRecordDir      string `json:"terminal_record_dir" gorm:"type:varchar(255);default:'./data/terminal_recordings'"` // 录制文件保存目录
+	TerminalRecordKeepDays int    `json:"terminal_record_keep_days" gorm:"default:30"`                                       // 保留天数，0 表示永久保留
+	TerminalRecordMaxSize  int    `json:"terminal_record_max_size" gorm:"default:100"`                                       // 单个录制文件上限（MB），超出后停止录制并标记为已截断，0 表示不限制
+	// 文件传输：通过 Agent 连接浏览、上传与下载节点文件
+	FileTransferMaxSize int `json:"file_transfer_max_size" gorm:"default:1024"` // 单个文件上传/下载上限（MB），0 表示不限制
+	// 端口转发：经由 Agent 的出站连接访问节点本机端口
+	TunnelListenHost  string `json:"tunnel_listen_host" gorm:"type:varchar(255);default:'127.0.0.1'"` // 临时监听地址，监听端口本身不做认证，对外开放需谨慎
+	TunnelIdleTimeout int    `json:"tunnel_idle_timeout" gorm:"default:10"`                           // 无连接且无流量多少分钟后关闭隧道
+	TunnelMaxDuration int    `json:"tunnel_max_duration" gorm:"default:120"`                          // 隧道最长存活时间（分钟）
+	// SSH 批量任务
+	SshJobKeepDays int `json:"ssh_job_keep_days" gorm:"default:30"` // 任务历史保留天数，0 表示永久保留
+	CreatedAt      LocalTime
+	UpdatedAt      LocalTime
 }
@@ -0,0 +1,22 @@
+package models
+
+// MaintenanceWindow 维护窗口，窗口内抑制离线、负载、流量与告警规则通知
+//
+// 节点范围：Clients、Groups、Tags 任一命中即生效，全部为空表示所有节点
+type MaintenanceWindow struct {
+	ID         uint        `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name       string      `json:"name" gorm:"type:varchar(255);not null"`
+	Enabled    bool        `json:"enabled"`
+	Clients    StringArray `json:"clients" gorm:"type:longtext"`
+	Groups     StringArray `json:"groups" gorm:"type:longtext"`
+	Tags       StringArray `json:"tags" gorm:"type:longtext"`
+	Recurrence string      `json:"recurrence" gorm:"type:varchar(16);default:'once'"` // once / daily / weekly
+	StartAt    *LocalTime  `json:"start_at" gorm:"type:timestamp"`                    // once：窗口开始；周期：生效起始（可选）
+	EndAt      *LocalTime  `json:"end_at" gorm:"type:timestamp"`                      // once：窗口结束；周期：生效截止（可选）
+	StartTime  string      `json:"start_time" gorm:"type:varchar(5)"`                 // 周期窗口每天的开始时间 HH:MM（服务器时区）
+	Duration   int         `json:"duration" gorm:"type:int;default:60"`               // 周期窗口时长（分钟）
+	Weekdays   string      `json:"weekdays" gorm:"type:varchar(20)"`                  // weekly 生效的星期，0 为周日，逗号分隔
+	Remark     string      `json:"remark" gorm:"type:text"`
+	CreatedAt  LocalTime   `json:"created_at"`
+	UpdatedAt  LocalTime   `json:"updated_at"`
+}
@@ -8,4 +8,8 @@ const (
 	Login   = "Login"
 	Alert   = "Alert"
 	Traffic = "Traffic"
+	// Resolved 告警规则恢复
+	Resolved = "Resolved"
+	// Test 管理面板发送的测试消息
+	Test = "Test"
 )
@@ -13,4 +13,34 @@ type EventMessage struct {
 	Time    time.Time `json:"time"`
 	Message string    `json:"message"`
 	Emoji   string    `json:"emoji"`
+	// Severity 告警级别（info / warning / critical），仅告警规则产生的事件携带
+	Severity string `json:"severity,omitempty"`
+}
+
+// MessageChannel 一个独立配置的发送渠道，同一提供者可以配置多个（如两个 Telegram 机器人）
+type MessageChannel struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name      string    `json:"name" gorm:"type:varchar(100);uniqueIndex;not null"`
+	Provider  string    `json:"provider" gorm:"type:varchar(50);not null"` // messageSender/factory 中的提供者名称
+	Addition  string    `json:"addition" gorm:"type:longtext"`             // 提供者配置 JSON
+	Template  string    `json:"template" gorm:"type:text"`                 // 为空时使用 NotificationTemplate
+	Enabled   bool      `json:"enabled"`
+	CreatedAt LocalTime `json:"created_at"`
+	UpdatedAt LocalTime `json:"updated_at"`
+}
+
+// MessageRoute 事件路由规则，各字段为空表示不限制；命中的规则把事件发送到 Channels
+type MessageRoute struct {
+	ID         uint        `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name       string      `json:"name" gorm:"type:varchar(100)"`
+	Enabled    bool        `json:"enabled"`
+	Weight     int         `json:"weight" gorm:"default:0"`
+	Events     StringArray `json:"events" gorm:"type:longtext"`     // 事件类型，如 Offline、Alert
+	Groups     StringArray `json:"groups" gorm:"type:longtext"`     // 节点分组
+	Tags       StringArray `json:"tags" gorm:"type:longtext"`       // 节点标签
+	Severities StringArray `json:"severities" gorm:"type:longtext"` // 告警级别
+	Channels   StringArray `json:"channels" gorm:"type:longtext"`   // 渠道名称
+	Stop       bool        `json:"stop" gorm:"default:false"`       // 命中后不再匹配后续规则
+	CreatedAt  LocalTime   `json:"created_at"`
+	UpdatedAt  LocalTime   `json:"updated_at"`
 }
@@ -39,6 +39,7 @@ type Client struct {
 	Hidden           bool      `json:"hidden" gorm:"default:false"`
 	TrafficLimit     int64     `json:"traffic_limit" gorm:"type:bigint"`
 	TrafficLimitType string    `json:"traffic_limit_type" gorm:"type:varchar(10);default:'max'"` // 流量阈值类型：sum max min up down
+	TrafficResetDay  int       `json:"traffic_reset_day" gorm:"type:int;default:1"`              // 服务端流量账本的每月结算日(1-31)
 	// SSH（仅用于面板侧辅助安装/管理；Agent 本身不使用）
 	SshEnabled       bool   `json:"ssh_enabled" gorm:"default:false"`
 	SshHost          string `json:"ssh_host" gorm:"type:varchar(255);default:''"`
@@ -51,15 +52,18 @@ type Client struct {
 
 // User represents an authenticated user
 type User struct {
-	UUID      string    `json:"uuid,omitempty" gorm:"type:varchar(36);primaryKey"`
-	Username  string    `json:"username" gorm:"type:varchar(50);unique;not null"`
-	Passwd    string    `json:"passwd,omitempty" gorm:"type:varchar(255);not null"` // Hashed password
-	SSOType   string    `json:"sso_type" gorm:"type:varchar(20)"`                   // e.g., "github", "google"
-	SSOID     string    `json:"sso_id" gorm:"type:varchar(100)"`                    // OAuth provider's user ID
-	TwoFactor string    `json:"two_factor,omitempty" gorm:"type:varchar(255)"`      // 2FA secret
-	Sessions  []Session `json:"sessions,omitempty" gorm:"foreignKey:UUID;references:UUID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
-	CreatedAt LocalTime `json:"created_at"`
-	UpdatedAt LocalTime `json:"updated_at"`
+	UUID        string    `json:"uuid,omitempty" gorm:"type:varchar(36);primaryKey"`
+	Username    string    `json:"username" gorm:"type:varchar(50);unique;not null"`
+	Passwd      string    `json:"passwd,omitempty" gorm:"type:varchar(255);not null"` // Hashed password
+	SSOType     string    `json:"sso_type" gorm:"type:varchar(20)"`                   // e.g., "github", "google"
+	SSOID       string    `json:"sso_id" gorm:"type:varchar(100)"`                    // OAuth provider's user ID
+	TwoFactor   string    `json:"two_factor,omitempty" gorm:"type:varchar(255)"`      // 2FA secret
+	Role        string    `json:"role" gorm:"type:varchar(20);default:'admin'"`       // admin / operator / readonly
+	ScopeGroups string    `json:"scope_groups" gorm:"type:text"`                      // 可见节点分组，分号分隔；与 ScopeTags 均为空时不限制
+	ScopeTags   string    `json:"scope_tags" gorm:"type:text"`                        // 可见节点标签，分号分隔
+	Sessions    []Session `json:"sessions,omitempty" gorm:"foreignKey:UUID;references:UUID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	CreatedAt   LocalTime `json:"created_at"`
+	UpdatedAt   LocalTime `json:"updated_at"`
 }
 
 // Session manages user sessions
@@ -112,16 +116,83 @@ type GPURecord struct {
 	Temperature int       `json:"temperature"`                                    // GPU温度(°C)
 }
 
+// TemperatureRecord 单个温度传感器的历史读数
+type TemperatureRecord struct {
+	Client      string    `json:"client" gorm:"type:varchar(36);index"` // 客户端UUID
+	Time        LocalTime `json:"time" gorm:"index"`                    // 记录时间
+	Sensor      string    `json:"sensor" gorm:"type:varchar(100)"`      // 传感器名称
+	Temperature float32   `json:"temperature" gorm:"type:decimal(5,2)"` // 温度(°C)
+}
+
+// DiskRecord 单个挂载点或块设备的历史数据：
+// Kind 为 mount 时 Name 是挂载点，记录容量与 inode；为 io 时 Name 是块设备名，记录吞吐与 IOPS
+type DiskRecord struct {
+	Client      string    `json:"client" gorm:"type:varchar(36);index"` // 客户端UUID
+	Time        LocalTime `json:"time" gorm:"index"`                    // 记录时间
+	Kind        string    `json:"kind" gorm:"type:varchar(8)"`          // mount | io
+	Name        string    `json:"name" gorm:"type:varchar(255)"`        // 挂载点或设备名
+	Total       int64     `json:"total,omitempty" gorm:"type:bigint"`   // 容量(字节)
+	Used        int64     `json:"used,omitempty" gorm:"type:bigint"`    // 已用(字节)
+	InodesTotal int64     `json:"inodes_total,omitempty" gorm:"type:bigint"`
+	InodesUsed  int64     `json:"inodes_used,omitempty" gorm:"type:bigint"`
+	ReadBytes   int64     `json:"read_bytes,omitempty" gorm:"type:bigint"`  // 读取速率(字节/秒)
+	WriteBytes  int64     `json:"write_bytes,omitempty" gorm:"type:bigint"` // 写入速率(字节/秒)
+	ReadIops    float32   `json:"read_iops,omitempty"`
+	WriteIops   float32   `json:"write_iops,omitempty"`
+}
+
+// NetworkRecord 单个网卡的历史速率与累计计数器
+type NetworkRecord struct {
+	Client    string    `json:"client" gorm:"type:varchar(36);index"` // 客户端UUID
+	Time      LocalTime `json:"time" gorm:"index"`                    // 记录时间
+	Interface string    `json:"interface" gorm:"type:varchar(100)"`   // 网卡名称
+	Up        int64     `json:"up" gorm:"type:bigint"`                // 发送速率(字节/秒)
+	Down      int64     `json:"down" gorm:"type:bigint"`              // 接收速率(字节/秒)
+	TotalUp   int64     `json:"total_up" gorm:"type:bigint"`          // 系统累计发送(字节)
+	TotalDown int64     `json:"total_down" gorm:"type:bigint"`        // 系统累计接收(字节)
+}
+
+// TrafficLedger 服务端按结算周期累计的节点流量，由上报的网卡计数器差值累加，不依赖 Agent 本地统计
+type TrafficLedger struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Client      string    `json:"client" gorm:"type:varchar(36);uniqueIndex:idx_traffic_ledger_period"`
+	PeriodStart LocalTime `json:"period_start" gorm:"type:timestamp;uniqueIndex:idx_traffic_ledger_period"` // 结算周期起点
+	Up          int64     `json:"up" gorm:"type:bigint"`
+	Down        int64     `json:"down" gorm:"type:bigint"`
+	UpdatedAt   LocalTime `json:"updated_at"`
+}
+
+const (
+	DiskRecordMount = "mount"
+	DiskRecordIO    = "io"
+)
+
+// scanJSON 解码 JSON 列：SQLite/MySQL 返回 []byte，PostgreSQL(pgx) 返回 string，NULL 与空串保持 dest 原值
+func scanJSON(value interface{}, dest interface{}, name string) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("failed to scan %s: unsupported type %T", name, value)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, dest)
+}
+
 // StringArray represents a slice of strings stored as JSON in the database
 // StringArray 存储为 JSON 的字符串切片类型
 type StringArray []string
 
 func (sa *StringArray) Scan(value interface{}) error {
-	bytes, ok := value.([]byte)
-	if !ok {
-		return fmt.Errorf("failed to scan StringArray: value is not []byte")
-	}
-	return json.Unmarshal(bytes, sa)
+	*sa = StringArray{}
+	return scanJSON(value, sa, "StringArray")
 }
 
 func (sa StringArray) Value() (driver.Value, error) {
@@ -0,0 +1,70 @@
+package models
+
+import "strings"
+
+// 用户角色
+const (
+	RoleAdmin    = "admin"    // 全部权限
+	RoleOperator = "operator" // 可执行命令、终端、脚本与探测任务管理，不可修改站点设置与账户
+	RoleReadOnly = "readonly" // 仅可查看
+)
+
+// RoleLevel 返回角色等级，数值越大权限越高；未知或为空的角色为 0
+func RoleLevel(role string) int {
+	switch role {
+	case RoleAdmin:
+		return 3
+	case RoleOperator:
+		return 2
+	case RoleReadOnly:
+		return 1
+	}
+	return 0
+}
+
+func IsValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleOperator || role == RoleReadOnly
+}
+
+// IsScoped 是否限制了节点可见范围
+func (u *User) IsScoped() bool {
+	if u.Role == RoleAdmin {
+		return false
+	}
+	return strings.TrimSpace(u.ScopeGroups) != "" || strings.TrimSpace(u.ScopeTags) != ""
+}
+
+// CanAccessClient 节点分组命中 ScopeGroups 或任一标签命中 ScopeTags 时可见
+func (u *User) CanAccessClient(c *Client) bool {
+	if !u.IsScoped() {
+		return true
+	}
+	if c == nil {
+		return false
+	}
+	for _, g := range splitScope(u.ScopeGroups) {
+		if g == c.Group {
+			return true
+		}
+	}
+	tags := splitScope(c.Tags)
+	for _, t := range splitScope(u.ScopeTags) {
+		for _, ct := range tags {
+			if t == ct {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func splitScope(s string) []string {
+	parts := strings.Split(s, ";")
+	res := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			res = append(res, p)
+		}
+	}
+	return res
+}
@@ -3,17 +3,13 @@ package models
 import (
 	"database/sql/driver"
 	"encoding/json"
-	"fmt"
 )
 
 type UIntArray []uint
 
 func (ua *UIntArray) Scan(value interface{}) error {
-	bytes, ok := value.([]byte)
-	if !ok {
-		return fmt.Errorf("failed to scan UIntArray: value is not []byte")
-	}
-	return json.Unmarshal(bytes, ua)
+	*ua = UIntArray{}
+	return scanJSON(value, ua, "UIntArray")
 }
 
 func (ua UIntArray) Value() (driver.Value, error) {
@@ -32,11 +28,8 @@ type ScriptClientStatus struct {
 type ScriptClientStatusList []ScriptClientStatus
 
 func (s *ScriptClientStatusList) Scan(value interface{}) error {
-	bytes, ok := value.([]byte)
-	if !ok {
-		return fmt.Errorf("failed to scan ScriptClientStatusList: value is not []byte")
-	}
-	return json.Unmarshal(bytes, s)
+	*s = ScriptClientStatusList{}
+	return scanJSON(value, s, "ScriptClientStatusList")
 }
 
 func (s ScriptClientStatusList) Value() (driver.Value, error) {
@@ -67,6 +60,7 @@ type Script struct {
 	CronExpr         string                 `json:"cron_expr" gorm:"type:varchar(255)"`
 	TriggerName      string                 `json:"trigger_name" gorm:"type:varchar(128)"`
 	MessageType      string                 `json:"message_type" gorm:"type:varchar(64)"`
+	RunnerClient     string                 `json:"runner_client" gorm:"type:varchar(36)"`
 	DependsOnScripts UIntArray              `json:"depends_on_scripts" gorm:"type:longtext"`
 	DependsOnFolders UIntArray              `json:"depends_on_folders" gorm:"type:longtext"`
 	CreatedAt        LocalTime              `json:"created_at"`
@@ -82,11 +76,8 @@ type ScriptLogEntry struct {
 type ScriptLogEntries []ScriptLogEntry
 
 func (s *ScriptLogEntries) Scan(value interface{}) error {
-	bytes, ok := value.([]byte)
-	if !ok {
-		return fmt.Errorf("failed to scan ScriptLogEntries: value is not []byte")
-	}
-	return json.Unmarshal(bytes, s)
+	*s = ScriptLogEntries{}
+	return scanJSON(value, s, "ScriptLogEntries")
 }
 
 func (s ScriptLogEntries) Value() (driver.Value, error) {
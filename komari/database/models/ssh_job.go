@@ -0,0 +1,43 @@
+package models
+
+// SSHJob 通过面板保存的 SSH 凭据在多个节点上批量执行的任务
+type SSHJob struct {
+	ID          string     `json:"id" gorm:"type:varchar(64);primaryKey"`
+	Kind        string     `json:"kind" gorm:"type:varchar(16)"`   // command / script
+	Script      string     `json:"script" gorm:"type:varchar(50)"` // Kind 为 script 时使用的安装脚本名称
+	Command     string     `json:"command" gorm:"type:text"`       // 执行的命令；Kind 为 script 时为追加的脚本参数
+	Concurrency int        `json:"concurrency" gorm:"default:5"`
+	Timeout     int        `json:"timeout" gorm:"default:600"` // 单个节点的超时（秒）
+	UserUUID    string     `json:"user_uuid" gorm:"type:varchar(36);index"`
+	RequesterIp string     `json:"requester_ip" gorm:"type:varchar(100)"`
+	Status      string     `json:"status" gorm:"type:varchar(16);index"` // running / success / failed / cancelled
+	Total       int        `json:"total" gorm:"default:0"`
+	Succeeded   int        `json:"succeeded" gorm:"default:0"`
+	Failed      int        `json:"failed" gorm:"default:0"`
+	StartedAt   LocalTime  `json:"started_at" gorm:"type:timestamp;index"`
+	FinishedAt  *LocalTime `json:"finished_at" gorm:"type:timestamp"`
+}
+
+// SSHJobTarget 批量任务在单个节点上的执行结果
+type SSHJobTarget struct {
+	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	JobID      string     `json:"job_id" gorm:"type:varchar(64);index"`
+	Client     string     `json:"client" gorm:"type:varchar(36)"`
+	ClientName string     `json:"client_name" gorm:"type:varchar(100)"`
+	Host       string     `json:"host" gorm:"type:varchar(255)"`
+	Status     string     `json:"status" gorm:"type:varchar(16)"` // pending / running / success / failed / cancelled
+	ExitCode   *int       `json:"exit_code"`
+	Error      string     `json:"error" gorm:"type:text"`
+	Output     string     `json:"output" gorm:"type:longtext"` // 合并的 stdout/stderr，仅保留末尾部分
+	StartedAt  *LocalTime `json:"started_at" gorm:"type:timestamp"`
+	FinishedAt *LocalTime `json:"finished_at" gorm:"type:timestamp"`
+}
+
+// SSHKnownHost 首次连接时记录的 SSH 主机密钥，之后的连接必须使用相同的密钥
+type SSHKnownHost struct {
+	Address     string    `json:"address" gorm:"type:varchar(255);primaryKey"` // host:port
+	KeyType     string    `json:"key_type" gorm:"type:varchar(64)"`
+	Key         string    `json:"-" gorm:"type:text"` // base64 编码的公钥
+	Fingerprint string    `json:"fingerprint" gorm:"type:varchar(100)"`
+	CreatedAt   LocalTime `json:"created_at" gorm:"type:timestamp"`
+}
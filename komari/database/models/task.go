@@ -4,6 +4,7 @@ type Task struct {
 	TaskId  string       `json:"task_id" gorm:"type:varchar(36);primaryKey;unique"`
 	Clients StringArray  `json:"clients" gorm:"type:longtext"`
 	Command string       `json:"command" gorm:"type:text"`
+	Timeout int          `json:"timeout" gorm:"type:int;default:0"` // 执行超时(秒)，0 表示不限制
 	Results []TaskResult `gorm:"foreignKey:TaskId;references:TaskId;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
 }
 
@@ -15,4 +16,17 @@ type TaskResult struct {
 	ExitCode   *int       `json:"exit_code" gorm:"type:int"`
 	FinishedAt *LocalTime `json:"finished_at" gorm:"type:timestamp"`
 	CreatedAt  LocalTime  `json:"created_at" gorm:"type:timestamp"`
+	// 离线排队：Status 为 pending 时等待节点上线后下发，超过 Deadline 仍未下发则标记为 expired
+	Status      string     `json:"status" gorm:"type:varchar(16);index"`
+	Deadline    *LocalTime `json:"deadline,omitempty" gorm:"type:timestamp"`
+	DeliveredAt *LocalTime `json:"delivered_at,omitempty" gorm:"type:timestamp"`
 }
+
+const (
+	TaskStatusPending   = "pending"   // 节点离线，排队等待下发
+	TaskStatusDelivered = "delivered" // 已下发，等待结果
+	TaskStatusFinished  = "finished"  // 已返回结果
+	TaskStatusExpired   = "expired"   // 截止前节点未上线
+	TaskStatusCancelled = "cancelled" // 被手动取消，Result 为取消前的输出
+	TaskStatusTimeout   = "timeout"   // 执行超时被终止，Result 为超时前的输出
+)
@@ -0,0 +1,16 @@
+package models
+
+// TerminalRecording 终端会话录制，ID 与审计日志中的 terminal id 一致
+type TerminalRecording struct {
+	ID          string     `json:"id" gorm:"type:varchar(64);primaryKey"`
+	Client      string     `json:"client" gorm:"type:varchar(36);index"`
+	UserUUID    string     `json:"user_uuid" gorm:"type:varchar(36);index"`
+	RequesterIp string     `json:"requester_ip" gorm:"type:varchar(100)"`
+	Input       bool       `json:"input" gorm:"default:false"` // 是否包含浏览器输入
+	Path        string     `json:"-" gorm:"type:varchar(512)"`
+	Size        int64      `json:"size" gorm:"type:bigint;default:0"`
+	Duration    float64    `json:"duration" gorm:"default:0"`      // 秒
+	Truncated   bool       `json:"truncated" gorm:"default:false"` // 超出大小上限后停止录制
+	StartedAt   LocalTime  `json:"started_at" gorm:"type:timestamp;index"`
+	EndedAt     *LocalTime `json:"ended_at" gorm:"type:timestamp"`
+}
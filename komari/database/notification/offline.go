@@ -0,0 +1,29 @@
+package notification
+
+import (
+	"github.com/komari-monitor/komari/database/dbcore"
+	"github.com/komari-monitor/komari/database/models"
+	"gorm.io/gorm/clause"
+)
+
+// UpsertOfflineNotifications 按节点新增或覆盖离线通知的开关与宽限期
+func UpsertOfflineNotifications(notifications []models.OfflineNotification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	return dbcore.GetDBInstance().Model(&models.OfflineNotification{}).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "client"}},
+			DoUpdates: clause.AssignmentColumns([]string{"enable", "grace_period"}),
+		}).
+		Select("*").
+		Create(notifications).Error
+}
+
+// DeleteOfflineNotifications 删除指定节点的离线通知设置
+func DeleteOfflineNotifications(clients []string) error {
+	if len(clients) == 0 {
+		return nil
+	}
+	return dbcore.GetDBInstance().Where("client IN ?", clients).Delete(&models.OfflineNotification{}).Error
+}
@@ -0,0 +1,149 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/komari-monitor/komari/database/dbcore"
+	"github.com/komari-monitor/komari/database/models"
+	"github.com/komari-monitor/komari/utils/notifier"
+	"gorm.io/gorm"
+)
+
+// ValidateAlertRule 校验并补全规则默认值
+func ValidateAlertRule(rule *models.AlertRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	rule.Logic = strings.ToLower(rule.Logic)
+	if rule.Logic == "" {
+		rule.Logic = "and"
+	}
+	if rule.Logic != "and" && rule.Logic != "or" {
+		return fmt.Errorf("logic must be 'and' or 'or'")
+	}
+	if len(rule.Conditions) == 0 {
+		return fmt.Errorf("at least one condition is required")
+	}
+	for _, cond := range rule.Conditions {
+		if !models.IsValidAlertMetric(cond.Metric) {
+			return fmt.Errorf("invalid metric: %s", cond.Metric)
+		}
+		switch cond.Operator {
+		case ">", ">=", "<", "<=", "==", "!=":
+		default:
+			return fmt.Errorf("invalid operator: %s", cond.Operator)
+		}
+		switch cond.Aggregate {
+		case "", "avg", "min", "max", "last":
+		default:
+			return fmt.Errorf("invalid aggregate: %s", cond.Aggregate)
+		}
+		if strings.HasPrefix(cond.Metric, "ping_") && cond.TaskID == 0 {
+			return fmt.Errorf("metric %s requires task_id", cond.Metric)
+		}
+	}
+	if rule.Severity == "" {
+		rule.Severity = models.SeverityWarning
+	}
+	if rule.Severity != models.SeverityInfo && rule.Severity != models.SeverityWarning && rule.Severity != models.SeverityCritical {
+		return fmt.Errorf("invalid severity: %s", rule.Severity)
+	}
+	if rule.Duration < 0 || rule.Duration > 24*3600 {
+		return fmt.Errorf("duration must be between 0 and 86400 seconds")
+	}
+	if rule.Cooldown < 0 {
+		return fmt.Errorf("cooldown must not be negative")
+	}
+	if rule.Clients == nil {
+		rule.Clients = models.StringArray{}
+	}
+	if rule.Providers == nil {
+		rule.Providers = models.StringArray{}
+	}
+	return nil
+}
+
+func AddAlertRule(rule *models.AlertRule) error {
+	if err := ValidateAlertRule(rule); err != nil {
+		return err
+	}
+	if err := dbcore.GetDBInstance().Create(rule).Error; err != nil {
+		return err
+	}
+	return ReloadAlertRuleSchedule()
+}
+
+func EditAlertRule(rule *models.AlertRule) error {
+	if err := ValidateAlertRule(rule); err != nil {
+		return err
+	}
+	db := dbcore.GetDBInstance()
+	// Select("*") 以便写入 false / 0 等零值
+	result := db.Model(&models.AlertRule{}).Where("id = ?", rule.ID).Select("*").Omit("id", "created_at").Updates(rule)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return ReloadAlertRuleSchedule()
+}
+
+func DeleteAlertRule(ids []uint) error {
+	db := dbcore.GetDBInstance()
+	result := db.Where("id IN ?", ids).Delete(&models.AlertRule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return ReloadAlertRuleSchedule()
+}
+
+func GetAllAlertRules() ([]models.AlertRule, error) {
+	var rules []models.AlertRule
+	if err := dbcore.GetDBInstance().Order("id asc").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetAlertIncidents 按开始时间倒序返回告警，activeOnly 时只返回未恢复的
+func GetAlertIncidents(activeOnly bool, limit int) ([]models.AlertIncident, error) {
+	var list []models.AlertIncident
+	q := dbcore.GetDBInstance().Order("started_at desc")
+	if activeOnly {
+		q = q.Where("resolved_at IS NULL")
+	}
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	if err := q.Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func ReloadAlertRuleSchedule() error {
+	rules, err := GetAllAlertRules()
+	if err != nil {
+		return err
+	}
+	return notifier.ReloadAlertRules(rules)
+}
+
+func GetAlertIncident(id uint) (*models.AlertIncident, error) {
+	var inc models.AlertIncident
+	if err := dbcore.GetDBInstance().First(&inc, id).Error; err != nil {
+		return nil, err
+	}
+	return &inc, nil
+}
+
+// AckAlertIncident 确认告警，停止重复通知直至恢复
+func AckAlertIncident(id uint, by string) error {
+	return notifier.AlertRuleManager.Acknowledge(id, by)
+}
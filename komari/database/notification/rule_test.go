@@ -0,0 +1,16 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/komari-monitor/komari/database/models"
+)
+
+func TestValidateAlertRuleMetric(t *testing.T) {
+	rule := models.AlertRule{Name: "r", Conditions: models.AlertConditions{{Metric: "cpu", Operator: ">", Threshold: 90}}}
+	assert.NoError(t, ValidateAlertRule(&rule))
+	rule.Conditions[0].Metric = "cpuu"
+	assert.ErrorContains(t, ValidateAlertRule(&rule), "invalid metric")
+}
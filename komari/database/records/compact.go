@@ -0,0 +1,72 @@
+package records
+
+import (
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// compactRecords 把 4 小时前的原始记录按 key 分组（key 需包含 15 分钟时间片），
+// 由 merge 合并为一条写入长期表 longTerm，已存在则更新；where 与 args 用于定位长期表中的同一条记录。
+// 原始记录按时间升序传给 merge，最后清理 5 小时前的原始记录
+func compactRecords[T any, K comparable](db *gorm.DB, longTerm, where string, key func(T) K, args func(K) []interface{}, merge func(K, []T) T) error {
+	fourHoursAgo := time.Now().Add(-4 * time.Hour)
+
+	var list []T
+	if err := db.Where("time < ?", fourHoursAgo).Order("time ASC").Find(&list).Error; err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		return nil
+	}
+
+	grouped := make(map[K][]T)
+	for _, rec := range list {
+		k := key(rec)
+		grouped[k] = append(grouped[k], rec)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for k, group := range grouped {
+			compressed := merge(k, group)
+			var existing int64
+			if err := tx.Table(longTerm).Where(where, args(k)...).Count(&existing).Error; err != nil {
+				return err
+			}
+			if existing > 0 {
+				if err := tx.Table(longTerm).Where(where, args(k)...).Updates(&compressed).Error; err != nil {
+					return err
+				}
+			} else if err := tx.Table(longTerm).Create(&compressed).Error; err != nil {
+				return err
+			}
+		}
+		var zero T
+		return tx.Where("time < ?", fourHoursAgo.Add(-1*time.Hour)).Delete(&zero).Error
+	})
+}
+
+// percentileOf 取 group 中由 field 提取的值的 p 分位
+func percentileOf[T any](group []T, p float64, field func(T) float64) float64 {
+	values := make([]float64, len(group))
+	for i, rec := range group {
+		values[i] = field(rec)
+	}
+	return percentile(values, p)
+}
+
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	index := float64(len(sorted)-1) * p
+	lower := int(index)
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	frac := index - float64(lower)
+	return sorted[lower] + frac*(sorted[lower+1]-sorted[lower])
+}
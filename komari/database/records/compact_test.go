@@ -0,0 +1,53 @@
+package records
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/komari-monitor/komari/database/models"
+)
+
+func TestMigrateNetworkRecords(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&models.NetworkRecord{}))
+	assert.NoError(t, db.Table("network_records_long_term").AutoMigrate(&models.NetworkRecord{}))
+
+	slot := time.Now().Add(-6 * time.Hour).Truncate(15 * time.Minute)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, db.Create(&models.NetworkRecord{
+			Client:    uuid,
+			Time:      models.FromTime(slot.Add(time.Duration(i+1) * time.Minute)),
+			Interface: "eth0",
+			Up:        int64(100 * (i + 1)),
+			TotalUp:   int64(1000 * (i + 1)),
+			TotalDown: int64(2000 * (i + 1)),
+		}).Error)
+	}
+	// 4 小时内的记录保持原样
+	recent := models.NetworkRecord{Client: uuid, Time: models.Now(), Interface: "eth0", Up: 1}
+	assert.NoError(t, db.Create(&recent).Error)
+
+	assert.NoError(t, migrateNetworkRecords(db))
+	// 重复执行只更新已有的长期记录
+	assert.NoError(t, migrateNetworkRecords(db))
+
+	var longTerm []models.NetworkRecord
+	assert.NoError(t, db.Table("network_records_long_term").Find(&longTerm).Error)
+	if assert.Len(t, longTerm, 1) {
+		rec := longTerm[0]
+		assert.True(t, rec.Time.ToTime().Equal(slot))
+		assert.Equal(t, "eth0", rec.Interface)
+		assert.Equal(t, int64(240), rec.Up)
+		assert.Equal(t, int64(3000), rec.TotalUp)
+		assert.Equal(t, int64(6000), rec.TotalDown)
+	}
+
+	var remaining int64
+	assert.NoError(t, db.Model(&models.NetworkRecord{}).Count(&remaining).Error)
+	assert.Equal(t, int64(1), remaining)
+}
@@ -0,0 +1,71 @@
+package records
+
+import (
+	"log"
+	"time"
+
+	"github.com/komari-monitor/komari/database/dbcore"
+	"github.com/komari-monitor/komari/database/models"
+	"gorm.io/gorm"
+)
+
+// GetDiskRecordsByClientAndTime 获取挂载点与块设备记录，近 4 小时取原始数据，更早的来自长期表
+func GetDiskRecordsByClientAndTime(uuid string, start, end time.Time) ([]models.DiskRecord, error) {
+	db := dbcore.GetDBInstance()
+	fourHoursAgo := time.Now().Add(-4*time.Hour - time.Minute)
+
+	var recent []models.DiskRecord
+	if end.After(fourHoursAgo) {
+		recentStart := start
+		if recentStart.Before(fourHoursAgo) {
+			recentStart = fourHoursAgo
+		}
+		err := db.Where("client = ? AND time >= ? AND time <= ?", uuid, recentStart, end).
+			Order("time ASC, kind ASC, name ASC").Find(&recent).Error
+		if err != nil {
+			log.Printf("Error fetching recent disk records for client %s between %s and %s: %v", uuid, recentStart, end, err)
+			return nil, err
+		}
+	}
+
+	var longTerm []models.DiskRecord
+	err := db.Table("disk_records_long_term").Where("client = ? AND time >= ? AND time <= ?", uuid, start, end).
+		Order("time ASC, kind ASC, name ASC").Find(&longTerm).Error
+	if err != nil {
+		log.Printf("Error fetching long-term disk records for client %s between %s and %s: %v", uuid, start, end, err)
+		return recent, nil
+	}
+	return append(longTerm, recent...), nil
+}
+
+// migrateDiskRecords 与 GPU 记录相同，按 15 分钟分组压缩到长期表，各字段分别取 70 分位
+func migrateDiskRecords(db *gorm.DB) error {
+	type groupKey struct {
+		Client   string
+		Kind     string
+		Name     string
+		TimeSlot time.Time
+	}
+	return compactRecords(db, "disk_records_long_term", "client = ? AND kind = ? AND name = ? AND time = ?",
+		func(rec models.DiskRecord) groupKey {
+			return groupKey{Client: rec.Client, Kind: rec.Kind, Name: rec.Name, TimeSlot: rec.Time.ToTime().Truncate(15 * time.Minute)}
+		},
+		func(key groupKey) []interface{} { return []interface{}{key.Client, key.Kind, key.Name, key.TimeSlot} },
+		func(key groupKey, group []models.DiskRecord) models.DiskRecord {
+			p := func(field func(models.DiskRecord) float64) float64 { return percentileOf(group, 0.7, field) }
+			return models.DiskRecord{
+				Client:      key.Client,
+				Time:        models.FromTime(key.TimeSlot),
+				Kind:        key.Kind,
+				Name:        key.Name,
+				Total:       int64(p(func(r models.DiskRecord) float64 { return float64(r.Total) })),
+				Used:        int64(p(func(r models.DiskRecord) float64 { return float64(r.Used) })),
+				InodesTotal: int64(p(func(r models.DiskRecord) float64 { return float64(r.InodesTotal) })),
+				InodesUsed:  int64(p(func(r models.DiskRecord) float64 { return float64(r.InodesUsed) })),
+				ReadBytes:   int64(p(func(r models.DiskRecord) float64 { return float64(r.ReadBytes) })),
+				WriteBytes:  int64(p(func(r models.DiskRecord) float64 { return float64(r.WriteBytes) })),
+				ReadIops:    float32(p(func(r models.DiskRecord) float64 { return float64(r.ReadIops) })),
+				WriteIops:   float32(p(func(r models.DiskRecord) float64 { return float64(r.WriteIops) })),
+			}
+		})
+}
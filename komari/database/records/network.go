@@ -0,0 +1,65 @@
+package records
+
+import (
+	"log"
+	"time"
+
+	"github.com/komari-monitor/komari/database/dbcore"
+	"github.com/komari-monitor/komari/database/models"
+	"gorm.io/gorm"
+)
+
+// GetNetworkRecordsByClientAndTime 获取各网卡记录，近 4 小时取原始数据，更早的来自长期表
+func GetNetworkRecordsByClientAndTime(uuid string, start, end time.Time) ([]models.NetworkRecord, error) {
+	db := dbcore.GetDBInstance()
+	fourHoursAgo := time.Now().Add(-4*time.Hour - time.Minute)
+
+	var recent []models.NetworkRecord
+	if end.After(fourHoursAgo) {
+		recentStart := start
+		if recentStart.Before(fourHoursAgo) {
+			recentStart = fourHoursAgo
+		}
+		err := db.Where("client = ? AND time >= ? AND time <= ?", uuid, recentStart, end).
+			Order("time ASC, interface ASC").Find(&recent).Error
+		if err != nil {
+			log.Printf("Error fetching recent network records for client %s between %s and %s: %v", uuid, recentStart, end, err)
+			return nil, err
+		}
+	}
+
+	var longTerm []models.NetworkRecord
+	err := db.Table("network_records_long_term").Where("client = ? AND time >= ? AND time <= ?", uuid, start, end).
+		Order("time ASC, interface ASC").Find(&longTerm).Error
+	if err != nil {
+		log.Printf("Error fetching long-term network records for client %s between %s and %s: %v", uuid, start, end, err)
+		return recent, nil
+	}
+	return append(longTerm, recent...), nil
+}
+
+// migrateNetworkRecords 按 15 分钟分组压缩到长期表，速率取 70 分位，累计计数器取组内最后一条
+func migrateNetworkRecords(db *gorm.DB) error {
+	type groupKey struct {
+		Client    string
+		Interface string
+		TimeSlot  time.Time
+	}
+	return compactRecords(db, "network_records_long_term", "client = ? AND interface = ? AND time = ?",
+		func(rec models.NetworkRecord) groupKey {
+			return groupKey{Client: rec.Client, Interface: rec.Interface, TimeSlot: rec.Time.ToTime().Truncate(15 * time.Minute)}
+		},
+		func(key groupKey) []interface{} { return []interface{}{key.Client, key.Interface, key.TimeSlot} },
+		func(key groupKey, group []models.NetworkRecord) models.NetworkRecord {
+			last := group[len(group)-1]
+			return models.NetworkRecord{
+				Client:    key.Client,
+				Time:      models.FromTime(key.TimeSlot),
+				Interface: key.Interface,
+				Up:        int64(percentileOf(group, 0.7, func(r models.NetworkRecord) float64 { return float64(r.Up) })),
+				Down:      int64(percentileOf(group, 0.7, func(r models.NetworkRecord) float64 { return float64(r.Down) })),
+				TotalUp:   last.TotalUp,
+				TotalDown: last.TotalDown,
+			}
+		})
+}
@@ -8,14 +8,25 @@ import (
 
 	"gorm.io/gorm"
 
-	"github.com/komari-monitor/komari/cmd/flags"
 	"github.com/komari-monitor/komari/database/dbcore"
 	"github.com/komari-monitor/komari/database/models"
+	"github.com/komari-monitor/komari/utils/recordsink"
 )
 
 func RecordOne(rec models.Record) error {
-	db := dbcore.GetDBInstance()
-	return db.Create(&rec).Error
+	return RecordMany([]models.Record{rec})
+}
+
+// RecordMany 写入面板存储，成功后异步推送到外部记录汇
+func RecordMany(recs []models.Record) error {
+	if len(recs) == 0 {
+		return nil
+	}
+	if err := currentStore().Save(recs); err != nil {
+		return err
+	}
+	recordsink.Push(recs...)
+	return nil
 }
 
 func RecordGPU(rec models.GPURecord) error {
@@ -34,6 +45,24 @@ func DeleteAll() error {
 	if err := db.Exec("DELETE FROM gpu_records").Error; err != nil {
 		return err
 	}
+	if err := db.Exec("DELETE FROM temperature_records_long_term").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM temperature_records").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM disk_records_long_term").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM disk_records").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM network_records_long_term").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM network_records").Error; err != nil {
+		return err
+	}
 	return db.Exec("DELETE FROM records").Error
 }
 
@@ -84,10 +113,21 @@ func DeleteRecordBefore(before time.Time) error {
 	db.Table("records_long_term").Where("time < ?", before).Delete(&models.Record{})
 	db.Table("gpu_records_long_term").Where("time < ?", before).Delete(&models.GPURecord{})
 	db.Where("time < ?", before).Delete(&models.GPURecord{})
+	db.Table("temperature_records_long_term").Where("time < ?", before).Delete(&models.TemperatureRecord{})
+	db.Where("time < ?", before).Delete(&models.TemperatureRecord{})
+	db.Table("disk_records_long_term").Where("time < ?", before).Delete(&models.DiskRecord{})
+	db.Where("time < ?", before).Delete(&models.DiskRecord{})
+	db.Table("network_records_long_term").Where("time < ?", before).Delete(&models.NetworkRecord{})
+	db.Where("time < ?", before).Delete(&models.NetworkRecord{})
 	return db.Where("time < ?", before).Delete(&models.Record{}).Error
 }
 
 func GetRecordsByClientAndTime(uuid string, start, end time.Time) ([]models.Record, error) {
+	return currentStore().Query(uuid, start, end)
+}
+
+// Query 近 4 小时返回原始记录（已有长期数据时按 15 分钟取一条），更早的数据来自 records_long_term
+func (sqlStore) Query(uuid string, start, end time.Time) ([]models.Record, error) {
 	db := dbcore.GetDBInstance()
 	var records []models.Record
 
@@ -172,11 +212,37 @@ func CompactRecord() error {
 		return err
 	}
 
-	if flags.DatabaseType == "sqlite" {
+	err = migrateTemperatureRecords(db)
+	if err != nil {
+		log.Printf("Error migrating temperature records: %v", err)
+		return err
+	}
+
+	err = migrateDiskRecords(db)
+	if err != nil {
+		log.Printf("Error migrating disk records: %v", err)
+		return err
+	}
+
+	err = migrateNetworkRecords(db)
+	if err != nil {
+		log.Printf("Error migrating network records: %v", err)
+		return err
+	}
+
+	switch db.Dialector.Name() {
+	case "sqlite":
 		if err := db.Exec("VACUUM").Error; err != nil {
 			log.Printf("Error vacuuming database: %v", err)
 		}
 		db.Exec("PRAGMA wal_checkpoint(TRUNCATE);")
+	case "postgres":
+		// 仅回收记录表的死元组并更新统计信息，不使用 VACUUM FULL 以免锁表
+		for _, table := range []string{"records", "records_long_term", "gpu_records", "gpu_records_long_term", "temperature_records", "temperature_records_long_term", "disk_records", "disk_records_long_term", "network_records", "network_records_long_term"} {
+			if err := db.Exec("VACUUM ANALYZE " + table).Error; err != nil {
+				log.Printf("Error vacuuming table %s: %v", table, err)
+			}
+		}
 	}
 	//log.Printf("Record compaction completed")
 	return nil
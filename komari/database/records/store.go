@@ -0,0 +1,43 @@
+package records
+
+import (
+	"sync"
+	"time"
+
+	"github.com/komari-monitor/komari/database/dbcore"
+	"github.com/komari-monitor/komari/database/models"
+)
+
+// Store 面板读写记录所用的存储，默认为当前 SQL 数据库；
+// 完整精度的历史数据另由 recordsink 写入外部时序库
+type Store interface {
+	Save(recs []models.Record) error
+	Query(uuid string, start, end time.Time) ([]models.Record, error)
+}
+
+var (
+	storeMu sync.RWMutex
+	store   Store = sqlStore{}
+)
+
+// SetStore 替换记录存储，传入 nil 恢复为 SQL 数据库
+func SetStore(s Store) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	if s == nil {
+		s = sqlStore{}
+	}
+	store = s
+}
+
+func currentStore() Store {
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+	return store
+}
+
+type sqlStore struct{}
+
+func (sqlStore) Save(recs []models.Record) error {
+	return dbcore.GetDBInstance().Create(&recs).Error
+}
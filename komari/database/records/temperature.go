@@ -0,0 +1,62 @@
+package records
+
+import (
+	"log"
+	"time"
+
+	"github.com/komari-monitor/komari/database/dbcore"
+	"github.com/komari-monitor/komari/database/models"
+	"gorm.io/gorm"
+)
+
+// GetTemperatureRecordsByClientAndTime 获取温度传感器记录，近 4 小时取原始数据，更早的来自长期表
+func GetTemperatureRecordsByClientAndTime(uuid string, start, end time.Time) ([]models.TemperatureRecord, error) {
+	db := dbcore.GetDBInstance()
+	fourHoursAgo := time.Now().Add(-4*time.Hour - time.Minute)
+
+	var recent []models.TemperatureRecord
+	if end.After(fourHoursAgo) {
+		recentStart := start
+		if recentStart.Before(fourHoursAgo) {
+			recentStart = fourHoursAgo
+		}
+		err := db.Where("client = ? AND time >= ? AND time <= ?", uuid, recentStart, end).
+			Order("time ASC, sensor ASC").Find(&recent).Error
+		if err != nil {
+			log.Printf("Error fetching recent temperature records for client %s between %s and %s: %v", uuid, recentStart, end, err)
+			return nil, err
+		}
+	}
+
+	var longTerm []models.TemperatureRecord
+	err := db.Table("temperature_records_long_term").Where("client = ? AND time >= ? AND time <= ?", uuid, start, end).
+		Order("time ASC, sensor ASC").Find(&longTerm).Error
+	if err != nil {
+		log.Printf("Error fetching long-term temperature records for client %s between %s and %s: %v", uuid, start, end, err)
+		return recent, nil
+	}
+	return append(longTerm, recent...), nil
+}
+
+// migrateTemperatureRecords 与 GPU 记录相同，按 15 分钟分组压缩到长期表，取 70 分位
+func migrateTemperatureRecords(db *gorm.DB) error {
+	type groupKey struct {
+		Client   string
+		Sensor   string
+		TimeSlot time.Time
+	}
+	return compactRecords(db, "temperature_records_long_term", "client = ? AND sensor = ? AND time = ?",
+		func(rec models.TemperatureRecord) groupKey {
+			return groupKey{Client: rec.Client, Sensor: rec.Sensor, TimeSlot: rec.Time.ToTime().Truncate(15 * time.Minute)}
+		},
+		func(key groupKey) []interface{} { return []interface{}{key.Client, key.Sensor, key.TimeSlot} },
+		func(key groupKey, group []models.TemperatureRecord) models.TemperatureRecord {
+			temperature := percentileOf(group, 0.7, func(r models.TemperatureRecord) float64 { return float64(r.Temperature) })
+			return models.TemperatureRecord{
+				Client:      key.Client,
+				Time:        models.FromTime(key.TimeSlot),
+				Sensor:      key.Sensor,
+				Temperature: float32(temperature),
+			}
+		})
+}
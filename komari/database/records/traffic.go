@@ -0,0 +1,149 @@
+package records
+
+import (
+	"sync"
+	"time"
+
+	"github.com/komari-monitor/komari/common"
+	"github.com/komari-monitor/komari/database/dbcore"
+	"github.com/komari-monitor/komari/database/models"
+	"gorm.io/gorm"
+)
+
+type nicCounter struct {
+	up, down int64
+}
+
+var (
+	trafficMu       sync.Mutex
+	trafficCounters = make(map[string]map[string]nicCounter) // client -> 网卡 -> 上次计数器
+)
+
+// TrafficPeriodStart 计算 now 所在结算周期的起点，结算日超过当月天数时取月末
+func TrafficPeriodStart(resetDay int, now time.Time) time.Time {
+	if resetDay < 1 || resetDay > 31 {
+		resetDay = 1
+	}
+	start := periodStartOfMonth(now.Year(), now.Month(), resetDay, now.Location())
+	if now.Before(start) {
+		start = periodStartOfMonth(now.Year(), now.Month()-1, resetDay, now.Location())
+	}
+	return start
+}
+
+func periodStartOfMonth(year int, month time.Month, day int, loc *time.Location) time.Time {
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// ObserveTraffic 根据网卡累计计数器的变化累加到流量账本。
+// 计数器变小视为系统重启后归零，此时按当前值计入；服务端启动后的第一次上报仅作为基准
+func ObserveTraffic(client string, resetDay int, interfaces []common.InterfaceReport, now time.Time) error {
+	if len(interfaces) == 0 {
+		return nil
+	}
+	trafficMu.Lock()
+	last, ok := trafficCounters[client]
+	current := make(map[string]nicCounter, len(interfaces))
+	var up, down int64
+	for _, nic := range interfaces {
+		cur := nicCounter{up: nic.TotalUp, down: nic.TotalDown}
+		current[nic.Name] = cur
+		if !ok {
+			continue
+		}
+		prev, seen := last[nic.Name]
+		if !seen {
+			// 新出现的网卡从下一次开始计算
+			continue
+		}
+		up += counterIncrease(cur.up, prev.up)
+		down += counterIncrease(cur.down, prev.down)
+	}
+	trafficCounters[client] = current
+	trafficMu.Unlock()
+
+	if up == 0 && down == 0 {
+		return nil
+	}
+	return AddTraffic(client, TrafficPeriodStart(resetDay, now), up, down)
+}
+
+func counterIncrease(cur, prev int64) int64 {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
+
+// AddTraffic 将流量累加到指定周期的账本记录
+func AddTraffic(client string, periodStart time.Time, up, down int64) error {
+	return addTraffic(dbcore.GetDBInstance(), client, periodStart, up, down)
+}
+
+// addTraffic 时间参数按 LocalTime 的存储格式绑定，直接传 time.Time 在 SQLite 上无法与已存的值比较
+func addTraffic(db *gorm.DB, client string, periodStart time.Time, up, down int64) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.TrafficLedger{}).
+			Where("client = ? AND period_start = ?", client, models.FromTime(periodStart)).
+			Updates(map[string]interface{}{
+				"up":         gorm.Expr("up + ?", up),
+				"down":       gorm.Expr("down + ?", down),
+				"updated_at": models.FromTime(time.Now()),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			return nil
+		}
+		return tx.Create(&models.TrafficLedger{
+			Client:      client,
+			PeriodStart: models.FromTime(periodStart),
+			Up:          up,
+			Down:        down,
+		}).Error
+	})
+}
+
+// GetTrafficLedger 按周期倒序返回节点的流量账本，limit <= 0 时返回全部
+func GetTrafficLedger(client string, limit int) ([]models.TrafficLedger, error) {
+	db := dbcore.GetDBInstance()
+	var ledger []models.TrafficLedger
+	query := db.Where("client = ?", client).Order("period_start DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&ledger).Error
+	return ledger, err
+}
+
+// GetCurrentTraffic 返回各节点当前结算周期的流量账本，resetDays 为节点 -> 结算日，无记录的节点不出现在结果中
+func GetCurrentTraffic(resetDays map[string]int, now time.Time) (map[string]models.TrafficLedger, error) {
+	result := make(map[string]models.TrafficLedger, len(resetDays))
+	if len(resetDays) == 0 {
+		return result, nil
+	}
+	clients := make([]string, 0, len(resetDays))
+	since := now
+	for client, day := range resetDays {
+		clients = append(clients, client)
+		if start := TrafficPeriodStart(day, now); start.Before(since) {
+			since = start
+		}
+	}
+	var ledger []models.TrafficLedger
+	db := dbcore.GetDBInstance()
+	if err := db.Where("client IN ? AND period_start >= ?", clients, models.FromTime(since)).Find(&ledger).Error; err != nil {
+		return nil, err
+	}
+	for _, l := range ledger {
+		if l.PeriodStart.ToTime().Equal(TrafficPeriodStart(resetDays[l.Client], now)) {
+			result[l.Client] = l
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,55 @@
+package records
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/komari-monitor/komari/database/models"
+)
+
+func TestTrafficPeriodStart(t *testing.T) {
+	loc := time.UTC
+	cases := []struct {
+		resetDay int
+		now      time.Time
+		want     time.Time
+	}{
+		{1, time.Date(2025, 3, 15, 10, 0, 0, 0, loc), time.Date(2025, 3, 1, 0, 0, 0, 0, loc)},
+		{20, time.Date(2025, 3, 15, 10, 0, 0, 0, loc), time.Date(2025, 2, 20, 0, 0, 0, 0, loc)},
+		{20, time.Date(2025, 3, 20, 0, 0, 0, 0, loc), time.Date(2025, 3, 20, 0, 0, 0, 0, loc)},
+		// 结算日超过当月天数时取月末
+		{31, time.Date(2025, 3, 15, 10, 0, 0, 0, loc), time.Date(2025, 2, 28, 0, 0, 0, 0, loc)},
+		{31, time.Date(2025, 1, 10, 0, 0, 0, 0, loc), time.Date(2024, 12, 31, 0, 0, 0, 0, loc)},
+		{0, time.Date(2025, 3, 15, 10, 0, 0, 0, loc), time.Date(2025, 3, 1, 0, 0, 0, 0, loc)},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, TrafficPeriodStart(c.resetDay, c.now), "resetDay=%d now=%s", c.resetDay, c.now)
+	}
+}
+
+func TestCounterIncrease(t *testing.T) {
+	assert.Equal(t, int64(50), counterIncrease(150, 100))
+	// 计数器归零（重启）时按当前值计入
+	assert.Equal(t, int64(30), counterIncrease(30, 100))
+}
+
+func TestAddTrafficAccumulates(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&models.TrafficLedger{}))
+	start := TrafficPeriodStart(1, time.Now())
+
+	assert.NoError(t, addTraffic(db, "a", start, 100, 10))
+	assert.NoError(t, addTraffic(db, "a", start, 50, 5))
+
+	var ledger []models.TrafficLedger
+	assert.NoError(t, db.Find(&ledger).Error)
+	if assert.Len(t, ledger, 1) {
+		assert.Equal(t, int64(150), ledger[0].Up)
+		assert.Equal(t, int64(15), ledger[0].Down)
+	}
+}
@@ -0,0 +1,96 @@
+package script
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/komari-monitor/komari/database/dbcore"
+	"github.com/komari-monitor/komari/database/models"
+	messageevent "github.com/komari-monitor/komari/database/models/messageEvent"
+)
+
+// TriggerKindEvent 事件触发：TriggerName 为事件名（Offline、Alert、Traffic、Expire 等），多个以逗号分隔，* 匹配除测试消息外的全部
+const TriggerKindEvent = "event"
+
+// HandleEvent 派发与事件匹配的脚本，事件内容作为 params 传入
+func HandleEvent(event models.EventMessage) {
+	var scripts []models.Script
+	if err := dbcore.GetDBInstance().Where("enabled = ? AND trigger_kind = ?", true, TriggerKindEvent).Find(&scripts).Error; err != nil {
+		log.Printf("failed to load event scripts: %v", err)
+		return
+	}
+	for i := range scripts {
+		s := scripts[i]
+		if !matchEventTrigger(s.TriggerName, event.Event) {
+			continue
+		}
+		targets := eventTargets(&s, event)
+		if len(targets) == 0 {
+			continue
+		}
+		if _, err := DispatchScript(&s, targets, TriggerKindEvent, eventParams(event)); err != nil {
+			log.Printf("failed to dispatch event script %d on %s: %v", s.ID, event.Event, err)
+		}
+	}
+}
+
+func matchEventTrigger(triggerName, event string) bool {
+	for _, name := range strings.Split(triggerName, ",") {
+		name = strings.TrimSpace(name)
+		// 测试消息只用于验证通知渠道，不应触发订阅全部事件的脚本
+		if name == "*" && event != messageevent.Test {
+			return true
+		}
+		if name != "" && name != "*" && strings.EqualFold(name, event) {
+			return true
+		}
+	}
+	return false
+}
+
+// eventTargets 指定了执行节点时只在该节点运行；否则在受影响节点上运行，脚本配置了节点列表时取交集
+func eventTargets(s *models.Script, event models.EventMessage) []string {
+	if s.RunnerClient != "" {
+		return []string{s.RunnerClient}
+	}
+	allowed := make(map[string]struct{}, len(s.Clients))
+	for _, id := range s.Clients {
+		allowed[id] = struct{}{}
+	}
+	targets := make([]string, 0, len(event.Clients))
+	for _, c := range event.Clients {
+		if c.UUID == "" {
+			continue
+		}
+		if len(allowed) > 0 {
+			if _, ok := allowed[c.UUID]; !ok {
+				continue
+			}
+		}
+		targets = append(targets, c.UUID)
+	}
+	return targets
+}
+
+// eventParams 只携带节点的公开信息，避免把 Token 等敏感字段下发到脚本
+func eventParams(event models.EventMessage) map[string]interface{} {
+	clients := make([]map[string]interface{}, 0, len(event.Clients))
+	for _, c := range event.Clients {
+		clients = append(clients, map[string]interface{}{
+			"uuid":   c.UUID,
+			"name":   c.Name,
+			"group":  c.Group,
+			"region": c.Region,
+			"ipv4":   c.IPv4,
+			"ipv6":   c.IPv6,
+		})
+	}
+	return map[string]interface{}{
+		"event":   event.Event,
+		"message": event.Message,
+		"emoji":   event.Emoji,
+		"time":    event.Time.Format(time.RFC3339),
+		"clients": clients,
+	}
+}
@@ -8,7 +8,7 @@ import (
 
 func GetAllFolders() ([]models.ScriptFolder, error) {
 	var folders []models.ScriptFolder
-	if err := dbcore.GetDBInstance().Order("`order` asc, id asc").Find(&folders).Error; err != nil {
+	if err := dbcore.GetDBInstance().Order(orderByOrder).Find(&folders).Error; err != nil {
 		return nil, err
 	}
 	return folders, nil
@@ -6,11 +6,18 @@ import (
 	"github.com/komari-monitor/komari/database/dbcore"
 	"github.com/komari-monitor/komari/database/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// orderByOrder 按 order、id 升序；order 是保留字，由方言负责引用
+var orderByOrder = clause.OrderBy{Columns: []clause.OrderByColumn{
+	{Column: clause.Column{Name: "order"}},
+	{Column: clause.Column{Name: "id"}},
+}}
+
 func GetAllScripts() ([]models.Script, error) {
 	var scripts []models.Script
-	if err := dbcore.GetDBInstance().Order("`order` asc, id asc").Find(&scripts).Error; err != nil {
+	if err := dbcore.GetDBInstance().Order(orderByOrder).Find(&scripts).Error; err != nil {
 		return nil, err
 	}
 	return scripts, nil
@@ -47,6 +54,7 @@ func UpdateScripts(scripts []*models.Script) error {
 			"cron_expr":          s.CronExpr,
 			"trigger_name":       s.TriggerName,
 			"message_type":       s.MessageType,
+			"runner_client":      s.RunnerClient,
 			"depends_on_scripts": s.DependsOnScripts,
 			"depends_on_folders": s.DependsOnFolders,
 			"updated_at":         models.Now(),
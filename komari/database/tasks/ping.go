@@ -155,3 +155,27 @@ func GetPingRecords(uuid string, taskId int, start, end time.Time) ([]models.Pin
 	}
 	return records, nil
 }
+
+// GetLatestPingRecords 返回 since 之后每个节点、每个任务的最新一条记录
+func GetLatestPingRecords(since time.Time) ([]models.PingRecord, error) {
+	db := dbcore.GetDBInstance()
+	var records []models.PingRecord
+	if err := db.Where("time >= ?", since).Order("time DESC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	type key struct {
+		client string
+		task   uint
+	}
+	seen := make(map[key]struct{}, len(records))
+	latest := make([]models.PingRecord, 0)
+	for _, r := range records {
+		k := key{client: r.Client, task: r.TaskId}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		latest = append(latest, r)
+	}
+	return latest, nil
+}
@@ -160,6 +160,30 @@ func GetSPPingRecords(uuid string, taskId int, start, end time.Time, bucketStep
 	return records, nil
 }
 
+// GetLatestSPPingRecords 返回 since 之后每个节点、每个任务最新一轮的原始记录（bucket_step = step）
+func GetLatestSPPingRecords(since time.Time) ([]models.SPPingRecord, error) {
+	db := dbcore.GetDBInstance()
+	var records []models.SPPingRecord
+	if err := db.Omit("samples").Where("bucket_step = step AND time >= ?", since).Order("time DESC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	type key struct {
+		client string
+		task   uint
+	}
+	seen := make(map[key]struct{}, len(records))
+	latest := make([]models.SPPingRecord, 0)
+	for _, r := range records {
+		k := key{client: r.Client, task: r.TaskId}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		latest = append(latest, r)
+	}
+	return latest, nil
+}
+
 // AggregateSPPingRecords 将原始数据按 SmokePing 风格聚合到更大步长
 func AggregateSPPingRecords(preserveHours int) error {
 	db := dbcore.GetDBInstance()
@@ -7,26 +7,30 @@ import (
 	"github.com/komari-monitor/komari/database/models"
 )
 
-func CreateTask(taskId string, clients []string, command string) error {
+func CreateTask(taskId string, clients []string, command string, timeout int) error {
 	db := dbcore.GetDBInstance()
 	// Create a new task in the database with clients as JSON array
 	task := models.Task{
 		TaskId:  taskId,
 		Clients: models.StringArray(clients),
 		Command: command,
+		Timeout: timeout,
 	}
 	if err := db.Create(&task).Error; err != nil {
 		return err
 	}
 	var taskResults []models.TaskResult
+	now := models.FromTime(time.Now())
 	for _, client := range clients {
 		taskResults = append(taskResults, models.TaskResult{
-			TaskId:     taskId,
-			Client:     client,
-			Result:     "",
-			ExitCode:   nil,
-			FinishedAt: nil,
-			CreatedAt:  models.FromTime(time.Now()),
+			TaskId:      taskId,
+			Client:      client,
+			Result:      "",
+			ExitCode:    nil,
+			FinishedAt:  nil,
+			CreatedAt:   now,
+			Status:      models.TaskStatusDelivered,
+			DeliveredAt: &now,
 		})
 	}
 	if len(taskResults) > 0 {
@@ -84,6 +88,11 @@ func DeleteTaskByTaskId(taskId string) error {
 }
 
 func SaveTaskResult(taskId, clientId, result string, exitCode int, timestamp models.LocalTime) error {
+	return SaveTaskResultWithStatus(taskId, clientId, result, exitCode, timestamp, models.TaskStatusFinished)
+}
+
+// SaveTaskResultWithStatus 保存任务结果，status 区分正常结束、取消与超时
+func SaveTaskResultWithStatus(taskId, clientId, result string, exitCode int, timestamp models.LocalTime, status string) error {
 	return dbcore.GetDBInstance().
 		Model(&models.TaskResult{}).
 		Where("task_id = ? AND client = ?", taskId, clientId).
@@ -91,9 +100,75 @@ func SaveTaskResult(taskId, clientId, result string, exitCode int, timestamp mod
 			"result":      result,
 			"exit_code":   exitCode,
 			"finished_at": timestamp,
+			"status":      status,
+		}).Error
+}
+
+// CancelPendingTask 取消仍在排队的任务，返回 false 表示任务已下发或已结束
+func CancelPendingTask(taskId, clientId string) (bool, error) {
+	result := dbcore.GetDBInstance().
+		Model(&models.TaskResult{}).
+		Where("task_id = ? AND client = ? AND status = ?", taskId, clientId, models.TaskStatusPending).
+		Updates(map[string]interface{}{
+			"status":      models.TaskStatusCancelled,
+			"result":      "Cancelled before delivery",
+			"exit_code":   -1,
+			"finished_at": models.FromTime(time.Now()),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// QueueTaskResults 将节点的任务结果标记为排队，节点在 deadline 前上线时再下发
+func QueueTaskResults(taskId string, clients []string, deadline time.Time) error {
+	if len(clients) == 0 {
+		return nil
+	}
+	return dbcore.GetDBInstance().
+		Model(&models.TaskResult{}).
+		Where("task_id = ? AND client IN ?", taskId, clients).
+		Updates(map[string]interface{}{
+			"status":       models.TaskStatusPending,
+			"deadline":     models.FromTime(deadline),
+			"delivered_at": nil,
+		}).Error
+}
+
+// GetPendingTaskResults 获取节点排队中的任务，按创建时间排序
+func GetPendingTaskResults(clientId string) ([]models.TaskResult, error) {
+	var results []models.TaskResult
+	if err := dbcore.GetDBInstance().Where("client = ? AND status = ?", clientId, models.TaskStatusPending).
+		Order("created_at ASC").Find(&results).Error; err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// MarkTaskDelivered 将排队中的任务标记为已下发，返回 false 表示已被其他连接下发或已过期
+func MarkTaskDelivered(taskId, clientId string) (bool, error) {
+	result := dbcore.GetDBInstance().
+		Model(&models.TaskResult{}).
+		Where("task_id = ? AND client = ? AND status = ?", taskId, clientId, models.TaskStatusPending).
+		Updates(map[string]interface{}{
+			"status":       models.TaskStatusDelivered,
+			"delivered_at": models.FromTime(time.Now()),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ExpirePendingTasks 将超过截止时间仍未下发的任务标记为过期
+func ExpirePendingTasks(now time.Time) error {
+	return dbcore.GetDBInstance().
+		Model(&models.TaskResult{}).
+		Where("status = ? AND deadline < ?", models.TaskStatusPending, now).
+		Updates(map[string]interface{}{
+			"status":      models.TaskStatusExpired,
+			"result":      "Client did not come online before the deadline",
+			"exit_code":   -1,
+			"finished_at": models.FromTime(now),
 		}).Error
 }
 
+// ClearTaskResultsByTimeBefore 清理旧的任务结果，仍在排队的不清理
 func ClearTaskResultsByTimeBefore(before time.Time) error {
-	return dbcore.GetDBInstance().Where("created_at < ?", before.Format(time.RFC3339)).Delete(&models.TaskResult{}).Error
+	return dbcore.GetDBInstance().Where("created_at < ? AND (status IS NULL OR status <> ?)", before.Format(time.RFC3339), models.TaskStatusPending).Delete(&models.TaskResult{}).Error
 }
@@ -0,0 +1,232 @@
+package backup
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/komari-monitor/komari/cmd/flags"
+	"github.com/komari-monitor/komari/database/dbcore"
+)
+
+// MarkupFile 备份包根目录的标记文件，恢复时据此校验
+const MarkupFile = "komari-backup-markup"
+
+// copyFile 复制单个文件到目标路径（会确保父目录存在）
+func copyFile(srcPath, destPath string) error {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create parent directory: %v", err)
+	}
+
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to open source file: %v", err)
+	}
+	defer src.Close()
+
+	dest, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("failed to create destination file: %v", err)
+	}
+	defer dest.Close()
+
+	if _, err = io.Copy(dest, src); err != nil {
+		return fmt.Errorf("failed to copy file: %v", err)
+	}
+	return nil
+}
+
+// copyDataToTempExcludingDB 将 ./data 下除了 .db/.db-wal/.db-shm 之外的所有文件复制到临时目录，
+// exclude 中的目录（例如位于 ./data 下的备份目录）会被跳过
+func copyDataToTempExcludingDB(tempDir string, exclude ...string) error {
+	dataRoot := "./data"
+
+	// 如果 data 目录不存在，视为无文件可复制
+	if stat, err := os.Stat(dataRoot); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("stat data dir: %v", err)
+	} else if !stat.IsDir() {
+		return fmt.Errorf("%s is not a directory", dataRoot)
+	}
+
+	excluded := make(map[string]bool, len(exclude))
+	for _, p := range exclude {
+		if p == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(p); err == nil {
+			excluded[abs] = true
+		}
+	}
+
+	return filepath.Walk(dataRoot, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dataRoot, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if abs, err := filepath.Abs(p); err == nil && excluded[abs] {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// 跳过数据库相关文件
+		name := info.Name()
+		if strings.HasSuffix(strings.ToLower(name), ".db") ||
+			strings.HasSuffix(strings.ToLower(name), ".db-wal") ||
+			strings.HasSuffix(strings.ToLower(name), ".db-shm") {
+			return nil
+		}
+
+		dst := filepath.Join(tempDir, rel)
+		if info.IsDir() {
+			return os.MkdirAll(dst, 0o755)
+		}
+		return copyFile(p, dst)
+	})
+}
+
+// backupSQLiteTo 使用 SQLite VACUUM INTO 将当前数据库一致性备份到指定路径
+func backupSQLiteTo(destDBPath string) error {
+	if err := os.MkdirAll(filepath.Dir(destDBPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create parent directory for db: %v", err)
+	}
+
+	db := dbcore.GetDBInstance()
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get underlying database connection: %v", err)
+	}
+
+	safePath := strings.ReplaceAll(destDBPath, "'", "''")
+	vacuumSQL := fmt.Sprintf("VACUUM INTO '%s'", safePath)
+	if _, err = sqlDB.Exec(vacuumSQL); err != nil {
+		return fmt.Errorf("sqlite VACUUM INTO failed: %v", err)
+	}
+	return nil
+}
+
+// dumpDatabaseTo 将 MySQL/PostgreSQL 逻辑备份写入指定路径
+func dumpDatabaseTo(destPath string) error {
+	f, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("failed to create dump file: %v", err)
+	}
+	if err := dbcore.DumpDatabase(dbcore.GetDBInstance(), f); err != nil {
+		f.Close()
+		return fmt.Errorf("database dump failed: %v", err)
+	}
+	return f.Close()
+}
+
+// PrepareArchive 将 ./data 与数据库的一致性副本放入新建的临时目录并返回该目录，调用方负责删除。
+// SQLite 使用 VACUUM INTO，其他数据库导出为逻辑备份
+func PrepareArchive(exclude ...string) (string, error) {
+	tempDir, err := os.MkdirTemp("", "komari-backup-*")
+	if err != nil {
+		return "", fmt.Errorf("error creating temporary directory: %v", err)
+	}
+	if err := copyDataToTempExcludingDB(tempDir, exclude...); err != nil {
+		os.RemoveAll(tempDir)
+		return "", fmt.Errorf("error copying data to temp: %v", err)
+	}
+	if flags.DatabaseType == "sqlite" || flags.DatabaseType == "" {
+		err = backupSQLiteTo(filepath.Join(tempDir, "komari.db"))
+	} else {
+		err = dumpDatabaseTo(filepath.Join(tempDir, dbcore.LogicalDumpFile))
+	}
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return "", err
+	}
+	return tempDir, nil
+}
+
+// WriteZip 将 PrepareArchive 生成的目录打包为 zip，并在根目录追加备份标记文件
+func WriteZip(w io.Writer, dir string) error {
+	zipWriter := zip.NewWriter(w)
+
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		// zip 内路径统一正斜杠
+		zipPath := filepath.ToSlash(rel)
+		if info.IsDir() {
+			_, err := zipWriter.CreateHeader(&zip.FileHeader{
+				Name:     zipPath + "/",
+				Method:   zip.Deflate,
+				Modified: info.ModTime(),
+			})
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w, err := zipWriter.CreateHeader(&zip.FileHeader{
+			Name:     zipPath,
+			Method:   zip.Deflate,
+			Modified: info.ModTime(),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, f)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error archiving temp folder: %v", err)
+	}
+
+	markupContent := "此文件为 Komari 备份标记文件，请勿删除。\nThis is a Komari backup markup file, please do not delete.\n\n备份时间 / Backup Time: " + time.Now().Format(time.RFC3339)
+	markupWriter, err := zipWriter.CreateHeader(&zip.FileHeader{
+		Name:     MarkupFile,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("error creating backup markup file: %v", err)
+	}
+	if _, err = markupWriter.Write([]byte(markupContent)); err != nil {
+		return fmt.Errorf("error writing backup markup file: %v", err)
+	}
+	return zipWriter.Close()
+}
+
+// HasMarkup 检查 zip 文件是否为 Komari 备份
+func HasMarkup(zipPath string) (bool, error) {
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return false, err
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.Name == MarkupFile {
+			return true, nil
+		}
+	}
+	return false, nil
+}
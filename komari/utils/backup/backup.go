@@ -0,0 +1,270 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/komari-monitor/komari/database/auditlog"
+	"github.com/komari-monitor/komari/database/models"
+	"github.com/robfig/cron/v3"
+)
+
+// ErrRunning 已有备份在进行
+var ErrRunning = errors.New("another backup is already running")
+
+// Status 最近一次定时或手动备份的结果
+type Status struct {
+	Running   bool       `json:"running"`
+	LastRun   *time.Time `json:"last_run,omitempty"`
+	LastName  string     `json:"last_name,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	NextRun   *time.Time `json:"next_run,omitempty"`
+}
+
+var (
+	mu        sync.Mutex
+	scheduler *cron.Cron
+	entryID   cron.EntryID
+	status    Status
+	running   sync.Mutex
+)
+
+// 支持标准 5 段与带秒的 6 段表达式
+var cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// ConfigChanged 判断备份相关配置是否变化
+func ConfigChanged(old, new models.Config) bool {
+	return old.BackupEnabled != new.BackupEnabled ||
+		old.BackupCron != new.BackupCron ||
+		old.BackupPassphrase != new.BackupPassphrase ||
+		old.BackupKeep != new.BackupKeep ||
+		old.BackupLocalDir != new.BackupLocalDir ||
+		old.BackupS3Endpoint != new.BackupS3Endpoint ||
+		old.BackupS3Region != new.BackupS3Region ||
+		old.BackupS3Bucket != new.BackupS3Bucket ||
+		old.BackupS3Prefix != new.BackupS3Prefix ||
+		old.BackupS3AccessKey != new.BackupS3AccessKey ||
+		old.BackupS3SecretKey != new.BackupS3SecretKey ||
+		old.BackupS3PathStyle != new.BackupS3PathStyle
+}
+
+// Storages 按配置返回已启用的存储位置
+func Storages(cfg models.Config) ([]Storage, error) {
+	var storages []Storage
+	if cfg.BackupLocalDir != "" {
+		storages = append(storages, &localStorage{dir: cfg.BackupLocalDir})
+	}
+	if cfg.BackupS3Bucket != "" {
+		s3, err := newS3Storage(cfg.BackupS3Endpoint, cfg.BackupS3Region, cfg.BackupS3Bucket, cfg.BackupS3Prefix,
+			cfg.BackupS3AccessKey, cfg.BackupS3SecretKey, cfg.BackupS3PathStyle)
+		if err != nil {
+			return nil, err
+		}
+		storages = append(storages, s3)
+	}
+	if len(storages) == 0 {
+		return nil, errors.New("no backup storage configured")
+	}
+	return storages, nil
+}
+
+func storageByName(cfg models.Config, name string) (Storage, error) {
+	storages, err := Storages(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range storages {
+		if s.Name() == name {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("backup storage not configured: %s", name)
+}
+
+// Reload 按配置重建定时任务，未启用时停止
+func Reload(cfg models.Config) error {
+	Stop()
+	if !cfg.BackupEnabled {
+		return nil
+	}
+	if cfg.BackupPassphrase == "" {
+		return errors.New("backup passphrase is required")
+	}
+	if _, err := Storages(cfg); err != nil {
+		return err
+	}
+	schedule, err := cronParser.Parse(cfg.BackupCron)
+	if err != nil {
+		return fmt.Errorf("invalid backup cron: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	scheduler = cron.New(cron.WithParser(cronParser))
+	entryID = scheduler.Schedule(schedule, cron.FuncJob(func() {
+		if _, err := Run(context.Background(), cfg); err != nil && !errors.Is(err, ErrRunning) {
+			auditlog.EventLog("error", fmt.Sprintf("Scheduled backup failed: %v", err))
+		}
+	}))
+	scheduler.Start()
+	log.Printf("Scheduled backup enabled: %s", cfg.BackupCron)
+	return nil
+}
+
+// Stop 停止定时任务，正在进行的备份不受影响
+func Stop() {
+	mu.Lock()
+	defer mu.Unlock()
+	if scheduler != nil {
+		scheduler.Stop()
+		scheduler = nil
+	}
+}
+
+// GetStatus 返回最近一次备份的结果与下次执行时间
+func GetStatus() Status {
+	mu.Lock()
+	defer mu.Unlock()
+	s := status
+	if scheduler != nil {
+		if next := scheduler.Entry(entryID).Next; !next.IsZero() {
+			s.NextRun = &next
+		}
+	}
+	return s
+}
+
+func setStatus(f func(s *Status)) {
+	mu.Lock()
+	defer mu.Unlock()
+	f(&status)
+}
+
+// Run 立即执行一次备份：打包、加密后写入所有存储位置并轮换旧备份，返回备份文件名。
+// 部分存储失败时其余存储仍会写入，错误合并返回
+func Run(ctx context.Context, cfg models.Config) (string, error) {
+	if !running.TryLock() {
+		return "", ErrRunning
+	}
+	defer running.Unlock()
+	setStatus(func(s *Status) { s.Running = true })
+
+	name, err := run(ctx, cfg)
+	now := time.Now()
+	setStatus(func(s *Status) {
+		s.Running = false
+		s.LastRun = &now
+		s.LastName = name
+		s.LastError = ""
+		if err != nil {
+			s.LastError = err.Error()
+		}
+	})
+	if err == nil {
+		log.Printf("Backup %s completed", name)
+	}
+	return name, err
+}
+
+func run(ctx context.Context, cfg models.Config) (string, error) {
+	if cfg.BackupPassphrase == "" {
+		return "", errors.New("backup passphrase is required")
+	}
+	storages, err := Storages(cfg)
+	if err != nil {
+		return "", err
+	}
+	dir, err := PrepareArchive(cfg.BackupLocalDir)
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	work, err := os.MkdirTemp("", "komari-backup-out-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(work)
+	name := backupName(time.Now())
+	zipPath := filepath.Join(work, "backup.zip")
+	encPath := filepath.Join(work, name)
+	if err := writeFile(zipPath, func(w io.Writer) error { return WriteZip(w, dir) }); err != nil {
+		return "", err
+	}
+	zipFile, err := os.Open(zipPath)
+	if err != nil {
+		return "", err
+	}
+	err = writeFile(encPath, func(w io.Writer) error { return Encrypt(w, zipFile, cfg.BackupPassphrase) })
+	zipFile.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt backup: %v", err)
+	}
+
+	var errs []error
+	for _, s := range storages {
+		if err := s.Put(ctx, name, encPath); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", s.Name(), err))
+			continue
+		}
+		if err := rotate(ctx, s, cfg.BackupKeep); err != nil {
+			errs = append(errs, fmt.Errorf("%s rotate: %v", s.Name(), err))
+		}
+	}
+	return name, errors.Join(errs...)
+}
+
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// List 列出所有存储位置中的备份，按时间从新到旧排序；单个存储不可用时返回其余结果与错误
+func List(ctx context.Context, cfg models.Config) ([]Item, error) {
+	storages, err := Storages(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var items []Item
+	var errs []error
+	for _, s := range storages {
+		list, err := s.List(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", s.Name(), err))
+			continue
+		}
+		items = append(items, list...)
+	}
+	sortItems(items)
+	return items, errors.Join(errs...)
+}
+
+// Fetch 下载并解密指定备份，写入 dst（zip 格式）
+func Fetch(ctx context.Context, cfg models.Config, storage, name, dst string) error {
+	if cfg.BackupPassphrase == "" {
+		return errors.New("backup passphrase is required")
+	}
+	s, err := storageByName(cfg, storage)
+	if err != nil {
+		return err
+	}
+	src, err := s.Open(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return writeFile(dst, func(w io.Writer) error { return Decrypt(w, src, cfg.BackupPassphrase) })
+}
@@ -0,0 +1,158 @@
+package backup
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// 加密文件格式：
+//
+//	magic(8) | salt(16) | nonce 前缀(7) | 分块...
+//
+// 每块为 长度(4, 大端) | AES-256-GCM 密文，明文最多 encryptChunkSize 字节。
+// 块 nonce = 前缀(7) | 序号(4) | 末块标记(1)，可检测块被截断、重排或替换
+const (
+	encryptMagic     = "KMRBAK1\n"
+	encryptChunkSize = 64 * 1024
+	saltSize         = 16
+	noncePrefixSize  = 7
+)
+
+// ErrDecrypt 口令错误或文件已损坏
+var ErrDecrypt = errors.New("failed to decrypt backup: wrong passphrase or corrupted file")
+
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(prefix []byte, index uint32, final bool) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], index)
+	if final {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// Encrypt 使用口令加密 src 并写入 dst
+func Encrypt(dst io.Writer, src io.Reader, passphrase string) error {
+	if passphrase == "" {
+		return errors.New("backup passphrase is required")
+	}
+	header := make([]byte, len(encryptMagic)+saltSize+noncePrefixSize)
+	copy(header, encryptMagic)
+	if _, err := io.ReadFull(rand.Reader, header[len(encryptMagic):]); err != nil {
+		return err
+	}
+	salt := header[len(encryptMagic) : len(encryptMagic)+saltSize]
+	prefix := header[len(encryptMagic)+saltSize:]
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	if _, err := dst.Write(header); err != nil {
+		return err
+	}
+
+	// 预读一块以判断当前块是否为末块
+	r := bufio.NewReaderSize(src, encryptChunkSize+1)
+	buf := make([]byte, encryptChunkSize)
+	var sealed []byte
+	var lenBuf [4]byte
+	for index := uint32(0); ; index++ {
+		n, err := io.ReadFull(r, buf)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return err
+		}
+		final := n < encryptChunkSize
+		if !final {
+			if _, peekErr := r.Peek(1); peekErr == io.EOF {
+				final = true
+			}
+		}
+		sealed = gcm.Seal(sealed[:0], chunkNonce(prefix, index, final), buf[:n], nil)
+		binary.BigEndian.PutUint32(lenBuf[:], uint32(len(sealed)))
+		if _, err := dst.Write(lenBuf[:]); err != nil {
+			return err
+		}
+		if _, err := dst.Write(sealed); err != nil {
+			return err
+		}
+		if final {
+			return nil
+		}
+		if index == ^uint32(0) {
+			return errors.New("backup too large")
+		}
+	}
+}
+
+// Decrypt 解密 Encrypt 生成的数据并写入 dst
+func Decrypt(dst io.Writer, src io.Reader, passphrase string) error {
+	header := make([]byte, len(encryptMagic)+saltSize+noncePrefixSize)
+	if _, err := io.ReadFull(src, header); err != nil || string(header[:len(encryptMagic)]) != encryptMagic {
+		return errors.New("not an encrypted komari backup")
+	}
+	salt := header[len(encryptMagic) : len(encryptMagic)+saltSize]
+	prefix := header[len(encryptMagic)+saltSize:]
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	maxSealed := encryptChunkSize + gcm.Overhead()
+	sealed := make([]byte, maxSealed)
+	var plain []byte
+	var lenBuf [4]byte
+	for index := uint32(0); ; index++ {
+		if _, err := io.ReadFull(src, lenBuf[:]); err != nil {
+			// 未读到末块即结束，文件被截断
+			return ErrDecrypt
+		}
+		size := int(binary.BigEndian.Uint32(lenBuf[:]))
+		if size < gcm.Overhead() || size > maxSealed {
+			return ErrDecrypt
+		}
+		if _, err := io.ReadFull(src, sealed[:size]); err != nil {
+			return ErrDecrypt
+		}
+		final := false
+		plain, err = gcm.Open(plain[:0], chunkNonce(prefix, index, false), sealed[:size], nil)
+		if err != nil {
+			final = true
+			plain, err = gcm.Open(plain[:0], chunkNonce(prefix, index, true), sealed[:size], nil)
+			if err != nil {
+				return ErrDecrypt
+			}
+		}
+		if _, err := dst.Write(plain); err != nil {
+			return err
+		}
+		if final {
+			if n, _ := src.Read(lenBuf[:1]); n > 0 {
+				return fmt.Errorf("%w: trailing data", ErrDecrypt)
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// s3Storage S3 兼容存储，使用 AWS Signature V4 签名，兼容 AWS、MinIO、R2 等
+type s3Storage struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	prefix    string
+	accessKey string
+	secretKey string
+	pathStyle bool
+	client    *http.Client
+}
+
+func newS3Storage(endpoint, region, bucket, prefix, accessKey, secretKey string, pathStyle bool) (*s3Storage, error) {
+	u, err := url.Parse(strings.TrimRight(strings.TrimSpace(endpoint), "/"))
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid s3 endpoint: %s", endpoint)
+	}
+	if bucket == "" {
+		return nil, fmt.Errorf("s3 bucket is required")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &s3Storage{
+		endpoint:  u,
+		region:    region,
+		bucket:    bucket,
+		prefix:    strings.Trim(prefix, "/"),
+		accessKey: accessKey,
+		secretKey: secretKey,
+		pathStyle: pathStyle,
+		client:    &http.Client{Timeout: 30 * time.Minute},
+	}, nil
+}
+
+func (s *s3Storage) Name() string { return StorageS3 }
+
+func (s *s3Storage) key(name string) string {
+	if s.prefix == "" {
+		return name
+	}
+	return s.prefix + "/" + name
+}
+
+// objectURL 返回对象地址，key 为空时为存储桶地址
+func (s *s3Storage) objectURL(key string, query url.Values) *url.URL {
+	u := *s.endpoint
+	basePath := strings.TrimRight(u.Path, "/")
+	if s.pathStyle {
+		u.Path = basePath + "/" + s.bucket + "/" + key
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = basePath + "/" + key
+	}
+	u.RawQuery = query.Encode()
+	return &u
+}
+
+func (s *s3Storage) do(ctx context.Context, method string, u *url.URL, body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	signV4(req, s.accessKey, s.secretKey, s.region, payloadHash, time.Now())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("s3 %s returned %s: %s", method, resp.Status, bytes.TrimSpace(msg))
+	}
+	return resp, nil
+}
+
+func (s *s3Storage) Put(ctx context.Context, name, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodPut, s.objectURL(s.key(name), nil), f, size, hex.EncodeToString(h.Sum(nil)))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (s *s3Storage) List(ctx context.Context) ([]Item, error) {
+	var items []Item
+	prefix := s.key("")
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := s.do(ctx, http.MethodGet, s.objectURL("", query), nil, 0, emptyPayloadHash)
+		if err != nil {
+			return nil, err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid s3 list response: %v", err)
+		}
+		for _, obj := range result.Contents {
+			name := strings.TrimPrefix(obj.Key, prefix)
+			if !isBackupName(name) {
+				continue
+			}
+			items = append(items, Item{Storage: StorageS3, Name: name, Size: obj.Size, Time: timeFromName(name)})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return items, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+func (s *s3Storage) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	if !isBackupName(name) {
+		return nil, fmt.Errorf("invalid backup name: %s", name)
+	}
+	resp, err := s.do(ctx, http.MethodGet, s.objectURL(s.key(name), nil), nil, 0, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *s3Storage) Delete(ctx context.Context, name string) error {
+	if !isBackupName(name) {
+		return fmt.Errorf("invalid backup name: %s", name)
+	}
+	resp, err := s.do(ctx, http.MethodDelete, s.objectURL(s.key(name), nil), nil, 0, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// signV4 为请求添加 AWS Signature V4 认证头，签名包含 Host 与请求上已设置的所有头
+func signV4(req *http.Request, accessKey, secretKey, region, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.EscapedPath(), false),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s,SignedHeaders=%s,Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode 按 SigV4 规则编码，path 为已转义路径时先还原再编码，保留 '/'
+func uriEncode(s string, encodeSlash bool) string {
+	if !encodeSlash {
+		if unescaped, err := url.PathUnescape(s); err == nil {
+			s = unescaped
+		}
+		if s == "" {
+			s = "/"
+		}
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,143 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	StorageLocal = "local"
+	StorageS3    = "s3"
+
+	filePrefix = "komari-backup-"
+	fileSuffix = ".zip.enc"
+)
+
+// Item 存储中的一份备份
+type Item struct {
+	Storage string    `json:"storage"`
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	Time    time.Time `json:"time"`
+}
+
+// Storage 备份存储位置，名称均为不含路径的文件名
+type Storage interface {
+	Name() string
+	Put(ctx context.Context, name, path string) error
+	List(ctx context.Context) ([]Item, error)
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// backupName 备份文件名按时间排序即为生成顺序
+func backupName(t time.Time) string {
+	return filePrefix + t.UTC().Format("20060102-150405") + fileSuffix
+}
+
+func isBackupName(name string) bool {
+	return strings.HasPrefix(name, filePrefix) && strings.HasSuffix(name, fileSuffix) && !strings.ContainsAny(name, `/\`)
+}
+
+func timeFromName(name string) time.Time {
+	ts := strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix)
+	t, _ := time.Parse("20060102-150405", ts)
+	return t
+}
+
+// sortItems 按时间从新到旧排序
+func sortItems(items []Item) {
+	sort.Slice(items, func(i, j int) bool { return items[i].Name > items[j].Name })
+}
+
+// rotate 只保留最新的 keep 份
+func rotate(ctx context.Context, s Storage, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	items, err := s.List(ctx)
+	if err != nil {
+		return err
+	}
+	sortItems(items)
+	for _, item := range items[min(keep, len(items)):] {
+		if err := s.Delete(ctx, item.Name); err != nil {
+			return fmt.Errorf("failed to delete %s: %v", item.Name, err)
+		}
+	}
+	return nil
+}
+
+type localStorage struct {
+	dir string
+}
+
+func (s *localStorage) Name() string { return StorageLocal }
+
+func (s *localStorage) Put(ctx context.Context, name, path string) error {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	tmp := filepath.Join(s.dir, name+".tmp")
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, name))
+}
+
+func (s *localStorage) List(ctx context.Context) ([]Item, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var items []Item
+	for _, e := range entries {
+		if e.IsDir() || !isBackupName(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		items = append(items, Item{Storage: StorageLocal, Name: e.Name(), Size: info.Size(), Time: timeFromName(e.Name())})
+	}
+	return items, nil
+}
+
+func (s *localStorage) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	if !isBackupName(name) {
+		return nil, fmt.Errorf("invalid backup name: %s", name)
+	}
+	return os.Open(filepath.Join(s.dir, name))
+}
+
+func (s *localStorage) Delete(ctx context.Context, name string) error {
+	if !isBackupName(name) {
+		return fmt.Errorf("invalid backup name: %s", name)
+	}
+	return os.Remove(filepath.Join(s.dir, name))
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/komari-monitor/komari/database"
 	"github.com/komari-monitor/komari/utils/messageSender/factory"
 )
 
@@ -28,6 +29,34 @@ func LoadProvider(name string, addition string) error {
 	return nil
 }
 
+// newConfiguredProvider 按数据库中保存的配置创建独立的提供者实例，不影响当前提供者
+func newConfiguredProvider(name string) (factory.IMessageSender, error) {
+	addition := "{}"
+	if senderConfig, err := database.GetMessageSenderConfigByName(name); err == nil && senderConfig.Addition != "" {
+		addition = senderConfig.Addition
+	}
+	return buildProvider(name, addition)
+}
+
+// buildProvider 创建并初始化提供者实例
+func buildProvider(name string, addition string) (factory.IMessageSender, error) {
+	constructor, exists := factory.GetConstructor(name)
+	if !exists {
+		return nil, fmt.Errorf("message sender provider not found: %s", name)
+	}
+	if addition == "" {
+		addition = "{}"
+	}
+	provider := constructor()
+	if err := json.Unmarshal([]byte(addition), provider.GetConfiguration()); err != nil {
+		return nil, fmt.Errorf("failed to load config for provider %s: %w", name, err)
+	}
+	if err := provider.Init(); err != nil {
+		return nil, fmt.Errorf("failed to init provider %s: %w", name, err)
+	}
+	return provider, nil
+}
+
 func GetProviderConfiguration(name string) (map[string]interface{}, error) {
 	constructor, exists := factory.GetConstructor(name)
 	if !exists {
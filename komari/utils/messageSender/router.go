@@ -0,0 +1,165 @@
+package messageSender
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/komari-monitor/komari/database"
+	"github.com/komari-monitor/komari/database/models"
+	"github.com/komari-monitor/komari/utils/messageSender/factory"
+)
+
+// channelInstance 已初始化的渠道，template 为空时使用全局 NotificationTemplate
+//
+// inflight 记录正在进行的发送，重新加载时旧实例在发送完成后才销毁
+type channelInstance struct {
+	name     string
+	provider factory.IMessageSender
+	template string
+	inflight sync.WaitGroup
+}
+
+// drain 等待进行中的发送结束后销毁提供者
+func (ch *channelInstance) drain() {
+	ch.inflight.Wait()
+	ch.provider.Destroy()
+}
+
+var (
+	channelsMu sync.RWMutex
+	channels   = make(map[string]*channelInstance)
+	routes     []models.MessageRoute
+)
+
+// ReloadChannels 从数据库重建全部渠道实例与路由规则
+func ReloadChannels() error {
+	list, err := database.GetAllMessageChannels()
+	if err != nil {
+		return err
+	}
+	allRoutes, err := database.GetAllMessageRoutes()
+	if err != nil {
+		return err
+	}
+	built := make(map[string]*channelInstance, len(list))
+	for _, ch := range list {
+		if !ch.Enabled {
+			continue
+		}
+		provider, err := buildProvider(ch.Provider, ch.Addition)
+		if err != nil {
+			log.Printf("Failed to load message channel %s: %v", ch.Name, err)
+			continue
+		}
+		built[ch.Name] = &channelInstance{name: ch.Name, provider: provider, template: ch.Template}
+	}
+	enabledRoutes := make([]models.MessageRoute, 0, len(allRoutes))
+	for _, r := range allRoutes {
+		if r.Enabled {
+			enabledRoutes = append(enabledRoutes, r)
+		}
+	}
+
+	channelsMu.Lock()
+	old := channels
+	channels = built
+	routes = enabledRoutes
+	channelsMu.Unlock()
+
+	// 替换后旧实例不会再被取用，正在发送的完成后再销毁
+	for _, ch := range old {
+		go ch.drain()
+	}
+	return nil
+}
+
+// getChannel 取用渠道实例，需经 deliverToChannels 发送以释放引用
+func getChannel(name string) *channelInstance {
+	channelsMu.RLock()
+	defer channelsMu.RUnlock()
+	ch := channels[name]
+	if ch != nil {
+		ch.inflight.Add(1)
+	}
+	return ch
+}
+
+// routeEvent 按路由规则顺序匹配事件，返回去重后的目标渠道，引用规则同 getChannel
+func routeEvent(event models.EventMessage) []*channelInstance {
+	channelsMu.RLock()
+	defer channelsMu.RUnlock()
+	seen := make(map[string]bool)
+	var targets []*channelInstance
+	for _, r := range routes {
+		if !matchRoute(r, event) {
+			continue
+		}
+		for _, name := range r.Channels {
+			ch, ok := channels[name]
+			if !ok || seen[name] {
+				continue
+			}
+			seen[name] = true
+			ch.inflight.Add(1)
+			targets = append(targets, ch)
+		}
+		if r.Stop {
+			break
+		}
+	}
+	return targets
+}
+
+func matchRoute(r models.MessageRoute, event models.EventMessage) bool {
+	if len(r.Events) > 0 && !containsFold(r.Events, event.Event) {
+		return false
+	}
+	if len(r.Severities) > 0 && !containsFold(r.Severities, event.Severity) {
+		return false
+	}
+	if len(r.Groups) == 0 && len(r.Tags) == 0 {
+		return true
+	}
+	// 任一相关节点命中分组或标签即可
+	for _, c := range event.Clients {
+		if len(r.Groups) > 0 && c.Group != "" && containsFold(r.Groups, c.Group) {
+			return true
+		}
+		for _, tag := range strings.Split(c.Tags, ";") {
+			if tag = strings.TrimSpace(tag); tag != "" && containsFold(r.Tags, tag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// deliverToChannels 逐个渠道发送，渠道模板优先于全局模板；发送后释放实例引用
+func deliverToChannels(targets []*channelInstance, event models.EventMessage, defaultTemplate string) error {
+	var errs []string
+	for _, ch := range targets {
+		tmpl := defaultTemplate
+		if strings.TrimSpace(ch.template) != "" {
+			tmpl = ch.template
+		}
+		if err := deliverEvent(ch.provider, event, tmpl); err != nil {
+			errs = append(errs, ch.name+": "+err.Error())
+		}
+		ch.inflight.Done()
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package messageSender
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/komari-monitor/komari/database/models"
+	"github.com/komari-monitor/komari/utils/messageSender/empty"
+)
+
+func TestMatchRoute(t *testing.T) {
+	event := models.EventMessage{
+		Event:    "Alert",
+		Severity: models.SeverityCritical,
+		Clients:  []models.Client{{UUID: "a", Group: "hk", Tags: "prod;db"}},
+	}
+	cases := []struct {
+		name  string
+		route models.MessageRoute
+		want  bool
+	}{
+		{"empty matches all", models.MessageRoute{}, true},
+		{"event", models.MessageRoute{Events: models.StringArray{"offline", "alert"}}, true},
+		{"event mismatch", models.MessageRoute{Events: models.StringArray{"Offline"}}, false},
+		{"severity", models.MessageRoute{Severities: models.StringArray{"critical"}}, true},
+		{"severity mismatch", models.MessageRoute{Severities: models.StringArray{"info"}}, false},
+		{"group", models.MessageRoute{Groups: models.StringArray{"HK"}}, true},
+		{"tag", models.MessageRoute{Tags: models.StringArray{"db"}}, true},
+		{"tag mismatch", models.MessageRoute{Tags: models.StringArray{"dev"}}, false},
+	}
+	for _, c := range cases {
+		if got := matchRoute(c.route, event); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+type destroyCounter struct {
+	empty.EmptyProvider
+	destroyed atomic.Bool
+}
+
+func (d *destroyCounter) Destroy() error {
+	d.destroyed.Store(true)
+	return nil
+}
+
+func TestReplacedChannelDrains(t *testing.T) {
+	provider := &destroyCounter{}
+	channelsMu.Lock()
+	channels = map[string]*channelInstance{"a": {name: "a", provider: provider}}
+	channelsMu.Unlock()
+
+	ch := getChannel("a")
+	channelsMu.Lock()
+	old := channels
+	channels = map[string]*channelInstance{}
+	channelsMu.Unlock()
+	go old["a"].drain()
+
+	time.Sleep(20 * time.Millisecond)
+	if provider.destroyed.Load() {
+		t.Fatal("provider destroyed while a send is in flight")
+	}
+	ch.inflight.Done()
+	deadline := time.Now().Add(time.Second)
+	for !provider.destroyed.Load() {
+		if time.Now().After(deadline) {
+			t.Fatal("provider not destroyed after send finished")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -19,8 +19,30 @@ var (
 	currentProvider factory.IMessageSender
 	mu              = sync.Mutex{}
 	once            = sync.Once{}
+
+	eventListeners   []func(models.EventMessage)
+	eventListenersMu sync.RWMutex
 )
 
+// OnEvent 注册事件监听，所有经 SendEvent 发出的事件都会异步回调，不受全局通知开关影响；
+// 调用方不应在全局开关关闭时跳过 SendEvent，各类事件自身的开关（如节点离线通知）仍决定事件是否产生
+func OnEvent(fn func(event models.EventMessage)) {
+	if fn == nil {
+		return
+	}
+	eventListenersMu.Lock()
+	defer eventListenersMu.Unlock()
+	eventListeners = append(eventListeners, fn)
+}
+
+func emitEvent(event models.EventMessage) {
+	eventListenersMu.RLock()
+	defer eventListenersMu.RUnlock()
+	for _, fn := range eventListeners {
+		go fn(event)
+	}
+}
+
 func CurrentProvider() factory.IMessageSender {
 	mu.Lock()
 	defer mu.Unlock()
@@ -52,6 +74,9 @@ func Initialize() {
 			}
 		})
 	}()
+	if err := ReloadChannels(); err != nil {
+		log.Printf("Failed to load message channels: %v", err)
+	}
 	cfg, _ := config.Get()
 
 	if cfg.NotificationMethod == "" || cfg.NotificationMethod == "none" {
@@ -91,11 +116,33 @@ func SendTextMessage(message string, title string) error {
 	auditlog.Log("", "", "Failed to send message after 3 attempts: "+err.Error()+","+title, "error")
 	return err
 }
+
+// SendEvent 命中路由规则时发送到对应渠道，否则使用当前通知方式
 func SendEvent(event models.EventMessage) error {
+	emitEvent(event)
+	cfg, err := config.Get()
+	if err != nil {
+		return err
+	}
+	if !cfg.NotificationEnabled {
+		return nil
+	}
+	if targets := routeEvent(event); len(targets) > 0 {
+		return deliverToChannels(targets, event, cfg.NotificationTemplate)
+	}
 	if CurrentProvider() == nil {
 		return fmt.Errorf("message sender provider is not initialized")
 	}
-	var err error
+	return deliverEvent(CurrentProvider(), event, cfg.NotificationTemplate)
+}
+
+// SendEventVia 发送到指定的渠道（按名称），名称不是渠道时按提供者名称使用其已保存的配置；
+// 未指定时等同于 SendEvent
+func SendEventVia(providers []string, event models.EventMessage) error {
+	if len(providers) == 0 {
+		return SendEvent(event)
+	}
+	emitEvent(event)
 	cfg, err := config.Get()
 	if err != nil {
 		return err
@@ -103,9 +150,61 @@ func SendEvent(event models.EventMessage) error {
 	if !cfg.NotificationEnabled {
 		return nil
 	}
+	var errs []string
+	for _, name := range providers {
+		if ch := getChannel(name); ch != nil {
+			if err := deliverToChannels([]*channelInstance{ch}, event, cfg.NotificationTemplate); err != nil {
+				errs = append(errs, err.Error())
+			}
+			continue
+		}
+		provider, err := newConfiguredProvider(name)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if err := deliverEvent(provider, event, cfg.NotificationTemplate); err != nil {
+			errs = append(errs, name+": "+err.Error())
+		}
+		provider.Destroy()
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
 
+// TestChannel 按数据库中的渠道配置发送一条测试消息，停用的渠道同样可以测试；
+// 不受全局通知开关影响，也不会触发事件监听
+func TestChannel(name string) error {
+	channel, err := database.GetMessageChannelByName(name)
+	if err != nil {
+		return fmt.Errorf("message channel not found: %s", name)
+	}
+	provider, err := buildProvider(channel.Provider, channel.Addition)
+	if err != nil {
+		return err
+	}
+	defer provider.Destroy()
+	tmpl := channel.Template
+	if strings.TrimSpace(tmpl) == "" {
+		cfg, err := config.Get()
+		if err != nil {
+			return err
+		}
+		tmpl = cfg.NotificationTemplate
+	}
+	return deliverEvent(provider, models.EventMessage{
+		Event:   "Test",
+		Time:    time.Now(),
+		Message: "This is a test message from Komari.",
+	}, tmpl)
+}
+
+func deliverEvent(provider factory.IMessageSender, event models.EventMessage, messageTemplate string) error {
+	var err error
 	// 检查提供者是否实现了 IEventMessageSender 接口
-	if eventSender, ok := CurrentProvider().(factory.IEventMessageSender); ok {
+	if eventSender, ok := provider.(factory.IEventMessageSender); ok {
 		// 如果实现了,直接调用 SendEvent
 		for i := 0; i < 3; i++ {
 			err = eventSender.SendEvent(event)
@@ -119,14 +218,13 @@ func SendEvent(event models.EventMessage) error {
 	}
 
 	// 如果没有实现,使用模板格式化为文本消息
-	messageTemplate := cfg.NotificationTemplate
 	if messageTemplate == "" {
 		messageTemplate = "{{emoji}}{{emoji}}{{emoji}}\nEvent: {{event}}\nClients: {{client}}\nMessage: {{message}}\nTime: {{time}}"
 	}
 	messageTemplate = parseTemplate(messageTemplate, event)
 
 	for i := 0; i < 3; i++ {
-		err = CurrentProvider().SendTextMessage(messageTemplate, event.Event)
+		err = provider.SendTextMessage(messageTemplate, event.Event)
 		if err == nil || err.Error() == "short response: \x00\x00\x00\x1a\x00\x00\x00" { // QQ 会返回这个错误，但实际上消息是发送成功的
 			auditlog.Log("", "", "Event message sent: "+event.Event, "info")
 			return nil
@@ -150,11 +248,12 @@ func parseTemplate(messageTemplate string, event models.EventMessage) string {
 	joinedClients := strings.Join(clientNames, ", ")
 
 	replaceMap := map[string]string{
-		"{{event}}":   event.Event,
-		"{{client}}":  joinedClients,
-		"{{time}}":    event.Time.Format(time.RFC3339),
-		"{{message}}": event.Message,
-		"{{emoji}}":   event.Emoji,
+		"{{event}}":    event.Event,
+		"{{client}}":   joinedClients,
+		"{{time}}":     event.Time.Format(time.RFC3339),
+		"{{message}}":  event.Message,
+		"{{emoji}}":    event.Emoji,
+		"{{severity}}": event.Severity,
 	}
 	result := messageTemplate
 	for placeholder, value := range replaceMap {
@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType Prometheus 文本格式（0.0.4）
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type Labels map[string]string
+
+type sample struct {
+	labels Labels
+	value  float64
+}
+
+type family struct {
+	name    string
+	help    string
+	typ     string
+	samples []sample
+}
+
+// Registry 按注册顺序收集指标族，最后一次性输出
+type Registry struct {
+	families []*family
+	index    map[string]*family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{index: make(map[string]*family)}
+}
+
+// Gauge 添加一个 gauge 样本，同名指标族只会输出一次 HELP/TYPE
+func (r *Registry) Gauge(name, help string, labels Labels, value float64) {
+	r.add(name, help, "gauge", labels, value)
+}
+
+// Counter 添加一个 counter 样本
+func (r *Registry) Counter(name, help string, labels Labels, value float64) {
+	r.add(name, help, "counter", labels, value)
+}
+
+func (r *Registry) add(name, help, typ string, labels Labels, value float64) {
+	f, ok := r.index[name]
+	if !ok {
+		f = &family{name: name, help: help, typ: typ}
+		r.index[name] = f
+		r.families = append(r.families, f)
+	}
+	f.samples = append(f.samples, sample{labels: labels, value: value})
+}
+
+// WriteTo 以 Prometheus 文本格式输出
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	for _, f := range r.families {
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+		fmt.Fprintf(&b, "# TYPE %s %s\n", f.name, f.typ)
+		for _, s := range f.samples {
+			b.WriteString(f.name)
+			writeLabels(&b, s.labels)
+			b.WriteByte(' ')
+			b.WriteString(formatValue(s.value))
+			b.WriteByte('\n')
+		}
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func writeLabels(b *strings.Builder, labels Labels) {
+	if len(labels) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	b.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(k)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(labels[k]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeLabelValue(s string) string {
+	return labelValueReplacer.Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
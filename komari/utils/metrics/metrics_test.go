@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWriteTo(t *testing.T) {
+	r := NewRegistry()
+	r.Gauge("komari_node_cpu_usage_percent", "CPU usage", Labels{"uuid": "a", "name": "node-a"}, 12.5)
+	r.Gauge("komari_node_cpu_usage_percent", "CPU usage", Labels{"uuid": "b", "name": "node-b"}, 3)
+	r.Counter("komari_node_network_transmit_bytes_total", "Total bytes sent", Labels{"uuid": "a"}, 1024)
+
+	var sb strings.Builder
+	if _, err := r.WriteTo(&sb); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	expected := `# HELP komari_node_cpu_usage_percent CPU usage
+# TYPE komari_node_cpu_usage_percent gauge
+komari_node_cpu_usage_percent{name="node-a",uuid="a"} 12.5
+komari_node_cpu_usage_percent{name="node-b",uuid="b"} 3
+# HELP komari_node_network_transmit_bytes_total Total bytes sent
+# TYPE komari_node_network_transmit_bytes_total counter
+komari_node_network_transmit_bytes_total{uuid="a"} 1024
+`
+	if sb.String() != expected {
+		t.Fatalf("unexpected output:\n%s", sb.String())
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	r := NewRegistry()
+	r.Gauge("m", "line1\nline2", Labels{"name": "a\"b\\c\nd"}, math.NaN())
+	var sb strings.Builder
+	r.WriteTo(&sb)
+	out := sb.String()
+	if !strings.Contains(out, `# HELP m line1\nline2`) {
+		t.Fatalf("help not escaped: %s", out)
+	}
+	if !strings.Contains(out, `m{name="a\"b\\c\nd"} NaN`) {
+		t.Fatalf("label not escaped: %s", out)
+	}
+}
@@ -47,6 +47,7 @@ func CheckExpireScheduledWork() {
 			}
 
 			var clientLeadToExpire []clientToExpireInfo
+			var expiringClients []models.Client
 
 			for _, client := range clients_all {
 				clientExpireTime := client.ExpiredAt.ToTime()
@@ -65,6 +66,7 @@ func CheckExpireScheduledWork() {
 						Name:     client.Name,
 						DaysLeft: daysLeft,
 					})
+					expiringClients = append(expiringClients, client)
 				}
 			}
 
@@ -75,6 +77,7 @@ func CheckExpireScheduledWork() {
 				}
 				messageSender.SendEvent(models.EventMessage{
 					Event:   messageevent.Expire,
+					Clients: expiringClients,
 					Time:    time.Now(),
 					Message: message,
 					Emoji:   "⏳",
@@ -8,6 +8,7 @@ import (
 
 	"github.com/komari-monitor/komari/database/clients"
 	"github.com/komari-monitor/komari/database/dbcore"
+	"github.com/komari-monitor/komari/database/maintenance"
 	"github.com/komari-monitor/komari/database/models"
 	messageevent "github.com/komari-monitor/komari/database/models/messageEvent"
 	"github.com/komari-monitor/komari/database/records"
@@ -196,6 +197,8 @@ func sendLoadNotification(clientUUIDs []string, task models.LoadNotification) {
 			ex_clients = append(ex_clients, cl)
 		}
 	}
+	// 维护窗口内的节点不通知
+	ex_clients = maintenance.FilterActive(ex_clients, time.Now())
 	if len(ex_clients) == 0 {
 		return
 	}
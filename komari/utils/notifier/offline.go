@@ -7,8 +7,8 @@ import (
 	"time"
 
 	"github.com/komari-monitor/komari/database/clients"
-	"github.com/komari-monitor/komari/database/config"
 	"github.com/komari-monitor/komari/database/dbcore"
+	"github.com/komari-monitor/komari/database/maintenance"
 	"github.com/komari-monitor/komari/database/models"
 	messageevent "github.com/komari-monitor/komari/database/models/messageEvent"
 	"github.com/komari-monitor/komari/utils/messageSender"
@@ -30,13 +30,9 @@ type notificationState struct {
 var clientStates sync.Map
 
 // getNotificationConfig 获取指定客户端的通知配置。
-// 返回配置对象和一个布尔值，指示全局和该客户端是否启用通知。
+// 返回配置对象和一个布尔值，指示该客户端是否启用离线通知。
+// 全局通知开关由 SendEvent 检查，关闭时事件仍会分发给事件脚本。
 func getNotificationConfig(clientID string) (*models.OfflineNotification, bool) {
-	conf, err := config.Get()
-	if err != nil || !conf.NotificationEnabled {
-		return nil, false
-	}
-
 	notiConf := models.OfflineNotification{Client: clientID}
 	db := dbcore.GetDBInstance()
 	if err := db.Model(&models.OfflineNotification{}).Where("client = ?", clientID).FirstOrCreate(&notiConf).Error; err != nil {
@@ -105,6 +101,12 @@ func OfflineNotification(clientID string, endedConnectionID int64) {
 		state.pendingOfflineSince = time.Time{}
 		state.isConnExist = false
 
+		// 维护窗口内不通知
+		if maintenance.IsActive(client, time.Now()) {
+			log.Printf("%s is offline during maintenance, notification suppressed", clientID)
+			return
+		}
+
 		// Send notification
 		message := fmt.Sprintf("🔴%s is offline", client.Name)
 		go func(msg string) {
@@ -174,7 +176,10 @@ func OnlineNotification(clientID string, connectionID int64) {
 		state.isConnExist = true
 	}
 
-	// 规则4：客户端离线足够久已通知（或未待离线），现在重新上线，发送上线通知。
+	// 规则4：客户端离线足够久已通知（或未待离线），现在重新上线，发送上线通知。维护窗口内不通知
+	if maintenance.IsActive(client, time.Now()) {
+		return
+	}
 	message := fmt.Sprintf("🟢%s is online", client.Name)
 	go func(msg string) {
 		if err := messageSender.SendEvent(models.EventMessage{
@@ -0,0 +1,454 @@
+package notifier
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/komari-monitor/komari/database/clients"
+	"github.com/komari-monitor/komari/database/dbcore"
+	"github.com/komari-monitor/komari/database/maintenance"
+	"github.com/komari-monitor/komari/database/models"
+	messageevent "github.com/komari-monitor/komari/database/models/messageEvent"
+	"github.com/komari-monitor/komari/database/records"
+	"github.com/komari-monitor/komari/database/tasks"
+	"github.com/komari-monitor/komari/utils/messageSender"
+	"github.com/komari-monitor/komari/ws"
+)
+
+// alertRuleInterval 规则评估间隔，与记录落库周期一致
+const alertRuleInterval = time.Minute
+
+// AlertRuleService 定时评估告警规则并维护未恢复的告警
+type AlertRuleService struct {
+	mu        sync.Mutex
+	rules     []models.AlertRule
+	incidents map[string]*models.AlertIncident // ruleID:client -> 未恢复的告警
+	startOnce sync.Once
+}
+
+var AlertRuleManager = &AlertRuleService{
+	incidents: make(map[string]*models.AlertIncident),
+}
+
+func incidentKey(ruleID uint, client string) string {
+	return fmt.Sprintf("%d:%s", ruleID, client)
+}
+
+// Reload 重载规则，首次调用时启动评估循环
+func (m *AlertRuleService) Reload(rules []models.AlertRule) error {
+	var open []models.AlertIncident
+	if err := dbcore.GetDBInstance().Where("resolved_at IS NULL").Find(&open).Error; err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.rules = rules
+	m.incidents = make(map[string]*models.AlertIncident, len(open))
+	for i := range open {
+		m.incidents[incidentKey(open[i].RuleID, open[i].Client)] = &open[i]
+	}
+	m.mu.Unlock()
+
+	// 已删除或停用的规则，关闭其未恢复的告警
+	enabled := make(map[uint]bool, len(rules))
+	for _, r := range rules {
+		if r.Enabled {
+			enabled[r.ID] = true
+		}
+	}
+	for _, inc := range open {
+		if !enabled[inc.RuleID] {
+			m.resolve(inc.RuleID, inc.Client)
+		}
+	}
+
+	m.startOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(alertRuleInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				m.evaluateAll()
+			}
+		}()
+	})
+	return nil
+}
+
+func (m *AlertRuleService) evaluateAll() {
+	m.mu.Lock()
+	rules := append([]models.AlertRule(nil), m.rules...)
+	m.mu.Unlock()
+	if len(rules) == 0 {
+		return
+	}
+	all, err := clients.GetAllClientBasicInfo()
+	if err != nil {
+		log.Printf("Failed to load clients for alert rules: %v", err)
+		return
+	}
+	online := make(map[string]bool)
+	for _, uuid := range ws.GetAllOnlineUUIDs() {
+		online[uuid] = true
+	}
+	now := time.Now()
+	for _, rule := range rules {
+		if !rule.Enabled || len(rule.Conditions) == 0 {
+			continue
+		}
+		for _, client := range ruleTargets(rule, all) {
+			firing, detail := evaluateRule(rule, client.UUID, online[client.UUID], now)
+			if firing {
+				m.fire(rule, client, detail, now)
+			} else {
+				m.resolveWithNotice(rule, client, now)
+			}
+		}
+	}
+}
+
+func ruleTargets(rule models.AlertRule, all []models.Client) []models.Client {
+	if len(rule.Clients) == 0 {
+		return all
+	}
+	selected := make(map[string]bool, len(rule.Clients))
+	for _, uuid := range rule.Clients {
+		selected[uuid] = true
+	}
+	targets := make([]models.Client, 0, len(rule.Clients))
+	for _, c := range all {
+		if selected[c.UUID] {
+			targets = append(targets, c)
+		}
+	}
+	return targets
+}
+
+// evaluateRule 逐个评估条件后按 Logic 组合；缺少数据的条件视为不满足
+func evaluateRule(rule models.AlertRule, client string, online bool, now time.Time) (bool, string) {
+	window := time.Duration(rule.Duration) * time.Second
+	if window < alertRuleInterval {
+		window = alertRuleInterval
+	}
+	start := now.Add(-window)
+	isOr := strings.EqualFold(rule.Logic, "or")
+	matched := make([]string, 0, len(rule.Conditions))
+	for _, cond := range rule.Conditions {
+		value, ok := conditionValue(cond, client, online, start, now)
+		hit := ok && compare(value, cond.Operator, cond.Threshold)
+		if hit {
+			matched = append(matched, fmt.Sprintf("%s %s %s %g", describeMetric(cond), formatValue(value), cond.Operator, cond.Threshold))
+			if isOr {
+				return true, strings.Join(matched, ", ")
+			}
+		} else if !isOr {
+			return false, ""
+		}
+	}
+	if isOr {
+		return false, ""
+	}
+	return true, strings.Join(matched, " AND ")
+}
+
+func describeMetric(cond models.AlertCondition) string {
+	agg := cond.Aggregate
+	if agg == "" {
+		agg = "avg"
+	}
+	if cond.Metric == "offline" {
+		return cond.Metric
+	}
+	if cond.TaskID != 0 {
+		return fmt.Sprintf("%s(%s, task %d)", cond.Metric, agg, cond.TaskID)
+	}
+	return fmt.Sprintf("%s(%s)", cond.Metric, agg)
+}
+
+func formatValue(v float64) string {
+	return fmt.Sprintf("%.2f", v)
+}
+
+func compare(value float64, op string, threshold float64) bool {
+	switch op {
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	case "==":
+		return value == threshold
+	case "!=":
+		return value != threshold
+	}
+	return false
+}
+
+// conditionValue 取窗口内的样本并按聚合方式计算
+func conditionValue(cond models.AlertCondition, client string, online bool, start, end time.Time) (float64, bool) {
+	switch cond.Metric {
+	case "offline":
+		if online {
+			return 0, true
+		}
+		return 1, true
+	case "ping_latency", "ping_loss":
+		return pingValue(cond, client, start, end)
+	case "gpu_usage", "gpu_mem", "gpu_temp":
+		recs, err := records.GetGPURecordsByClientAndTime(client, start, end)
+		if err != nil {
+			return 0, false
+		}
+		samples := make([]float64, 0, len(recs))
+		for _, r := range recs {
+			switch cond.Metric {
+			case "gpu_usage":
+				samples = append(samples, float64(r.Utilization))
+			case "gpu_mem":
+				if r.MemTotal > 0 {
+					samples = append(samples, float64(r.MemUsed)/float64(r.MemTotal)*100)
+				}
+			case "gpu_temp":
+				samples = append(samples, float64(r.Temperature))
+			}
+		}
+		return aggregate(samples, cond.Aggregate)
+	}
+	recs, err := records.GetRecordsByClientAndTime(client, start, end)
+	if err != nil {
+		return 0, false
+	}
+	samples := make([]float64, 0, len(recs))
+	for _, r := range recs {
+		if v, ok := recordMetric(r, cond.Metric); ok {
+			samples = append(samples, v)
+		}
+	}
+	return aggregate(samples, cond.Aggregate)
+}
+
+func recordMetric(r models.Record, metric string) (float64, bool) {
+	percent := func(used, total int64) (float64, bool) {
+		if total <= 0 {
+			return 0, false
+		}
+		return float64(used) / float64(total) * 100, true
+	}
+	switch metric {
+	case "cpu":
+		return float64(r.Cpu), true
+	case "gpu":
+		return float64(r.Gpu), true
+	case "ram":
+		return percent(r.Ram, r.RamTotal)
+	case "swap":
+		return percent(r.Swap, r.SwapTotal)
+	case "disk":
+		return percent(r.Disk, r.DiskTotal)
+	case "load":
+		return float64(r.Load), true
+	case "temp":
+		return float64(r.Temp), true
+	case "net_in":
+		return float64(r.NetIn), true
+	case "net_out":
+		return float64(r.NetOut), true
+	case "net_total_up":
+		return float64(r.NetTotalUp), true
+	case "net_total_down":
+		return float64(r.NetTotalDown), true
+	case "process":
+		return float64(r.Process), true
+	case "connections":
+		return float64(r.Connections), true
+	case "connections_udp":
+		return float64(r.ConnectionsUdp), true
+	}
+	return 0, false
+}
+
+// pingValue ping_latency 为成功样本的延迟聚合，ping_loss 为窗口内丢包率（%）
+func pingValue(cond models.AlertCondition, client string, start, end time.Time) (float64, bool) {
+	if cond.TaskID == 0 {
+		return 0, false
+	}
+	recs, err := tasks.GetPingRecords(client, int(cond.TaskID), start, end)
+	if err != nil || len(recs) == 0 {
+		return 0, false
+	}
+	if cond.Metric == "ping_loss" {
+		lost := 0
+		for _, r := range recs {
+			if r.Value < 0 {
+				lost++
+			}
+		}
+		return float64(lost) / float64(len(recs)) * 100, true
+	}
+	samples := make([]float64, 0, len(recs))
+	for _, r := range recs {
+		if r.Value >= 0 {
+			samples = append(samples, float64(r.Value))
+		}
+	}
+	return aggregate(samples, cond.Aggregate)
+}
+
+func aggregate(samples []float64, method string) (float64, bool) {
+	if len(samples) == 0 {
+		return 0, false
+	}
+	switch method {
+	case "min":
+		v := math.Inf(1)
+		for _, s := range samples {
+			v = math.Min(v, s)
+		}
+		return v, true
+	case "max":
+		v := math.Inf(-1)
+		for _, s := range samples {
+			v = math.Max(v, s)
+		}
+		return v, true
+	case "last":
+		return samples[len(samples)-1], true
+	}
+	sum := 0.0
+	for _, s := range samples {
+		sum += s
+	}
+	return sum / float64(len(samples)), true
+}
+
+func severityEmoji(severity string) string {
+	switch severity {
+	case models.SeverityCritical:
+		return "🚨"
+	case models.SeverityInfo:
+		return "ℹ️"
+	}
+	return "⚠️"
+}
+
+// fire 首次满足条件时创建告警并通知；持续告警按 Cooldown 重复通知
+func (m *AlertRuleService) fire(rule models.AlertRule, client models.Client, detail string, now time.Time) {
+	key := incidentKey(rule.ID, client.UUID)
+	m.mu.Lock()
+	inc, exists := m.incidents[key]
+	if exists {
+		// 已确认的告警不再重复通知
+		if inc.AckedAt != nil || rule.Cooldown <= 0 || now.Sub(inc.LastNotified.ToTime()) < time.Duration(rule.Cooldown)*time.Second {
+			m.mu.Unlock()
+			return
+		}
+		inc.LastNotified = models.FromTime(now)
+		inc.Message = detail
+		m.mu.Unlock()
+		dbcore.GetDBInstance().Model(&models.AlertIncident{}).Where("id = ?", inc.ID).
+			Updates(map[string]interface{}{"last_notified": inc.LastNotified, "message": detail})
+	} else {
+		inc = &models.AlertIncident{
+			RuleID:       rule.ID,
+			RuleName:     rule.Name,
+			Client:       client.UUID,
+			Severity:     rule.Severity,
+			Message:      detail,
+			StartedAt:    models.FromTime(now),
+			LastNotified: models.FromTime(now),
+		}
+		if err := dbcore.GetDBInstance().Create(inc).Error; err != nil {
+			m.mu.Unlock()
+			log.Printf("Failed to save alert incident for rule %d: %v", rule.ID, err)
+			return
+		}
+		m.incidents[key] = inc
+		m.mu.Unlock()
+	}
+	// 维护窗口内仍记录告警，但不发送通知
+	if maintenance.IsActive(client, now) {
+		return
+	}
+	go func() {
+		if err := messageSender.SendEventVia(rule.Providers, models.EventMessage{
+			Event:    messageevent.Alert,
+			Clients:  []models.Client{client},
+			Time:     now,
+			Emoji:    severityEmoji(rule.Severity),
+			Message:  fmt.Sprintf("[%s] %s: %s", rule.Severity, rule.Name, detail),
+			Severity: rule.Severity,
+		}); err != nil {
+			log.Printf("Failed to send alert for rule %d: %v", rule.ID, err)
+		}
+	}()
+}
+
+// resolveWithNotice 条件不再满足时关闭告警，按规则发送恢复通知
+func (m *AlertRuleService) resolveWithNotice(rule models.AlertRule, client models.Client, now time.Time) {
+	inc := m.resolve(rule.ID, client.UUID)
+	if inc == nil || !rule.NotifyRecovery || maintenance.IsActive(client, now) {
+		return
+	}
+	go func() {
+		if err := messageSender.SendEventVia(rule.Providers, models.EventMessage{
+			Event:    messageevent.Resolved,
+			Clients:  []models.Client{client},
+			Time:     now,
+			Emoji:    "✅",
+			Message:  fmt.Sprintf("[%s] %s resolved after %s", rule.Severity, rule.Name, now.Sub(inc.StartedAt.ToTime()).Round(time.Second)),
+			Severity: rule.Severity,
+		}); err != nil {
+			log.Printf("Failed to send recovery for rule %d: %v", rule.ID, err)
+		}
+	}()
+}
+
+func (m *AlertRuleService) resolve(ruleID uint, client string) *models.AlertIncident {
+	key := incidentKey(ruleID, client)
+	m.mu.Lock()
+	inc, exists := m.incidents[key]
+	delete(m.incidents, key)
+	m.mu.Unlock()
+	if !exists {
+		return nil
+	}
+	resolved := models.FromTime(time.Now())
+	inc.ResolvedAt = &resolved
+	if err := dbcore.GetDBInstance().Model(&models.AlertIncident{}).Where("id = ?", inc.ID).Update("resolved_at", resolved).Error; err != nil {
+		log.Printf("Failed to resolve alert incident %d: %v", inc.ID, err)
+	}
+	return inc
+}
+
+// Acknowledge 确认未恢复的告警，之后不再重复通知
+func (m *AlertRuleService) Acknowledge(id uint, by string) error {
+	acked := models.FromTime(time.Now())
+	result := dbcore.GetDBInstance().Model(&models.AlertIncident{}).
+		Where("id = ? AND resolved_at IS NULL", id).
+		Updates(map[string]interface{}{"acked_at": acked, "acked_by": by})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("incident not found or already resolved")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, inc := range m.incidents {
+		if inc.ID == id {
+			inc.AckedAt = &acked
+			inc.AckedBy = by
+		}
+	}
+	return nil
+}
+
+// ReloadAlertRules 加载或重载告警规则
+func ReloadAlertRules(rules []models.AlertRule) error {
+	return AlertRuleManager.Reload(rules)
+}
@@ -62,7 +62,7 @@ func (m *PingTaskManager) runPreciseLoop(ctx context.Context, interval time.Dura
 	for {
 		select {
 		case <-timer.C:
-			onlineClients := ws.GetConnectedClients()
+			onlineClients := ws.GetAllMessageWriters()
 			for _, task := range tasks {
 				go executePingTask(ctx, task, onlineClients)
 			}
@@ -77,7 +77,7 @@ func (m *PingTaskManager) runPreciseLoop(ctx context.Context, interval time.Dura
 }
 
 // executePingTask 执行单个PingTask
-func executePingTask(ctx context.Context, task models.PingTask, onlineClients map[string]*ws.SafeConn) {
+func executePingTask(ctx context.Context, task models.PingTask, onlineClients map[string]ws.MessageWriter) {
 	var message struct {
 		TaskID  uint   `json:"ping_task_id"`
 		Message string `json:"message"`
@@ -0,0 +1,92 @@
+package recordsink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/komari-monitor/komari/database/models"
+)
+
+const influxMeasurement = "komari_record"
+
+// influxSink 写入 InfluxDB 行协议，url 为完整的写入地址，
+// 例如 v2 的 /api/v2/write?org=..&bucket=.. 或 v1 的 /write?db=..
+type influxSink struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func newInfluxSink(endpoint, token string, client *http.Client) (*influxSink, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid record sink url: %v", err)
+	}
+	// 时间戳统一按秒写入
+	q := u.Query()
+	q.Set("precision", "s")
+	u.RawQuery = q.Encode()
+	return &influxSink{url: u.String(), token: token, client: client}, nil
+}
+
+func (s *influxSink) Write(ctx context.Context, recs []models.Record) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, strings.NewReader(encodeLines(recs)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Token "+s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("influxdb write returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// encodeLines 每条记录一行：komari_record,uuid=<uuid> cpu=1.5,ram=1024i,... <unix 秒>
+func encodeLines(recs []models.Record) string {
+	var b strings.Builder
+	for _, rec := range recs {
+		b.WriteString(influxMeasurement)
+		b.WriteString(",uuid=")
+		b.WriteString(escapeTag(rec.Client))
+		for i, f := range recordFields(rec) {
+			if i == 0 {
+				b.WriteByte(' ')
+			} else {
+				b.WriteByte(',')
+			}
+			b.WriteString(f.key)
+			b.WriteByte('=')
+			if f.isInt {
+				b.WriteString(strconv.FormatInt(int64(f.value), 10))
+				b.WriteByte('i')
+			} else {
+				b.WriteString(strconv.FormatFloat(f.value, 'f', -1, 64))
+			}
+		}
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatInt(rec.Time.ToTime().Unix(), 10))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+var tagEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+func escapeTag(s string) string {
+	return tagEscaper.Replace(s)
+}
@@ -0,0 +1,108 @@
+package recordsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+
+	"github.com/komari-monitor/komari/database/models"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type prometheusSink struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func (s *prometheusSink) Write(ctx context.Context, recs []models.Record) error {
+	body := snappyEncode(encodeWriteRequest(recs))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("remote write returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// encodeWriteRequest 按 prompb.WriteRequest 编码，每条记录的每个字段为一条时间序列
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(recs []models.Record) []byte {
+	var out []byte
+	for _, rec := range recs {
+		ts := rec.Time.ToTime().UnixMilli()
+		for _, f := range recordFields(rec) {
+			labels := append([][2]string{{"__name__", f.metric}, {"uuid", rec.Client}}, f.labels...)
+			sort.Slice(labels, func(i, j int) bool { return labels[i][0] < labels[j][0] })
+
+			var series []byte
+			for _, l := range labels {
+				var lb []byte
+				lb = protowire.AppendTag(lb, 1, protowire.BytesType)
+				lb = protowire.AppendString(lb, l[0])
+				lb = protowire.AppendTag(lb, 2, protowire.BytesType)
+				lb = protowire.AppendString(lb, l[1])
+				series = protowire.AppendTag(series, 1, protowire.BytesType)
+				series = protowire.AppendBytes(series, lb)
+			}
+			var sample []byte
+			sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+			sample = protowire.AppendFixed64(sample, math.Float64bits(f.value))
+			sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+			sample = protowire.AppendVarint(sample, uint64(ts))
+			series = protowire.AppendTag(series, 2, protowire.BytesType)
+			series = protowire.AppendBytes(series, sample)
+
+			out = protowire.AppendTag(out, 1, protowire.BytesType)
+			out = protowire.AppendBytes(out, series)
+		}
+	}
+	return out
+}
+
+// snappyEncode 生成仅含字面量块的 snappy block 格式数据，
+// 不做压缩但符合格式要求，避免为 remote_write 引入额外依赖
+func snappyEncode(src []byte) []byte {
+	dst := binary.AppendUvarint(make([]byte, 0, len(src)+len(src)/65536*3+16), uint64(len(src)))
+	for len(src) > 0 {
+		n := len(src)
+		if n > 65536 {
+			n = 65536
+		}
+		m := n - 1
+		switch {
+		case m < 60:
+			dst = append(dst, byte(m)<<2)
+		case m < 1<<8:
+			dst = append(dst, 60<<2, byte(m))
+		default:
+			dst = append(dst, 61<<2, byte(m), byte(m>>8))
+		}
+		dst = append(dst, src[:n]...)
+		src = src[n:]
+	}
+	return dst
+}
@@ -0,0 +1,253 @@
+package recordsink
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/komari-monitor/komari/database/models"
+)
+
+const (
+	TypeNone       = ""
+	TypePrometheus = "prometheus" // Prometheus remote_write
+	TypeInfluxDB   = "influxdb"   // InfluxDB 行协议
+
+	queueSize     = 10000
+	batchSize     = 500
+	flushInterval = 10 * time.Second
+	writeTimeout  = 15 * time.Second
+	maxBackoff    = 5 * time.Minute
+)
+
+// Sink 外部时序存储，只写不读；面板查询仍由 SQL 数据库提供
+type Sink interface {
+	Write(ctx context.Context, recs []models.Record) error
+}
+
+// New 按类型创建记录汇，token 为空时不附带认证头（Prometheus 可在 URL 中携带 Basic 认证）
+func New(typ, endpoint, token string) (Sink, error) {
+	endpoint = strings.TrimSpace(endpoint)
+	if endpoint == "" {
+		return nil, fmt.Errorf("record sink url is required")
+	}
+	client := &http.Client{Timeout: writeTimeout}
+	switch strings.ToLower(typ) {
+	case TypePrometheus:
+		return &prometheusSink{url: endpoint, token: token, client: client}, nil
+	case TypeInfluxDB:
+		return newInfluxSink(endpoint, token, client)
+	default:
+		return nil, fmt.Errorf("unsupported record sink type: %s", typ)
+	}
+}
+
+var (
+	mu      sync.Mutex
+	current *dispatcher
+)
+
+// Reload 按配置重建记录汇，类型为空时关闭；旧队列中的数据会在关闭前尽量写出
+func Reload(cfg models.Config) error {
+	Close()
+	mu.Lock()
+	defer mu.Unlock()
+	if cfg.RecordSinkType == TypeNone {
+		return nil
+	}
+	sink, err := New(cfg.RecordSinkType, cfg.RecordSinkURL, cfg.RecordSinkToken)
+	if err != nil {
+		return err
+	}
+	current = newDispatcher(sink)
+	log.Printf("Record sink enabled: %s", cfg.RecordSinkType)
+	return nil
+}
+
+// Close 关闭记录汇并写出队列中剩余的数据
+func Close() {
+	mu.Lock()
+	defer mu.Unlock()
+	if current != nil {
+		current.stop()
+		current = nil
+	}
+}
+
+// Push 非阻塞投递记录，未启用时直接忽略，队列已满时丢弃
+func Push(recs ...models.Record) {
+	mu.Lock()
+	d := current
+	mu.Unlock()
+	if d == nil {
+		return
+	}
+	for _, rec := range recs {
+		select {
+		case d.queue <- rec:
+		default:
+			d.dropped.Add(1)
+		}
+	}
+}
+
+type dispatcher struct {
+	sink    Sink
+	queue   chan models.Record
+	done    chan struct{}
+	exited  chan struct{}
+	written chan error
+	pending []models.Record
+	dropped atomic.Int64
+
+	// 以下字段只在 run 中访问
+	inflight []models.Record // 正在后台写出的批次
+	backoff  time.Duration
+	retryAt  time.Time
+}
+
+func newDispatcher(sink Sink) *dispatcher {
+	d := &dispatcher{
+		sink:    sink,
+		queue:   make(chan models.Record, queueSize),
+		done:    make(chan struct{}),
+		exited:  make(chan struct{}),
+		written: make(chan error, 1),
+	}
+	go d.run()
+	return d
+}
+
+// run 只负责收集记录，写出在后台进行，汇不可用时不会阻塞接收
+func (d *dispatcher) run() {
+	defer close(d.exited)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case rec := <-d.queue:
+			d.pending = append(d.pending, rec)
+			d.trim()
+			if len(d.pending) >= batchSize {
+				d.flush()
+			}
+		case <-ticker.C:
+			d.flush()
+		case err := <-d.written:
+			d.finish(err)
+			if len(d.pending) >= batchSize {
+				d.flush()
+			}
+		case <-d.done:
+			if d.inflight != nil {
+				d.finish(<-d.written)
+			}
+			for {
+				select {
+				case rec := <-d.queue:
+					d.pending = append(d.pending, rec)
+				default:
+					d.trim()
+					d.reportDropped()
+					if len(d.pending) > 0 {
+						d.finish(d.write(d.pending))
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush 在后台写出积压的记录；已有写出进行中或处于失败退避期时跳过
+func (d *dispatcher) flush() {
+	d.reportDropped()
+	if d.inflight != nil || len(d.pending) == 0 || time.Now().Before(d.retryAt) {
+		return
+	}
+	d.inflight, d.pending = d.pending, nil
+	go func(batch []models.Record) {
+		d.written <- d.write(batch)
+	}(d.inflight)
+}
+
+func (d *dispatcher) write(recs []models.Record) error {
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+	return d.sink.Write(ctx, recs)
+}
+
+// finish 处理写出结果：失败时把批次放回队首并按指数退避延后重试
+func (d *dispatcher) finish(err error) {
+	batch := d.inflight
+	d.inflight = nil
+	if err == nil {
+		d.backoff = 0
+		d.retryAt = time.Time{}
+		return
+	}
+	log.Printf("Failed to write %d records to record sink: %v", len(batch), err)
+	d.pending = append(batch, d.pending...)
+	d.trim()
+	if d.backoff == 0 {
+		d.backoff = flushInterval
+	} else if d.backoff *= 2; d.backoff > maxBackoff {
+		d.backoff = maxBackoff
+	}
+	d.retryAt = time.Now().Add(d.backoff)
+}
+
+// trim 积压超过队列容量时丢弃最旧的部分
+func (d *dispatcher) trim() {
+	if over := len(d.pending) - queueSize; over > 0 {
+		d.pending = d.pending[over:]
+		d.dropped.Add(int64(over))
+	}
+}
+
+func (d *dispatcher) reportDropped() {
+	if n := d.dropped.Swap(0); n > 0 {
+		log.Printf("Record sink backlog full, dropped %d records", n)
+	}
+}
+
+func (d *dispatcher) stop() {
+	close(d.done)
+	<-d.exited
+}
+
+type field struct {
+	key    string // InfluxDB 字段名
+	metric string // Prometheus 指标名
+	labels [][2]string
+	value  float64
+	isInt  bool
+}
+
+// recordFields 两种汇共用的字段映射，指标名与 /api/metrics 保持一致
+func recordFields(rec models.Record) []field {
+	return []field{
+		{key: "cpu", metric: "komari_node_cpu_usage_percent", value: float64(rec.Cpu)},
+		{key: "gpu", metric: "komari_node_gpu_average_usage_percent", value: float64(rec.Gpu)},
+		{key: "load", metric: "komari_node_load1", value: float64(rec.Load)},
+		{key: "temp", metric: "komari_node_temperature_celsius", value: float64(rec.Temp)},
+		{key: "ram", metric: "komari_node_memory_used_bytes", value: float64(rec.Ram), isInt: true},
+		{key: "ram_total", metric: "komari_node_memory_total_bytes", value: float64(rec.RamTotal), isInt: true},
+		{key: "swap", metric: "komari_node_swap_used_bytes", value: float64(rec.Swap), isInt: true},
+		{key: "swap_total", metric: "komari_node_swap_total_bytes", value: float64(rec.SwapTotal), isInt: true},
+		{key: "disk", metric: "komari_node_disk_used_bytes", value: float64(rec.Disk), isInt: true},
+		{key: "disk_total", metric: "komari_node_disk_total_bytes", value: float64(rec.DiskTotal), isInt: true},
+		{key: "net_in", metric: "komari_node_network_receive_bytes_per_second", value: float64(rec.NetIn), isInt: true},
+		{key: "net_out", metric: "komari_node_network_transmit_bytes_per_second", value: float64(rec.NetOut), isInt: true},
+		{key: "net_total_down", metric: "komari_node_network_receive_bytes_total", value: float64(rec.NetTotalDown), isInt: true},
+		{key: "net_total_up", metric: "komari_node_network_transmit_bytes_total", value: float64(rec.NetTotalUp), isInt: true},
+		{key: "process", metric: "komari_node_processes", value: float64(rec.Process), isInt: true},
+		{key: "connections", metric: "komari_node_connections", labels: [][2]string{{"protocol", "tcp"}}, value: float64(rec.Connections), isInt: true},
+		{key: "connections_udp", metric: "komari_node_connections", labels: [][2]string{{"protocol", "udp"}}, value: float64(rec.ConnectionsUdp), isInt: true},
+	}
+}
@@ -0,0 +1,228 @@
+package recordsink
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/komari-monitor/komari/database/models"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func testRecord() models.Record {
+	return models.Record{
+		Client:         "node a",
+		Time:           models.FromTime(time.Unix(1700000000, 0)),
+		Cpu:            12.5,
+		Ram:            1024,
+		Connections:    3,
+		ConnectionsUdp: 1,
+	}
+}
+
+func TestEncodeLines(t *testing.T) {
+	line := encodeLines([]models.Record{testRecord()})
+	if !strings.HasPrefix(line, `komari_record,uuid=node\ a cpu=12.5,gpu=0,`) {
+		t.Fatalf("unexpected prefix: %s", line)
+	}
+	for _, want := range []string{",ram=1024i,", ",connections=3i,", ",connections_udp=1i "} {
+		if !strings.Contains(line, want) {
+			t.Fatalf("missing %q in %s", want, line)
+		}
+	}
+	if !strings.HasSuffix(line, " 1700000000\n") {
+		t.Fatalf("unexpected timestamp: %s", line)
+	}
+}
+
+func TestEncodeWriteRequest(t *testing.T) {
+	raw := snappyEncode(encodeWriteRequest([]models.Record{testRecord()}))
+	// snappy 头部为原始长度，其后均为字面量块
+	size, n := protowire.ConsumeVarint(raw)
+	if n <= 0 {
+		t.Fatalf("invalid snappy header")
+	}
+	data := decodeLiterals(t, raw[n:])
+	if uint64(len(data)) != size {
+		t.Fatalf("length mismatch: header %d, data %d", size, len(data))
+	}
+
+	series := 0
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if num != 1 || typ != protowire.BytesType {
+			t.Fatalf("unexpected field %d/%d", num, typ)
+		}
+		data = data[n:]
+		ts, n := protowire.ConsumeBytes(data)
+		data = data[n:]
+		if series == 0 {
+			checkFirstSeries(t, ts)
+		}
+		series++
+	}
+	if series != len(recordFields(testRecord())) {
+		t.Fatalf("expected %d series, got %d", len(recordFields(testRecord())), series)
+	}
+}
+
+func checkFirstSeries(t *testing.T, ts []byte) {
+	var labels []string
+	var value float64
+	var stamp int64
+	for len(ts) > 0 {
+		num, _, n := protowire.ConsumeTag(ts)
+		ts = ts[n:]
+		body, n := protowire.ConsumeBytes(ts)
+		ts = ts[n:]
+		switch num {
+		case 1:
+			_, _, n := protowire.ConsumeTag(body)
+			name, m := protowire.ConsumeString(body[n:])
+			body = body[n+m:]
+			_, _, n = protowire.ConsumeTag(body)
+			val, _ := protowire.ConsumeString(body[n:])
+			labels = append(labels, name+"="+val)
+		case 2:
+			_, _, n := protowire.ConsumeTag(body)
+			bits, m := protowire.ConsumeFixed64(body[n:])
+			value = math.Float64frombits(bits)
+			body = body[n+m:]
+			_, _, n = protowire.ConsumeTag(body)
+			v, _ := protowire.ConsumeVarint(body[n:])
+			stamp = int64(v)
+		}
+	}
+	if strings.Join(labels, ",") != "__name__=komari_node_cpu_usage_percent,uuid=node a" {
+		t.Fatalf("unexpected labels: %v", labels)
+	}
+	if value != 12.5 || stamp != 1700000000000 {
+		t.Fatalf("unexpected sample: %v @ %d", value, stamp)
+	}
+}
+
+func decodeLiterals(t *testing.T, src []byte) []byte {
+	var out []byte
+	for len(src) > 0 {
+		tag := src[0]
+		if tag&3 != 0 {
+			t.Fatalf("unexpected non-literal tag %x", tag)
+		}
+		var length, skip int
+		switch m := int(tag >> 2); {
+		case m < 60:
+			length, skip = m+1, 1
+		case m == 60:
+			length, skip = int(src[1])+1, 2
+		case m == 61:
+			length, skip = int(src[1])|int(src[2])<<8+1, 3
+		default:
+			t.Fatalf("unexpected literal length tag %d", m)
+		}
+		out = append(out, src[skip:skip+length]...)
+		src = src[skip+length:]
+	}
+	return out
+}
+
+// fakeSink 每次写出前等待 release，按 fail 返回错误
+type fakeSink struct {
+	mu      sync.Mutex
+	calls   int
+	written int
+	fail    bool
+	release chan struct{}
+}
+
+func (s *fakeSink) Write(ctx context.Context, recs []models.Record) error {
+	s.mu.Lock()
+	s.calls++
+	s.mu.Unlock()
+	if s.release != nil {
+		<-s.release
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return errors.New("sink down")
+	}
+	s.written += len(recs)
+	return nil
+}
+
+func (s *fakeSink) stats() (calls, written int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls, s.written
+}
+
+func waitDrained(t *testing.T, d *dispatcher) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(d.queue) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("dispatcher stopped consuming, %d records queued", len(d.queue))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDispatcherWritesInBackground(t *testing.T) {
+	sink := &fakeSink{release: make(chan struct{})}
+	d := newDispatcher(sink)
+	for i := 0; i < 3*batchSize; i++ {
+		d.queue <- testRecord()
+	}
+	// 写出阻塞时仍继续接收，且同一时间只有一个批次在写
+	waitDrained(t, d)
+	if calls, _ := sink.stats(); calls != 1 {
+		t.Fatalf("expected 1 write in flight, got %d", calls)
+	}
+	close(sink.release)
+	d.stop()
+	if _, written := sink.stats(); written != 3*batchSize {
+		t.Fatalf("expected %d records written, got %d", 3*batchSize, written)
+	}
+}
+
+func TestDispatcherBacksOffWhileFailing(t *testing.T) {
+	sink := &fakeSink{fail: true}
+	d := newDispatcher(sink)
+	for i := 0; i < batchSize; i++ {
+		d.queue <- testRecord()
+	}
+	waitDrained(t, d)
+	deadline := time.Now().Add(5 * time.Second)
+	for calls, _ := sink.stats(); calls == 0; calls, _ = sink.stats() {
+		if time.Now().After(deadline) {
+			t.Fatal("batch was never written")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// 失败后的退避期内，新记录不会再触发写出
+	for i := 0; i < 2*batchSize; i++ {
+		d.queue <- testRecord()
+	}
+	waitDrained(t, d)
+	time.Sleep(50 * time.Millisecond)
+	if calls, _ := sink.stats(); calls != 1 {
+		t.Fatalf("expected no retry during backoff, got %d writes", calls)
+	}
+	d.stop()
+}
+
+func TestDispatcherTrim(t *testing.T) {
+	d := &dispatcher{pending: make([]models.Record, queueSize+5)}
+	d.pending[5].Client = "oldest kept"
+	d.trim()
+	if len(d.pending) != queueSize || d.pending[0].Client != "oldest kept" {
+		t.Fatalf("unexpected backlog: %d records, first %q", len(d.pending), d.pending[0].Client)
+	}
+	if n := d.dropped.Load(); n != 5 {
+		t.Fatalf("expected 5 dropped, got %d", n)
+	}
+}
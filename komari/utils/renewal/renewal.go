@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/komari-monitor/komari/database/auditlog"
+	"github.com/komari-monitor/komari/database/billing"
 	"github.com/komari-monitor/komari/database/clients"
 	"github.com/komari-monitor/komari/database/models"
 	messageevent "github.com/komari-monitor/komari/database/models/messageEvent"
@@ -49,41 +50,13 @@ func CheckAndAutoRenewal(client models.Client) {
 
 		// 如果有账单周期且不为0，进行自动续费
 		if client.BillingCycle > 0 {
-			// 根据账单周期计算新的过期时间
-			var newExpireTime time.Time
-			billingCycle := client.BillingCycle
-
 			// 如果服务器的过期时间太早了，那么直接设置为从当前时间算的下一个到期时间
 			baseTime := clientExpireTime
 			if clientExpireTime.Before(now.AddDate(0, 0, -30)) { // 过期时间超过30天前
 				baseTime = now
 			}
-
-			if billingCycle >= 27 && billingCycle <= 32 {
-				// 月度计费 - 加1个月
-				newExpireTime = baseTime.AddDate(0, 1, 0)
-			} else if billingCycle >= 87 && billingCycle <= 95 {
-				// 季度计费 - 加3个月
-				newExpireTime = baseTime.AddDate(0, 3, 0)
-			} else if billingCycle >= 175 && billingCycle <= 185 {
-				// 半年计费 - 加6个月
-				newExpireTime = baseTime.AddDate(0, 6, 0)
-			} else if billingCycle >= 360 && billingCycle <= 370 {
-				// 年度计费 - 加1年
-				newExpireTime = baseTime.AddDate(1, 0, 0)
-			} else if billingCycle >= 720 && billingCycle <= 750 {
-				// 两年计费 - 加2年
-				newExpireTime = baseTime.AddDate(2, 0, 0)
-			} else if billingCycle >= 1080 && billingCycle <= 1150 {
-				// 三年计费 - 加3年
-				newExpireTime = baseTime.AddDate(3, 0, 0)
-			} else if billingCycle >= 1800 && billingCycle <= 1850 {
-				// 五年计费 - 加5年
-				newExpireTime = baseTime.AddDate(5, 0, 0)
-			} else {
-				// 其他情况，直接加上账单周期天数
-				newExpireTime = baseTime.AddDate(0, 0, billingCycle)
-			}
+			// 根据账单周期计算新的过期时间
+			newExpireTime := billing.AdvanceByCycle(baseTime, client.BillingCycle)
 
 			// 更新客户端过期时间
 			updates := map[string]interface{}{
@@ -97,6 +70,10 @@ func CheckAndAutoRenewal(client models.Client) {
 				return
 			}
 
+			if err := billing.RecordRenewal(client, baseTime, newExpireTime); err != nil {
+				auditlog.EventLog("renewal", fmt.Sprintf("Failed to record billing for client %s (%s): %v", client.Name, client.UUID, err))
+			}
+
 			//renewedClients = append(renewedClients, renewedClient{
 			//	Name:          client.Name,
 			//	NewExpireTime: newExpireTime,
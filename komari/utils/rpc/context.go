@@ -18,8 +18,10 @@ type ContextMeta struct {
 	Permission string
 	// User 登录的管理员用户（仅 admin 会话存在）
 	User *models.User
-	// UserUUID 方便无需解引用就能快速判断
+	// UserUUID 方便无需解引用就能快速判断；API 令牌请求为令牌标识
 	UserUUID string
+	// ApiToken 使用 API 令牌鉴权时存在
+	ApiToken *models.ApiToken
 	// ClientToken 来自客户端的私钥 / 鉴权 token（仅 client 链接可能存在）
 	ClientToken string
 	// ClientUUID 解析出的客户端 UUID（若 token 合法）
@@ -53,7 +53,7 @@ func (m *SPPingTaskManager) Reload(tasks []models.SPPingTask) error {
 
 func (m *SPPingTaskManager) runPreciseLoop(ctx context.Context, interval time.Duration, tasks []models.SPPingTask) {
 	dispatch := func() {
-		onlineClients := ws.GetConnectedClients()
+		onlineClients := ws.GetAllMessageWriters()
 		for _, task := range tasks {
 			go executeSPPingTask(ctx, task, onlineClients)
 		}
@@ -88,7 +88,7 @@ func (m *SPPingTaskManager) runPreciseLoop(ctx context.Context, interval time.Du
 	}
 }
 
-func executeSPPingTask(ctx context.Context, task models.SPPingTask, onlineClients map[string]*ws.SafeConn) {
+func executeSPPingTask(ctx context.Context, task models.SPPingTask, onlineClients map[string]ws.MessageWriter) {
 	var message struct {
 		TaskID      uint   `json:"sp_ping_task_id"`
 		Message     string `json:"message"`
@@ -0,0 +1,608 @@
+package sshjob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/komari-monitor/komari/database/clients"
+	"github.com/komari-monitor/komari/database/config"
+	"github.com/komari-monitor/komari/database/dbcore"
+	"github.com/komari-monitor/komari/database/installscripts"
+	"github.com/komari-monitor/komari/database/models"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
+)
+
+const (
+	KindCommand = "command" // 执行任意命令
+	KindScript  = "script"  // 执行面板保存的安装脚本
+)
+
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusSuccess   = "success"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+const (
+	DefaultConcurrency = 5
+	MaxConcurrency     = 32
+	DefaultTimeout     = 10 * time.Minute
+	MaxTimeout         = 2 * time.Hour
+
+	maxOutput = 256 << 10 // 每个节点保存的输出上限
+	maxEvents = 5000      // 运行中的任务在内存中保留的事件数
+
+	killGrace = 5 * time.Second // 超时或取消后等待远端命令被终止的时间，超过后直接断开连接
+)
+
+var (
+	ErrNotRunning = errors.New("job is not running")
+	ErrRunning    = errors.New("job is still running")
+)
+
+// Options 创建批量任务的参数
+type Options struct {
+	Clients     []string // 留空表示所有已配置 SSH 的节点
+	Kind        string
+	Command     string
+	Script      string   // 安装脚本名称，仅支持 .sh
+	Endpoint    string   // 安装脚本中 Agent 连接的面板地址
+	Args        []string // 追加给安装脚本的参数
+	Concurrency int
+	Timeout     time.Duration // 单个节点的超时
+	UserUUID    string
+	RequesterIp string
+}
+
+// Event 任务事件，通过 SSE 推送给前端
+type Event struct {
+	Type     string `json:"type"` // log / status / done
+	Client   string `json:"client,omitempty"`
+	Line     string `json:"line,omitempty"`
+	Status   string `json:"status,omitempty"`
+	ExitCode *int   `json:"exit_code,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// dialFunc 连接节点，测试中替换为进程内的 SSH 服务端
+type dialFunc func(node models.Client) (*ssh.Client, *models.Credential, error)
+
+type job struct {
+	db     *gorm.DB
+	dial   dialFunc
+	record models.SSHJob
+	opts   Options
+	script string // 安装脚本内容
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	events []Event
+	subs   map[chan Event]struct{}
+	done   bool
+}
+
+var (
+	mu   sync.Mutex
+	jobs = map[string]*job{}
+)
+
+// Start 校验参数并在后台执行任务
+func Start(opts Options) (models.SSHJob, error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	if opts.Concurrency > MaxConcurrency {
+		opts.Concurrency = MaxConcurrency
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Timeout > MaxTimeout {
+		opts.Timeout = MaxTimeout
+	}
+	j := &job{db: dbcore.GetDBInstance(), dial: dialNode, opts: opts, subs: map[chan Event]struct{}{}}
+	record := models.SSHJob{
+		ID:          uuid.NewString(),
+		Kind:        opts.Kind,
+		Concurrency: opts.Concurrency,
+		Timeout:     int(opts.Timeout.Seconds()),
+		UserUUID:    opts.UserUUID,
+		RequesterIp: opts.RequesterIp,
+		Status:      StatusRunning,
+		StartedAt:   models.Now(),
+	}
+	switch opts.Kind {
+	case KindCommand:
+		if strings.TrimSpace(opts.Command) == "" {
+			return record, errors.New("command is required")
+		}
+		record.Command = opts.Command
+	case KindScript:
+		if !strings.HasSuffix(opts.Script, ".sh") {
+			return record, errors.New("only shell install scripts are supported")
+		}
+		if strings.TrimSpace(opts.Endpoint) == "" {
+			return record, errors.New("endpoint is required")
+		}
+		script, err := installscripts.GetByName(opts.Script)
+		if err != nil {
+			return record, fmt.Errorf("install script %s not found: %w", opts.Script, err)
+		}
+		j.script = script.Body
+		record.Script = opts.Script
+		record.Command = QuoteArgs(opts.Args)
+	default:
+		return record, fmt.Errorf("invalid kind: %s", opts.Kind)
+	}
+
+	nodes, err := selectNodes(opts.Clients)
+	if err != nil {
+		return record, err
+	}
+	targets, err := j.prepare(record, nodes)
+	if err != nil {
+		return record, err
+	}
+	mu.Lock()
+	jobs[j.record.ID] = j
+	mu.Unlock()
+	go j.run(nodes, targets)
+	return j.record, nil
+}
+
+// prepare 保存任务与各节点的记录
+func (j *job) prepare(record models.SSHJob, nodes []models.Client) ([]models.SSHJobTarget, error) {
+	record.Total = len(nodes)
+	targets := make([]models.SSHJobTarget, len(nodes))
+	for i, node := range nodes {
+		targets[i] = models.SSHJobTarget{
+			JobID:      record.ID,
+			Client:     node.UUID,
+			ClientName: node.Name,
+			Host:       node.SshHost,
+			Status:     StatusPending,
+		}
+	}
+	err := j.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		return tx.Create(&targets).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	j.record = record
+	j.ctx, j.cancel = context.WithCancel(context.Background())
+	return targets, nil
+}
+
+func dialNode(node models.Client) (*ssh.Client, *models.Credential, error) {
+	return Dial(Target{Host: node.SshHost, Port: node.SshPort, CredentialID: node.SshCredentialID})
+}
+
+// selectNodes 按 UUID 选取节点，未指定时选取所有已配置 SSH 的节点
+func selectNodes(uuids []string) ([]models.Client, error) {
+	all, err := clients.GetAllClientBasicInfo()
+	if err != nil {
+		return nil, err
+	}
+	configured := func(c models.Client) bool {
+		return c.SshEnabled && c.SshHost != "" && c.SshCredentialID != 0
+	}
+	var nodes []models.Client
+	if len(uuids) == 0 {
+		for _, c := range all {
+			if configured(c) {
+				nodes = append(nodes, c)
+			}
+		}
+		if len(nodes) == 0 {
+			return nil, errors.New("no node has SSH configured")
+		}
+		return nodes, nil
+	}
+	byUUID := make(map[string]models.Client, len(all))
+	for _, c := range all {
+		byUUID[c.UUID] = c
+	}
+	seen := map[string]bool{}
+	for _, id := range uuids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		c, ok := byUUID[id]
+		if !ok {
+			return nil, fmt.Errorf("node %s not found", id)
+		}
+		if !configured(c) {
+			return nil, fmt.Errorf("SSH is not configured for node %s", c.Name)
+		}
+		nodes = append(nodes, c)
+	}
+	return nodes, nil
+}
+
+func (j *job) run(nodes []models.Client, targets []models.SSHJobTarget) {
+	sem := make(chan struct{}, j.record.Concurrency)
+	var wg sync.WaitGroup
+	for i := range targets {
+		wg.Add(1)
+		go func(t *models.SSHJobTarget, node models.Client) {
+			defer wg.Done()
+			defer func() {
+				// 防止 panic 中断
+				if r := recover(); r != nil {
+					j.finishTarget(t, StatusFailed, nil, fmt.Sprintf("panic: %v", r), "")
+				}
+			}()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-j.ctx.Done():
+			}
+			j.runTarget(t, node)
+		}(&targets[i], nodes[i])
+	}
+	wg.Wait()
+
+	record := j.record
+	for _, t := range targets {
+		switch t.Status {
+		case StatusSuccess:
+			record.Succeeded++
+		case StatusFailed:
+			record.Failed++
+		}
+	}
+	switch {
+	case j.ctx.Err() != nil:
+		record.Status = StatusCancelled
+	case record.Failed > 0:
+		record.Status = StatusFailed
+	default:
+		record.Status = StatusSuccess
+	}
+	finishedAt := models.Now()
+	record.FinishedAt = &finishedAt
+	if err := j.db.Model(&models.SSHJob{}).Where("id = ?", record.ID).Updates(map[string]any{
+		"status":      record.Status,
+		"succeeded":   record.Succeeded,
+		"failed":      record.Failed,
+		"finished_at": finishedAt,
+	}).Error; err != nil {
+		log.Printf("Failed to update SSH job %s: %v", record.ID, err)
+	}
+
+	j.emit(Event{Type: "done", Status: record.Status})
+	j.mu.Lock()
+	j.done = true
+	for ch := range j.subs {
+		close(ch)
+	}
+	j.subs = nil
+	j.mu.Unlock()
+	mu.Lock()
+	delete(jobs, record.ID)
+	mu.Unlock()
+	j.cancel()
+}
+
+func (j *job) runTarget(t *models.SSHJobTarget, node models.Client) {
+	if j.ctx.Err() != nil {
+		j.finishTarget(t, StatusCancelled, nil, "cancelled", "")
+		return
+	}
+	startedAt := models.Now()
+	t.Status = StatusRunning
+	t.StartedAt = &startedAt
+	j.updateTarget(t, map[string]any{"status": t.Status, "started_at": startedAt})
+	j.emit(Event{Type: "status", Client: t.Client, Status: t.Status})
+
+	ctx, cancel := context.WithTimeout(j.ctx, j.opts.Timeout)
+	defer cancel()
+	out := &output{}
+	onLine := func(line string) {
+		line = strings.TrimRight(line, "\r\n")
+		out.write(line)
+		j.emit(Event{Type: "log", Client: t.Client, Line: line})
+	}
+	err := j.execute(ctx, node, onLine)
+
+	status, errMsg := StatusSuccess, ""
+	var exitCode *int
+	switch {
+	case err == nil:
+		code := 0
+		exitCode = &code
+		onLine("[SUCCESS] Finished")
+	case j.ctx.Err() != nil:
+		status, errMsg = StatusCancelled, "cancelled"
+	case ctx.Err() != nil:
+		status, errMsg = StatusFailed, "timed out after "+j.opts.Timeout.String()
+	default:
+		status, errMsg = StatusFailed, err.Error()
+		if code := ExitCode(err); code >= 0 {
+			exitCode = &code
+		}
+	}
+	if errMsg != "" {
+		onLine("[ERROR] " + errMsg)
+	}
+	j.finishTarget(t, status, exitCode, errMsg, out.String())
+}
+
+// execute 连接节点并执行命令，超时或取消时向远端命令发送 SIGKILL（限制见 RunContext），
+// 若 killGrace 内仍未结束则断开连接
+func (j *job) execute(ctx context.Context, node models.Client, onLine func(string)) error {
+	client, cred, err := j.dial(node)
+	if err != nil {
+		return fmt.Errorf("SSH connection failed: %w", err)
+	}
+	defer client.Close()
+	stop := context.AfterFunc(ctx, func() {
+		time.AfterFunc(killGrace, func() { client.Close() })
+	})
+	defer stop()
+
+	port := node.SshPort
+	if port <= 0 {
+		port = 22
+	}
+	onLine(fmt.Sprintf("[INFO] Connected: %s@%s", cred.Username, net.JoinHostPort(node.SshHost, strconv.Itoa(port))))
+
+	cmd := j.opts.Command
+	var stdin io.Reader
+	if j.opts.Kind == KindScript {
+		// 安装脚本需要 root
+		root, err := IsRoot(client)
+		if err != nil {
+			return fmt.Errorf("id -u failed: %w", err)
+		}
+		if !root {
+			return errors.New("only root supported (id -u != 0)")
+		}
+		// 先写入临时文件再执行，避免脚本中的命令读取 stdin 时吞掉脚本内容
+		args := append([]string{"-e", j.opts.Endpoint, "-t", node.Token}, j.opts.Args...)
+		cmd = `f=$(mktemp) && cat > "$f" && bash "$f" ` + QuoteArgs(args) + `; rc=$?; rm -f "$f"; exit $rc`
+		stdin = strings.NewReader(j.script)
+		onLine(strings.TrimSpace("[INFO] Running " + j.opts.Script + " " + j.record.Command))
+	} else {
+		onLine("[INFO] Running: " + cmd)
+	}
+	return RunContext(ctx, client, cmd, stdin, onLine)
+}
+
+func (j *job) finishTarget(t *models.SSHJobTarget, status string, exitCode *int, errMsg, output string) {
+	finishedAt := models.Now()
+	t.Status = status
+	t.ExitCode = exitCode
+	t.Error = errMsg
+	t.Output = output
+	t.FinishedAt = &finishedAt
+	j.updateTarget(t, map[string]any{
+		"status":      status,
+		"exit_code":   exitCode,
+		"error":       errMsg,
+		"output":      output,
+		"finished_at": finishedAt,
+	})
+	j.emit(Event{Type: "status", Client: t.Client, Status: status, ExitCode: exitCode, Error: errMsg})
+}
+
+func (j *job) updateTarget(t *models.SSHJobTarget, updates map[string]any) {
+	if err := j.db.Model(&models.SSHJobTarget{}).Where("id = ?", t.ID).Updates(updates).Error; err != nil {
+		log.Printf("Failed to update SSH job target %s/%s: %v", t.JobID, t.Client, err)
+	}
+}
+
+func (j *job) emit(ev Event) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if len(j.events) >= maxEvents {
+		j.events = append([]Event(nil), j.events[len(j.events)-maxEvents/2:]...)
+	}
+	j.events = append(j.events, ev)
+	for ch := range j.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// Subscribe 返回任务已有的事件；运行中的任务同时返回后续事件的通道，任务结束后通道关闭。
+// 已结束的任务从历史记录重建事件，通道为 nil。调用方结束时需调用返回的取消函数
+func Subscribe(id string) ([]Event, <-chan Event, func(), error) {
+	mu.Lock()
+	j, ok := jobs[id]
+	mu.Unlock()
+	if !ok {
+		events, err := replay(id)
+		return events, nil, func() {}, err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	events := append([]Event(nil), j.events...)
+	if j.done {
+		return events, nil, func() {}, nil
+	}
+	ch := make(chan Event, 256)
+	j.subs[ch] = struct{}{}
+	return events, ch, func() {
+		j.mu.Lock()
+		delete(j.subs, ch)
+		j.mu.Unlock()
+	}, nil
+}
+
+// replay 从历史记录重建已结束任务的事件
+func replay(id string) ([]Event, error) {
+	record, targets, err := Get(id)
+	if err != nil {
+		return nil, err
+	}
+	var events []Event
+	for _, t := range targets {
+		if t.Output != "" {
+			for _, line := range strings.Split(strings.TrimSuffix(t.Output, "\n"), "\n") {
+				events = append(events, Event{Type: "log", Client: t.Client, Line: line})
+			}
+		}
+		events = append(events, Event{Type: "status", Client: t.Client, Status: t.Status, ExitCode: t.ExitCode, Error: t.Error})
+	}
+	return append(events, Event{Type: "done", Status: record.Status}), nil
+}
+
+// Cancel 取消运行中的任务，未开始的节点直接标记为取消
+func Cancel(id string) error {
+	mu.Lock()
+	j, ok := jobs[id]
+	mu.Unlock()
+	if !ok {
+		return ErrNotRunning
+	}
+	j.cancel()
+	return nil
+}
+
+// Filter 任务列表查询条件
+type Filter struct {
+	Status string
+	Limit  int
+	Offset int
+}
+
+// List 按开始时间倒序列出任务
+func List(filter Filter) ([]models.SSHJob, int64, error) {
+	db := dbcore.GetDBInstance().Model(&models.SSHJob{})
+	if filter.Status != "" {
+		db = db.Where("status = ?", filter.Status)
+	}
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if filter.Limit <= 0 || filter.Limit > 500 {
+		filter.Limit = 100
+	}
+	list := []models.SSHJob{}
+	err := db.Order("started_at DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&list).Error
+	return list, total, err
+}
+
+// Get 获取任务及各节点的执行结果
+func Get(id string) (models.SSHJob, []models.SSHJobTarget, error) {
+	db := dbcore.GetDBInstance()
+	var record models.SSHJob
+	if err := db.Where("id = ?", id).First(&record).Error; err != nil {
+		return record, nil, err
+	}
+	targets := []models.SSHJobTarget{}
+	err := db.Where("job_id = ?", id).Order("id ASC").Find(&targets).Error
+	return record, targets, err
+}
+
+// Delete 删除已结束的任务
+func Delete(id string) error {
+	mu.Lock()
+	_, running := jobs[id]
+	mu.Unlock()
+	if running {
+		return ErrRunning
+	}
+	return dbcore.GetDBInstance().Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.SSHJob{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Delete(&models.SSHJobTarget{}, "job_id = ?", id).Error
+	})
+}
+
+// RemoveOldJobs 按保留天数清理任务历史
+func RemoveOldJobs() {
+	cfg, err := config.Get()
+	if err != nil || cfg.SshJobKeepDays <= 0 {
+		return
+	}
+	before := time.Now().AddDate(0, 0, -cfg.SshJobKeepDays)
+	db := dbcore.GetDBInstance()
+	expired := db.Model(&models.SSHJob{}).Select("id").Where("started_at < ? AND status <> ?", before, StatusRunning)
+	if err := db.Where("job_id IN (?)", expired).Delete(&models.SSHJobTarget{}).Error; err != nil {
+		log.Printf("Failed to remove old SSH job targets: %v", err)
+		return
+	}
+	if err := db.Where("started_at < ? AND status <> ?", before, StatusRunning).Delete(&models.SSHJob{}).Error; err != nil {
+		log.Printf("Failed to remove old SSH jobs: %v", err)
+	}
+}
+
+// MarkInterrupted 将上次运行时未结束的任务标记为失败，仅在启动时调用
+func MarkInterrupted() {
+	db := dbcore.GetDBInstance()
+	now := models.Now()
+	if err := db.Model(&models.SSHJobTarget{}).Where("status IN ?", []string{StatusPending, StatusRunning}).Updates(map[string]any{
+		"status":      StatusFailed,
+		"error":       "interrupted by server restart",
+		"finished_at": now,
+	}).Error; err != nil {
+		log.Printf("Failed to mark interrupted SSH job targets: %v", err)
+	}
+	if err := db.Model(&models.SSHJob{}).Where("status = ?", StatusRunning).Updates(map[string]any{
+		"status":      StatusFailed,
+		"finished_at": now,
+	}).Error; err != nil {
+		log.Printf("Failed to mark interrupted SSH jobs: %v", err)
+	}
+}
+
+// output 保存节点输出的末尾部分
+type output struct {
+	mu        sync.Mutex
+	buf       []byte
+	truncated bool
+}
+
+func (o *output) write(line string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.buf = append(o.buf, line...)
+	o.buf = append(o.buf, '\n')
+	if len(o.buf) > maxOutput {
+		// 一次裁掉一半，避免每行都复制整个缓冲区
+		cut := len(o.buf) - maxOutput/2
+		for cut < len(o.buf) && o.buf[cut-1] != '\n' {
+			cut++
+		}
+		o.buf = append([]byte(nil), o.buf[cut:]...)
+		o.truncated = true
+	}
+}
+
+func (o *output) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.truncated {
+		return "... (output truncated)\n" + string(o.buf)
+	}
+	return string(o.buf)
+}
@@ -0,0 +1,51 @@
+package sshjob
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	cases := map[string]string{
+		"":                        "''",
+		"--install-dir":           "--install-dir",
+		"https://a.example.com:8": "https://a.example.com:8",
+		"a b":                     "'a b'",
+		"x;rm -rf /":              "'x;rm -rf /'",
+		"it's":                    `'it'\''s'`,
+		"$(id)":                   "'$(id)'",
+	}
+	for in, want := range cases {
+		if got := Quote(in); got != want {
+			t.Errorf("Quote(%q) = %s, want %s", in, got, want)
+		}
+	}
+	if got := QuoteArgs([]string{"-e", "a|b"}); got != "-e 'a|b'" {
+		t.Errorf("unexpected QuoteArgs result %s", got)
+	}
+}
+
+func TestOutputKeepsTail(t *testing.T) {
+	o := &output{}
+	o.write("first")
+	if o.String() != "first\n" {
+		t.Fatalf("unexpected output %q", o.String())
+	}
+	line := strings.Repeat("x", 1023)
+	for i := 0; i < 512; i++ {
+		o.write(line)
+	}
+	o.write("last")
+	got := o.String()
+	if !strings.HasPrefix(got, "... (output truncated)\n") || !strings.HasSuffix(got, "\nlast\n") {
+		t.Fatalf("unexpected truncated output")
+	}
+	if strings.Contains(got, "first") || len(got) > maxOutput+64 {
+		t.Fatalf("output should only keep the tail, got %d bytes", len(got))
+	}
+	// 裁剪后仍以完整行开头
+	body := strings.TrimPrefix(got, "... (output truncated)\n")
+	if !strings.HasPrefix(body, line+"\n") {
+		t.Fatal("truncated output should start at a line boundary")
+	}
+}
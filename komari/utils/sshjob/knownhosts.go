@@ -0,0 +1,61 @@
+package sshjob
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/komari-monitor/komari/database/dbcore"
+	"github.com/komari-monitor/komari/database/models"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrHostKeyMismatch 主机密钥与首次连接时记录的不一致，节点重装后需先删除记录
+var ErrHostKeyMismatch = errors.New("host key mismatch")
+
+// hostKeyCallback 首次连接某个地址时记录其主机密钥，之后只接受相同的密钥
+func hostKeyCallback(db *gorm.DB) ssh.HostKeyCallback {
+	return func(hostname string, _ net.Addr, key ssh.PublicKey) error {
+		record := models.SSHKnownHost{
+			Address:     hostname,
+			KeyType:     key.Type(),
+			Key:         base64.StdEncoding.EncodeToString(key.Marshal()),
+			Fingerprint: ssh.FingerprintSHA256(key),
+			CreatedAt:   models.Now(),
+		}
+		// 并发的首次连接只有一个能写入，其余与已写入的记录比较
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record).Error; err != nil {
+			return err
+		}
+		var known models.SSHKnownHost
+		if err := db.Where("address = ?", hostname).First(&known).Error; err != nil {
+			return err
+		}
+		if known.Key != record.Key {
+			return fmt.Errorf("%w for %s: expected %s, got %s", ErrHostKeyMismatch, hostname, known.Fingerprint, record.Fingerprint)
+		}
+		return nil
+	}
+}
+
+// ListKnownHosts 列出已记录的主机密钥
+func ListKnownHosts() ([]models.SSHKnownHost, error) {
+	list := []models.SSHKnownHost{}
+	err := dbcore.GetDBInstance().Order("address ASC").Find(&list).Error
+	return list, err
+}
+
+// ForgetHostKey 删除记录的主机密钥，下次连接时重新记录
+func ForgetHostKey(address string) error {
+	result := dbcore.GetDBInstance().Delete(&models.SSHKnownHost{}, "address = ?", address)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
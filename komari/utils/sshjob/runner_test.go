@@ -0,0 +1,360 @@
+package sshjob
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/komari-monitor/komari/database/models"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// testServer 进程内的 SSH 服务端，按登录用户名决定命令的行为：
+// ok 输出一行后成功，fail 以退出码 1 结束，slow 稍后成功，hang 一直运行到收到信号或会话关闭
+type testServer struct {
+	host    string
+	port    int
+	execs   atomic.Int32
+	active  atomic.Int32
+	peak    atomic.Int32
+	started chan struct{}
+	signals chan string
+}
+
+func newTestSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func startTestServer(t *testing.T) *testServer {
+	t.Helper()
+	cfg := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, _ []byte) (*ssh.Permissions, error) {
+			return nil, nil
+		},
+	}
+	cfg.AddHostKey(newTestSigner(t))
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	addr := l.Addr().(*net.TCPAddr)
+	srv := &testServer{host: addr.IP.String(), port: addr.Port, started: make(chan struct{}, 8), signals: make(chan string, 8)}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn, cfg)
+		}
+	}()
+	return srv
+}
+
+func (s *testServer) serve(conn net.Conn, cfg *ssh.ServerConfig) {
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, cfg)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	defer sconn.Close()
+	go ssh.DiscardRequests(reqs)
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			nc.Reject(ssh.UnknownChannelType, "unsupported")
+			continue
+		}
+		ch, requests, err := nc.Accept()
+		if err != nil {
+			return
+		}
+		go s.session(sconn.User(), ch, requests)
+	}
+}
+
+func (s *testServer) session(user string, ch ssh.Channel, requests <-chan *ssh.Request) {
+	defer ch.Close()
+	exit := func(code uint32) {
+		ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{code}))
+		ch.Close()
+	}
+	for req := range requests {
+		switch req.Type {
+		case "exec":
+			req.Reply(true, nil)
+			s.execs.Add(1)
+			switch user {
+			case "ok":
+				fmt.Fprintln(ch, "hello")
+				exit(0)
+			case "fail":
+				fmt.Fprintln(ch.Stderr(), "boom")
+				exit(1)
+			case "slow":
+				go func() {
+					n := s.active.Add(1)
+					for {
+						peak := s.peak.Load()
+						if n <= peak || s.peak.CompareAndSwap(peak, n) {
+							break
+						}
+					}
+					time.Sleep(100 * time.Millisecond)
+					s.active.Add(-1)
+					exit(0)
+				}()
+			case "hang":
+				s.started <- struct{}{}
+			}
+		case "signal":
+			var msg struct{ Signal string }
+			ssh.Unmarshal(req.Payload, &msg)
+			s.signals <- msg.Signal
+			ch.Close()
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库每个连接相互独立，固定为单个连接
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&models.SSHJob{}, &models.SSHJobTarget{}, &models.SSHKnownHost{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// newTestJob 创建连接到 srv 的任务，每个用户名对应一个节点
+func newTestJob(t *testing.T, db *gorm.DB, srv *testServer, concurrency int, timeout time.Duration, users ...string) (*job, []models.Client, []models.SSHJobTarget) {
+	t.Helper()
+	dial := func(node models.Client) (*ssh.Client, *models.Credential, error) {
+		client, err := ssh.Dial("tcp", net.JoinHostPort(node.SshHost, strconv.Itoa(node.SshPort)), &ssh.ClientConfig{
+			User:            node.Name,
+			Auth:            []ssh.AuthMethod{ssh.Password("secret")},
+			HostKeyCallback: hostKeyCallback(db),
+			Timeout:         5 * time.Second,
+		})
+		return client, &models.Credential{Username: node.Name}, err
+	}
+	j := &job{
+		db:   db,
+		dial: dial,
+		opts: Options{Kind: KindCommand, Command: "run", Timeout: timeout},
+		subs: map[chan Event]struct{}{},
+	}
+	nodes := make([]models.Client, len(users))
+	for i, user := range users {
+		nodes[i] = models.Client{UUID: strconv.Itoa(i), Name: user, SshHost: srv.host, SshPort: srv.port}
+	}
+	record := models.SSHJob{
+		ID:          fmt.Sprintf("%s-%d", t.Name(), time.Now().UnixNano()),
+		Kind:        KindCommand,
+		Command:     "run",
+		Concurrency: concurrency,
+		Status:      StatusRunning,
+		StartedAt:   models.Now(),
+	}
+	targets, err := j.prepare(record, nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return j, nodes, targets
+}
+
+func loadJob(t *testing.T, db *gorm.DB, id string) (models.SSHJob, map[string]models.SSHJobTarget) {
+	t.Helper()
+	var record models.SSHJob
+	if err := db.Where("id = ?", id).First(&record).Error; err != nil {
+		t.Fatal(err)
+	}
+	var targets []models.SSHJobTarget
+	if err := db.Where("job_id = ?", id).Find(&targets).Error; err != nil {
+		t.Fatal(err)
+	}
+	byClient := map[string]models.SSHJobTarget{}
+	for _, target := range targets {
+		byClient[target.Client] = target
+	}
+	return record, byClient
+}
+
+func runAsync(j *job, nodes []models.Client, targets []models.SSHJobTarget) chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		j.run(nodes, targets)
+		close(done)
+	}()
+	return done
+}
+
+func waitFor[T any](t *testing.T, ch <-chan T, what string) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(10 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+	var zero T
+	return zero
+}
+
+func TestRunnerRollup(t *testing.T) {
+	db := newTestDB(t)
+	srv := startTestServer(t)
+	j, nodes, targets := newTestJob(t, db, srv, 2, time.Minute, "ok", "fail")
+	j.run(nodes, targets)
+
+	record, byClient := loadJob(t, db, j.record.ID)
+	if record.Status != StatusFailed || record.Succeeded != 1 || record.Failed != 1 || record.FinishedAt == nil {
+		t.Fatalf("unexpected job %+v", record)
+	}
+	ok, fail := byClient["0"], byClient["1"]
+	if ok.Status != StatusSuccess || ok.ExitCode == nil || *ok.ExitCode != 0 || !strings.Contains(ok.Output, "hello\n") {
+		t.Errorf("unexpected ok target %+v", ok)
+	}
+	if fail.Status != StatusFailed || fail.ExitCode == nil || *fail.ExitCode != 1 || !strings.Contains(fail.Output, "boom\n") {
+		t.Errorf("unexpected failed target %+v", fail)
+	}
+
+	j, nodes, targets = newTestJob(t, db, srv, 2, time.Minute, "ok", "ok")
+	j.run(nodes, targets)
+	if record, _ := loadJob(t, db, j.record.ID); record.Status != StatusSuccess || record.Succeeded != 2 {
+		t.Fatalf("unexpected job %+v", record)
+	}
+}
+
+func TestRunnerConcurrencyLimit(t *testing.T) {
+	db := newTestDB(t)
+	srv := startTestServer(t)
+	j, nodes, targets := newTestJob(t, db, srv, 2, time.Minute, "slow", "slow", "slow", "slow", "slow")
+	j.run(nodes, targets)
+
+	if peak := srv.peak.Load(); peak != 2 {
+		t.Errorf("expected at most 2 concurrent commands, got %d", peak)
+	}
+	if record, _ := loadJob(t, db, j.record.ID); record.Status != StatusSuccess || record.Succeeded != 5 {
+		t.Fatalf("unexpected job %+v", record)
+	}
+}
+
+func TestRunnerCancelBeforeRun(t *testing.T) {
+	db := newTestDB(t)
+	srv := startTestServer(t)
+	j, nodes, targets := newTestJob(t, db, srv, 1, time.Minute, "ok", "ok")
+	j.cancel()
+	j.run(nodes, targets)
+
+	record, byClient := loadJob(t, db, j.record.ID)
+	if record.Status != StatusCancelled || record.Succeeded != 0 || record.Failed != 0 {
+		t.Fatalf("unexpected job %+v", record)
+	}
+	for client, target := range byClient {
+		if target.Status != StatusCancelled {
+			t.Errorf("target %s should be cancelled, got %s", client, target.Status)
+		}
+	}
+	if n := srv.execs.Load(); n != 0 {
+		t.Errorf("no command should run, got %d", n)
+	}
+}
+
+func TestRunnerCancelDuringRun(t *testing.T) {
+	db := newTestDB(t)
+	srv := startTestServer(t)
+	j, nodes, targets := newTestJob(t, db, srv, 1, time.Minute, "hang", "hang")
+	done := runAsync(j, nodes, targets)
+	waitFor(t, srv.started, "command to start")
+	j.cancel()
+	// 远端命令收到 SIGKILL，而不只是断开连接
+	if sig := waitFor(t, srv.signals, "signal"); sig != string(ssh.SIGKILL) {
+		t.Errorf("expected KILL, got %s", sig)
+	}
+	waitFor(t, done, "job to finish")
+
+	record, byClient := loadJob(t, db, j.record.ID)
+	if record.Status != StatusCancelled {
+		t.Fatalf("unexpected job %+v", record)
+	}
+	for client, target := range byClient {
+		if target.Status != StatusCancelled {
+			t.Errorf("target %s should be cancelled, got %s", client, target.Status)
+		}
+	}
+	// 排队中的节点不再执行
+	if n := srv.execs.Load(); n != 1 {
+		t.Errorf("expected 1 command to run, got %d", n)
+	}
+}
+
+func TestRunnerTimeoutKillsCommand(t *testing.T) {
+	db := newTestDB(t)
+	srv := startTestServer(t)
+	j, nodes, targets := newTestJob(t, db, srv, 1, 200*time.Millisecond, "hang")
+	done := runAsync(j, nodes, targets)
+	if sig := waitFor(t, srv.signals, "signal"); sig != string(ssh.SIGKILL) {
+		t.Errorf("expected KILL, got %s", sig)
+	}
+	waitFor(t, done, "job to finish")
+
+	record, byClient := loadJob(t, db, j.record.ID)
+	if record.Status != StatusFailed || record.Failed != 1 {
+		t.Fatalf("unexpected job %+v", record)
+	}
+	if target := byClient["0"]; !strings.HasPrefix(target.Error, "timed out") {
+		t.Errorf("unexpected target error %q", target.Error)
+	}
+}
+
+func TestHostKeyPinning(t *testing.T) {
+	db := newTestDB(t)
+	check := hostKeyCallback(db)
+	first, second := newTestSigner(t).PublicKey(), newTestSigner(t).PublicKey()
+
+	if err := check("node:22", nil, first); err != nil {
+		t.Fatalf("first connection should record the key: %v", err)
+	}
+	if err := check("node:22", nil, first); err != nil {
+		t.Fatalf("same key should be accepted: %v", err)
+	}
+	if err := check("node:22", nil, second); !errors.Is(err, ErrHostKeyMismatch) {
+		t.Fatalf("changed key should be rejected, got %v", err)
+	}
+	if err := check("other:22", nil, second); err != nil {
+		t.Fatalf("keys are pinned per address: %v", err)
+	}
+	var known models.SSHKnownHost
+	if err := db.Where("address = ?", "node:22").First(&known).Error; err != nil || known.Fingerprint != ssh.FingerprintSHA256(first) {
+		t.Fatalf("unexpected known host %+v, %v", known, err)
+	}
+}
@@ -0,0 +1,179 @@
+package sshjob
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/komari-monitor/komari/database/credentials"
+	"github.com/komari-monitor/komari/database/dbcore"
+	"github.com/komari-monitor/komari/database/models"
+	"golang.org/x/crypto/ssh"
+)
+
+// Target SSH 连接目标
+type Target struct {
+	Host         string
+	Port         int
+	CredentialID uint
+}
+
+// Dial 使用保存的凭据建立 SSH 连接，首次连接时记录主机密钥，之后密钥变化会拒绝连接
+func Dial(target Target) (*ssh.Client, *models.Credential, error) {
+	if target.Port <= 0 {
+		target.Port = 22
+	}
+	cred, err := credentials.Get(target.CredentialID)
+	if err != nil {
+		return nil, nil, err
+	}
+	secret, err := credentials.RevealSecret(cred.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	passphrase, err := credentials.RevealPassphrase(cred.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var auth ssh.AuthMethod
+	switch cred.Type {
+	case models.CredentialTypePassword:
+		auth = ssh.Password(secret)
+	case models.CredentialTypeKey:
+		var signer ssh.Signer
+		if strings.TrimSpace(passphrase) != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(secret), []byte(passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey([]byte(secret))
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid private key: %w", err)
+		}
+		auth = ssh.PublicKeys(signer)
+	default:
+		return nil, nil, fmt.Errorf("unsupported credential type: %s", cred.Type)
+	}
+
+	cfg := &ssh.ClientConfig{
+		User:            cred.Username,
+		Auth:            []ssh.AuthMethod{auth},
+		HostKeyCallback: hostKeyCallback(dbcore.GetDBInstance()),
+		Timeout:         10 * time.Second,
+	}
+	addr := net.JoinHostPort(target.Host, strconv.Itoa(target.Port))
+	client, err := ssh.Dial("tcp", addr, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, cred, nil
+}
+
+// Run 执行命令并逐行回调 stdout 与 stderr，stdin 可为 nil
+func Run(client *ssh.Client, cmd string, stdin io.Reader, onLine func(string)) error {
+	return RunContext(context.Background(), client, cmd, stdin, onLine)
+}
+
+// RunContext 同 Run，ctx 结束时向远端命令发送 SIGKILL 并关闭会话。
+//
+// 注意：signal 请求需要服务端支持（OpenSSH 7.9 起），且 sshd 只把信号发给会话的 shell，
+// 命令自行启动的子进程或后台进程可能继续运行；不支持的服务端会忽略该请求，
+// 此时命令会一直运行到结束，断开连接也无法中止
+func RunContext(ctx context.Context, client *ssh.Client, cmd string, stdin io.Reader, onLine func(string)) error {
+	sess, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+
+	if stdin != nil {
+		sess.Stdin = stdin
+	}
+	stdout, err := sess.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := sess.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := sess.Start(cmd); err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() {
+		sess.Signal(ssh.SIGKILL)
+		sess.Close()
+	})
+	defer stop()
+
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		sc := bufio.NewScanner(stdout)
+		for sc.Scan() {
+			onLine(sc.Text())
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		sc := bufio.NewScanner(stderr)
+		for sc.Scan() {
+			onLine(sc.Text())
+		}
+	}()
+
+	err = sess.Wait()
+	wg.Wait()
+	return err
+}
+
+// IsRoot 检查登录用户是否为 root
+func IsRoot(client *ssh.Client) (bool, error) {
+	var out strings.Builder
+	if err := Run(client, "id -u", nil, func(line string) { out.WriteString(line) }); err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out.String()) == "0", nil
+}
+
+// ExitCode 返回远端命令的退出码，无法获取时返回 -1
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus()
+	}
+	return -1
+}
+
+var safeArg = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+func QuoteArgs(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, a := range args {
+		quoted = append(quoted, Quote(a))
+	}
+	return strings.Join(quoted, " ")
+}
+
+func Quote(s string) string {
+	// 单引号安全引用：' -> '\''
+	if s == "" {
+		return "''"
+	}
+	if safeArg.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,263 @@
+package termrec
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 事件类型，见 https://docs.asciinema.org/manual/asciicast/v2/
+const (
+	EventOutput = "o"
+	EventInput  = "i"
+	EventResize = "r"
+)
+
+// Header asciicast v2 文件头
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Event 一条录制事件：[相对秒数, 类型, 数据]
+type Event struct {
+	Time float64
+	Type string
+	Data string
+}
+
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{e.Time, e.Type, e.Data})
+}
+
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if len(raw) != 3 {
+		return fmt.Errorf("invalid event: expected 3 elements, got %d", len(raw))
+	}
+	if err := json.Unmarshal(raw[0], &e.Time); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw[1], &e.Type); err != nil {
+		return err
+	}
+	return json.Unmarshal(raw[2], &e.Data)
+}
+
+// Recorder 将终端输入输出写为 asciicast v2
+//
+// 文件头在第一条事件时写入，以便使用浏览器首次上报的终端尺寸；
+// 被截断在两次读取之间的 UTF-8 字符会缓存到下一次再写入；
+// 设置了大小上限时，超出上限的事件被丢弃并标记为已截断
+type Recorder struct {
+	mu      sync.Mutex
+	dst     io.WriteCloser
+	w       *bufio.Writer
+	now     func() time.Time
+	start   time.Time
+	header  Header
+	started bool
+	input   bool
+	closed  bool
+	pending map[string][]byte
+	written int64
+	limit   int64
+	cut     bool
+}
+
+// NewRecorder 创建录制器，input 为 false 时忽略输入事件
+func NewRecorder(dst io.WriteCloser, title string, input bool) *Recorder {
+	return &Recorder{
+		dst: dst,
+		w:   bufio.NewWriterSize(dst, 32*1024),
+		now: time.Now,
+		header: Header{
+			Version: 2,
+			Width:   80,
+			Height:  24,
+			Title:   title,
+			Env:     map[string]string{"TERM": "xterm-256color"},
+		},
+		input:   input,
+		pending: map[string][]byte{},
+	}
+}
+
+// SetLimit 设置文件大小上限（字节），0 表示不限制
+func (r *Recorder) SetLimit(n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.limit = n
+}
+
+// Truncated 是否因超出大小上限而停止录制
+func (r *Recorder) Truncated() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cut
+}
+
+// Output 记录被控端输出
+func (r *Recorder) Output(data []byte) {
+	r.write(EventOutput, data)
+}
+
+// Input 记录浏览器输入
+func (r *Recorder) Input(data []byte) {
+	if r.input {
+		r.write(EventInput, data)
+	}
+}
+
+// Resize 记录终端尺寸变化
+func (r *Recorder) Resize(cols, rows int) {
+	if cols <= 0 || rows <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	if !r.started {
+		r.header.Width, r.header.Height = cols, rows
+		return
+	}
+	r.emit(EventResize, fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// Close 写出缓冲内容并关闭文件，返回录制时长与文件大小
+func (r *Recorder) Close() (time.Duration, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return 0, r.written, nil
+	}
+	// 会话没有任何事件时也写入文件头，保证文件可被播放器读取
+	if !r.started {
+		r.writeHeader()
+	}
+	for _, typ := range []string{EventOutput, EventInput} {
+		if len(r.pending[typ]) > 0 {
+			r.emit(typ, string(r.pending[typ]))
+		}
+	}
+	r.closed = true
+	duration := r.now().Sub(r.start)
+	err := r.w.Flush()
+	if cerr := r.dst.Close(); err == nil {
+		err = cerr
+	}
+	return duration, r.written, err
+}
+
+func (r *Recorder) write(typ string, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	buf := append(r.pending[typ], data...)
+	complete, rest := splitUTF8(buf)
+	r.pending[typ] = append([]byte(nil), rest...)
+	if len(complete) > 0 {
+		r.emit(typ, string(complete))
+	}
+}
+
+func (r *Recorder) writeHeader() {
+	r.started = true
+	r.start = r.now()
+	r.header.Timestamp = r.start.Unix()
+	line, _ := json.Marshal(r.header)
+	r.writeLine(line)
+}
+
+func (r *Recorder) emit(typ, data string) {
+	if !r.started {
+		r.writeHeader()
+	}
+	elapsed := math.Round(r.now().Sub(r.start).Seconds()*1e6) / 1e6
+	line := []byte("[" + strconv.FormatFloat(elapsed, 'f', -1, 64) + ",")
+	t, _ := json.Marshal(typ)
+	d, _ := json.Marshal(data)
+	line = append(line, t...)
+	line = append(line, ',')
+	line = append(line, d...)
+	line = append(line, ']')
+	r.writeLine(line)
+}
+
+func (r *Recorder) writeLine(line []byte) {
+	if r.cut {
+		return
+	}
+	// 文件头总是写入，保证截断的文件仍可播放
+	if r.limit > 0 && r.written > 0 && r.written+int64(len(line))+1 > r.limit {
+		r.cut = true
+		return
+	}
+	n, _ := r.w.Write(line)
+	m, _ := r.w.Write([]byte{'\n'})
+	r.written += int64(n + m)
+}
+
+// splitUTF8 拆出末尾不完整的 UTF-8 字符
+func splitUTF8(b []byte) (complete, rest []byte) {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return b[:i], b[i:]
+			}
+			break
+		}
+	}
+	return b, nil
+}
+
+// ReadCast 解析 asciicast v2 文件
+func ReadCast(r io.Reader) (Header, []Event, error) {
+	var header Header
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return header, nil, err
+		}
+		return header, nil, fmt.Errorf("empty recording")
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return header, nil, fmt.Errorf("invalid header: %v", err)
+	}
+	if header.Version != 2 {
+		return header, nil, fmt.Errorf("unsupported asciicast version: %d", header.Version)
+	}
+	events := []Event{}
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return header, events, fmt.Errorf("invalid event on line %d: %v", len(events)+2, err)
+		}
+		events = append(events, e)
+	}
+	return header, events, scanner.Err()
+}
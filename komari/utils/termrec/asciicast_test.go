@@ -0,0 +1,89 @@
+package termrec
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type nopCloser struct{ *bytes.Buffer }
+
+func (nopCloser) Close() error { return nil }
+
+func TestRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	clock := time.Unix(1700000000, 0)
+	r := NewRecorder(nopCloser{&buf}, "node", false)
+	r.now = func() time.Time { return clock }
+
+	r.Resize(120, 40)
+	r.Output([]byte("hello "))
+	clock = clock.Add(1500 * time.Millisecond)
+	// “中” 被拆到两次读取中
+	r.Output([]byte{0xe4, 0xb8})
+	r.Output([]byte{0xad, '\n'})
+	r.Input([]byte("ignored"))
+	r.Resize(100, 30)
+	clock = clock.Add(time.Second)
+	duration, size, err := r.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, 2500*time.Millisecond, duration)
+	assert.Equal(t, int64(buf.Len()), size)
+
+	// 关闭后的事件被忽略
+	r.Output([]byte("late"))
+
+	header, events, err := ReadCast(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, 120, header.Width)
+	assert.Equal(t, 40, header.Height)
+	assert.Equal(t, int64(1700000000), header.Timestamp)
+	assert.Equal(t, []Event{
+		{Time: 0, Type: EventOutput, Data: "hello "},
+		{Time: 1.5, Type: EventOutput, Data: "中\n"},
+		{Time: 1.5, Type: EventResize, Data: "100x30"},
+	}, events)
+}
+
+func TestRecorderInput(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRecorder(nopCloser{&buf}, "", true)
+	r.Input([]byte("ls\r"))
+	_, _, err := r.Close()
+	assert.NoError(t, err)
+	_, events, err := ReadCast(&buf)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, EventInput, events[0].Type)
+		assert.Equal(t, "ls\r", events[0].Data)
+	}
+
+	_, _, err = ReadCast(bytes.NewReader(nil))
+	assert.Error(t, err)
+	_, _, err = ReadCast(bytes.NewReader([]byte(`{"version":1}`)))
+	assert.Error(t, err)
+}
+
+func TestRecorderLimit(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRecorder(nopCloser{&buf}, "", false)
+	clock := time.Unix(1700000000, 0)
+	r.now = func() time.Time { return clock }
+	r.SetLimit(200)
+	r.Output([]byte("first"))
+	assert.False(t, r.Truncated())
+	r.Output(bytes.Repeat([]byte("x"), 300))
+	// 截断后即使事件足够小也不再写入
+	r.Output([]byte("after"))
+	_, size, err := r.Close()
+	assert.NoError(t, err)
+	assert.True(t, r.Truncated())
+	assert.LessOrEqual(t, size, int64(200))
+	assert.Equal(t, int64(buf.Len()), size)
+
+	_, events, err := ReadCast(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, []Event{{Time: 0, Type: EventOutput, Data: "first"}}, events)
+}
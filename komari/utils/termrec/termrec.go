@@ -0,0 +1,179 @@
+package termrec
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/komari-monitor/komari/database/config"
+	"github.com/komari-monitor/komari/database/dbcore"
+	"github.com/komari-monitor/komari/database/models"
+	"gorm.io/gorm"
+)
+
+var unsafeIDChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// Session 正在录制的终端会话，nil 表示未录制，所有方法均可安全调用
+type Session struct {
+	*Recorder
+	record models.TerminalRecording
+}
+
+// Start 按站点设置开始录制，未启用或创建文件失败时返回 nil
+func Start(id, client, userUUID, ip string) *Session {
+	cfg, err := config.Get()
+	if err != nil || !cfg.TerminalRecordEnabled {
+		return nil
+	}
+	dir := cfg.TerminalRecordDir
+	if dir == "" {
+		dir = "./data/terminal_recordings"
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		log.Printf("Failed to create terminal recording dir: %v", err)
+		return nil
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.cast", time.Now().Format("20060102-150405"), unsafeIDChars.ReplaceAllString(id, "_")))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Printf("Failed to create terminal recording: %v", err)
+		return nil
+	}
+	record := models.TerminalRecording{
+		ID:          id,
+		Client:      client,
+		UserUUID:    userUUID,
+		RequesterIp: ip,
+		Input:       cfg.TerminalRecordInput,
+		Path:        path,
+		StartedAt:   models.Now(),
+	}
+	if err := dbcore.GetDBInstance().Create(&record).Error; err != nil {
+		log.Printf("Failed to save terminal recording: %v", err)
+		f.Close()
+		os.Remove(path)
+		return nil
+	}
+	recorder := NewRecorder(f, client, cfg.TerminalRecordInput)
+	recorder.SetLimit(int64(cfg.TerminalRecordMaxSize) << 20)
+	return &Session{Recorder: recorder, record: record}
+}
+
+func (s *Session) Output(data []byte) {
+	if s != nil {
+		s.Recorder.Output(data)
+	}
+}
+
+func (s *Session) Input(data []byte) {
+	if s != nil {
+		s.Recorder.Input(data)
+	}
+}
+
+func (s *Session) Resize(cols, rows int) {
+	if s != nil {
+		s.Recorder.Resize(cols, rows)
+	}
+}
+
+// Finish 结束录制并更新时长、大小与截断状态
+func (s *Session) Finish() {
+	if s == nil {
+		return
+	}
+	duration, size, err := s.Recorder.Close()
+	if err != nil {
+		log.Printf("Failed to close terminal recording %s: %v", s.record.ID, err)
+	}
+	endedAt := models.Now()
+	if err := dbcore.GetDBInstance().Model(&models.TerminalRecording{}).Where("id = ?", s.record.ID).Updates(map[string]any{
+		"size":      size,
+		"duration":  duration.Seconds(),
+		"ended_at":  endedAt,
+		"truncated": s.Recorder.Truncated(),
+	}).Error; err != nil {
+		log.Printf("Failed to update terminal recording %s: %v", s.record.ID, err)
+	}
+}
+
+// Filter 录制列表查询条件
+type Filter struct {
+	Client   string
+	UserUUID string
+	Limit    int
+	Offset   int
+}
+
+// List 按开始时间倒序列出录制
+func List(filter Filter) ([]models.TerminalRecording, int64, error) {
+	db := dbcore.GetDBInstance().Model(&models.TerminalRecording{})
+	if filter.Client != "" {
+		db = db.Where("client = ?", filter.Client)
+	}
+	if filter.UserUUID != "" {
+		db = db.Where("user_uuid = ?", filter.UserUUID)
+	}
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if filter.Limit <= 0 || filter.Limit > 500 {
+		filter.Limit = 100
+	}
+	list := []models.TerminalRecording{}
+	err := db.Order("started_at DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&list).Error
+	return list, total, err
+}
+
+// Get 获取录制信息
+func Get(id string) (models.TerminalRecording, error) {
+	var record models.TerminalRecording
+	err := dbcore.GetDBInstance().Where("id = ?", id).First(&record).Error
+	return record, err
+}
+
+// Open 打开录制文件
+func Open(id string) (models.TerminalRecording, *os.File, error) {
+	record, err := Get(id)
+	if err != nil {
+		return record, nil, err
+	}
+	f, err := os.Open(record.Path)
+	return record, f, err
+}
+
+// Delete 删除录制及其文件
+func Delete(id string) error {
+	record, err := Get(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(record.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return dbcore.GetDBInstance().Delete(&models.TerminalRecording{}, "id = ?", id).Error
+}
+
+// RemoveOldRecordings 按保留天数清理录制
+func RemoveOldRecordings() {
+	cfg, err := config.Get()
+	if err != nil || cfg.TerminalRecordKeepDays <= 0 {
+		return
+	}
+	before := time.Now().AddDate(0, 0, -cfg.TerminalRecordKeepDays)
+	var expired []models.TerminalRecording
+	if err := dbcore.GetDBInstance().Where("started_at < ?", before).Find(&expired).Error; err != nil {
+		log.Printf("Failed to query old terminal recordings: %v", err)
+		return
+	}
+	for _, record := range expired {
+		if err := Delete(record.ID); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Failed to remove terminal recording %s: %v", record.ID, err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package tunnel
+
+import (
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsConn 将 Agent 的 WebSocket 连接适配为 net.Conn，数据以 binary 帧传输
+type wsConn struct {
+	conn    *websocket.Conn
+	reader  io.Reader
+	writeMu sync.Mutex
+	onData  func(in, out int)
+	onClose func()
+	once    sync.Once
+}
+
+func newWSConn(conn *websocket.Conn, onData func(in, out int), onClose func()) *wsConn {
+	return &wsConn{conn: conn, onData: onData, onClose: onClose}
+}
+
+func (c *wsConn) Read(p []byte) (int, error) {
+	for {
+		if c.reader == nil {
+			messageType, r, err := c.conn.NextReader()
+			if err != nil {
+				if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+					return 0, io.EOF
+				}
+				return 0, err
+			}
+			if messageType != websocket.BinaryMessage {
+				continue
+			}
+			c.reader = r
+		}
+		n, err := c.reader.Read(p)
+		if err == io.EOF {
+			c.reader = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		if n > 0 && c.onData != nil {
+			c.onData(n, 0)
+		}
+		return n, err
+	}
+}
+
+func (c *wsConn) Write(p []byte) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := c.conn.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	if c.onData != nil {
+		c.onData(0, len(p))
+	}
+	return len(p), nil
+}
+
+func (c *wsConn) Close() error {
+	var err error
+	c.once.Do(func() {
+		c.writeMu.Lock()
+		c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		c.writeMu.Unlock()
+		err = c.conn.Close()
+		if c.onClose != nil {
+			c.onClose()
+		}
+	})
+	return err
+}
+
+func (c *wsConn) LocalAddr() net.Addr  { return c.conn.LocalAddr() }
+func (c *wsConn) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
+
+func (c *wsConn) SetDeadline(t time.Time) error {
+	if err := c.conn.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.conn.SetWriteDeadline(t)
+}
+
+func (c *wsConn) SetReadDeadline(t time.Time) error  { return c.conn.SetReadDeadline(t) }
+func (c *wsConn) SetWriteDeadline(t time.Time) error { return c.conn.SetWriteDeadline(t) }
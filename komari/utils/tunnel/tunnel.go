@@ -0,0 +1,489 @@
+package tunnel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/komari-monitor/komari/database/auditlog"
+	"github.com/komari-monitor/komari/utils"
+	"github.com/komari-monitor/komari/ws"
+)
+
+const (
+	ModeProxy    = "proxy"    // 通过面板的 HTTP 反向代理路径访问
+	ModeListener = "listener" // 在面板服务器上临时监听一个 TCP 端口
+)
+
+// 等待 Agent 回连并连上目标端口的时间
+const dialTimeout = 15 * time.Second
+
+// ProxyPrefix 反向代理路径前缀，与路由保持一致
+const ProxyPrefix = "/api/admin/tunnel/"
+
+// proxySandbox 代理的节点内容与面板同源，以 sandbox 将页面放入不透明源，
+// 使其脚本无法读取面板的 Cookie 或以面板身份发起同源请求
+const proxySandbox = "sandbox allow-scripts allow-forms allow-popups allow-modals allow-downloads"
+
+var ErrClosed = errors.New("tunnel closed")
+
+// Options 创建隧道的参数
+type Options struct {
+	Client      string
+	Port        int
+	Mode        string
+	UserUUID    string
+	RequesterIp string
+	ListenHost  string        // listener 模式的监听地址
+	Duration    time.Duration // 存活时间
+	IdleTimeout time.Duration // 无连接且无流量多久后关闭
+}
+
+// Info 隧道信息与统计
+type Info struct {
+	ID          string    `json:"id"`
+	Client      string    `json:"client"`
+	Port        int       `json:"port"`
+	Mode        string    `json:"mode"`
+	ListenAddr  string    `json:"listen_addr,omitempty"`
+	ProxyPath   string    `json:"proxy_path,omitempty"`
+	UserUUID    string    `json:"user_uuid"`
+	RequesterIp string    `json:"requester_ip"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	LastActive  time.Time `json:"last_active"`
+	Connections int64     `json:"connections"` // 累计连接数
+	Active      int64     `json:"active"`      // 当前连接数
+	BytesIn     int64     `json:"bytes_in"`    // 节点 -> 面板
+	BytesOut    int64     `json:"bytes_out"`   // 面板 -> 节点
+}
+
+// Tunnel 经由 Agent WebSocket 到节点 127.0.0.1:<port> 的隧道，每个 TCP 连接对应一条 Agent 回连
+type Tunnel struct {
+	info        Info
+	idleTimeout time.Duration
+
+	connections atomic.Int64
+	bytesIn     atomic.Int64
+	bytesOut    atomic.Int64
+	lastActive  atomic.Int64
+
+	listener  net.Listener
+	transport *http.Transport
+	proxy     *httputil.ReverseProxy
+
+	mu     sync.Mutex
+	conns  map[*wsConn]struct{}
+	closed bool
+	done   chan struct{}
+}
+
+type pendingDial struct {
+	tunnel *Tunnel
+	ch     chan *websocket.Conn
+}
+
+var (
+	mu      sync.Mutex
+	tunnels = map[string]*Tunnel{}
+	pending = map[string]*pendingDial{}
+)
+
+// Open 创建隧道
+func Open(opts Options) (*Tunnel, error) {
+	if opts.Port < 1 || opts.Port > 65535 {
+		return nil, fmt.Errorf("invalid port: %d", opts.Port)
+	}
+	if opts.Mode == "" {
+		opts.Mode = ModeProxy
+	}
+	if opts.Mode != ModeProxy && opts.Mode != ModeListener {
+		return nil, fmt.Errorf("invalid mode: %s", opts.Mode)
+	}
+	if opts.Duration <= 0 {
+		return nil, fmt.Errorf("invalid duration")
+	}
+	if ws.GetMessageWriter(opts.Client) == nil {
+		return nil, fmt.Errorf("client is offline")
+	}
+	now := time.Now()
+	t := &Tunnel{
+		info: Info{
+			ID:          utils.GenerateRandomString(16),
+			Client:      opts.Client,
+			Port:        opts.Port,
+			Mode:        opts.Mode,
+			UserUUID:    opts.UserUUID,
+			RequesterIp: opts.RequesterIp,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(opts.Duration),
+		},
+		idleTimeout: opts.IdleTimeout,
+		conns:       map[*wsConn]struct{}{},
+		done:        make(chan struct{}),
+	}
+	t.lastActive.Store(now.UnixNano())
+
+	switch opts.Mode {
+	case ModeListener:
+		host := opts.ListenHost
+		if host == "" {
+			host = "127.0.0.1"
+		}
+		l, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen: %v", err)
+		}
+		t.listener = l
+		t.info.ListenAddr = l.Addr().String()
+	case ModeProxy:
+		t.info.ProxyPath = ProxyPrefix + t.info.ID + "/proxy/"
+		t.transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return t.Dial(ctx)
+			},
+			MaxIdleConnsPerHost: 4,
+			IdleConnTimeout:     30 * time.Second,
+		}
+		t.proxy = &httputil.ReverseProxy{
+			Director:       t.direct,
+			Transport:      t.transport,
+			ModifyResponse: t.modifyResponse,
+		}
+	}
+
+	mu.Lock()
+	tunnels[t.info.ID] = t
+	mu.Unlock()
+	if t.listener != nil {
+		go t.acceptLoop()
+	}
+	go t.watch()
+	target := t.info.ProxyPath
+	if opts.Mode == ModeListener {
+		target = t.info.ListenAddr
+	}
+	auditlog.Log(opts.RequesterIp, opts.UserUUID, fmt.Sprintf("opened, tunnel id:%s, client:%s, port:%d, %s:%s, expires:%s",
+		t.info.ID, opts.Client, opts.Port, opts.Mode, target, t.info.ExpiresAt.Format(time.RFC3339)), "tunnel")
+	return t, nil
+}
+
+// Get 获取隧道
+func Get(id string) *Tunnel {
+	mu.Lock()
+	defer mu.Unlock()
+	return tunnels[id]
+}
+
+// List 列出所有隧道
+func List() []Info {
+	mu.Lock()
+	list := make([]*Tunnel, 0, len(tunnels))
+	for _, t := range tunnels {
+		list = append(list, t)
+	}
+	mu.Unlock()
+	infos := make([]Info, 0, len(list))
+	for _, t := range list {
+		infos = append(infos, t.Info())
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].CreatedAt.Before(infos[j].CreatedAt) })
+	return infos
+}
+
+// CloseAll 关闭所有隧道，用于服务退出
+func CloseAll() {
+	mu.Lock()
+	list := make([]*Tunnel, 0, len(tunnels))
+	for _, t := range tunnels {
+		list = append(list, t)
+	}
+	mu.Unlock()
+	for _, t := range list {
+		t.Close("server shutdown")
+	}
+}
+
+// Info 返回隧道信息与当前统计
+func (t *Tunnel) Info() Info {
+	info := t.info
+	info.Connections = t.connections.Load()
+	info.BytesIn = t.bytesIn.Load()
+	info.BytesOut = t.bytesOut.Load()
+	info.LastActive = time.Unix(0, t.lastActive.Load())
+	t.mu.Lock()
+	info.Active = int64(len(t.conns))
+	t.mu.Unlock()
+	return info
+}
+
+// Close 关闭隧道及其所有连接
+func (t *Tunnel) Close(reason string) {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return
+	}
+	t.closed = true
+	close(t.done)
+	conns := make([]*wsConn, 0, len(t.conns))
+	for c := range t.conns {
+		conns = append(conns, c)
+	}
+	t.mu.Unlock()
+
+	if t.listener != nil {
+		t.listener.Close()
+	}
+	if t.transport != nil {
+		t.transport.CloseIdleConnections()
+	}
+	for _, c := range conns {
+		c.Close()
+	}
+	mu.Lock()
+	delete(tunnels, t.info.ID)
+	mu.Unlock()
+
+	info := t.Info()
+	auditlog.Log(info.RequesterIp, info.UserUUID, fmt.Sprintf("closed (%s), tunnel id:%s, duration:%s, connections:%d, in:%d bytes, out:%d bytes",
+		reason, info.ID, time.Since(info.CreatedAt).Round(time.Second), info.Connections, info.BytesIn, info.BytesOut), "tunnel")
+}
+
+// Dial 请求 Agent 回连并连接到目标端口
+func (t *Tunnel) Dial(ctx context.Context) (net.Conn, error) {
+	t.mu.Lock()
+	closed := t.closed
+	t.mu.Unlock()
+	if closed {
+		return nil, ErrClosed
+	}
+	agent := ws.GetMessageWriter(t.info.Client)
+	if agent == nil {
+		return nil, fmt.Errorf("client is offline")
+	}
+	requestID := utils.GenerateRandomString(32)
+	p := &pendingDial{tunnel: t, ch: make(chan *websocket.Conn, 1)}
+	mu.Lock()
+	pending[requestID] = p
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		delete(pending, requestID)
+		mu.Unlock()
+		// 超时后才到达的回连直接关闭
+		select {
+		case conn := <-p.ch:
+			conn.Close()
+		default:
+		}
+	}()
+
+	if err := agent.WriteJSON(map[string]any{
+		"message":    "tunnel",
+		"request_id": requestID,
+		"port":       t.info.Port,
+	}); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(dialTimeout)
+	defer timer.Stop()
+	var conn *websocket.Conn
+	select {
+	case conn = <-p.ch:
+	case <-timer.C:
+		return nil, fmt.Errorf("timeout waiting for agent")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-t.done:
+		return nil, ErrClosed
+	}
+
+	// Agent 先回复一条文本消息告知是否连上目标端口
+	conn.SetReadDeadline(time.Now().Add(dialTimeout))
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	messageType, data, err := conn.ReadMessage()
+	if err == nil && (messageType != websocket.TextMessage || json.Unmarshal(data, &status) != nil) {
+		err = fmt.Errorf("invalid agent response")
+	}
+	if err == nil && !status.OK {
+		err = fmt.Errorf("agent failed to connect: %s", status.Error)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	var c *wsConn
+	c = newWSConn(conn, t.onData, func() {
+		t.mu.Lock()
+		delete(t.conns, c)
+		t.mu.Unlock()
+		t.touch()
+	})
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		conn.Close()
+		return nil, ErrClosed
+	}
+	t.conns[c] = struct{}{}
+	t.mu.Unlock()
+	t.connections.Add(1)
+	t.touch()
+	return c, nil
+}
+
+// Accept 交付 Agent 的回连，client 为回连所用 token 对应的节点
+func Accept(requestID, client string, conn *websocket.Conn) error {
+	mu.Lock()
+	defer mu.Unlock()
+	p, ok := pending[requestID]
+	if !ok {
+		return fmt.Errorf("tunnel request not found")
+	}
+	if p.tunnel.info.Client != client {
+		return fmt.Errorf("tunnel request does not belong to this client")
+	}
+	// 在锁内交付，Dial 放弃等待后会在同一把锁之后清理
+	delete(pending, requestID)
+	p.ch <- conn
+	return nil
+}
+
+// ServeProxy 以反向代理方式转发请求，path 为代理前缀之后的路径
+func (t *Tunnel) ServeProxy(w http.ResponseWriter, r *http.Request, path string) {
+	if t.proxy == nil {
+		http.Error(w, "tunnel is not in proxy mode", http.StatusBadRequest)
+		return
+	}
+	r = r.Clone(r.Context())
+	r.URL.Path = "/" + strings.TrimPrefix(path, "/")
+	r.URL.RawPath = ""
+	t.touch()
+	t.proxy.ServeHTTP(w, r)
+}
+
+func (t *Tunnel) direct(r *http.Request) {
+	host := "127.0.0.1:" + strconv.Itoa(t.info.Port)
+	r.URL.Scheme = "http"
+	r.URL.Host = host
+	r.Host = host
+	// 不把面板的登录态与 API 令牌转发给节点上的服务
+	r.Header.Del("Authorization")
+	if cookies := r.Cookies(); len(cookies) > 0 {
+		r.Header.Del("Cookie")
+		for _, c := range cookies {
+			if c.Name != "session_token" {
+				r.AddCookie(c)
+			}
+		}
+	}
+}
+
+// modifyResponse 隔离节点返回的内容，并丢弃 Set-Cookie 以免覆盖面板的 Cookie
+func (t *Tunnel) modifyResponse(resp *http.Response) error {
+	resp.Header.Del("Set-Cookie")
+	// 追加而非覆盖，节点自身的 CSP 仍然生效
+	resp.Header.Add("Content-Security-Policy", proxySandbox)
+	return t.rewriteLocation(resp)
+}
+
+// rewriteLocation 将重定向到站内绝对路径的 Location 改写到代理路径下
+func (t *Tunnel) rewriteLocation(resp *http.Response) error {
+	loc := resp.Header.Get("Location")
+	if strings.HasPrefix(loc, "/") && !strings.HasPrefix(loc, "//") {
+		resp.Header.Set("Location", strings.TrimSuffix(t.info.ProxyPath, "/")+loc)
+	}
+	return nil
+}
+
+func (t *Tunnel) acceptLoop() {
+	for {
+		c, err := t.listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer c.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+			remote, err := t.Dial(ctx)
+			cancel()
+			if err != nil {
+				log.Printf("Tunnel %s: %v", t.info.ID, err)
+				return
+			}
+			pipe(c, remote)
+		}()
+	}
+}
+
+func pipe(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	copyConn := func(dst, src net.Conn) {
+		io.Copy(dst, src)
+		done <- struct{}{}
+	}
+	go copyConn(a, b)
+	go copyConn(b, a)
+	<-done
+	a.Close()
+	b.Close()
+	<-done
+}
+
+func (t *Tunnel) onData(in, out int) {
+	if in > 0 {
+		t.bytesIn.Add(int64(in))
+	}
+	if out > 0 {
+		t.bytesOut.Add(int64(out))
+	}
+	t.touch()
+}
+
+func (t *Tunnel) touch() {
+	t.lastActive.Store(time.Now().UnixNano())
+}
+
+// watch 到期或空闲超时后关闭隧道
+func (t *Tunnel) watch() {
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.done:
+			return
+		case now := <-ticker.C:
+			if now.After(t.info.ExpiresAt) {
+				t.Close("expired")
+				return
+			}
+			t.mu.Lock()
+			active := len(t.conns)
+			t.mu.Unlock()
+			if t.idleTimeout > 0 && active == 0 && now.Sub(time.Unix(0, t.lastActive.Load())) > t.idleTimeout {
+				t.Close("idle timeout")
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package tunnel
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWSConnRoundTrip(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// 模拟 Agent：文本帧应被忽略，binary 帧原样回显后正常关闭
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"ok":true}`))
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		conn.WriteMessage(websocket.BinaryMessage, data)
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	}))
+	defer srv.Close()
+
+	raw, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var in, out atomic.Int64
+	var closed atomic.Bool
+	c := newWSConn(raw, func(i, o int) {
+		in.Add(int64(i))
+		out.Add(int64(o))
+	}, func() { closed.Store(true) })
+
+	if _, err := c.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(c)
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("unexpected read %q, %v", data, err)
+	}
+	if in.Load() != 5 || out.Load() != 5 {
+		t.Fatalf("unexpected counters in=%d out=%d", in.Load(), out.Load())
+	}
+	c.Close()
+	c.Close()
+	if !closed.Load() {
+		t.Fatal("onClose should be called")
+	}
+}
+
+func TestRewriteLocation(t *testing.T) {
+	tun := &Tunnel{info: Info{ProxyPath: ProxyPrefix + "abc/proxy/"}}
+	cases := map[string]string{
+		"/login?next=/":        ProxyPrefix + "abc/proxy/login?next=/",
+		"//example.com/a":      "//example.com/a",
+		"http://example.com/a": "http://example.com/a",
+		"relative":             "relative",
+	}
+	for loc, want := range cases {
+		resp := &http.Response{Header: http.Header{"Location": {loc}}}
+		tun.rewriteLocation(resp)
+		if got := resp.Header.Get("Location"); got != want {
+			t.Errorf("%s: expected %s, got %s", loc, want, got)
+		}
+	}
+}
+
+func TestDirectStripsCredentials(t *testing.T) {
+	tun := &Tunnel{info: Info{Port: 8080}}
+	r := httptest.NewRequest(http.MethodGet, "http://panel.example/api/admin/tunnel/abc/proxy/", nil)
+	r.Header.Set("Authorization", "Bearer kmt_secret")
+	r.AddCookie(&http.Cookie{Name: "session_token", Value: "secret"})
+	r.AddCookie(&http.Cookie{Name: "app", Value: "1"})
+	tun.direct(r)
+	if r.URL.Host != "127.0.0.1:8080" || r.Host != "127.0.0.1:8080" {
+		t.Errorf("unexpected target %s, host %s", r.URL.Host, r.Host)
+	}
+	if got := r.Header.Get("Authorization"); got != "" {
+		t.Errorf("authorization should be stripped, got %s", got)
+	}
+	if _, err := r.Cookie("session_token"); err == nil {
+		t.Error("session cookie should be stripped")
+	}
+	if c, err := r.Cookie("app"); err != nil || c.Value != "1" {
+		t.Error("other cookies should be kept")
+	}
+}
+
+func TestModifyResponse(t *testing.T) {
+	tun := &Tunnel{info: Info{ProxyPath: ProxyPrefix + "abc/proxy/"}}
+	resp := &http.Response{Header: http.Header{
+		"Set-Cookie":              {"session_token=evil; Path=/"},
+		"Content-Security-Policy": {"default-src 'self'"},
+		"Location":                {"/login"},
+	}}
+	if err := tun.modifyResponse(resp); err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Header.Values("Set-Cookie"); len(got) != 0 {
+		t.Errorf("Set-Cookie should be dropped, got %v", got)
+	}
+	csp := resp.Header.Values("Content-Security-Policy")
+	if len(csp) != 2 || csp[0] != "default-src 'self'" || !strings.HasPrefix(csp[1], "sandbox") || strings.Contains(csp[1], "allow-same-origin") {
+		t.Errorf("unexpected CSP %v", csp)
+	}
+	if got := resp.Header.Get("Location"); got != ProxyPrefix+"abc/proxy/login" {
+		t.Errorf("unexpected location %s", got)
+	}
+}
@@ -49,7 +49,7 @@ func AverageReport(uuid string, time time.Time, records []common.Report, topPerc
 		}
 	}
 
-	var sumCPU, sumLOAD, sumGPU float32
+	var sumCPU, sumLOAD, sumGPU, sumTEMP float32
 	var sumRAM, sumRAMTotal, sumSWAP, sumSWAPTotal, sumDISK, sumDISKTotal, sumNETIn, sumNETOut, sumNETTotalUp, sumNETTotalDown int64
 	var sumPROCESS, sumConnections, sumConnectionsUDP int
 
@@ -62,6 +62,7 @@ func AverageReport(uuid string, time time.Time, records []common.Report, topPerc
 			}
 			return 0
 		}, nil, true)
+		sumTEMP, _ = sumAndSort(func(r common.Report) float32 { return float32(r.Temperature.Primary()) }, nil, true)
 
 		_, sumRAM = sumAndSort(nil, func(r common.Report) int64 { return r.Ram.Used }, false)
 		//_, sumRAMTotal = sumAndSort(nil, nil, func(r common.Report) int64 { return r.Ram.Total }, false)
@@ -88,6 +89,7 @@ func AverageReport(uuid string, time time.Time, records []common.Report, topPerc
 			if r.GPU != nil {
 				sumGPU += float32(r.GPU.AverageUsage)
 			}
+			sumTEMP += float32(r.Temperature.Primary())
 			sumRAM += r.Ram.Used
 			sumRAMTotal += r.Ram.Total
 			sumSWAP += r.Swap.Used
@@ -115,7 +117,7 @@ func AverageReport(uuid string, time time.Time, records []common.Report, topPerc
 		Swap:           sumSWAP / int64(recordsToAverageCount),
 		SwapTotal:      records[0].Swap.Total,
 		Load:           sumLOAD / float32(recordsToAverageCount),
-		Temp:           0, // 保持原始行为
+		Temp:           sumTEMP / float32(recordsToAverageCount),
 		Disk:           sumDISK / int64(recordsToAverageCount),
 		DiskTotal:      records[0].Disk.Total,
 		NetIn:          sumNETIn / int64(recordsToAverageCount),
@@ -259,6 +261,150 @@ func AverageGPUReports(uuid string, time time.Time, reports []common.Report, top
 	return result
 }
 
+// AverageTemperatureReports 按传感器聚合温度，取值方式与 AverageReport 一致
+func AverageTemperatureReports(uuid string, time time.Time, reports []common.Report, topPercentage float64) []models.TemperatureRecord {
+	sensors := make(map[string][]float64)
+	var order []string
+	for _, report := range reports {
+		if report.Temperature == nil {
+			continue
+		}
+		for _, s := range report.Temperature.Sensors {
+			if _, ok := sensors[s.Name]; !ok {
+				order = append(order, s.Name)
+			}
+			sensors[s.Name] = append(sensors[s.Name], s.Temperature)
+		}
+	}
+
+	result := make([]models.TemperatureRecord, 0, len(order))
+	for _, name := range order {
+		result = append(result, models.TemperatureRecord{
+			Client:      uuid,
+			Time:        models.FromTime(time),
+			Sensor:      name,
+			Temperature: float32(topAverage(sensors[name], topPercentage)),
+		})
+	}
+	return result
+}
+
+// topAverage 取最高的 topPercentage 部分求平均，topPercentage 不在 (0,1] 时取全部
+func topAverage(values []float64, topPercentage float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	count := len(values)
+	if topPercentage > 0 && topPercentage <= 1 {
+		count = int(float64(len(values)) * topPercentage)
+		if count == 0 {
+			count = 1
+		}
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(values)))
+	var sum float64
+	for _, v := range values[:count] {
+		sum += v
+	}
+	return sum / float64(count)
+}
+
+// AverageDiskReports 挂载点容量取最新一次上报；块设备速率按设备聚合，取值方式与 AverageReport 一致
+func AverageDiskReports(uuid string, time time.Time, reports []common.Report, topPercentage float64) []models.DiskRecord {
+	var result []models.DiskRecord
+	for i := len(reports) - 1; i >= 0; i-- {
+		if len(reports[i].Mounts) == 0 {
+			continue
+		}
+		for _, m := range reports[i].Mounts {
+			result = append(result, models.DiskRecord{
+				Client:      uuid,
+				Time:        models.FromTime(time),
+				Kind:        models.DiskRecordMount,
+				Name:        m.Mountpoint,
+				Total:       m.Total,
+				Used:        m.Used,
+				InodesTotal: m.InodesTotal,
+				InodesUsed:  m.InodesUsed,
+			})
+		}
+		break
+	}
+
+	type ioData struct {
+		ReadBytes, WriteBytes, ReadIops, WriteIops []float64
+	}
+	devices := make(map[string]*ioData)
+	var order []string
+	for _, report := range reports {
+		for _, d := range report.DiskIO {
+			data, ok := devices[d.Device]
+			if !ok {
+				data = &ioData{}
+				devices[d.Device] = data
+				order = append(order, d.Device)
+			}
+			data.ReadBytes = append(data.ReadBytes, float64(d.ReadBytes))
+			data.WriteBytes = append(data.WriteBytes, float64(d.WriteBytes))
+			data.ReadIops = append(data.ReadIops, d.ReadIOPS)
+			data.WriteIops = append(data.WriteIops, d.WriteIOPS)
+		}
+	}
+	for _, name := range order {
+		data := devices[name]
+		result = append(result, models.DiskRecord{
+			Client:     uuid,
+			Time:       models.FromTime(time),
+			Kind:       models.DiskRecordIO,
+			Name:       name,
+			ReadBytes:  int64(topAverage(data.ReadBytes, topPercentage)),
+			WriteBytes: int64(topAverage(data.WriteBytes, topPercentage)),
+			ReadIops:   float32(topAverage(data.ReadIops, topPercentage)),
+			WriteIops:  float32(topAverage(data.WriteIops, topPercentage)),
+		})
+	}
+	return result
+}
+
+// AverageNetworkReports 按网卡聚合速率，取值方式与 AverageReport 一致；累计计数器取最新一次上报
+func AverageNetworkReports(uuid string, time time.Time, reports []common.Report, topPercentage float64) []models.NetworkRecord {
+	type nicData struct {
+		Up, Down           []float64
+		TotalUp, TotalDown int64
+	}
+	nics := make(map[string]*nicData)
+	var order []string
+	for _, report := range reports {
+		for _, nic := range report.Interfaces {
+			data, ok := nics[nic.Name]
+			if !ok {
+				data = &nicData{}
+				nics[nic.Name] = data
+				order = append(order, nic.Name)
+			}
+			data.Up = append(data.Up, float64(nic.Up))
+			data.Down = append(data.Down, float64(nic.Down))
+			data.TotalUp = nic.TotalUp
+			data.TotalDown = nic.TotalDown
+		}
+	}
+
+	result := make([]models.NetworkRecord, 0, len(order))
+	for _, name := range order {
+		data := nics[name]
+		result = append(result, models.NetworkRecord{
+			Client:    uuid,
+			Time:      models.FromTime(time),
+			Interface: name,
+			Up:        int64(topAverage(data.Up, topPercentage)),
+			Down:      int64(topAverage(data.Down, topPercentage)),
+			TotalUp:   data.TotalUp,
+			TotalDown: data.TotalDown,
+		})
+	}
+	return result
+}
+
 func DataMasking(str string, private []string) string {
 	if str == "" || len(private) == 0 {
 		return str
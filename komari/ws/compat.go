@@ -0,0 +1,58 @@
+package ws
+
+import "sync"
+
+// MessageWriter 下行消息写入接口，消息格式与 Agent WebSocket 协议一致
+type MessageWriter interface {
+	WriteJSON(v interface{}) error
+}
+
+var (
+	// compatWriters 保存非 WebSocket 通道（如 Nezha gRPC）的下行写入器
+	compatWriters   = make(map[string]MessageWriter)
+	compatWritersMu sync.RWMutex
+)
+
+func SetCompatWriter(uuid string, w MessageWriter) {
+	compatWritersMu.Lock()
+	defer compatWritersMu.Unlock()
+	compatWriters[uuid] = w
+}
+
+// DeleteCompatWriterConditionally 仅当当前写入器就是 w 时才删除，避免误删重连后的新通道
+func DeleteCompatWriterConditionally(uuid string, w MessageWriter) {
+	compatWritersMu.Lock()
+	defer compatWritersMu.Unlock()
+	if cur, ok := compatWriters[uuid]; ok && cur == w {
+		delete(compatWriters, uuid)
+	}
+}
+
+// GetMessageWriter 返回节点的下行通道，优先使用 WebSocket 连接
+func GetMessageWriter(uuid string) MessageWriter {
+	if conn := GetConnectedClients()[uuid]; conn != nil {
+		return conn
+	}
+	compatWritersMu.RLock()
+	defer compatWritersMu.RUnlock()
+	if w, ok := compatWriters[uuid]; ok && w != nil {
+		return w
+	}
+	return nil
+}
+
+// GetAllMessageWriters 返回所有可下发任务的节点，WebSocket 连接优先
+func GetAllMessageWriters() map[string]MessageWriter {
+	res := make(map[string]MessageWriter)
+	compatWritersMu.RLock()
+	for k, v := range compatWriters {
+		res[k] = v
+	}
+	compatWritersMu.RUnlock()
+	for k, v := range GetConnectedClients() {
+		if v != nil {
+			res[k] = v
+		}
+	}
+	return res
+}